@@ -3,6 +3,8 @@
 #include <string>
 #include <memory>
 #include <fstream>
+#include <vector>
+#include <climits>
 #include "LogAVLTree.hpp"
 
 class AVLTreeInterface {
@@ -12,6 +14,11 @@ private:
     int tree_size;
     bool interactive_mode;
     
+    // Tree options, applied on every init
+    bool allow_duplicates;
+    std::vector<int> initial_values;
+    bool verbose_rotations;
+    
     // Separate output streams
     std::ostream* program_out;    // For program messages
     std::ostream* tree_log_out;   // For tree operation logs
@@ -43,7 +50,7 @@ private:
     
     void showStatus() {
         *program_out << "STATUS tree_size=" << tree_size 
-                     << " type=AVL" << std::endl;
+                     << " type=AVL duplicates=" << (allow_duplicates ? "allow" : "reject") << std::endl;
     }
     
     void clearLogs() {
@@ -69,9 +76,8 @@ private:
             return;
         }
         
-        // Check if value already exists
-        bool already_exists = tree->exist_in_tree(value);
-        if (already_exists) {
+        // Check if value already exists; duplicates go to the right of their equal when allowed
+        if (!allow_duplicates && tree->exist_in_tree(value)) {
             *program_out << "INSERT_DUPLICATE value=" << value << " size=" << tree_size << std::endl;
             return;
         }
@@ -159,7 +165,7 @@ private:
         }
         
         *program_out << "TREE_INORDER_START" << std::endl;
-        tree->inorder();
+        tree->inorder(*program_out);
         *program_out << "TREE_INORDER_END" << std::endl;
     }
     
@@ -170,7 +176,7 @@ private:
         }
         
         *program_out << "TREE_STRUCTURE_START" << std::endl;
-        tree->printTreeStructure();
+        tree->printTreeStructure(*program_out);
         *program_out << "TREE_STRUCTURE_END" << std::endl;
     }
     
@@ -243,16 +249,33 @@ private:
     
     void initTree() {
         tree = std::make_unique<datas::LogAVLTree<int>>(log_stream);
+        tree->setVerboseRotations(verbose_rotations);
         tree_size = 0;
         log_stream.str("");
         log_stream.clear();
         
+        // Build the initial values, forwarding their logs so clients see the tree form
+        for (int value : initial_values) {
+            if (!allow_duplicates && tree->exist_in_tree(value)) {
+                continue;
+            }
+            tree->insert(value);
+            tree_size++;
+        }
+        
         *program_out << "INIT_SUCCESS type=AVL size=" << tree_size << std::endl;
+        std::string new_logs = log_stream.str();
+        if (!new_logs.empty()) {
+            *tree_log_out << new_logs;
+            tree_log_out->flush();
+        }
     }
 
 public:
-    AVLTreeInterface(bool interactive = true) 
+    AVLTreeInterface(bool allow_dups = false, const std::vector<int>& initial = {},
+                     bool verbose = false, bool interactive = true) 
         : tree_size(0), interactive_mode(interactive),
+          allow_duplicates(allow_dups), initial_values(initial), verbose_rotations(verbose),
           program_out(&std::cout), tree_log_out(&std::cout) {
         // Tree will be initialized after streams are set
     }
@@ -328,7 +351,29 @@ public:
     }
 };
 
+// Parses a comma-separated list of integers such as "5,3,8"
+bool parseValueList(const std::string& list, std::vector<int>& values) {
+    std::istringstream iss(list);
+    std::string item;
+    while (std::getline(iss, item, ',')) {
+        try {
+            size_t used = 0;
+            long value = std::stol(item, &used);
+            if (used != item.size() || value < INT_MIN || value > INT_MAX) {
+                return false;
+            }
+            values.push_back(static_cast<int>(value));
+        } catch (const std::exception&) {
+            return false;
+        }
+    }
+    return !values.empty();
+}
+
 int main(int argc, char* argv[]) {
+    bool allow_duplicates = false;
+    std::vector<int> initial_values;
+    bool verbose_rotations = false;
     bool interactive = true;
     std::string program_output = "stdout";
     std::string tree_log_output = "stdout";
@@ -336,7 +381,30 @@ int main(int argc, char* argv[]) {
     // Parse command line arguments
     for (int i = 1; i < argc; i++) {
         std::string arg = argv[i];
-        if (arg == "--batch") {
+        if (arg == "--duplicates" && i + 1 < argc) {
+            std::string policy = argv[++i];
+            if (policy != "reject" && policy != "allow") {
+                std::cerr << "Error: Duplicates must be reject or allow" << std::endl;
+                return 1;
+            }
+            allow_duplicates = policy == "allow";
+        }
+        else if (arg == "--initial" && i + 1 < argc) {
+            initial_values.clear();
+            if (!parseValueList(argv[++i], initial_values)) {
+                std::cerr << "Error: Initial values must be a comma-separated list of integers" << std::endl;
+                return 1;
+            }
+        }
+        else if (arg == "--verbose-rotations" && i + 1 < argc) {
+            std::string toggle = argv[++i];
+            if (toggle != "on" && toggle != "off") {
+                std::cerr << "Error: Verbose rotations must be on or off" << std::endl;
+                return 1;
+            }
+            verbose_rotations = toggle == "on";
+        }
+        else if (arg == "--batch") {
             interactive = false;
         }
         else if (arg == "--program-out" && i + 1 < argc) {
@@ -345,14 +413,23 @@ int main(int argc, char* argv[]) {
         else if (arg == "--tree-log-out" && i + 1 < argc) {
             tree_log_output = argv[++i];
         }
+        else if (arg == "--version") {
+            std::cout << "avltreeInterface 1.0" << std::endl;
+            return 0;
+        }
         else if (arg == "--help") {
             std::cout << "Usage: " << argv[0] << " [options]\n";
             std::cout << "Options:\n";
+            std::cout << "  --duplicates <policy> reject (default) or allow equal values as separate nodes\n";
+            std::cout << "  --initial <values>    Comma-separated values inserted on every init, e.g. 5,3,8\n";
+            std::cout << "  --verbose-rotations <on|off>\n";
+            std::cout << "                        Log the imbalance case before each rotation (default: off)\n";
             std::cout << "  --batch               Run in batch mode (no interactive prompts)\n";
             std::cout << "  --program-out <file>  Program output destination:\n";
             std::cout << "                        stdout (default), stderr, null, or filename\n";
             std::cout << "  --tree-log-out <file> Tree log output destination:\n";
             std::cout << "                        stdout (default), stderr, null, or filename\n";
+            std::cout << "  --version             Show version and exit\n";
             std::cout << "  --help                Show this help\n";
             std::cout << "\nCommands:\n";
             std::cout << "  init             - Initialize new tree\n";
@@ -386,7 +463,7 @@ int main(int argc, char* argv[]) {
     }
     
     try {
-        AVLTreeInterface interface(interactive);
+        AVLTreeInterface interface(allow_duplicates, initial_values, verbose_rotations, interactive);
         
         // Configure output streams
         interface.setProgramOutput(program_output);
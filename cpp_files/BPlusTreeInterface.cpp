@@ -0,0 +1,153 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogBPlusTree.hpp"
+
+class BPlusTreeInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogBPlusTree<int>> tree;
+    int order;
+    bool leaf_links;
+
+    std::string settings() const {
+        return "order=" + std::to_string(order) + " leaf_links=" + (leaf_links ? "on" : "off");
+    }
+
+protected:
+    std::string title() const override { return "B+ Tree"; }
+
+    std::string readyLine() const override {
+        return "READY type=BPLUS " + settings();
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>  - Insert a value\n";
+        *program_out << "  remove <value>  - Remove a value\n";
+        *program_out << "  find <value>    - Search for a value\n";
+        *program_out << "  scan <a> <b>    - List the values from a to b\n";
+        *program_out << "  print           - Display the tree\n";
+        *program_out << "  leaves          - Display the leaves left to right\n";
+        *program_out << "  size            - Show tree size\n";
+        *program_out << "  order           - Show tree order\n";
+        *program_out << "  status          - Show tree status\n";
+    }
+
+    void initStructure() override {
+        tree = std::make_unique<datas::LogBPlusTree<int>>(order, leaf_links, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=BPLUS " << settings() << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (tree->insert(value)) {
+                *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << value << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (tree->remove(value)) {
+                *program_out << "REMOVE_SUCCESS value=" << value << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << value << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool found = tree->find(value);
+            *program_out << "FIND_RESULT value=" << value << " found=" << (found ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "scan") {
+            int from, to;
+            if (!(iss >> from >> to) || to < from) {
+                *program_out << "ERROR invalid_scan_syntax usage=scan_<from>_<to>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            size_t leaves;
+            std::vector<int> keys = tree->scan(from, to, leaves);
+            *program_out << "SCAN_RESULT from=" << from << " to=" << to << " count=" << keys.size()
+                         << " leaves=" << leaves << " keys=";
+            for (size_t i = 0; i < keys.size(); i++) {
+                *program_out << (i ? "," : "") << keys[i];
+            }
+            *program_out << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "TREE_START" << std::endl;
+            tree->printTree(*program_out);
+            *program_out << "TREE_END" << std::endl;
+        }
+        else if (command == "leaves") {
+            *program_out << "LEAVES_START" << std::endl;
+            tree->printLeaves(*program_out);
+            *program_out << "LEAVES_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << tree->size() << std::endl;
+        }
+        else if (command == "order") {
+            *program_out << "ORDER " << order << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS tree_size=" << tree->size() << " type=BPLUS height=" << tree->height()
+                         << " " << settings() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    BPlusTreeInterface(int tree_order, bool links, bool interactive = true)
+        : DataInterface(interactive), order(tree_order), leaf_links(links) {}
+};
+
+int main(int argc, char* argv[]) {
+    int order = 4;
+    bool leaf_links = true;
+    return runDataInterface(argc, argv, "bplustreeInterface 1.0",
+        "  --order <n>               Most children of a node (default: 4, minimum: 3)\n"
+        "  --leaf-links <on|off>     Chain the leaves for range scans (default: on)\n",
+        [&](bool interactive) { return std::make_unique<BPlusTreeInterface>(order, leaf_links, interactive); },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--order" && i + 1 < argc) {
+                order = std::atoi(argv[++i]);
+                if (order < 3) {
+                    std::cerr << "Error: Order must be >= 3" << std::endl;
+                    return false;
+                }
+            }
+            else if (arg == "--leaf-links" && i + 1 < argc) {
+                std::string value = argv[++i];
+                if (value != "on" && value != "off") {
+                    std::cerr << "Error: Leaf links must be on or off" << std::endl;
+                    return false;
+                }
+                leaf_links = value == "on";
+            }
+            return true;
+        });
+}
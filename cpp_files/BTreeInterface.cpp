@@ -209,8 +209,9 @@ private:
             clearLogs();
         }
         else if (command == "init") {
-            int new_order;
-            if (iss >> new_order && new_order >= 3) {
+            // A bare init empties the tree and keeps its order
+            int new_order = order;
+            if ((iss >> std::ws).eof() || (iss >> new_order && new_order >= 3)) {
                 initTree(new_order);
             } else {
                 *program_out << "ERROR invalid_init_syntax usage=init_<order> order_must_be_>=3" << std::endl;
@@ -339,6 +340,10 @@ int main(int argc, char* argv[]) {
         else if (arg == "--tree-log-out" && i + 1 < argc) {
             tree_log_output = argv[++i];
         }
+        else if (arg == "--version") {
+            std::cout << "btreeInterface 1.0" << std::endl;
+            return 0;
+        }
         else if (arg == "--help") {
             std::cout << "Usage: " << argv[0] << " [options]\n";
             std::cout << "Options:\n";
@@ -348,9 +353,10 @@ int main(int argc, char* argv[]) {
             std::cout << "                        stdout (default), stderr, null, or filename\n";
             std::cout << "  --tree-log-out <file> Tree log output destination:\n";
             std::cout << "                        stdout (default), stderr, null, or filename\n";
+            std::cout << "  --version             Show version and exit\n";
             std::cout << "  --help                Show this help\n";
             std::cout << "\nCommands:\n";
-            std::cout << "  init <order>     - Initialize new tree with given order\n";
+            std::cout << "  init [order]     - Initialize new tree (default: current order)\n";
             std::cout << "  insert <value>   - Insert a value\n";
             std::cout << "  remove <value>   - Remove a value\n";
             std::cout << "  find <value>     - Search for a value\n";
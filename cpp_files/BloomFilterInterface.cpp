@@ -0,0 +1,114 @@
+#include <cstdlib>
+#include <iomanip>
+#include "DataInterface.hpp"
+#include "LogBloomFilter.hpp"
+
+class BloomFilterInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogBloomFilter<int>> filter;
+    size_t bits;
+    size_t hashes;
+
+protected:
+    std::string title() const override { return "Bloom Filter"; }
+
+    std::string readyLine() const override {
+        return "READY type=BLOOM bits=" + std::to_string(bits) + " hashes=" + std::to_string(hashes);
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <key>     - Set the bits of a key\n";
+        *program_out << "  find <key>       - Check whether a key may have been inserted (alias: contains)\n";
+        *program_out << "  print            - Display the bit array rows that have bits set\n";
+        *program_out << "  size             - Show how many keys were inserted\n";
+        *program_out << "  status           - Show filter status\n";
+    }
+
+    void initStructure() override {
+        filter = std::make_unique<datas::LogBloomFilter<int>>(bits, hashes, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=BLOOM bits=" << bits << " hashes=" << hashes << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int key;
+        if (command == "insert") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            size_t newly_set = filter->insert(key);
+            *program_out << "INSERT_SUCCESS value=" << key << " new_size=" << filter->insertedCount()
+                         << " bits_set=" << newly_set << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search" || command == "contains") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool maybe = filter->mightContain(key);
+            *program_out << "FIND_RESULT value=" << key << " found=" << (maybe ? "maybe" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            // Clearing a key's bits would also clear them for every key sharing one
+            *program_out << "ERROR unsupported_remove type=BLOOM" << std::endl;
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "BITS_START" << std::endl;
+            filter->printBits(*program_out);
+            *program_out << "BITS_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << filter->insertedCount() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS filter_size=" << filter->insertedCount() << " type=BLOOM bits=" << filter->bitCount()
+                         << " hashes=" << filter->hashCount() << " bits_set=" << filter->setBits()
+                         << " fp_rate=" << std::setprecision(4) << filter->currentFalsePositiveRate() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    BloomFilterInterface(size_t bit_count, size_t hash_count, bool interactive = true)
+        : DataInterface(interactive), bits(bit_count), hashes(hash_count) {}
+};
+
+int main(int argc, char* argv[]) {
+    // The server sizes the filter from expected_items and fp_rate; these defaults
+    // are that sizing for 100 items at a 1% false-positive rate
+    int bits = 959;
+    int hashes = 7;
+    return runDataInterface(argc, argv, "bloomfilterInterface 1.0",
+        "  --bits <m>            Bits in the filter (default: 959, 8 to 4194304)\n"
+        "  --hashes <k>          Hash functions per key (default: 7, 1 to 32)\n",
+        [&](bool interactive) {
+            return std::make_unique<BloomFilterInterface>(static_cast<size_t>(bits), static_cast<size_t>(hashes), interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--bits" && i + 1 < argc) {
+                bits = std::atoi(argv[++i]);
+                if (bits < 8 || bits > 4194304) {
+                    std::cerr << "Error: Bits must be between 8 and 4194304" << std::endl;
+                    return false;
+                }
+            }
+            else if (arg == "--hashes" && i + 1 < argc) {
+                hashes = std::atoi(argv[++i]);
+                if (hashes < 1 || hashes > 32) {
+                    std::cerr << "Error: Hashes must be between 1 and 32" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
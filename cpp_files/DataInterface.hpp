@@ -0,0 +1,229 @@
+#ifndef DATA_INTERFACE_HPP
+#define DATA_INTERFACE_HPP
+
+#include <iostream>
+#include <sstream>
+#include <string>
+#include <memory>
+#include <fstream>
+
+// Shared plumbing of the batch interfaces: output streams, log forwarding,
+// the commands every structure answers and the command loop.
+// A structure implements initStructure, handleCommand and printCommands.
+class DataInterface {
+protected:
+    std::ostringstream log_stream;
+    bool interactive_mode;
+
+    // Separate output streams
+    std::ostream* program_out;    // For program messages
+    std::ostream* tree_log_out;   // For tree operation logs
+
+    // For file handling
+    std::unique_ptr<std::ofstream> program_file;
+    std::unique_ptr<std::ofstream> tree_log_file;
+
+    // Title shown in interactive mode, e.g. "Red-Black Tree"
+    virtual std::string title() const = 0;
+    // Line announcing batch mode, e.g. "READY type=RB"
+    virtual std::string readyLine() const = 0;
+    // Menu lines of the structure's own commands
+    virtual void printCommands() = 0;
+    // (Re)creates an empty structure and prints INIT_SUCCESS
+    virtual void initStructure() = 0;
+    // Runs a structure command; returns false if the command is unknown
+    virtual bool handleCommand(const std::string& command, std::istringstream& iss) = 0;
+
+    // Current end of the log history
+    size_t logMark() const {
+        return log_stream.str().length();
+    }
+
+    // Send the logs written since mark to the tree log stream
+    void forwardLogs(size_t mark) {
+        std::string new_logs = log_stream.str().substr(mark);
+        if (!new_logs.empty()) {
+            *tree_log_out << new_logs;
+            tree_log_out->flush();
+        }
+    }
+
+    void printMenu() {
+        if (interactive_mode) {
+            *program_out << "\n=== " << title() << " Interface ===\n";
+            *program_out << "Commands:\n";
+            printCommands();
+            *program_out << "  logs            - Show operation logs\n";
+            *program_out << "  clear_logs      - Clear operation logs\n";
+            *program_out << "  init            - Start over with an empty structure\n";
+            *program_out << "  help            - Show this menu\n";
+            *program_out << "  quit            - Exit program\n";
+            *program_out << "========================\n";
+            program_out->flush();
+        }
+    }
+
+    void clearLogs() {
+        log_stream.str("");
+        log_stream.clear();
+        *program_out << "LOGS_CLEARED" << std::endl;
+    }
+
+    void showLogs() {
+        std::string logs = log_stream.str();
+        if (logs.empty()) {
+            *program_out << "LOGS_EMPTY" << std::endl;
+        } else {
+            *program_out << "LOGS_START" << std::endl;
+            *program_out << logs;
+            *program_out << "LOGS_END" << std::endl;
+        }
+    }
+
+    bool processCommand(const std::string& line) {
+        std::istringstream iss(line);
+        std::string command;
+        iss >> command;
+
+        if (command == "quit" || command == "exit") {
+            *program_out << "GOODBYE" << std::endl;
+            return false;
+        }
+        else if (command == "help" || command == "menu") {
+            printMenu();
+        }
+        else if (command == "logs") {
+            showLogs();
+        }
+        else if (command == "clear_logs") {
+            clearLogs();
+        }
+        else if (command == "init") {
+            initStructure();
+        }
+        else if (command.empty() || command[0] == '#') {
+            // Ignore empty lines and comments
+        }
+        else if (!handleCommand(command, iss)) {
+            *program_out << "ERROR unknown_command=" << command << " use_help_for_commands" << std::endl;
+        }
+
+        return true;
+    }
+
+    static std::ostream* openOutput(const std::string& filename, std::unique_ptr<std::ofstream>& file, const char* what) {
+        if (filename == "stdout" || filename == "-") {
+            return &std::cout;
+        } else if (filename == "stderr") {
+            return &std::cerr;
+        } else if (filename == "null" || filename == "/dev/null") {
+            static std::ofstream null_stream;
+            return &null_stream;
+        }
+        file = std::make_unique<std::ofstream>(filename);
+        if (file->is_open()) {
+            return file.get();
+        }
+        std::cerr << "Warning: Could not open " << what << " output file: " << filename << std::endl;
+        return &std::cout;
+    }
+
+public:
+    explicit DataInterface(bool interactive = true)
+        : interactive_mode(interactive), program_out(&std::cout), tree_log_out(&std::cout) {}
+
+    virtual ~DataInterface() = default;
+
+    void setProgramOutput(const std::string& filename) {
+        program_out = openOutput(filename, program_file, "program");
+    }
+
+    void setTreeLogOutput(const std::string& filename) {
+        tree_log_out = openOutput(filename, tree_log_file, "tree log");
+    }
+
+    void run() {
+        // Initialize the structure after streams are configured
+        initStructure();
+
+        if (interactive_mode) {
+            *program_out << title() << " Interface Started" << std::endl;
+            printMenu();
+        } else {
+            *program_out << readyLine() << std::endl;
+        }
+
+        std::string line;
+        while (std::getline(std::cin, line)) {
+            if (!processCommand(line)) {
+                break;
+            }
+
+            if (interactive_mode) {
+                *program_out << "\nEnter command (help for menu): ";
+                program_out->flush();
+            }
+        }
+    }
+};
+
+// Parses the flags every interface accepts and runs it.
+// extra(arg, i) may consume a structure specific flag (advancing i past its value)
+// and returns false on an invalid value; usage lists those flags for --help.
+template<typename Make, typename Extra>
+int runDataInterface(int argc, char* argv[], const std::string& version, const std::string& usage,
+                     Make make, Extra extra) {
+    bool interactive = true;
+    std::string program_output = "stdout";
+    std::string tree_log_output = "stdout";
+
+    for (int i = 1; i < argc; i++) {
+        std::string arg = argv[i];
+        if (arg == "--batch") {
+            interactive = false;
+        }
+        else if (arg == "--program-out" && i + 1 < argc) {
+            program_output = argv[++i];
+        }
+        else if (arg == "--tree-log-out" && i + 1 < argc) {
+            tree_log_output = argv[++i];
+        }
+        else if (arg == "--version") {
+            std::cout << version << std::endl;
+            return 0;
+        }
+        else if (arg == "--help") {
+            std::cout << "Usage: " << argv[0] << " [options]\n";
+            std::cout << "Options:\n";
+            std::cout << usage;
+            std::cout << "  --batch               Run in batch mode (no interactive prompts)\n";
+            std::cout << "  --program-out <file>  Program output destination:\n";
+            std::cout << "                        stdout (default), stderr, null, or filename\n";
+            std::cout << "  --tree-log-out <file> Tree log output destination:\n";
+            std::cout << "                        stdout (default), stderr, null, or filename\n";
+            std::cout << "  --version             Show version and exit\n";
+            std::cout << "  --help                Show this help\n";
+            return 0;
+        }
+        else if (!extra(arg, i)) {
+            return 1;
+        }
+    }
+
+    try {
+        std::unique_ptr<DataInterface> interface = make(interactive);
+
+        // Configure output streams
+        interface->setProgramOutput(program_output);
+        interface->setTreeLogOutput(tree_log_output);
+
+        interface->run();
+    } catch (const std::exception& e) {
+        std::cerr << "FATAL_ERROR " << e.what() << std::endl;
+        return 1;
+    }
+
+    return 0;
+}
+
+#endif // DATA_INTERFACE_HPP
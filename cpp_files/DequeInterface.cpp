@@ -0,0 +1,117 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogCircularDeque.hpp"
+
+class DequeInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogCircularDeque<int>> deque;
+    size_t capacity;
+
+protected:
+    std::string title() const override { return "Deque"; }
+
+    std::string readyLine() const override {
+        return "READY type=DEQUE capacity=" + std::to_string(capacity);
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>      - Add a value at the back (alias: push_back)\n";
+        *program_out << "  push_front <value>  - Add a value at the front\n";
+        *program_out << "  pop_front           - Remove the value at the front\n";
+        *program_out << "  pop_back            - Remove the value at the back\n";
+        *program_out << "  front               - Show the value at the front\n";
+        *program_out << "  back                - Show the value at the back\n";
+        *program_out << "  print               - Display the values, front first\n";
+        *program_out << "  buffer              - Display the circular buffer slots\n";
+        *program_out << "  size                - Show how many values there are\n";
+        *program_out << "  status              - Show deque status\n";
+    }
+
+    void initStructure() override {
+        deque = std::make_unique<datas::LogCircularDeque<int>>(capacity, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=DEQUE capacity=" << capacity << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert" || command == "push_back" || command == "push_front") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_" << command << "_syntax usage=" << command << "_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool added = command == "push_front" ? deque->pushFront(value) : deque->pushBack(value);
+            if (added) {
+                *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << deque->size() << std::endl;
+            } else {
+                *program_out << "INSERT_FULL value=" << value << " capacity=" << deque->capacity() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "pop_front" || command == "pop_back") {
+            size_t mark = logMark();
+            bool removed = command == "pop_front" ? deque->popFront(value) : deque->popBack(value);
+            if (removed) {
+                *program_out << "POP_SUCCESS end=" << (command == "pop_front" ? "front" : "back")
+                             << " value=" << value << " new_size=" << deque->size() << std::endl;
+            } else {
+                *program_out << "POP_EMPTY size=0" << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "front" || command == "back") {
+            if (command == "front" ? deque->front(value) : deque->back(value)) {
+                *program_out << "PEEK_RESULT end=" << command << " value=" << value << " size=" << deque->size() << std::endl;
+            } else {
+                *program_out << "PEEK_EMPTY size=0" << std::endl;
+            }
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "DEQUE_START" << std::endl;
+            deque->printItems(*program_out);
+            *program_out << "DEQUE_END" << std::endl;
+        }
+        else if (command == "buffer") {
+            *program_out << "BUFFER_START" << std::endl;
+            deque->printSlots(*program_out);
+            *program_out << "BUFFER_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << deque->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS deque_size=" << deque->size() << " type=DEQUE capacity=" << deque->capacity()
+                         << " head=" << deque->headIndex() << " tail=" << deque->tailIndex() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    DequeInterface(size_t cap, bool interactive = true)
+        : DataInterface(interactive), capacity(cap) {}
+};
+
+int main(int argc, char* argv[]) {
+    int capacity = 8;
+    return runDataInterface(argc, argv, "dequeInterface 1.0",
+        "  --capacity <n>        Slots in the circular buffer (default: 8, 1 to 1024)\n",
+        [&](bool interactive) {
+            return std::make_unique<DequeInterface>(static_cast<size_t>(capacity), interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--capacity" && i + 1 < argc) {
+                capacity = std::atoi(argv[++i]);
+                if (capacity < 1 || capacity > 1024) {
+                    std::cerr << "Error: Capacity must be between 1 and 1024" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
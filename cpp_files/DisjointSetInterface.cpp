@@ -0,0 +1,169 @@
+#include "DataInterface.hpp"
+#include "LogDisjointSet.hpp"
+
+class DisjointSetInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogDisjointSet<int>> dsu;
+    bool path_compression;
+    bool union_by_rank;
+
+    std::string heuristics() const {
+        return std::string("path_compression=") + (path_compression ? "on" : "off") +
+               " union_by_rank=" + (union_by_rank ? "on" : "off");
+    }
+
+    // Replies element_not_found for the first of values that is not an element
+    bool requireElements(std::initializer_list<int> values) {
+        for (int value : values) {
+            if (!dsu->contains(value)) {
+                *program_out << "ERROR element_not_found value=" << value << std::endl;
+                return false;
+            }
+        }
+        return true;
+    }
+
+protected:
+    std::string title() const override { return "Disjoint Set"; }
+
+    std::string readyLine() const override {
+        return "READY type=DSU " + heuristics();
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>     - Add a value as a set of its own (alias: make)\n";
+        *program_out << "  union <a> <b>      - Merge the sets of a and b\n";
+        *program_out << "  find <value>       - Find the root of a value's set\n";
+        *program_out << "  connected <a> <b>  - Check whether a and b are in one set\n";
+        *program_out << "  print              - Display the sets\n";
+        *program_out << "  structure          - Display parents, ranks and depths\n";
+        *program_out << "  size               - Show how many elements there are\n";
+        *program_out << "  status             - Show forest status\n";
+    }
+
+    void initStructure() override {
+        dsu = std::make_unique<datas::LogDisjointSet<int>>(path_compression, union_by_rank, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=DSU " << heuristics() << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int a, b;
+        if (command == "insert" || command == "make") {
+            if (!(iss >> a)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (dsu->makeSet(a)) {
+                *program_out << "INSERT_SUCCESS value=" << a << " new_size=" << dsu->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << a << " size=" << dsu->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "union") {
+            if (!(iss >> a >> b)) {
+                *program_out << "ERROR invalid_union_syntax usage=union_<a>_<b>" << std::endl;
+                return true;
+            }
+            if (!requireElements({a, b})) return true;
+            size_t mark = logMark();
+            int root;
+            if (dsu->unite(a, b, root)) {
+                *program_out << "UNION_SUCCESS a=" << a << " b=" << b << " root=" << root
+                             << " set_size=" << dsu->setSize(root) << " sets=" << dsu->sets() << std::endl;
+            } else {
+                *program_out << "UNION_SAME_SET a=" << a << " b=" << b << " root=" << root << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> a)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<value>" << std::endl;
+                return true;
+            }
+            if (!dsu->contains(a)) {
+                *program_out << "FIND_RESULT value=" << a << " found=false" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            size_t depth;
+            int root = dsu->find(a, depth);
+            *program_out << "FIND_RESULT value=" << a << " found=true root=" << root << " depth=" << depth << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "connected") {
+            if (!(iss >> a >> b)) {
+                *program_out << "ERROR invalid_connected_syntax usage=connected_<a>_<b>" << std::endl;
+                return true;
+            }
+            if (!requireElements({a, b})) return true;
+            size_t mark = logMark();
+            size_t depth;
+            bool connected = dsu->find(a, depth) == dsu->find(b, depth);
+            *program_out << "CONNECTED_RESULT a=" << a << " b=" << b << " connected=" << (connected ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "SETS_START" << std::endl;
+            dsu->printSets(*program_out);
+            *program_out << "SETS_END" << std::endl;
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            dsu->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << dsu->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS dsu_size=" << dsu->size() << " type=DSU sets=" << dsu->sets()
+                         << " height=" << dsu->height() << " " << heuristics() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    DisjointSetInterface(bool compress, bool by_rank, bool interactive = true)
+        : DataInterface(interactive), path_compression(compress), union_by_rank(by_rank) {}
+};
+
+// Parses an on/off flag value into enabled; returns false for anything else
+static bool parseToggle(const std::string& value, bool& enabled) {
+    if (value != "on" && value != "off") return false;
+    enabled = value == "on";
+    return true;
+}
+
+int main(int argc, char* argv[]) {
+    bool path_compression = true;
+    bool union_by_rank = true;
+    return runDataInterface(argc, argv, "dsuInterface 1.0",
+        "  --path-compression <on|off>  Point visited nodes at the root on find (default: on)\n"
+        "  --union-by-rank <on|off>     Hang the lower rank tree under the higher (default: on)\n",
+        [&](bool interactive) {
+            return std::make_unique<DisjointSetInterface>(path_compression, union_by_rank, interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--path-compression" && i + 1 < argc) {
+                if (!parseToggle(argv[++i], path_compression)) {
+                    std::cerr << "Error: Path compression must be on or off" << std::endl;
+                    return false;
+                }
+            }
+            else if (arg == "--union-by-rank" && i + 1 < argc) {
+                if (!parseToggle(argv[++i], union_by_rank)) {
+                    std::cerr << "Error: Union by rank must be on or off" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
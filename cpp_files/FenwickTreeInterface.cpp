@@ -0,0 +1,148 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogFenwickTree.hpp"
+
+class FenwickTreeInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogFenwickTree> tree;
+    size_t array_size;
+
+    bool readRange(std::istringstream& iss, size_t& from, size_t& to) {
+        long long a, b;
+        if (!(iss >> a >> b) || a < 0 || b < a || static_cast<size_t>(b) >= tree->capacity()) return false;
+        from = static_cast<size_t>(a);
+        to = static_cast<size_t>(b);
+        return true;
+    }
+
+protected:
+    std::string title() const override { return "Fenwick Tree"; }
+
+    std::string readyLine() const override {
+        return "READY type=FENWICK size=" + std::to_string(array_size);
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>       - Set the first slot not set yet\n";
+        *program_out << "  set <i> <value>      - Set the value at index i\n";
+        *program_out << "  update <i> <delta>   - Add delta to the value at index i\n";
+        *program_out << "  query <l> <r>        - Sum the values at indexes l to r\n";
+        *program_out << "  prefix <i>           - Sum the values at indexes 0 to i\n";
+        *program_out << "  print                - Display the array\n";
+        *program_out << "  structure            - Display the range and sum of every node\n";
+        *program_out << "  size                 - Show how many slots are set\n";
+        *program_out << "  status               - Show tree status\n";
+    }
+
+    void initStructure() override {
+        tree = std::make_unique<datas::LogFenwickTree>(array_size, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=FENWICK size=0 capacity=" << array_size << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        long long value;
+        if (command == "insert") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            long index = tree->append(value);
+            if (index >= 0) {
+                *program_out << "INSERT_SUCCESS value=" << value << " index=" << index << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "ERROR array_full capacity=" << tree->capacity() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "set" || command == "update" || command == "add") {
+            long long index;
+            bool adding = command != "set";
+            if (!(iss >> index >> value)) {
+                *program_out << "ERROR invalid_" << command << "_syntax usage=" << command
+                             << (adding ? "_<index>_<delta>" : "_<index>_<value>") << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool in_range = index >= 0 && (adding ? tree->update(static_cast<size_t>(index), value)
+                                                  : tree->set(static_cast<size_t>(index), value));
+            if (in_range && adding) {
+                *program_out << "UPDATE_SUCCESS index=" << index << " delta=" << value
+                             << " value=" << tree->valueAt(static_cast<size_t>(index)) << " size=" << tree->size() << std::endl;
+            } else if (in_range) {
+                *program_out << "SET_SUCCESS index=" << index << " value=" << value << " size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "ERROR index_out_of_range index=" << index << " capacity=" << tree->capacity() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "query") {
+            size_t from, to;
+            if (!readRange(iss, from, to)) {
+                *program_out << "ERROR invalid_query_syntax usage=query_<l>_<r> capacity=" << tree->capacity() << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            long long result = tree->query(from, to);
+            *program_out << "QUERY_RESULT from=" << from << " to=" << to << " value=" << result << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "prefix") {
+            long long index;
+            if (!(iss >> index) || index < 0 || static_cast<size_t>(index) >= tree->capacity()) {
+                *program_out << "ERROR invalid_prefix_syntax usage=prefix_<i> capacity=" << tree->capacity() << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            long long result = tree->prefixSum(static_cast<size_t>(index));
+            *program_out << "PREFIX_RESULT to=" << index << " value=" << result << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "ARRAY_START" << std::endl;
+            tree->printArray(*program_out);
+            *program_out << "ARRAY_END" << std::endl;
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            tree->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << tree->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS fenwick_size=" << tree->size() << " type=FENWICK capacity=" << tree->capacity() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    FenwickTreeInterface(size_t size, bool interactive = true)
+        : DataInterface(interactive), array_size(size) {}
+};
+
+int main(int argc, char* argv[]) {
+    int size = 16;
+    return runDataInterface(argc, argv, "fenwickInterface 1.0",
+        "  --size <n>            Array size (default: 16, 1 to 1024)\n",
+        [&](bool interactive) {
+            return std::make_unique<FenwickTreeInterface>(static_cast<size_t>(size), interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--size" && i + 1 < argc) {
+                size = std::atoi(argv[++i]);
+                if (size < 1 || size > 1024) {
+                    std::cerr << "Error: Size must be between 1 and 1024" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
@@ -0,0 +1,196 @@
+#include "DataInterface.hpp"
+#include "LogGraph.hpp"
+
+class GraphInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogGraph<int>> graph;
+    bool directed;
+
+    std::string kind() const { return std::string("directed=") + (directed ? "true" : "false"); }
+
+    // Replies traversal with the visit order of a BFS or DFS
+    void printOrder(const std::string& algorithm, int source, const std::vector<int>& order) {
+        *program_out << "TRAVERSAL_RESULT algorithm=" << algorithm << " source=" << source
+                     << " visited=" << order.size() << " order=";
+        for (size_t i = 0; i < order.size(); i++) {
+            *program_out << (i ? "," : "") << order[i];
+        }
+        *program_out << std::endl;
+    }
+
+protected:
+    std::string title() const override { return "Graph"; }
+
+    std::string readyLine() const override {
+        return "READY type=GRAPH " + kind();
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <v>             - Add a vertex\n";
+        *program_out << "  remove <v>             - Remove a vertex and its edges\n";
+        *program_out << "  edge <u> <v> [weight]  - Add an edge, or set its weight (default 1)\n";
+        *program_out << "  unedge <u> <v>         - Remove an edge\n";
+        *program_out << "  find <v>               - Check whether a vertex exists\n";
+        *program_out << "  bfs <source>           - Breadth-first traversal\n";
+        *program_out << "  dfs <source>           - Depth-first traversal\n";
+        *program_out << "  dijkstra <source>      - Shortest paths from a vertex\n";
+        *program_out << "  print                  - Display the adjacency lists\n";
+        *program_out << "  size                   - Show how many vertices there are\n";
+        *program_out << "  status                 - Show graph status\n";
+    }
+
+    void initStructure() override {
+        graph = std::make_unique<datas::LogGraph<int>>(directed, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=GRAPH " << kind() << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int u, v;
+        if (command == "insert") {
+            if (!(iss >> v)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<v>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (graph->addVertex(v)) {
+                *program_out << "INSERT_SUCCESS value=" << v << " new_size=" << graph->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << v << " size=" << graph->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> v)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<v>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (graph->removeVertex(v)) {
+                *program_out << "REMOVE_SUCCESS value=" << v << " new_size=" << graph->size() << " edges=" << graph->edges() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << v << " size=" << graph->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "edge" || command == "connect") {
+            long long weight = 1;
+            if (!(iss >> u >> v)) {
+                *program_out << "ERROR invalid_edge_syntax usage=edge_<u>_<v>_[weight]" << std::endl;
+                return true;
+            }
+            std::string token;
+            if (iss >> token) {
+                std::istringstream parsed(token);
+                if (!(parsed >> weight) || !parsed.eof()) {
+                    *program_out << "ERROR invalid_edge_syntax usage=edge_<u>_<v>_[weight]" << std::endl;
+                    return true;
+                }
+            }
+            if (weight < 0) {
+                *program_out << "ERROR negative_weight weight=" << weight << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool added = graph->addEdge(u, v, weight);
+            *program_out << (added ? "EDGE_SUCCESS" : "EDGE_UPDATED") << " from=" << u << " to=" << v
+                         << " weight=" << weight << " edges=" << graph->edges() << " size=" << graph->size() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "unedge" || command == "disconnect") {
+            if (!(iss >> u >> v)) {
+                *program_out << "ERROR invalid_unedge_syntax usage=unedge_<u>_<v>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (graph->removeEdge(u, v)) {
+                *program_out << "UNEDGE_SUCCESS from=" << u << " to=" << v << " edges=" << graph->edges() << std::endl;
+            } else {
+                *program_out << "UNEDGE_NOT_FOUND from=" << u << " to=" << v << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> v)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<v>" << std::endl;
+                return true;
+            }
+            if (graph->contains(v)) {
+                *program_out << "FIND_RESULT value=" << v << " found=true degree=" << graph->degree(v) << std::endl;
+            } else {
+                *program_out << "FIND_RESULT value=" << v << " found=false" << std::endl;
+            }
+        }
+        else if (command == "bfs" || command == "dfs" || command == "dijkstra") {
+            if (!(iss >> v)) {
+                *program_out << "ERROR invalid_" << command << "_syntax usage=" << command << "_<source>" << std::endl;
+                return true;
+            }
+            if (!graph->contains(v)) {
+                *program_out << "ERROR vertex_not_found value=" << v << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (command == "dijkstra") {
+                std::map<int, long long> distance;
+                std::map<int, int> previous;
+                graph->dijkstra(v, distance, previous);
+                *program_out << "PATHS_START source=" << v << " reached=" << distance.size() << std::endl;
+                for (const auto& [vertex, dist] : distance) {
+                    *program_out << vertex << " distance=" << dist;
+                    if (previous.count(vertex)) *program_out << " via=" << previous[vertex];
+                    *program_out << std::endl;
+                }
+                *program_out << "PATHS_END" << std::endl;
+            } else {
+                std::vector<int> order;
+                if (command == "bfs") graph->bfs(v, order);
+                else graph->dfs(v, order);
+                printOrder(command, v, order);
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "ADJACENCY_START" << std::endl;
+            graph->printAdjacency(*program_out);
+            *program_out << "ADJACENCY_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << graph->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS graph_size=" << graph->size() << " type=GRAPH edges=" << graph->edges()
+                         << " " << kind() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    GraphInterface(bool is_directed, bool interactive = true)
+        : DataInterface(interactive), directed(is_directed) {}
+};
+
+int main(int argc, char* argv[]) {
+    bool directed = false;
+    return runDataInterface(argc, argv, "graphInterface 1.0",
+        "  --directed <on|off>   Edges go one way only (default: off)\n",
+        [&](bool interactive) {
+            return std::make_unique<GraphInterface>(directed, interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--directed" && i + 1 < argc) {
+                std::string value = argv[++i];
+                if (value != "on" && value != "off") {
+                    std::cerr << "Error: Directed must be on or off" << std::endl;
+                    return false;
+                }
+                directed = value == "on";
+            }
+            return true;
+        });
+}
@@ -0,0 +1,137 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogHashTable.hpp"
+
+class HashTableInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogHashTable> table;
+    datas::LogHashTable::Strategy strategy;
+    size_t initial_buckets;
+
+protected:
+    std::string title() const override { return "Hash Table"; }
+
+    std::string readyLine() const override {
+        return std::string("READY type=HASHTABLE strategy=") + table->strategyName() +
+               " buckets=" + std::to_string(table->bucketCount());
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <key>    - Insert a key\n";
+        *program_out << "  remove <key>    - Remove a key\n";
+        *program_out << "  find <key>      - Search for a key\n";
+        *program_out << "  print           - Display every bucket\n";
+        *program_out << "  keys            - Display the stored keys\n";
+        *program_out << "  size            - Show number of keys\n";
+        *program_out << "  status          - Show table status\n";
+    }
+
+    void initStructure() override {
+        table = std::make_unique<datas::LogHashTable>(strategy, initial_buckets, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=HASHTABLE strategy=" << table->strategyName()
+                     << " buckets=" << table->bucketCount() << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int key;
+        if (command == "insert") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (table->insert(key)) {
+                *program_out << "INSERT_SUCCESS value=" << key << " new_size=" << table->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << key << " size=" << table->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (table->remove(key)) {
+                *program_out << "REMOVE_SUCCESS value=" << key << " new_size=" << table->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << key << " size=" << table->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool found = table->find(key);
+            *program_out << "FIND_RESULT value=" << key << " found=" << (found ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "HASHTABLE_START" << std::endl;
+            table->printBuckets(*program_out);
+            *program_out << "HASHTABLE_END" << std::endl;
+        }
+        else if (command == "keys") {
+            *program_out << "KEYS_START" << std::endl;
+            *program_out << "[";
+            std::vector<int> keys = table->keys();
+            for (size_t i = 0; i < keys.size(); i++) {
+                *program_out << (i ? ", " : "") << keys[i];
+            }
+            *program_out << "]" << std::endl;
+            *program_out << "KEYS_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << table->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS table_size=" << table->size() << " type=HASHTABLE strategy=" << table->strategyName()
+                         << " buckets=" << table->bucketCount() << " load=" << table->loadFactor()
+                         << " rehashes=" << table->rehashCount() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    HashTableInterface(datas::LogHashTable::Strategy s, size_t buckets, bool interactive = true)
+        : DataInterface(interactive), strategy(s), initial_buckets(buckets) {}
+};
+
+int main(int argc, char* argv[]) {
+    datas::LogHashTable::Strategy strategy = datas::LogHashTable::CHAINING;
+    int buckets = 8;
+    return runDataInterface(argc, argv, "hashtableInterface 1.0",
+        "  --buckets <n>                   Initial bucket count (default: 8, 1 to 65536)\n"
+        "  --strategy <chaining|openaddr>  Collision handling (default: chaining)\n",
+        [&](bool interactive) {
+            return std::make_unique<HashTableInterface>(strategy, static_cast<size_t>(buckets), interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--buckets" && i + 1 < argc) {
+                buckets = std::atoi(argv[++i]);
+                if (buckets < 1 || buckets > 65536) {
+                    std::cerr << "Error: Buckets must be between 1 and 65536" << std::endl;
+                    return false;
+                }
+            }
+            else if (arg == "--strategy" && i + 1 < argc) {
+                std::string name = argv[++i];
+                if (name != "chaining" && name != "openaddr") {
+                    std::cerr << "Error: Strategy must be chaining or openaddr" << std::endl;
+                    return false;
+                }
+                strategy = name == "chaining" ? datas::LogHashTable::CHAINING : datas::LogHashTable::OPEN_ADDRESSING;
+            }
+            return true;
+        });
+}
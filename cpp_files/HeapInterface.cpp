@@ -0,0 +1,140 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogHeap.hpp"
+
+class HeapInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogHeap<int>> heap;
+    bool max_heap;
+    int arity;
+
+protected:
+    std::string title() const override { return "Heap"; }
+
+    std::string readyLine() const override {
+        return std::string("READY type=HEAP kind=") + heap->kind() + " arity=" + std::to_string(arity);
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>      - Insert a value and sift it up\n";
+        *program_out << "  extract             - Remove the top value and sift down\n";
+        *program_out << "  peek                - Show the top value\n";
+        *program_out << "  heapify <v1> <v2>.. - Build a heap from values bottom-up\n";
+        *program_out << "  print               - Display the heap one level per line\n";
+        *program_out << "  array               - Display the backing array\n";
+        *program_out << "  size                - Show heap size\n";
+        *program_out << "  status              - Show heap status\n";
+    }
+
+    void initStructure() override {
+        heap = std::make_unique<datas::LogHeap<int>>(max_heap, arity, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=HEAP kind=" << heap->kind() << " arity=" << arity << " size=0" << std::endl;
+    }
+
+    void printArray() {
+        *program_out << "ARRAY [";
+        const std::vector<int>& items = heap->array();
+        for (size_t i = 0; i < items.size(); i++) {
+            *program_out << (i ? "," : "") << items[i];
+        }
+        *program_out << "]" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert" || command == "push") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            heap->insert(value);
+            *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << heap->size() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "extract" || command == "pop") {
+            if (heap->size() == 0) {
+                *program_out << "EXTRACT_EMPTY size=0" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            int top = heap->extract();
+            *program_out << "EXTRACT_SUCCESS value=" << top << " new_size=" << heap->size() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "peek" || command == "top") {
+            if (heap->size() == 0) {
+                *program_out << "PEEK_EMPTY size=0" << std::endl;
+            } else {
+                *program_out << "PEEK value=" << heap->peek() << std::endl;
+            }
+        }
+        else if (command == "heapify") {
+            std::vector<int> values;
+            while (iss >> value) {
+                values.push_back(value);
+            }
+            if (!iss.eof()) {
+                *program_out << "ERROR invalid_heapify_syntax usage=heapify_<v1>_<v2>..." << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            heap->heapify(values);
+            *program_out << "HEAPIFY_SUCCESS new_size=" << heap->size() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "HEAP_START" << std::endl;
+            heap->printLevels(*program_out);
+            *program_out << "HEAP_END" << std::endl;
+        }
+        else if (command == "array") {
+            printArray();
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << heap->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS heap_size=" << heap->size() << " type=HEAP kind=" << heap->kind()
+                         << " arity=" << arity << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    HeapInterface(bool max, int heap_arity, bool interactive = true)
+        : DataInterface(interactive), max_heap(max), arity(heap_arity) {}
+};
+
+int main(int argc, char* argv[]) {
+    bool max_heap = false;
+    int arity = 2;
+    return runDataInterface(argc, argv, "heapInterface 1.0",
+        "  --kind <min|max>      Heap order (default: min)\n"
+        "  --arity <n>           Children per node (default: 2, minimum: 2)\n",
+        [&](bool interactive) { return std::make_unique<HeapInterface>(max_heap, arity, interactive); },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--kind" && i + 1 < argc) {
+                std::string kind = argv[++i];
+                if (kind != "min" && kind != "max") {
+                    std::cerr << "Error: Kind must be min or max" << std::endl;
+                    return false;
+                }
+                max_heap = kind == "max";
+            }
+            else if (arg == "--arity" && i + 1 < argc) {
+                arity = std::atoi(argv[++i]);
+                if (arity < 2) {
+                    std::cerr << "Error: Arity must be >= 2" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
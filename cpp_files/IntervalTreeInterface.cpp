@@ -0,0 +1,128 @@
+#include "DataInterface.hpp"
+#include "LogIntervalTree.hpp"
+
+class IntervalTreeInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogIntervalTree<int>> tree;
+
+    // Reads "<low> <high>" into low and high; reports a usage error for command otherwise
+    bool readInterval(const std::string& command, std::istringstream& iss, int& low, int& high) {
+        if (!(iss >> low >> high)) {
+            *program_out << "ERROR invalid_" << command << "_syntax usage=" << command << "_<low>_<high>" << std::endl;
+            return false;
+        }
+        if (low > high) {
+            *program_out << "ERROR invalid_interval low=" << low << " high=" << high << " reason=low_above_high" << std::endl;
+            return false;
+        }
+        return true;
+    }
+
+protected:
+    std::string title() const override { return "Interval Tree"; }
+
+    std::string readyLine() const override { return "READY type=INTERVAL"; }
+
+    void printCommands() override {
+        *program_out << "  insert <low> <high>   - Insert the closed interval [low, high]\n";
+        *program_out << "  remove <low> <high>   - Remove an interval\n";
+        *program_out << "  find <low> <high>     - Search for an exact interval\n";
+        *program_out << "  overlap <low> <high>  - List the intervals overlapping [low, high] (alias: query)\n";
+        *program_out << "  stab <point>          - List the intervals containing a point\n";
+        *program_out << "  print                 - Display the intervals in order\n";
+        *program_out << "  structure             - Display tree structure with subtree maxima\n";
+        *program_out << "  size                  - Show tree size\n";
+        *program_out << "  status                - Show tree status\n";
+    }
+
+    void initStructure() override {
+        tree = std::make_unique<datas::LogIntervalTree<int>>(log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=INTERVAL size=0" << std::endl;
+    }
+
+    // Runs an overlap query and prints its result line
+    void query(int low, int high) {
+        size_t mark = logMark();
+        auto found = tree->overlapping(low, high);
+        *program_out << "QUERY_RESULT low=" << low << " high=" << high << " count=" << found.size() << " intervals=";
+        for (size_t i = 0; i < found.size(); i++) {
+            *program_out << (i ? " " : "") << "[" << found[i].first << "," << found[i].second << "]";
+        }
+        *program_out << std::endl;
+        forwardLogs(mark);
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int low, high;
+        if (command == "insert") {
+            if (!readInterval(command, iss, low, high)) return true;
+            size_t mark = logMark();
+            if (tree->insert(low, high)) {
+                *program_out << "INSERT_SUCCESS low=" << low << " high=" << high << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE low=" << low << " high=" << high << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!readInterval(command, iss, low, high)) return true;
+            size_t mark = logMark();
+            if (tree->remove(low, high)) {
+                *program_out << "REMOVE_SUCCESS low=" << low << " high=" << high << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND low=" << low << " high=" << high << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!readInterval("find", iss, low, high)) return true;
+            size_t mark = logMark();
+            bool found = tree->find(low, high);
+            *program_out << "FIND_RESULT low=" << low << " high=" << high << " found=" << (found ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "overlap" || command == "query") {
+            if (!readInterval("overlap", iss, low, high)) return true;
+            query(low, high);
+        }
+        else if (command == "stab") {
+            if (!(iss >> low)) {
+                *program_out << "ERROR invalid_stab_syntax usage=stab_<point>" << std::endl;
+                return true;
+            }
+            query(low, low);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "TREE_INORDER_START" << std::endl;
+            tree->inorder(*program_out);
+            *program_out << "TREE_INORDER_END" << std::endl;
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            tree->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << tree->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS tree_size=" << tree->size() << " type=INTERVAL height=" << tree->height() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    explicit IntervalTreeInterface(bool interactive = true) : DataInterface(interactive) {}
+};
+
+int main(int argc, char* argv[]) {
+    return runDataInterface(argc, argv, "intervaltreeInterface 1.0", "",
+        [](bool interactive) { return std::make_unique<IntervalTreeInterface>(interactive); },
+        [](const std::string&, int&) { return true; });
+}
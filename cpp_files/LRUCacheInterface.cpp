@@ -0,0 +1,118 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogLRUCache.hpp"
+
+class LRUCacheInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogLRUCache<int>> cache;
+    size_t capacity;
+
+protected:
+    std::string title() const override { return "LRU Cache"; }
+
+    std::string readyLine() const override {
+        return "READY type=LRU capacity=" + std::to_string(capacity);
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <key>     - Add a key as the most recent, evicting the least recent when full\n";
+        *program_out << "  find <key>       - Look a key up, making it the most recent on a hit (alias: access)\n";
+        *program_out << "  remove <key>     - Remove a key\n";
+        *program_out << "  print            - Display the keys, most recent first\n";
+        *program_out << "  size             - Show how many keys are cached\n";
+        *program_out << "  status           - Show cache status\n";
+    }
+
+    void initStructure() override {
+        cache = std::make_unique<datas::LogLRUCache<int>>(capacity, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=LRU capacity=" << capacity << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int key;
+        if (command == "insert") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool evicted;
+            int evicted_key = 0;
+            if (cache->insert(key, evicted, evicted_key)) {
+                *program_out << "INSERT_SUCCESS value=" << key << " new_size=" << cache->size();
+                if (evicted) *program_out << " evicted=" << evicted_key;
+                *program_out << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << key << " size=" << cache->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search" || command == "access") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool hit = cache->access(key);
+            *program_out << "FIND_RESULT value=" << key << " found=" << (hit ? "true" : "false")
+                         << " hits=" << cache->hits() << " misses=" << cache->misses() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> key)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<key>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (cache->remove(key)) {
+                *program_out << "REMOVE_SUCCESS value=" << key << " new_size=" << cache->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << key << " size=" << cache->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "CACHE_START" << std::endl;
+            cache->printRecency(*program_out);
+            *program_out << "CACHE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << cache->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS cache_size=" << cache->size() << " type=LRU capacity=" << cache->capacity()
+                         << " hits=" << cache->hits() << " misses=" << cache->misses()
+                         << " evictions=" << cache->evictions() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    LRUCacheInterface(size_t cap, bool interactive = true)
+        : DataInterface(interactive), capacity(cap) {}
+};
+
+int main(int argc, char* argv[]) {
+    int capacity = 4;
+    return runDataInterface(argc, argv, "lrucacheInterface 1.0",
+        "  --capacity <n>        Keys kept before the least recent is evicted (default: 4, at least 1)\n",
+        [&](bool interactive) {
+            return std::make_unique<LRUCacheInterface>(static_cast<size_t>(capacity), interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--capacity" && i + 1 < argc) {
+                capacity = std::atoi(argv[++i]);
+                if (capacity < 1) {
+                    std::cerr << "Error: Capacity must be a positive integer" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
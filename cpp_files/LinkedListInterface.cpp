@@ -0,0 +1,159 @@
+#include "DataInterface.hpp"
+#include "LogLinkedList.hpp"
+
+class LinkedListInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogLinkedList<int>> list;
+    bool doubly;
+
+    const char* kindName() const {
+        return doubly ? "doubly" : "singly";
+    }
+
+protected:
+    std::string title() const override { return "Linked List"; }
+
+    std::string readyLine() const override {
+        return std::string("READY type=LINKEDLIST kind=") + kindName();
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>          - Append a value at the tail\n";
+        *program_out << "  push_front <value>      - Insert a value at the head\n";
+        *program_out << "  insert_at <i> <value>   - Insert a value at position i\n";
+        *program_out << "  remove <value>          - Remove the first node holding value\n";
+        *program_out << "  remove_at <i>           - Remove the node at position i\n";
+        *program_out << "  find <value>            - Find the position of a value\n";
+        *program_out << "  reverse                 - Reverse the list in place\n";
+        *program_out << "  print                   - Display the list\n";
+        *program_out << "  size                    - Show list size\n";
+        *program_out << "  status                  - Show list status\n";
+    }
+
+    void initStructure() override {
+        list = std::make_unique<datas::LogLinkedList<int>>(doubly, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=LINKEDLIST kind=" << kindName() << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        size_t index;
+        if (command == "insert" || command == "push_back") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            list->pushBack(value);
+            *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << list->size() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "push_front") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_push_front_syntax usage=push_front_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            list->pushFront(value);
+            *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << list->size() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "insert_at") {
+            if (!(iss >> index >> value)) {
+                *program_out << "ERROR invalid_insert_at_syntax usage=insert_at_<index>_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (list->insertAt(index, value)) {
+                *program_out << "INSERT_SUCCESS value=" << value << " index=" << index << " new_size=" << list->size() << std::endl;
+            } else {
+                *program_out << "ERROR index_out_of_range index=" << index << " size=" << list->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (list->remove(value)) {
+                *program_out << "REMOVE_SUCCESS value=" << value << " new_size=" << list->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << value << " size=" << list->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove_at") {
+            if (!(iss >> index)) {
+                *program_out << "ERROR invalid_remove_at_syntax usage=remove_at_<index>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (list->removeAt(index, value)) {
+                *program_out << "REMOVE_SUCCESS value=" << value << " index=" << index << " new_size=" << list->size() << std::endl;
+            } else {
+                *program_out << "ERROR index_out_of_range index=" << index << " size=" << list->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            long found = list->find(value);
+            *program_out << "FIND_RESULT value=" << value << " found=" << (found >= 0 ? "true" : "false");
+            if (found >= 0) *program_out << " index=" << found;
+            *program_out << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "reverse") {
+            size_t mark = logMark();
+            list->reverse();
+            *program_out << "REVERSE_SUCCESS size=" << list->size() << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "LIST_START" << std::endl;
+            list->printList(*program_out);
+            *program_out << "LIST_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << list->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS list_size=" << list->size() << " type=LINKEDLIST kind=" << kindName() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    LinkedListInterface(bool is_doubly, bool interactive = true)
+        : DataInterface(interactive), doubly(is_doubly) {}
+};
+
+int main(int argc, char* argv[]) {
+    bool doubly = false;
+    return runDataInterface(argc, argv, "linkedlistInterface 1.0",
+        "  --kind <singly|doubly>  Links per node (default: singly)\n",
+        [&](bool interactive) { return std::make_unique<LinkedListInterface>(doubly, interactive); },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--kind" && i + 1 < argc) {
+                std::string kind = argv[++i];
+                if (kind != "singly" && kind != "doubly") {
+                    std::cerr << "Error: Kind must be singly or doubly" << std::endl;
+                    return false;
+                }
+                doubly = kind == "doubly";
+            }
+            return true;
+        });
+}
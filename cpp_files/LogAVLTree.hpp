@@ -1,6 +1,7 @@
 #ifndef ENHANCED_LOGAVL_TREE_HPP
 #define ENHANCED_LOGAVL_TREE_HPP
 
+#include <vector>
 #include "AVLTree.hpp"
 #include "LogDatas.hpp"
 
@@ -56,6 +57,23 @@ protected:
             return new_root;
         }
 
+        // Names the imbalance before the rotations fixing it when verbose rotations are on
+        AVLNode* balance() override {
+            if (owner->verbose_rotations) {
+                this->update_height();
+                int factor = AVLNode::get_balance(this);
+                if (factor > 1 || factor < -1) {
+                    const char* rotation_case = factor > 1
+                        ? (AVLNode::get_balance(this->left) < 0 ? "LR" : "LL")
+                        : (AVLNode::get_balance(this->right) > 0 ? "RL" : "RR");
+                    owner->buffer << "[IMBALANCE] node=" << this << " value=" << this->data
+                                  << " balance=" << factor << " case=" << rotation_case;
+                    owner->log();
+                }
+            }
+            return AVLNode::balance();
+        }
+
         AVLNode* insert(T value) override {
             owner->buffer << "[INSERT] node=" << this << " value=" << value;
             
@@ -98,7 +116,7 @@ protected:
             }
             
             // Use original balance logic (will call our virtual rotations if needed)
-            return AVLNode::balance();
+            return this->balance();
         }
 
         AVLNode* find(const T& val) override {
@@ -241,27 +259,47 @@ protected:
 
         return node->balance();
     }
+    void inorder(std::ostream& os, const AVLNode* node, bool& first) const {
+        if (!node) return;
+        inorder(os, node->left, first);
+        os << (first ? "" : " ") << node->data;
+        first = false;
+        inorder(os, node->right, first);
+    }
+
     // Helper function for LogAVLTree - add this to your LogAVLTree class as a private method
-    void printNodeStructure(AVLNode* node, const std::string& prefix = "", bool isLast = true) const {
+    void printNodeStructure(std::ostream& os, AVLNode* node, const std::string& prefix = "", bool isLast = true) const {
         if (node == nullptr) {
-            std::cout << prefix << (isLast ? "└── " : "├── ") << "null" << std::endl;
+            os << prefix << (isLast ? "└── " : "├── ") << "null" << std::endl;
             return;
         }
         
-        std::cout << prefix << (isLast ? "└── " : "├── ") << node->data << std::endl;
+        os << prefix << (isLast ? "└── " : "├── ") << node->data << std::endl;
         
         if (node->left != nullptr || node->right != nullptr) {
             // Print left child
-            printNodeStructure(node->left, prefix + (isLast ? "    " : "│   "), node->right == nullptr);
+            printNodeStructure(os, node->left, prefix + (isLast ? "    " : "│   "), node->right == nullptr);
             // Print right child  
-            printNodeStructure(node->right, prefix + (isLast ? "    " : "│   "), true);
+            printNodeStructure(os, node->right, prefix + (isLast ? "    " : "│   "), true);
         }
     }
 
+    bool verbose_rotations = false;
+
 public:
     explicit LogAVLTree(std::ostream& os = std::cout)
         : LogDatas(os) {}
 
+    // Logs the imbalance (LL, LR, RR or RL) a node has before the rotations that fix it
+    void setVerboseRotations(bool verbose) { verbose_rotations = verbose; }
+
+    // Writes the values in order on one line
+    void inorder(std::ostream& os) const {
+        bool first = true;
+        inorder(os, this->root, first);
+        if (!first) os << std::endl;
+    }
+
     bool exist_in_tree(const T& val) override {
         this->buffer << "[TREE_FIND] value=" << val;
         this->log();
@@ -311,12 +349,12 @@ public:
         }
     }
     // Function for LogAVLTree - add this to your LogAVLTree class as a public method
-    void printTreeStructure() const {
-        std::cout << "LogAVLTree Structure:" << std::endl;
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogAVLTree Structure:" << std::endl;
         if (this->root == nullptr) {
-            std::cout << "└── (empty)" << std::endl;
+            os << "└── (empty)" << std::endl;
         } else {
-            printNodeStructure(this->root);
+            printNodeStructure(os, this->root);
         }
     }
 };
@@ -0,0 +1,391 @@
+#ifndef LOG_BPLUS_TREE_HPP
+#define LOG_BPLUS_TREE_HPP
+
+#include <algorithm>
+#include <string>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// B+ tree: every key lives in a leaf, internal nodes only hold separators
+// copied up from the leaves (a separator may outlive the key it was copied
+// from; it still routes searches correctly). With leaf links on the leaves are
+// chained left to right, so a range scan walks the chain like a database index;
+// with them off it has to descend through the internal nodes for every leaf.
+// order is the most children a node may have.
+template<typename T>
+class LogBPlusTree : public LogDatas {
+private:
+    struct BPlusNode {
+        bool leaf;
+        std::vector<T> keys;
+        std::vector<BPlusNode*> children;   // internal nodes only, keys.size() + 1 of them
+        BPlusNode* next;                    // next leaf, kept only with leaf links
+
+        explicit BPlusNode(bool is_leaf) : leaf(is_leaf), next(nullptr) {}
+    };
+
+    BPlusNode* root;
+    size_t count;
+    size_t order;
+    bool leaf_links;
+
+    size_t maxKeys() const { return order - 1; }
+    size_t minKeys() const { return (order - 1) / 2; }
+
+    void logKeys(const std::vector<T>& keys) {
+        this->buffer << "[";
+        for (size_t i = 0; i < keys.size(); i++) {
+            this->buffer << (i ? "," : "") << keys[i];
+        }
+        this->buffer << "]";
+    }
+
+    // Index of the child whose range holds value: keys[i-1] <= value < keys[i]
+    static size_t childIndex(const BPlusNode* node, const T& value) {
+        return std::upper_bound(node->keys.begin(), node->keys.end(), value) - node->keys.begin();
+    }
+
+    void logTraverse(BPlusNode* node, size_t index) {
+        this->buffer << "[TRAVERSE] node=" << node << " keys=";
+        logKeys(node->keys);
+        this->buffer << " child_index=" << index << " child=" << node->children[index];
+        this->log();
+    }
+
+    void link(BPlusNode* leaf, BPlusNode* next) {
+        if (!leaf_links) return;
+        this->buffer << "[LEAF_LINK] leaf=" << leaf << " next=" << next;
+        this->log();
+        leaf->next = next;
+    }
+
+    // Inserts below node; returns the new right sibling if node split, its first key in promoted
+    BPlusNode* insertAt(BPlusNode* node, const T& value, T& promoted, bool& inserted) {
+        if (node->leaf) {
+            auto pos = std::lower_bound(node->keys.begin(), node->keys.end(), value);
+            if (pos != node->keys.end() && *pos == value) {
+                inserted = false;
+                return nullptr;
+            }
+            node->keys.insert(pos, value);
+            inserted = true;
+            this->buffer << "[LEAF_INSERT] leaf=" << node << " value=" << value << " keys=";
+            logKeys(node->keys);
+            this->log();
+            if (node->keys.size() <= maxKeys()) return nullptr;
+
+            // The right half starts a new leaf whose first key is copied up
+            BPlusNode* right = new BPlusNode(true);
+            size_t mid = node->keys.size() / 2;
+            right->keys.assign(node->keys.begin() + mid, node->keys.end());
+            node->keys.resize(mid);
+            promoted = right->keys.front();
+            this->buffer << "[LEAF_SPLIT] leaf=" << node << " new_leaf=" << right << " separator=" << promoted << " left_keys=";
+            logKeys(node->keys);
+            this->buffer << " right_keys=";
+            logKeys(right->keys);
+            this->log();
+            link(right, node->next);
+            link(node, right);
+            return right;
+        }
+
+        size_t index = childIndex(node, value);
+        logTraverse(node, index);
+        T child_promoted;
+        BPlusNode* split = insertAt(node->children[index], value, child_promoted, inserted);
+        if (!split) return nullptr;
+
+        node->keys.insert(node->keys.begin() + index, child_promoted);
+        node->children.insert(node->children.begin() + index + 1, split);
+        this->buffer << "[SEPARATOR_INSERT] node=" << node << " separator=" << child_promoted << " keys=";
+        logKeys(node->keys);
+        this->log();
+        if (node->keys.size() <= maxKeys()) return nullptr;
+
+        // The middle separator moves up, it is not kept in either half
+        BPlusNode* right = new BPlusNode(false);
+        size_t mid = node->keys.size() / 2;
+        promoted = node->keys[mid];
+        right->keys.assign(node->keys.begin() + mid + 1, node->keys.end());
+        right->children.assign(node->children.begin() + mid + 1, node->children.end());
+        node->keys.resize(mid);
+        node->children.resize(mid + 1);
+        this->buffer << "[INTERNAL_SPLIT] node=" << node << " new_node=" << right << " promoted=" << promoted << " left_keys=";
+        logKeys(node->keys);
+        this->buffer << " right_keys=";
+        logKeys(right->keys);
+        this->log();
+        return right;
+    }
+
+    bool removeAt(BPlusNode* node, const T& value) {
+        if (node->leaf) {
+            auto pos = std::lower_bound(node->keys.begin(), node->keys.end(), value);
+            if (pos == node->keys.end() || *pos != value) return false;
+            node->keys.erase(pos);
+            this->buffer << "[LEAF_REMOVE] leaf=" << node << " value=" << value << " keys=";
+            logKeys(node->keys);
+            this->log();
+            return true;
+        }
+
+        size_t index = childIndex(node, value);
+        logTraverse(node, index);
+        if (!removeAt(node->children[index], value)) return false;
+        if (node->children[index]->keys.size() < minKeys()) {
+            this->buffer << "[UNDERFLOW] node=" << node->children[index] << " keys=" << node->children[index]->keys.size()
+                         << " min_keys=" << minKeys();
+            this->log();
+            rebalance(node, index);
+        }
+        return true;
+    }
+
+    // Refills the child at index of parent from a sibling, or merges it with one
+    void rebalance(BPlusNode* parent, size_t index) {
+        BPlusNode* child = parent->children[index];
+        BPlusNode* left = index > 0 ? parent->children[index - 1] : nullptr;
+        BPlusNode* right = index + 1 < parent->children.size() ? parent->children[index + 1] : nullptr;
+
+        if (left && left->keys.size() > minKeys()) {
+            if (child->leaf) {
+                child->keys.insert(child->keys.begin(), left->keys.back());
+                left->keys.pop_back();
+                parent->keys[index - 1] = child->keys.front();
+            } else {
+                child->keys.insert(child->keys.begin(), parent->keys[index - 1]);
+                parent->keys[index - 1] = left->keys.back();
+                left->keys.pop_back();
+                child->children.insert(child->children.begin(), left->children.back());
+                left->children.pop_back();
+            }
+            this->buffer << "[BORROW_LEFT] node=" << child << " from=" << left << " separator=" << parent->keys[index - 1];
+            this->log();
+        } else if (right && right->keys.size() > minKeys()) {
+            if (child->leaf) {
+                child->keys.push_back(right->keys.front());
+                right->keys.erase(right->keys.begin());
+                parent->keys[index] = right->keys.front();
+            } else {
+                child->keys.push_back(parent->keys[index]);
+                parent->keys[index] = right->keys.front();
+                right->keys.erase(right->keys.begin());
+                child->children.push_back(right->children.front());
+                right->children.erase(right->children.begin());
+            }
+            this->buffer << "[BORROW_RIGHT] node=" << child << " from=" << right << " separator=" << parent->keys[index];
+            this->log();
+        } else if (left) {
+            merge(parent, index - 1);
+        } else {
+            merge(parent, index);
+        }
+    }
+
+    // Merges the child at index + 1 of parent into the child at index
+    void merge(BPlusNode* parent, size_t index) {
+        BPlusNode* left = parent->children[index];
+        BPlusNode* right = parent->children[index + 1];
+        if (left->leaf) {
+            left->keys.insert(left->keys.end(), right->keys.begin(), right->keys.end());
+            link(left, right->next);
+        } else {
+            // The separator between them comes back down
+            left->keys.push_back(parent->keys[index]);
+            left->keys.insert(left->keys.end(), right->keys.begin(), right->keys.end());
+            left->children.insert(left->children.end(), right->children.begin(), right->children.end());
+            right->children.clear();
+        }
+        this->buffer << "[MERGE] node=" << left << " deleted=" << right << " separator=" << parent->keys[index] << " keys=";
+        logKeys(left->keys);
+        this->log();
+        parent->keys.erase(parent->keys.begin() + index);
+        parent->children.erase(parent->children.begin() + index + 1);
+        delete right;
+    }
+
+    BPlusNode* findLeaf(const T& value) {
+        BPlusNode* node = root;
+        while (!node->leaf) {
+            size_t index = childIndex(node, value);
+            logTraverse(node, index);
+            node = node->children[index];
+        }
+        return node;
+    }
+
+    // Range scan without leaf links: visits every subtree that overlaps [from, to]
+    void scanSubtree(BPlusNode* node, const T& from, const T& to, std::vector<T>& keys, size_t& leaves) {
+        if (node->leaf) {
+            scanLeaf(node, from, to, keys);
+            leaves++;
+            return;
+        }
+        for (size_t i = 0; i < node->children.size(); i++) {
+            bool after_from = i == node->keys.size() || from < node->keys[i];
+            bool before_to = i == 0 || node->keys[i - 1] <= to;
+            if (after_from && before_to) {
+                logTraverse(node, i);
+                scanSubtree(node->children[i], from, to, keys, leaves);
+            }
+        }
+    }
+
+    // Collects the keys of leaf within [from, to]; returns false once past to
+    bool scanLeaf(BPlusNode* leaf, const T& from, const T& to, std::vector<T>& keys) {
+        this->buffer << "[SCAN_LEAF] leaf=" << leaf << " keys=";
+        logKeys(leaf->keys);
+        this->log();
+        for (const T& key : leaf->keys) {
+            if (to < key) return false;
+            if (!(key < from)) keys.push_back(key);
+        }
+        return true;
+    }
+
+    void destroy(BPlusNode* node) {
+        for (BPlusNode* child : node->children) destroy(child);
+        delete node;
+    }
+
+    void printNode(std::ostream& os, const BPlusNode* node, size_t depth) const {
+        os << std::string(depth * 4, ' ') << "[";
+        for (size_t i = 0; i < node->keys.size(); i++) {
+            os << (i ? ", " : "") << node->keys[i];
+        }
+        os << "]" << std::endl;
+        for (const BPlusNode* child : node->children) printNode(os, child, depth + 1);
+    }
+
+    void collectLeaves(const BPlusNode* node, std::vector<const BPlusNode*>& leaves) const {
+        if (node->leaf) {
+            leaves.push_back(node);
+            return;
+        }
+        for (const BPlusNode* child : node->children) collectLeaves(child, leaves);
+    }
+
+public:
+    LogBPlusTree(size_t tree_order, bool links, std::ostream& os = std::cout)
+        : LogDatas(os), root(new BPlusNode(true)), count(0), order(tree_order), leaf_links(links) {
+        this->buffer << "[TREE_INIT] order=" << order << " leaf_links=" << (leaf_links ? "on" : "off") << " root=" << root;
+        this->log();
+    }
+
+    ~LogBPlusTree() override {
+        destroy(root);
+    }
+
+    LogBPlusTree(const LogBPlusTree&) = delete;
+    LogBPlusTree& operator=(const LogBPlusTree&) = delete;
+
+    // Returns false if the value was already present
+    bool insert(const T& value) {
+        this->buffer << "[BPT_INSERT] value=" << value;
+        this->log();
+        T promoted;
+        bool inserted = false;
+        BPlusNode* split = insertAt(root, value, promoted, inserted);
+        if (split) {
+            BPlusNode* new_root = new BPlusNode(false);
+            new_root->keys.push_back(promoted);
+            new_root->children = {root, split};
+            this->buffer << "[NEW_ROOT] root=" << new_root << " separator=" << promoted << " left=" << root << " right=" << split;
+            this->log();
+            root = new_root;
+        }
+        if (inserted) count++;
+        return inserted;
+    }
+
+    // Returns false if the value was not found
+    bool remove(const T& value) {
+        this->buffer << "[BPT_REMOVE] value=" << value;
+        this->log();
+        if (!removeAt(root, value)) {
+            this->buffer << "[BPT_REMOVE_FAILED] value=" << value;
+            this->log();
+            return false;
+        }
+        count--;
+        if (!root->leaf && root->keys.empty()) {
+            BPlusNode* old_root = root;
+            root = root->children.front();
+            this->buffer << "[ROOT_SHRINK] old=" << old_root << " new=" << root;
+            this->log();
+            old_root->children.clear();
+            delete old_root;
+        }
+        return true;
+    }
+
+    bool find(const T& value) {
+        this->buffer << "[BPT_FIND] value=" << value;
+        this->log();
+        BPlusNode* leaf = findLeaf(value);
+        bool found = std::binary_search(leaf->keys.begin(), leaf->keys.end(), value);
+        this->buffer << "[BPT_FIND_RESULT] value=" << value << " leaf=" << leaf << " found=" << (found ? "true" : "false");
+        this->log();
+        return found;
+    }
+
+    // Returns the keys within [from, to] in order; leaves counts the leaves read
+    std::vector<T> scan(const T& from, const T& to, size_t& leaves) {
+        this->buffer << "[BPT_SCAN] from=" << from << " to=" << to << " leaf_links=" << (leaf_links ? "on" : "off");
+        this->log();
+        std::vector<T> keys;
+        leaves = 0;
+        if (!leaf_links) {
+            scanSubtree(root, from, to, keys, leaves);
+        } else {
+            // Descend once, then follow the chain
+            BPlusNode* leaf = findLeaf(from);
+            while (leaf) {
+                leaves++;
+                if (!scanLeaf(leaf, from, to, keys) || !leaf->next) break;
+                this->buffer << "[LEAF_FOLLOW] from=" << leaf << " to=" << leaf->next;
+                this->log();
+                leaf = leaf->next;
+            }
+        }
+        this->buffer << "[BPT_SCAN_RESULT] from=" << from << " to=" << to << " count=" << keys.size() << " leaves=" << leaves;
+        this->log();
+        return keys;
+    }
+
+    size_t size() const { return count; }
+    size_t getOrder() const { return order; }
+    bool leafLinks() const { return leaf_links; }
+
+    size_t height() const {
+        size_t height = 1;
+        for (const BPlusNode* node = root; !node->leaf; node = node->children.front()) height++;
+        return height;
+    }
+
+    // Nodes indented by depth, like the B-tree dump
+    void printTree(std::ostream& os) const {
+        printNode(os, root, 0);
+    }
+
+    // One line per leaf, left to right
+    void printLeaves(std::ostream& os) const {
+        std::vector<const BPlusNode*> leaves;
+        collectLeaves(root, leaves);
+        for (const BPlusNode* leaf : leaves) {
+            if (leaf->keys.empty()) continue;
+            os << "[";
+            for (size_t i = 0; i < leaf->keys.size(); i++) {
+                os << (i ? ", " : "") << leaf->keys[i];
+            }
+            os << "]" << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_BPLUS_TREE_HPP
@@ -0,0 +1,105 @@
+#ifndef LOG_BLOOM_FILTER_HPP
+#define LOG_BLOOM_FILTER_HPP
+
+#include <algorithm>
+#include <cstdint>
+#include <functional>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Bloom filter over a fixed bit array. A key sets the bits chosen by its hash
+// functions and a lookup checks the same bits: any clear bit proves the key was
+// never inserted, all set only means it may have been. The functions are derived
+// from two hashes of the key (index_i = h1 + i * h2), so the bits a key maps to
+// are the same on every run and the logs can be followed bit by bit.
+template<typename T>
+class LogBloomFilter : public LogDatas {
+private:
+    std::vector<bool> bits;
+    size_t hashes;
+    size_t inserted;
+    size_t set_count;
+
+    static uint64_t mix(uint64_t x) {
+        // splitmix64 finalizer: std::hash of an int is the int itself
+        x += 0x9e3779b97f4a7c15ULL;
+        x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9ULL;
+        x = (x ^ (x >> 27)) * 0x94d049bb133111ebULL;
+        return x ^ (x >> 31);
+    }
+
+    size_t indexOf(const T& key, size_t i) const {
+        uint64_t h1 = mix(std::hash<T>{}(key));
+        uint64_t h2 = mix(h1) | 1; // odd, so the indexes of one key do not repeat early
+        return static_cast<size_t>((h1 + i * h2) % bits.size());
+    }
+
+public:
+    LogBloomFilter(size_t bit_count, size_t hash_count, std::ostream& os = std::cout)
+        : LogDatas(os), bits(bit_count, false), hashes(hash_count), inserted(0), set_count(0) {}
+
+    // Sets the bits of key; returns how many of them were newly set
+    // (0 means the filter already reported the key as maybe present)
+    size_t insert(const T& key) {
+        size_t newly_set = 0;
+        for (size_t i = 0; i < hashes; i++) {
+            size_t index = indexOf(key, i);
+            if (bits[index]) {
+                this->buffer << "[BIT_ALREADY_SET] key=" << key << " hash=" << i << " index=" << index;
+            } else {
+                bits[index] = true;
+                set_count++;
+                newly_set++;
+                this->buffer << "[BIT_SET] key=" << key << " hash=" << i << " index=" << index;
+            }
+            this->log();
+        }
+        inserted++;
+        return newly_set;
+    }
+
+    // Checks the bits of key, stopping at the first clear one
+    // Returns false when the key is certainly absent
+    bool mightContain(const T& key) {
+        for (size_t i = 0; i < hashes; i++) {
+            size_t index = indexOf(key, i);
+            this->buffer << "[BIT_CHECK] key=" << key << " hash=" << i << " index=" << index
+                         << " set=" << (bits[index] ? "true" : "false");
+            this->log();
+            if (!bits[index]) return false;
+        }
+        return true;
+    }
+
+    size_t bitCount() const { return bits.size(); }
+    size_t hashCount() const { return hashes; }
+    size_t insertedCount() const { return inserted; }
+    size_t setBits() const { return set_count; }
+
+    // Chance that a key never inserted is reported as maybe present, given the bits set now
+    double currentFalsePositiveRate() const {
+        double fill = static_cast<double>(set_count) / bits.size();
+        double rate = 1.0;
+        for (size_t i = 0; i < hashes; i++) rate *= fill;
+        return rate;
+    }
+
+    // Prints the bit array in rows of 64 as "offset: 0101...", skipping rows with no bit set
+    void printBits(std::ostream& out) const {
+        for (size_t row = 0; row < bits.size(); row += 64) {
+            size_t end = std::min(row + 64, bits.size());
+            bool any = false;
+            for (size_t i = row; i < end && !any; i++) any = bits[i];
+            if (!any) continue;
+            out << row << ": ";
+            for (size_t i = row; i < end; i++) out << (bits[i] ? '1' : '0');
+            out << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_BLOOM_FILTER_HPP
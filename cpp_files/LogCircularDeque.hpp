@@ -0,0 +1,144 @@
+#ifndef LOG_CIRCULAR_DEQUE_HPP
+#define LOG_CIRCULAR_DEQUE_HPP
+
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Double-ended queue in a fixed circular buffer. head is the slot of the front
+// item and the items run forward from it, wrapping past the last slot; the logs
+// name every slot written or cleared so the wrap-around can be followed.
+// A queue is this deque used from the back for pushes and the front for pops.
+template<typename T>
+class LogCircularDeque : public LogDatas {
+private:
+    std::vector<T> slots;
+    std::vector<bool> used;
+    size_t head;
+    size_t count;
+
+    size_t wrap(size_t index) const { return index % slots.size(); }
+    size_t tailSlot() const { return wrap(head + count - 1); }
+
+    void logWrite(const char* end, size_t index, const T& value) {
+        this->buffer << "[SLOT_WRITE] end=" << end << " index=" << index << " value=" << value
+                     << " head=" << head << " size=" << count;
+        this->log();
+    }
+
+    void logClear(const char* end, size_t index, const T& value) {
+        this->buffer << "[SLOT_CLEAR] end=" << end << " index=" << index << " value=" << value
+                     << " head=" << head << " size=" << count;
+        this->log();
+    }
+
+    bool full() {
+        if (count < slots.size()) return false;
+        this->buffer << "[BUFFER_FULL] capacity=" << slots.size();
+        this->log();
+        return true;
+    }
+
+public:
+    LogCircularDeque(size_t capacity, std::ostream& os = std::cout)
+        : LogDatas(os), slots(capacity), used(capacity, false), head(0), count(0) {}
+
+    // Adds value behind the back item; returns false when the buffer is full
+    bool pushBack(const T& value) {
+        if (full()) return false;
+        size_t index = wrap(head + count);
+        if (count > 0 && index < tailSlot()) {
+            this->buffer << "[WRAP] end=back from=" << tailSlot() << " to=" << index;
+            this->log();
+        }
+        slots[index] = value;
+        used[index] = true;
+        count++;
+        logWrite("back", index, value);
+        return true;
+    }
+
+    // Adds value before the front item; returns false when the buffer is full
+    bool pushFront(const T& value) {
+        if (full()) return false;
+        size_t index = wrap(head + slots.size() - 1);
+        if (count > 0 && index > head) {
+            this->buffer << "[WRAP] end=front from=" << head << " to=" << index;
+            this->log();
+        }
+        if (count > 0) head = index;
+        else index = head;
+        slots[index] = value;
+        used[index] = true;
+        count++;
+        logWrite("front", index, value);
+        return true;
+    }
+
+    // Removes the front item into value; returns false when empty
+    bool popFront(T& value) {
+        if (count == 0) return false;
+        size_t index = head;
+        value = slots[index];
+        used[index] = false;
+        count--;
+        head = count == 0 ? head : wrap(head + 1);
+        logClear("front", index, value);
+        return true;
+    }
+
+    // Removes the back item into value; returns false when empty
+    bool popBack(T& value) {
+        if (count == 0) return false;
+        size_t index = tailSlot();
+        value = slots[index];
+        used[index] = false;
+        count--;
+        logClear("back", index, value);
+        return true;
+    }
+
+    bool front(T& value) const {
+        if (count == 0) return false;
+        value = slots[head];
+        return true;
+    }
+
+    bool back(T& value) const {
+        if (count == 0) return false;
+        value = slots[tailSlot()];
+        return true;
+    }
+
+    size_t size() const { return count; }
+    size_t capacity() const { return slots.size(); }
+    size_t headIndex() const { return head; }
+    // Slot the next pushBack writes
+    size_t tailIndex() const { return wrap(head + count); }
+
+    // Prints the items front to back as "[a, b, c]"
+    void printItems(std::ostream& out) const {
+        out << "[";
+        for (size_t i = 0; i < count; i++) {
+            out << (i ? ", " : "") << slots[wrap(head + i)];
+        }
+        out << "]" << std::endl;
+    }
+
+    // Prints one line per slot, "index: value" or "index: -" when free, marking the front and back
+    void printSlots(std::ostream& out) const {
+        for (size_t i = 0; i < slots.size(); i++) {
+            out << i << ": ";
+            if (used[i]) out << slots[i];
+            else out << "-";
+            if (count > 0 && i == head) out << " <front";
+            if (count > 0 && i == tailSlot()) out << " <back";
+            out << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_CIRCULAR_DEQUE_HPP
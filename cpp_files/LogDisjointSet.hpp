@@ -0,0 +1,170 @@
+#ifndef LOG_DISJOINT_SET_HPP
+#define LOG_DISJOINT_SET_HPP
+
+#include <algorithm>
+#include <map>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Disjoint set forest (union-find) over int elements. Path compression and
+// union by rank can each be turned off, so the logs show what every heuristic
+// saves: without them finds walk long parent chains, with them the trees stay flat.
+template<typename T>
+class LogDisjointSet : public LogDatas {
+private:
+    struct SetNode {
+        T parent;
+        size_t rank;   // upper bound of the height while the node is a root; 0 without union by rank
+        size_t size;   // elements in the set while the node is a root
+    };
+
+    std::map<T, SetNode> nodes;   // ordered, so dumps list elements ascending
+    size_t set_count;
+    bool path_compression;
+    bool union_by_rank;
+
+    // Follows parents to the root, logging every step, then compresses the path if enabled
+    T findRoot(const T& value, size_t& depth) {
+        std::vector<T> path;
+        T current = value;
+        while (nodes.at(current).parent != current) {
+            const T& parent = nodes.at(current).parent;
+            this->buffer << "[FIND_STEP] node=" << current << " parent=" << parent;
+            this->log();
+            path.push_back(current);
+            current = parent;
+        }
+        depth = path.size();
+        if (path_compression) {
+            for (const T& node : path) {
+                SetNode& entry = nodes.at(node);
+                if (entry.parent == current) continue;
+                this->buffer << "[PATH_COMPRESS] node=" << node << " old_parent=" << entry.parent << " new_parent=" << current;
+                this->log();
+                entry.parent = current;
+            }
+        }
+        return current;
+    }
+
+    size_t depthOf(T value) const {
+        size_t depth = 0;
+        while (nodes.at(value).parent != value) {
+            value = nodes.at(value).parent;
+            depth++;
+        }
+        return depth;
+    }
+
+public:
+    LogDisjointSet(bool compress, bool by_rank, std::ostream& os = std::cout)
+        : LogDatas(os), set_count(0), path_compression(compress), union_by_rank(by_rank) {}
+
+    // Adds value as a set of its own; returns false if it is already an element
+    bool makeSet(const T& value) {
+        if (nodes.count(value)) return false;
+        nodes[value] = SetNode{value, 0, 1};
+        set_count++;
+        this->buffer << "[MAKE_SET] value=" << value;
+        this->log();
+        return true;
+    }
+
+    bool contains(const T& value) const { return nodes.count(value) > 0; }
+
+    // Returns the root of value's set and the steps it took to get there; value must be an element
+    T find(const T& value, size_t& depth) {
+        this->buffer << "[DSU_FIND] value=" << value;
+        this->log();
+        T root = findRoot(value, depth);
+        this->buffer << "[DSU_FIND_RESULT] value=" << value << " root=" << root << " depth=" << depth;
+        this->log();
+        return root;
+    }
+
+    // Merges the sets of a and b; returns false if they were already one set
+    // root receives the root of the merged set
+    bool unite(const T& a, const T& b, T& root) {
+        this->buffer << "[DSU_UNION] a=" << a << " b=" << b;
+        this->log();
+        size_t depth;
+        T root_a = findRoot(a, depth);
+        T root_b = findRoot(b, depth);
+        if (root_a == root_b) {
+            root = root_a;
+            this->buffer << "[UNION_SAME_SET] root=" << root;
+            this->log();
+            return false;
+        }
+
+        // Without union by rank the second set always hangs under the first
+        T child = root_b, parent = root_a;
+        if (union_by_rank && nodes.at(root_a).rank < nodes.at(root_b).rank) {
+            std::swap(child, parent);
+        }
+        SetNode& parent_node = nodes.at(parent);
+        SetNode& child_node = nodes.at(child);
+        this->buffer << "[LINK] child=" << child << " child_rank=" << child_node.rank
+                     << " parent=" << parent << " parent_rank=" << parent_node.rank;
+        this->log();
+        child_node.parent = parent;
+        parent_node.size += child_node.size;
+        if (union_by_rank && parent_node.rank <= child_node.rank) {
+            parent_node.rank = child_node.rank + 1;
+            this->buffer << "[RANK_INCREASE] node=" << parent << " rank=" << parent_node.rank;
+            this->log();
+        }
+        set_count--;
+        root = parent;
+        return true;
+    }
+
+    size_t setSize(const T& root) const { return nodes.at(root).size; }
+    size_t size() const { return nodes.size(); }
+    size_t sets() const { return set_count; }
+    bool pathCompression() const { return path_compression; }
+    bool unionByRank() const { return union_by_rank; }
+
+    // Longest parent chain in the forest
+    size_t height() const {
+        size_t height = 0;
+        for (const auto& entry : nodes) {
+            height = std::max(height, depthOf(entry.first));
+        }
+        return height;
+    }
+
+    // One line per set: "root: member member ..."
+    void printSets(std::ostream& os) const {
+        std::map<T, std::vector<T>> members;
+        for (const auto& entry : nodes) {
+            T root = entry.first;
+            while (nodes.at(root).parent != root) root = nodes.at(root).parent;
+            members[root].push_back(entry.first);
+        }
+        for (const auto& set : members) {
+            os << set.first << ":";
+            for (const T& member : set.second) os << " " << member;
+            os << std::endl;
+        }
+    }
+
+    // One line per element with its parent, rank and depth
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogDisjointSet Structure:" << std::endl;
+        if (nodes.empty()) {
+            os << "(empty)" << std::endl;
+            return;
+        }
+        for (const auto& entry : nodes) {
+            os << entry.first << " -> " << entry.second.parent << " (rank=" << entry.second.rank
+               << " depth=" << depthOf(entry.first) << ")" << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_DISJOINT_SET_HPP
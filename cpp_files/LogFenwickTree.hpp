@@ -0,0 +1,128 @@
+#ifndef LOG_FENWICK_TREE_HPP
+#define LOG_FENWICK_TREE_HPP
+
+#include <string>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Fenwick tree (binary indexed tree) of prefix sums over a fixed size array.
+// Indexes are 0-based outside; node i (1-based) holds the sum of the lowbit(i)
+// values ending at i, logged with that range so a visualizer can draw it.
+// Slots not set yet count as 0 and print as "_".
+class LogFenwickTree : public LogDatas {
+private:
+    size_t n;
+    std::vector<long long> tree;   // tree[0] is unused
+    std::vector<long long> values;
+    std::vector<bool> used;
+    size_t used_count;
+
+    static size_t lowbit(size_t i) { return i & (~i + 1); }
+
+    void logNode(const char* event, size_t node) {
+        this->buffer << "[" << event << "] node=" << node << " range=[" << node - lowbit(node) << ","
+                     << node - 1 << "] value=" << tree[node];
+        this->log();
+    }
+
+    // Adds delta to values[index] and every node covering it
+    void add(size_t index, long long delta) {
+        for (size_t node = index + 1; node <= n; node += lowbit(node)) {
+            tree[node] += delta;
+            logNode("NODE_UPDATE", node);
+        }
+    }
+
+    long long prefix(size_t count) {
+        long long sum = 0;
+        for (size_t node = count; node > 0; node -= lowbit(node)) {
+            logNode("PREFIX_NODE", node);
+            sum += tree[node];
+        }
+        return sum;
+    }
+
+public:
+    LogFenwickTree(size_t size, std::ostream& os = std::cout)
+        : LogDatas(os), n(size), tree(size + 1, 0), values(size, 0), used(size, false), used_count(0) {}
+
+    // Sets the value at index; returns false if index is out of range
+    bool set(size_t index, long long value) {
+        if (index >= n) return false;
+        this->buffer << "[FENWICK_SET] index=" << index << " value=" << value << " delta=" << value - values[index];
+        this->log();
+        if (!used[index]) {
+            used[index] = true;
+            used_count++;
+        }
+        add(index, value - values[index]);
+        values[index] = value;
+        return true;
+    }
+
+    // Adds delta to the value at index; returns false if index is out of range
+    bool update(size_t index, long long delta) {
+        if (index >= n) return false;
+        return set(index, values[index] + delta);
+    }
+
+    // Sets the first slot not set yet; returns its index, or -1 when every slot is set
+    long append(long long value) {
+        for (size_t i = 0; i < n; i++) {
+            if (!used[i]) {
+                set(i, value);
+                return static_cast<long>(i);
+            }
+        }
+        return -1;
+    }
+
+    // Sums values[0..index]
+    long long prefixSum(size_t index) {
+        this->buffer << "[FENWICK_PREFIX] to=" << index;
+        this->log();
+        long long sum = prefix(index + 1);
+        this->buffer << "[FENWICK_PREFIX_RESULT] to=" << index << " value=" << sum;
+        this->log();
+        return sum;
+    }
+
+    // Sums values[from..to] as two prefix sums
+    long long query(size_t from, size_t to) {
+        this->buffer << "[FENWICK_QUERY] from=" << from << " to=" << to;
+        this->log();
+        long long sum = prefix(to + 1) - prefix(from);
+        this->buffer << "[FENWICK_QUERY_RESULT] from=" << from << " to=" << to << " value=" << sum;
+        this->log();
+        return sum;
+    }
+
+    long long valueAt(size_t index) const { return values[index]; }
+    size_t capacity() const { return n; }
+    size_t size() const { return used_count; }
+
+    // "[3, _, 7]" with "_" for slots not set yet
+    void printArray(std::ostream& os) const {
+        os << "[";
+        for (size_t i = 0; i < n; i++) {
+            os << (i ? ", " : "");
+            if (used[i]) os << values[i];
+            else os << "_";
+        }
+        os << "]" << std::endl;
+    }
+
+    // One line per node with the range it sums
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogFenwickTree Structure:" << std::endl;
+        for (size_t node = 1; node <= n; node++) {
+            os << "node " << node << " [" << node - lowbit(node) << "," << node - 1 << "] " << tree[node] << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_FENWICK_TREE_HPP
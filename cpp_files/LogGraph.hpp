@@ -0,0 +1,216 @@
+#ifndef LOG_GRAPH_HPP
+#define LOG_GRAPH_HPP
+
+#include <deque>
+#include <functional>
+#include <map>
+#include <queue>
+#include <set>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Weighted graph over int vertices, directed or undirected. Traversals log their
+// frontier after every step, so BFS shows its queue growing level by level, DFS its
+// stack, and Dijkstra the tentative distances waiting in its priority queue.
+template<typename T>
+class LogGraph : public LogDatas {
+private:
+    std::map<T, std::map<T, long long>> adjacency;   // ordered, so traversals are deterministic
+    size_t edge_count;
+    bool directed;
+
+    template<typename Container>
+    void logFrontier(const char* algorithm, const Container& frontier) {
+        this->buffer << "[FRONTIER] algorithm=" << algorithm << " size=" << frontier.size() << " vertices=";
+        bool first = true;
+        for (const T& vertex : frontier) {
+            this->buffer << (first ? "" : ",") << vertex;
+            first = false;
+        }
+        this->log();
+    }
+
+public:
+    LogGraph(bool is_directed, std::ostream& os = std::cout)
+        : LogDatas(os), edge_count(0), directed(is_directed) {}
+
+    // Adds a vertex without edges; returns false if it exists
+    bool addVertex(const T& vertex) {
+        if (adjacency.count(vertex)) return false;
+        adjacency[vertex];
+        this->buffer << "[VERTEX_ADD] vertex=" << vertex;
+        this->log();
+        return true;
+    }
+
+    // Adds an edge, creating missing endpoints; an existing edge only gets the new weight
+    // Returns false if the edge existed
+    bool addEdge(const T& from, const T& to, long long weight) {
+        addVertex(from);
+        addVertex(to);
+        bool existed = adjacency[from].count(to) > 0;
+        adjacency[from][to] = weight;
+        if (!directed) adjacency[to][from] = weight;
+        if (existed) {
+            this->buffer << "[EDGE_UPDATE] from=" << from << " to=" << to << " weight=" << weight;
+        } else {
+            edge_count++;
+            this->buffer << "[EDGE_ADD] from=" << from << " to=" << to << " weight=" << weight
+                         << " directed=" << (directed ? "true" : "false");
+        }
+        this->log();
+        return !existed;
+    }
+
+    // Removes an edge; returns false if there is none
+    bool removeEdge(const T& from, const T& to) {
+        auto it = adjacency.find(from);
+        if (it == adjacency.end() || !it->second.erase(to)) return false;
+        if (!directed) adjacency[to].erase(from);
+        edge_count--;
+        this->buffer << "[EDGE_REMOVE] from=" << from << " to=" << to;
+        this->log();
+        return true;
+    }
+
+    // Removes a vertex and every edge touching it; returns false if it does not exist
+    bool removeVertex(const T& vertex) {
+        auto it = adjacency.find(vertex);
+        if (it == adjacency.end()) return false;
+        size_t removed = it->second.size();
+        adjacency.erase(it);
+        for (auto& [other, edges] : adjacency) {
+            if (edges.erase(vertex) && directed) removed++;
+        }
+        edge_count -= removed;
+        this->buffer << "[VERTEX_REMOVE] vertex=" << vertex << " edges_removed=" << removed;
+        this->log();
+        return true;
+    }
+
+    bool contains(const T& vertex) const { return adjacency.count(vertex) > 0; }
+    size_t degree(const T& vertex) const { return adjacency.at(vertex).size(); }
+    size_t size() const { return adjacency.size(); }
+    size_t edges() const { return edge_count; }
+    bool isDirected() const { return directed; }
+
+    // Breadth-first traversal from source; fills order with the vertices as they are visited
+    void bfs(const T& source, std::vector<T>& order) {
+        this->buffer << "[TRAVERSAL_START] algorithm=bfs source=" << source;
+        this->log();
+        std::map<T, size_t> depth{{source, 0}};
+        std::deque<T> frontier{source};
+        logFrontier("bfs", frontier);
+        while (!frontier.empty()) {
+            T vertex = frontier.front();
+            frontier.pop_front();
+            order.push_back(vertex);
+            this->buffer << "[VISIT] algorithm=bfs vertex=" << vertex << " depth=" << depth[vertex];
+            this->log();
+            for (const auto& [next, weight] : adjacency.at(vertex)) {
+                bool discovered = depth.count(next) == 0;
+                this->buffer << "[EDGE_EXPLORE] from=" << vertex << " to=" << next
+                             << " discovered=" << (discovered ? "true" : "false");
+                this->log();
+                if (discovered) {
+                    depth[next] = depth[vertex] + 1;
+                    frontier.push_back(next);
+                }
+            }
+            logFrontier("bfs", frontier);
+        }
+        this->buffer << "[TRAVERSAL_END] algorithm=bfs visited=" << order.size();
+        this->log();
+    }
+
+    // Depth-first traversal from source with an explicit stack, visiting lower neighbors first
+    void dfs(const T& source, std::vector<T>& order) {
+        this->buffer << "[TRAVERSAL_START] algorithm=dfs source=" << source;
+        this->log();
+        std::set<T> visited;
+        std::vector<T> frontier{source};
+        logFrontier("dfs", frontier);
+        while (!frontier.empty()) {
+            T vertex = frontier.back();
+            frontier.pop_back();
+            if (!visited.insert(vertex).second) {
+                this->buffer << "[SKIP] algorithm=dfs vertex=" << vertex << " reason=visited";
+                this->log();
+                continue;
+            }
+            order.push_back(vertex);
+            this->buffer << "[VISIT] algorithm=dfs vertex=" << vertex << " stack_depth=" << frontier.size();
+            this->log();
+            const auto& edges = adjacency.at(vertex);
+            for (auto it = edges.rbegin(); it != edges.rend(); ++it) {
+                bool discovered = visited.count(it->first) == 0;
+                this->buffer << "[EDGE_EXPLORE] from=" << vertex << " to=" << it->first
+                             << " discovered=" << (discovered ? "true" : "false");
+                this->log();
+                if (discovered) frontier.push_back(it->first);
+            }
+            logFrontier("dfs", frontier);
+        }
+        this->buffer << "[TRAVERSAL_END] algorithm=dfs visited=" << order.size();
+        this->log();
+    }
+
+    // Shortest paths from source; weights must not be negative
+    // Fills distance and previous for every reached vertex
+    void dijkstra(const T& source, std::map<T, long long>& distance, std::map<T, T>& previous) {
+        this->buffer << "[TRAVERSAL_START] algorithm=dijkstra source=" << source;
+        this->log();
+        using Entry = std::pair<long long, T>;
+        std::priority_queue<Entry, std::vector<Entry>, std::greater<Entry>> queue;
+        std::set<T> settled;
+        std::set<T> frontier{source};
+        distance[source] = 0;
+        queue.push({0, source});
+        logFrontier("dijkstra", frontier);
+        while (!queue.empty()) {
+            auto [dist, vertex] = queue.top();
+            queue.pop();
+            if (!settled.insert(vertex).second) continue;   // a stale entry of a relaxed vertex
+            frontier.erase(vertex);
+            this->buffer << "[VISIT] algorithm=dijkstra vertex=" << vertex << " distance=" << dist;
+            this->log();
+            for (const auto& [next, weight] : adjacency.at(vertex)) {
+                if (settled.count(next)) continue;
+                long long candidate = dist + weight;
+                auto known = distance.find(next);
+                if (known != distance.end() && known->second <= candidate) continue;
+                this->buffer << "[RELAX] from=" << vertex << " to=" << next << " old=";
+                if (known == distance.end()) this->buffer << "inf";
+                else this->buffer << known->second;
+                this->buffer << " new=" << candidate;
+                this->log();
+                distance[next] = candidate;
+                previous[next] = vertex;
+                frontier.insert(next);
+                queue.push({candidate, next});
+            }
+            logFrontier("dijkstra", frontier);
+        }
+        this->buffer << "[TRAVERSAL_END] algorithm=dijkstra visited=" << settled.size();
+        this->log();
+    }
+
+    // Prints one line per vertex: "v: a(w=1), b(w=2)"
+    void printAdjacency(std::ostream& out) const {
+        for (const auto& [vertex, edges] : adjacency) {
+            out << vertex << ":";
+            bool first = true;
+            for (const auto& [next, weight] : edges) {
+                out << (first ? " " : ", ") << next << "(w=" << weight << ")";
+                first = false;
+            }
+            out << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_GRAPH_HPP
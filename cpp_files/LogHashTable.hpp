@@ -0,0 +1,254 @@
+#ifndef LOG_HASH_TABLE_HPP
+#define LOG_HASH_TABLE_HPP
+
+#include <list>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Hash table of int keys that logs every bucket it touches, with either separate
+// chaining or open addressing by linear probing. The table doubles its buckets
+// when the load factor passes max_load, logging each key it moves.
+class LogHashTable : public LogDatas {
+public:
+    enum Strategy { CHAINING, OPEN_ADDRESSING };
+
+private:
+    enum SlotState { EMPTY, OCCUPIED, DELETED };
+
+    struct Slot {
+        SlotState state = EMPTY;
+        int key = 0;
+    };
+
+    static constexpr double max_load = 0.75;
+
+    Strategy strategy;
+    std::vector<std::list<int>> chains; // used with CHAINING
+    std::vector<Slot> slots;             // used with OPEN_ADDRESSING
+    size_t buckets;
+    size_t count;
+    size_t rehashes;
+
+    size_t bucketOf(int key) const {
+        long long n = static_cast<long long>(buckets);
+        return static_cast<size_t>(((key % n) + n) % n);
+    }
+
+    size_t hashLogged(int key) {
+        size_t bucket = bucketOf(key);
+        this->buffer << "[HASH] key=" << key << " bucket=" << bucket << " buckets=" << buckets;
+        this->log();
+        return bucket;
+    }
+
+    static const char* stateName(SlotState state) {
+        switch (state) {
+            case OCCUPIED: return "occupied";
+            case DELETED: return "deleted";
+            default: return "empty";
+        }
+    }
+
+    // Walks the probe sequence of key; returns the slot holding it, or slots.size()
+    // first_free receives the first empty or deleted slot passed on the way
+    size_t probe(int key, size_t& first_free) {
+        size_t bucket = hashLogged(key);
+        first_free = slots.size();
+        for (size_t step = 0; step < slots.size(); step++) {
+            size_t index = (bucket + step) % slots.size();
+            Slot& slot = slots[index];
+            this->buffer << "[PROBE] bucket=" << index << " step=" << step << " state=" << stateName(slot.state);
+            if (slot.state == OCCUPIED) this->buffer << " key=" << slot.key;
+            this->log();
+            if (slot.state == OCCUPIED && slot.key == key) return index;
+            if (slot.state != OCCUPIED && first_free == slots.size()) first_free = index;
+            if (slot.state == EMPTY) break;
+        }
+        return slots.size();
+    }
+
+    void place(int key) {
+        if (strategy == CHAINING) {
+            chains[bucketOf(key)].push_back(key);
+            return;
+        }
+        size_t index = bucketOf(key);
+        while (slots[index].state == OCCUPIED) index = (index + 1) % slots.size();
+        slots[index].state = OCCUPIED;
+        slots[index].key = key;
+    }
+
+    void rehash() {
+        std::vector<int> keys = this->keys();
+        size_t old_buckets = buckets;
+        this->buffer << "[REHASH_START] from=" << old_buckets << " to=" << old_buckets * 2 << " size=" << count;
+        this->log();
+
+        std::vector<size_t> old_bucket_of;
+        for (int key : keys) old_bucket_of.push_back(bucketOf(key));
+        buckets = old_buckets * 2;
+        chains.assign(strategy == CHAINING ? buckets : 0, std::list<int>());
+        slots.assign(strategy == OPEN_ADDRESSING ? buckets : 0, Slot());
+        for (size_t i = 0; i < keys.size(); i++) {
+            place(keys[i]);
+            this->buffer << "[REHASH_MOVE] key=" << keys[i] << " from=" << old_bucket_of[i] << " to=" << bucketOf(keys[i]);
+            this->log();
+        }
+        rehashes++;
+        this->buffer << "[REHASH_END] buckets=" << buckets;
+        this->log();
+    }
+
+public:
+    LogHashTable(Strategy s = CHAINING, size_t initial_buckets = 8, std::ostream& os = std::cout)
+        : LogDatas(os), strategy(s), buckets(initial_buckets), count(0), rehashes(0) {
+        if (strategy == CHAINING) chains.resize(buckets);
+        else slots.resize(buckets);
+    }
+
+    // Returns false if the key was already present
+    bool insert(int key) {
+        this->buffer << "[HASH_INSERT] key=" << key;
+        this->log();
+
+        if (strategy == CHAINING) {
+            size_t bucket = hashLogged(key);
+            size_t position = 0;
+            for (int existing : chains[bucket]) {
+                this->buffer << "[CHAIN_WALK] bucket=" << bucket << " position=" << position++ << " key=" << existing;
+                this->log();
+                if (existing == key) {
+                    this->buffer << "[DUPLICATE] key=" << key;
+                    this->log();
+                    return false;
+                }
+            }
+            chains[bucket].push_back(key);
+            this->buffer << "[BUCKET_INSERT] bucket=" << bucket << " key=" << key << " position=" << position;
+            this->log();
+        } else {
+            size_t first_free;
+            if (probe(key, first_free) != slots.size()) {
+                this->buffer << "[DUPLICATE] key=" << key;
+                this->log();
+                return false;
+            }
+            slots[first_free].state = OCCUPIED;
+            slots[first_free].key = key;
+            this->buffer << "[BUCKET_INSERT] bucket=" << first_free << " key=" << key;
+            this->log();
+        }
+        count++;
+
+        if (loadFactor() > max_load) {
+            rehash();
+        }
+        return true;
+    }
+
+    bool find(int key) {
+        this->buffer << "[HASH_FIND] key=" << key;
+        this->log();
+        bool found = false;
+        if (strategy == CHAINING) {
+            size_t bucket = hashLogged(key);
+            size_t position = 0;
+            for (int existing : chains[bucket]) {
+                this->buffer << "[CHAIN_WALK] bucket=" << bucket << " position=" << position++ << " key=" << existing;
+                this->log();
+                if (existing == key) {
+                    found = true;
+                    break;
+                }
+            }
+        } else {
+            size_t first_free;
+            found = probe(key, first_free) != slots.size();
+        }
+        this->buffer << "[FIND_RESULT] key=" << key << " found=" << (found ? "true" : "false");
+        this->log();
+        return found;
+    }
+
+    // Returns false if the key was not present
+    // Open addressing leaves a tombstone so later probe sequences stay intact
+    bool remove(int key) {
+        this->buffer << "[HASH_REMOVE] key=" << key;
+        this->log();
+        if (strategy == CHAINING) {
+            size_t bucket = hashLogged(key);
+            size_t position = 0;
+            for (auto it = chains[bucket].begin(); it != chains[bucket].end(); ++it, ++position) {
+                this->buffer << "[CHAIN_WALK] bucket=" << bucket << " position=" << position << " key=" << *it;
+                this->log();
+                if (*it == key) {
+                    chains[bucket].erase(it);
+                    this->buffer << "[BUCKET_REMOVE] bucket=" << bucket << " key=" << key << " position=" << position;
+                    this->log();
+                    count--;
+                    return true;
+                }
+            }
+        } else {
+            size_t first_free;
+            size_t index = probe(key, first_free);
+            if (index != slots.size()) {
+                slots[index].state = DELETED;
+                this->buffer << "[TOMBSTONE] bucket=" << index << " key=" << key;
+                this->log();
+                count--;
+                return true;
+            }
+        }
+        this->buffer << "[FIND_RESULT] key=" << key << " found=false";
+        this->log();
+        return false;
+    }
+
+    std::vector<int> keys() const {
+        std::vector<int> out;
+        if (strategy == CHAINING) {
+            for (const auto& chain : chains) out.insert(out.end(), chain.begin(), chain.end());
+        } else {
+            for (const Slot& slot : slots) {
+                if (slot.state == OCCUPIED) out.push_back(slot.key);
+            }
+        }
+        return out;
+    }
+
+    size_t size() const { return count; }
+    size_t bucketCount() const { return buckets; }
+    size_t rehashCount() const { return rehashes; }
+    double loadFactor() const { return static_cast<double>(count) / buckets; }
+
+    const char* strategyName() const {
+        return strategy == CHAINING ? "chaining" : "openaddr";
+    }
+
+    // One line per bucket: "3: 11 -> 19" when chaining, "3: 11", "3: (empty)" or "3: (deleted)" otherwise
+    void printBuckets(std::ostream& os = std::cout) const {
+        for (size_t i = 0; i < buckets; i++) {
+            os << i << ":";
+            if (strategy == CHAINING) {
+                if (chains[i].empty()) os << " (empty)";
+                bool first = true;
+                for (int key : chains[i]) {
+                    os << (first ? " " : " -> ") << key;
+                    first = false;
+                }
+            } else if (slots[i].state == OCCUPIED) {
+                os << " " << slots[i].key;
+            } else {
+                os << " (" << stateName(slots[i].state) << ")";
+            }
+            os << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_HASH_TABLE_HPP
@@ -0,0 +1,146 @@
+#ifndef LOG_HEAP_HPP
+#define LOG_HEAP_HPP
+
+#include <vector>
+#include <stdexcept>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// d-ary min or max heap stored in an array, logging every comparison and swap
+// so sift-up, sift-down and heapify can be replayed on the array and the tree
+template<typename T>
+class LogHeap : public LogDatas {
+private:
+    std::vector<T> items;
+    bool is_max;
+    int arity;
+
+    // True if a belongs above b
+    bool before(const T& a, const T& b) const {
+        return is_max ? b < a : a < b;
+    }
+
+    size_t parentOf(size_t index) const {
+        return (index - 1) / arity;
+    }
+
+    void swapItems(size_t i, size_t j) {
+        this->buffer << "[SWAP] i=" << i << " j=" << j << " value_i=" << items[i] << " value_j=" << items[j];
+        this->log();
+        std::swap(items[i], items[j]);
+    }
+
+    void siftUp(size_t index) {
+        while (index > 0) {
+            size_t parent = parentOf(index);
+            bool moves = before(items[index], items[parent]);
+            this->buffer << "[SIFT_UP] index=" << index << " value=" << items[index]
+                         << " parent=" << parent << " parent_value=" << items[parent]
+                         << " swap=" << (moves ? "true" : "false");
+            this->log();
+            if (!moves) return;
+            swapItems(index, parent);
+            index = parent;
+        }
+    }
+
+    void siftDown(size_t index) {
+        while (true) {
+            size_t best = index;
+            size_t first = index * arity + 1;
+            for (size_t child = first; child < first + arity && child < items.size(); child++) {
+                if (before(items[child], items[best])) best = child;
+            }
+            this->buffer << "[SIFT_DOWN] index=" << index << " value=" << items[index]
+                         << " best_child=" << (best == index ? -1 : static_cast<long>(best))
+                         << " swap=" << (best != index ? "true" : "false");
+            this->log();
+            if (best == index) return;
+            swapItems(index, best);
+            index = best;
+        }
+    }
+
+public:
+    explicit LogHeap(bool max_heap, int heap_arity, std::ostream& os = std::cout)
+        : LogDatas(os), is_max(max_heap), arity(heap_arity) {
+        if (heap_arity < 2) {
+            throw std::invalid_argument("arity must be >= 2");
+        }
+    }
+
+    void insert(const T& value) {
+        items.push_back(value);
+        this->buffer << "[HEAP_INSERT] value=" << value << " index=" << items.size() - 1;
+        this->log();
+        siftUp(items.size() - 1);
+    }
+
+    // Removes and returns the top item
+    T extract() {
+        if (items.empty()) {
+            throw std::out_of_range("heap is empty");
+        }
+        T top = items.front();
+        this->buffer << "[HEAP_EXTRACT] value=" << top << " last_index=" << items.size() - 1;
+        this->log();
+        if (items.size() > 1) {
+            swapItems(0, items.size() - 1);
+        }
+        items.pop_back();
+        this->buffer << "[NODE_DELETE] index=" << items.size() << " value=" << top;
+        this->log();
+        if (!items.empty()) {
+            siftDown(0);
+        }
+        return top;
+    }
+
+    const T& peek() const {
+        if (items.empty()) {
+            throw std::out_of_range("heap is empty");
+        }
+        return items.front();
+    }
+
+    // Replaces the contents with values and restores the heap bottom-up (Floyd)
+    void heapify(const std::vector<T>& values) {
+        items = values;
+        this->buffer << "[HEAPIFY] size=" << items.size() << " array=[";
+        for (size_t i = 0; i < items.size(); i++) {
+            this->buffer << (i ? "," : "") << items[i];
+        }
+        this->buffer << "]";
+        this->log();
+        if (items.size() < 2) return;
+        for (size_t i = parentOf(items.size() - 1) + 1; i-- > 0; ) {
+            siftDown(i);
+        }
+    }
+
+    size_t size() const { return items.size(); }
+
+    const std::vector<T>& array() const { return items; }
+
+    const char* kind() const { return is_max ? "max" : "min"; }
+
+    int get_arity() const { return arity; }
+
+    // Writes the array one tree level per line
+    void printLevels(std::ostream& os) const {
+        size_t start = 0, width = 1;
+        while (start < items.size()) {
+            for (size_t i = start; i < start + width && i < items.size(); i++) {
+                os << (i == start ? "" : " ") << items[i];
+            }
+            os << std::endl;
+            start += width;
+            width *= arity;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_HEAP_HPP
@@ -0,0 +1,305 @@
+#ifndef LOG_INTERVAL_TREE_HPP
+#define LOG_INTERVAL_TREE_HPP
+
+#include <algorithm>
+#include <utility>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Interval tree: an AVL tree of closed intervals ordered by low end, then high
+// end, where every node also keeps the highest high end in its subtree. An
+// overlap query uses that maximum to skip a left subtree that ends before the
+// query starts, and the ordering to skip a right subtree that starts after it
+// ends; the logs name every node visited and every subtree pruned.
+template<typename T>
+class LogIntervalTree : public LogDatas {
+public:
+    using Interval = std::pair<T, T>;
+
+private:
+    struct IntervalNode {
+        Interval span;
+        T max_high;
+        int height;
+        IntervalNode* left;
+        IntervalNode* right;
+
+        explicit IntervalNode(const Interval& interval)
+            : span(interval), max_high(interval.second), height(1), left(nullptr), right(nullptr) {}
+    };
+
+    IntervalNode* root;
+    size_t count;
+
+    static int heightOf(const IntervalNode* node) { return node ? node->height : 0; }
+
+    void logInterval(const Interval& interval) {
+        this->buffer << "[" << interval.first << "," << interval.second << "]";
+    }
+
+    // Recomputes height and max_high from the children, logging a changed maximum
+    void update(IntervalNode* node) {
+        node->height = 1 + std::max(heightOf(node->left), heightOf(node->right));
+        T high = node->span.second;
+        if (node->left) high = std::max(high, node->left->max_high);
+        if (node->right) high = std::max(high, node->right->max_high);
+        if (high != node->max_high) {
+            this->buffer << "[MAX_UPDATE] node=";
+            logInterval(node->span);
+            this->buffer << " old=" << node->max_high << " new=" << high;
+            this->log();
+            node->max_high = high;
+        }
+    }
+
+    IntervalNode* rotateRight(IntervalNode* node) {
+        IntervalNode* pivot = node->left;
+        this->buffer << "[ROTATE_RIGHT] node=";
+        logInterval(node->span);
+        this->buffer << " left=";
+        logInterval(pivot->span);
+        this->log();
+        node->left = pivot->right;
+        pivot->right = node;
+        update(node);
+        update(pivot);
+        return pivot;
+    }
+
+    IntervalNode* rotateLeft(IntervalNode* node) {
+        IntervalNode* pivot = node->right;
+        this->buffer << "[ROTATE_LEFT] node=";
+        logInterval(node->span);
+        this->buffer << " right=";
+        logInterval(pivot->span);
+        this->log();
+        node->right = pivot->left;
+        pivot->left = node;
+        update(node);
+        update(pivot);
+        return pivot;
+    }
+
+    // Updates node and rotates it back into AVL balance; returns the subtree's new root
+    IntervalNode* rebalance(IntervalNode* node) {
+        update(node);
+        int balance = heightOf(node->left) - heightOf(node->right);
+        if (balance > 1) {
+            if (heightOf(node->left->left) < heightOf(node->left->right)) node->left = rotateLeft(node->left);
+            return rotateRight(node);
+        }
+        if (balance < -1) {
+            if (heightOf(node->right->right) < heightOf(node->right->left)) node->right = rotateRight(node->right);
+            return rotateLeft(node);
+        }
+        return node;
+    }
+
+    void logTraverse(const IntervalNode* node, bool go_left) {
+        this->buffer << "[TRAVERSE] node=";
+        logInterval(node->span);
+        this->buffer << " max=" << node->max_high << " direction=" << (go_left ? "left" : "right");
+        this->log();
+    }
+
+    IntervalNode* insertAt(IntervalNode* node, const Interval& interval, bool& inserted) {
+        if (!node) {
+            this->buffer << "[NODE_CREATE] interval=";
+            logInterval(interval);
+            this->log();
+            inserted = true;
+            count++;
+            return new IntervalNode(interval);
+        }
+        if (interval == node->span) return node;
+        bool go_left = interval < node->span;
+        logTraverse(node, go_left);
+        if (go_left) node->left = insertAt(node->left, interval, inserted);
+        else node->right = insertAt(node->right, interval, inserted);
+        return inserted ? rebalance(node) : node;
+    }
+
+    IntervalNode* removeAt(IntervalNode* node, const Interval& interval, bool& removed) {
+        if (!node) return nullptr;
+        if (interval != node->span) {
+            bool go_left = interval < node->span;
+            logTraverse(node, go_left);
+            if (go_left) node->left = removeAt(node->left, interval, removed);
+            else node->right = removeAt(node->right, interval, removed);
+            return removed ? rebalance(node) : node;
+        }
+
+        removed = true;
+        if (!node->left || !node->right) {
+            IntervalNode* child = node->left ? node->left : node->right;
+            this->buffer << "[NODE_DELETE] interval=";
+            logInterval(node->span);
+            this->log();
+            delete node;
+            count--;
+            return child;
+        }
+
+        // Two children: take the place of the next interval in order, then remove that one
+        IntervalNode* successor = node->right;
+        while (successor->left) successor = successor->left;
+        this->buffer << "[SUCCESSOR] node=";
+        logInterval(node->span);
+        this->buffer << " successor=";
+        logInterval(successor->span);
+        this->log();
+        Interval next = successor->span;
+        node->span = next;
+        bool successor_removed = false;
+        node->right = removeAt(node->right, next, successor_removed);
+        return rebalance(node);
+    }
+
+    void collect(IntervalNode* node, const Interval& query, std::vector<Interval>& found) {
+        if (!node) return;
+        this->buffer << "[QUERY_VISIT] node=";
+        logInterval(node->span);
+        this->buffer << " max=" << node->max_high;
+        this->log();
+
+        if (node->left && node->left->max_high >= query.first) {
+            collect(node->left, query, found);
+        } else if (node->left) {
+            this->buffer << "[PRUNE] node=";
+            logInterval(node->span);
+            this->buffer << " side=left reason=max_below_low max=" << node->left->max_high;
+            this->log();
+        }
+
+        if (node->span.first <= query.second && query.first <= node->span.second) {
+            this->buffer << "[OVERLAP] node=";
+            logInterval(node->span);
+            this->log();
+            found.push_back(node->span);
+        }
+
+        // Everything to the right starts at or after this node
+        if (node->right && node->span.first > query.second) {
+            this->buffer << "[PRUNE] node=";
+            logInterval(node->span);
+            this->buffer << " side=right reason=low_above_high low=" << node->span.first;
+            this->log();
+        } else if (node->right && node->right->max_high < query.first) {
+            this->buffer << "[PRUNE] node=";
+            logInterval(node->span);
+            this->buffer << " side=right reason=max_below_low max=" << node->right->max_high;
+            this->log();
+        } else {
+            collect(node->right, query, found);
+        }
+    }
+
+    void destroy(IntervalNode* node) {
+        if (!node) return;
+        destroy(node->left);
+        destroy(node->right);
+        delete node;
+    }
+
+    void inorder(std::ostream& os, const IntervalNode* node, bool& first) const {
+        if (!node) return;
+        inorder(os, node->left, first);
+        os << (first ? "" : " ") << "[" << node->span.first << "," << node->span.second << "]";
+        first = false;
+        inorder(os, node->right, first);
+    }
+
+    void printNodeStructure(std::ostream& os, const IntervalNode* node, const std::string& prefix = "", bool isLast = true) const {
+        if (node == nullptr) {
+            os << prefix << (isLast ? "└── " : "├── ") << "null" << std::endl;
+            return;
+        }
+
+        os << prefix << (isLast ? "└── " : "├── ") << "[" << node->span.first << "," << node->span.second
+           << "] (max=" << node->max_high << ")" << std::endl;
+
+        if (node->left != nullptr || node->right != nullptr) {
+            printNodeStructure(os, node->left, prefix + (isLast ? "    " : "│   "), node->right == nullptr);
+            printNodeStructure(os, node->right, prefix + (isLast ? "    " : "│   "), true);
+        }
+    }
+
+public:
+    explicit LogIntervalTree(std::ostream& os = std::cout) : LogDatas(os), root(nullptr), count(0) {}
+
+    ~LogIntervalTree() override {
+        destroy(root);
+    }
+
+    LogIntervalTree(const LogIntervalTree&) = delete;
+    LogIntervalTree& operator=(const LogIntervalTree&) = delete;
+
+    // Returns false if the interval was already present
+    bool insert(const T& low, const T& high) {
+        this->buffer << "[INTERVAL_INSERT] interval=[" << low << "," << high << "]";
+        this->log();
+        bool inserted = false;
+        root = insertAt(root, {low, high}, inserted);
+        return inserted;
+    }
+
+    // Returns false if the interval was not found
+    bool remove(const T& low, const T& high) {
+        this->buffer << "[INTERVAL_REMOVE] interval=[" << low << "," << high << "]";
+        this->log();
+        bool removed = false;
+        root = removeAt(root, {low, high}, removed);
+        if (removed) return true;
+        this->buffer << "[INTERVAL_REMOVE_FAILED] interval=[" << low << "," << high << "]";
+        this->log();
+        return false;
+    }
+
+    // Looks up the exact interval
+    bool find(const T& low, const T& high) {
+        Interval interval{low, high};
+        const IntervalNode* node = root;
+        while (node && node->span != interval) {
+            bool go_left = interval < node->span;
+            logTraverse(node, go_left);
+            node = go_left ? node->left : node->right;
+        }
+        return node != nullptr;
+    }
+
+    // Returns every stored interval sharing at least one point with [low, high], in order
+    std::vector<Interval> overlapping(const T& low, const T& high) {
+        this->buffer << "[QUERY_START] interval=[" << low << "," << high << "]";
+        this->log();
+        std::vector<Interval> found;
+        collect(root, {low, high}, found);
+        this->buffer << "[QUERY_END] interval=[" << low << "," << high << "] matches=" << found.size();
+        this->log();
+        return found;
+    }
+
+    size_t size() const { return count; }
+    size_t height() const { return heightOf(root); }
+
+    // Writes the intervals in order on one line as "[low,high]"
+    void inorder(std::ostream& os) const {
+        bool first = true;
+        inorder(os, root, first);
+        if (!first) os << std::endl;
+    }
+
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogIntervalTree Structure:" << std::endl;
+        if (root == nullptr) {
+            os << "└── (empty)" << std::endl;
+        } else {
+            printNodeStructure(os, root);
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_INTERVAL_TREE_HPP
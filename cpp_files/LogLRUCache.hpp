@@ -0,0 +1,108 @@
+#ifndef LOG_LRU_CACHE_HPP
+#define LOG_LRU_CACHE_HPP
+
+#include <list>
+#include <unordered_map>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Least recently used cache of int keys with a fixed capacity. Keys live in a
+// recency list, most recent first, indexed by a hash map; every hit moves its key
+// to the front and an insert into a full cache evicts the key at the back.
+template<typename T>
+class LogLRUCache : public LogDatas {
+private:
+    std::list<T> recency;   // front is the most recently used key
+    std::unordered_map<T, typename std::list<T>::iterator> index;
+    size_t cap;
+    size_t hit_count;
+    size_t miss_count;
+    size_t eviction_count;
+
+    size_t positionOf(typename std::list<T>::iterator it) const {
+        size_t position = 0;
+        for (auto walk = recency.begin(); walk != it; ++walk) position++;
+        return position;
+    }
+
+    void moveToFront(typename std::list<T>::iterator it) {
+        size_t position = positionOf(it);
+        if (position == 0) return;
+        this->buffer << "[MOVE_TO_FRONT] key=" << *it << " from_position=" << position;
+        this->log();
+        recency.splice(recency.begin(), recency, it);
+    }
+
+public:
+    LogLRUCache(size_t capacity, std::ostream& os = std::cout)
+        : LogDatas(os), cap(capacity), hit_count(0), miss_count(0), eviction_count(0) {}
+
+    // Inserts key as the most recent; a present key is only refreshed
+    // Returns false for a present key; evicted is set when the cache was full
+    bool insert(const T& key, bool& evicted, T& evicted_key) {
+        evicted = false;
+        auto found = index.find(key);
+        if (found != index.end()) {
+            this->buffer << "[CACHE_REFRESH] key=" << key;
+            this->log();
+            moveToFront(found->second);
+            return false;
+        }
+        if (recency.size() == cap) {
+            evicted_key = recency.back();
+            evicted = true;
+            eviction_count++;
+            this->buffer << "[EVICT] key=" << evicted_key << " reason=capacity capacity=" << cap;
+            this->log();
+            index.erase(evicted_key);
+            recency.pop_back();
+        }
+        recency.push_front(key);
+        index[key] = recency.begin();
+        this->buffer << "[CACHE_INSERT] key=" << key << " size=" << recency.size() << " capacity=" << cap;
+        this->log();
+        return true;
+    }
+
+    // Looks key up, counting a hit or a miss; a hit becomes the most recent key
+    bool access(const T& key) {
+        auto found = index.find(key);
+        if (found == index.end()) {
+            miss_count++;
+            this->buffer << "[CACHE_MISS] key=" << key;
+            this->log();
+            return false;
+        }
+        hit_count++;
+        this->buffer << "[CACHE_HIT] key=" << key << " position=" << positionOf(found->second);
+        this->log();
+        moveToFront(found->second);
+        return true;
+    }
+
+    bool remove(const T& key) {
+        auto found = index.find(key);
+        if (found == index.end()) return false;
+        this->buffer << "[CACHE_REMOVE] key=" << key << " position=" << positionOf(found->second);
+        this->log();
+        recency.erase(found->second);
+        index.erase(found);
+        return true;
+    }
+
+    size_t size() const { return recency.size(); }
+    size_t capacity() const { return cap; }
+    size_t hits() const { return hit_count; }
+    size_t misses() const { return miss_count; }
+    size_t evictions() const { return eviction_count; }
+
+    // Prints one key per line, most recent first
+    void printRecency(std::ostream& out) const {
+        for (const T& key : recency) out << key << std::endl;
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_LRU_CACHE_HPP
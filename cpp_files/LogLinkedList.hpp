@@ -0,0 +1,242 @@
+#ifndef LOG_LINKED_LIST_HPP
+#define LOG_LINKED_LIST_HPP
+
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Singly or doubly linked list that logs every node it walks past and every
+// pointer it rewires, so beginners can follow how links change
+template<typename T>
+class LogLinkedList : public LogDatas {
+private:
+    struct ListNode {
+        T data;
+        ListNode* next = nullptr;
+        ListNode* prev = nullptr; // unused when singly linked
+
+        explicit ListNode(const T& value) : data(value) {}
+    };
+
+    ListNode* head;
+    ListNode* tail;
+    bool doubly;
+    size_t count;
+
+    void setNext(ListNode* node, ListNode* next) {
+        this->buffer << "[POINTER_CHANGE] node=" << node << " field=next from=" << node->next << " to=" << next;
+        this->log();
+        node->next = next;
+    }
+
+    void setPrev(ListNode* node, ListNode* prev) {
+        if (!doubly) return;
+        this->buffer << "[POINTER_CHANGE] node=" << node << " field=prev from=" << node->prev << " to=" << prev;
+        this->log();
+        node->prev = prev;
+    }
+
+    void setHead(ListNode* node) {
+        if (head == node) return;
+        this->buffer << "[HEAD_CHANGE] from=" << head << " to=" << node;
+        this->log();
+        head = node;
+    }
+
+    void setTail(ListNode* node) {
+        if (tail == node) return;
+        this->buffer << "[TAIL_CHANGE] from=" << tail << " to=" << node;
+        this->log();
+        tail = node;
+    }
+
+    ListNode* createNode(const T& value) {
+        ListNode* node = new ListNode(value);
+        this->buffer << "[NODE_CREATE] address=" << node << " value=" << value;
+        this->log();
+        return node;
+    }
+
+    // Walks from the head to the node at index, logging each hop
+    ListNode* walkTo(size_t index) {
+        ListNode* node = head;
+        for (size_t i = 0; i < index && node; i++) {
+            this->buffer << "[TRAVERSE] index=" << i << " node=" << node << " value=" << node->data;
+            this->log();
+            node = node->next;
+        }
+        return node;
+    }
+
+    // Unlinks node, whose predecessor is prev (null for the head), and frees it
+    void unlink(ListNode* node, ListNode* prev) {
+        if (prev) setNext(prev, node->next);
+        else setHead(node->next);
+        if (node->next) setPrev(node->next, prev);
+        else setTail(prev);
+
+        this->buffer << "[NODE_DELETE] address=" << node << " value=" << node->data;
+        this->log();
+        delete node;
+        count--;
+    }
+
+public:
+    explicit LogLinkedList(bool is_doubly = false, std::ostream& os = std::cout)
+        : LogDatas(os), head(nullptr), tail(nullptr), doubly(is_doubly), count(0) {}
+
+    ~LogLinkedList() override {
+        while (head) {
+            ListNode* next = head->next;
+            delete head;
+            head = next;
+        }
+    }
+
+    LogLinkedList(const LogLinkedList&) = delete;
+    LogLinkedList& operator=(const LogLinkedList&) = delete;
+
+    void pushFront(const T& value) {
+        this->buffer << "[LIST_INSERT] value=" << value << " position=0";
+        this->log();
+        ListNode* node = createNode(value);
+        if (head) {
+            setNext(node, head);
+            setPrev(head, node);
+        } else {
+            setTail(node);
+        }
+        setHead(node);
+        count++;
+    }
+
+    void pushBack(const T& value) {
+        this->buffer << "[LIST_INSERT] value=" << value << " position=" << count;
+        this->log();
+        ListNode* node = createNode(value);
+        if (tail) {
+            setNext(tail, node);
+            setPrev(node, tail);
+        } else {
+            setHead(node);
+        }
+        setTail(node);
+        count++;
+    }
+
+    // Returns false if index is past the end
+    bool insertAt(size_t index, const T& value) {
+        if (index > count) return false;
+        if (index == 0) {
+            pushFront(value);
+            return true;
+        }
+        if (index == count) {
+            pushBack(value);
+            return true;
+        }
+
+        this->buffer << "[LIST_INSERT] value=" << value << " position=" << index;
+        this->log();
+        ListNode* before = walkTo(index - 1);
+        ListNode* node = createNode(value);
+        setNext(node, before->next);
+        setPrev(node, before);
+        setPrev(before->next, node);
+        setNext(before, node);
+        count++;
+        return true;
+    }
+
+    // Removes the first node holding value; returns false if there is none
+    bool remove(const T& value) {
+        this->buffer << "[LIST_REMOVE] value=" << value;
+        this->log();
+        ListNode* prev = nullptr;
+        size_t index = 0;
+        for (ListNode* node = head; node; prev = node, node = node->next, index++) {
+            this->buffer << "[TRAVERSE] index=" << index << " node=" << node << " value=" << node->data;
+            this->log();
+            if (node->data == value) {
+                unlink(node, prev);
+                return true;
+            }
+        }
+        this->buffer << "[FIND_RESULT] value=" << value << " found=false";
+        this->log();
+        return false;
+    }
+
+    // Removes the node at index; returns false if index is past the end
+    bool removeAt(size_t index, T& removed) {
+        if (index >= count) return false;
+        this->buffer << "[LIST_REMOVE] position=" << index;
+        this->log();
+        ListNode* prev = index == 0 ? nullptr : walkTo(index - 1);
+        ListNode* node = prev ? prev->next : head;
+        removed = node->data;
+        unlink(node, prev);
+        return true;
+    }
+
+    // Returns the index of the first node holding value, or -1
+    long find(const T& value) {
+        this->buffer << "[LIST_FIND] value=" << value;
+        this->log();
+        long index = 0;
+        for (ListNode* node = head; node; node = node->next, index++) {
+            this->buffer << "[TRAVERSE] index=" << index << " node=" << node << " value=" << node->data;
+            this->log();
+            if (node->data == value) {
+                this->buffer << "[FIND_RESULT] value=" << value << " found=true index=" << index;
+                this->log();
+                return index;
+            }
+        }
+        this->buffer << "[FIND_RESULT] value=" << value << " found=false";
+        this->log();
+        return -1;
+    }
+
+    // Reverses the links in place
+    void reverse() {
+        this->buffer << "[LIST_REVERSE] size=" << count;
+        this->log();
+        ListNode* prev = nullptr;
+        ListNode* node = head;
+        ListNode* old_head = head;
+        while (node) {
+            ListNode* next = node->next;
+            setNext(node, prev);
+            setPrev(node, next);
+            prev = node;
+            node = next;
+        }
+        setHead(prev);
+        setTail(old_head);
+    }
+
+    size_t size() const { return count; }
+    bool isDoubly() const { return doubly; }
+
+    std::vector<T> values() const {
+        std::vector<T> out;
+        for (ListNode* node = head; node; node = node->next) out.push_back(node->data);
+        return out;
+    }
+
+    // "HEAD -> 1 -> 2 -> NIL", with <-> between nodes when doubly linked
+    void printList(std::ostream& os = std::cout) const {
+        const char* link = doubly ? " <-> " : " -> ";
+        os << "HEAD";
+        for (ListNode* node = head; node; node = node->next) {
+            os << (node == head ? " -> " : link) << node->data;
+        }
+        os << " -> NIL" << std::endl;
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_LINKED_LIST_HPP
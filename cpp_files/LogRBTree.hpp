@@ -0,0 +1,340 @@
+#ifndef LOG_RB_TREE_HPP
+#define LOG_RB_TREE_HPP
+
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Red-black tree that logs every pointer change and recoloring,
+// so the insert and delete fix-ups can be replayed step by step
+template<typename T>
+class LogRBTree : public LogDatas {
+public:
+    enum Color { RED, BLACK };
+
+private:
+    struct RBNode {
+        T data;
+        Color color;
+        RBNode* left;
+        RBNode* right;
+        RBNode* parent;
+
+        explicit RBNode(const T& value)
+            : data(value), color(RED), left(nullptr), right(nullptr), parent(nullptr) {}
+    };
+
+    RBNode* root;
+
+    static const char* colorName(Color color) {
+        return color == RED ? "RED" : "BLACK";
+    }
+
+    // Null leaves count as black
+    static Color colorOf(const RBNode* node) {
+        return node ? node->color : BLACK;
+    }
+
+    void setColor(RBNode* node, Color color) {
+        if (!node || node->color == color) return;
+        node->color = color;
+        this->buffer << "[RECOLOR] node=" << node << " value=" << node->data << " color=" << colorName(color);
+        this->log();
+    }
+
+    void setLeft(RBNode* parent, RBNode* child) {
+        parent->left = child;
+        if (child) child->parent = parent;
+        this->buffer << "[POINTER_CHANGE] " << parent << ".left=" << child;
+        this->log();
+    }
+
+    void setRight(RBNode* parent, RBNode* child) {
+        parent->right = child;
+        if (child) child->parent = parent;
+        this->buffer << "[POINTER_CHANGE] " << parent << ".right=" << child;
+        this->log();
+    }
+
+    // Puts replacement where node hangs under parent (or at the root)
+    void replaceChild(RBNode* parent, RBNode* node, RBNode* replacement) {
+        if (!parent) {
+            RBNode* old_root = root;
+            root = replacement;
+            if (replacement) replacement->parent = nullptr;
+            this->buffer << "[ROOT_CHANGE] old=" << old_root << " new=" << root;
+            this->log();
+        } else if (parent->left == node) {
+            setLeft(parent, replacement);
+        } else {
+            setRight(parent, replacement);
+        }
+    }
+
+    void rotateLeft(RBNode* node) {
+        RBNode* pivot = node->right;
+        this->buffer << "[ROTATE_LEFT] node=" << node << " right=" << pivot << " right_left=" << pivot->left;
+        this->log();
+        RBNode* parent = node->parent;
+        setRight(node, pivot->left);
+        replaceChild(parent, node, pivot);
+        setLeft(pivot, node);
+    }
+
+    void rotateRight(RBNode* node) {
+        RBNode* pivot = node->left;
+        this->buffer << "[ROTATE_RIGHT] node=" << node << " left=" << pivot << " left_right=" << pivot->right;
+        this->log();
+        RBNode* parent = node->parent;
+        setLeft(node, pivot->right);
+        replaceChild(parent, node, pivot);
+        setRight(pivot, node);
+    }
+
+    void fixInsert(RBNode* node) {
+        while (colorOf(node->parent) == RED) {
+            RBNode* parent = node->parent;
+            RBNode* grand = parent->parent; // a red parent is never the root
+            bool parent_is_left = parent == grand->left;
+            RBNode* uncle = parent_is_left ? grand->right : grand->left;
+
+            if (colorOf(uncle) == RED) {
+                this->buffer << "[FIX_INSERT] node=" << node << " case=red_uncle uncle=" << uncle;
+                this->log();
+                setColor(parent, BLACK);
+                setColor(uncle, BLACK);
+                setColor(grand, RED);
+                node = grand;
+                continue;
+            }
+
+            if (parent_is_left ? node == parent->right : node == parent->left) {
+                this->buffer << "[FIX_INSERT] node=" << node << " case=triangle parent=" << parent;
+                this->log();
+                if (parent_is_left) rotateLeft(parent); else rotateRight(parent);
+                node = parent;
+                parent = node->parent;
+            }
+
+            this->buffer << "[FIX_INSERT] node=" << node << " case=line grandparent=" << grand;
+            this->log();
+            setColor(parent, BLACK);
+            setColor(grand, RED);
+            if (parent_is_left) rotateRight(grand); else rotateLeft(grand);
+        }
+        setColor(root, BLACK);
+    }
+
+    // node may be null: it stands for the double black leaf under parent
+    void fixRemove(RBNode* node, RBNode* parent) {
+        while (node != root && colorOf(node) == BLACK) {
+            bool is_left = node == parent->left;
+            RBNode* sibling = is_left ? parent->right : parent->left;
+
+            if (colorOf(sibling) == RED) {
+                this->buffer << "[FIX_REMOVE] node=" << node << " case=red_sibling sibling=" << sibling;
+                this->log();
+                setColor(sibling, BLACK);
+                setColor(parent, RED);
+                if (is_left) rotateLeft(parent); else rotateRight(parent);
+                sibling = is_left ? parent->right : parent->left;
+            }
+
+            RBNode* near_nephew = is_left ? sibling->left : sibling->right;
+            RBNode* far_nephew = is_left ? sibling->right : sibling->left;
+            if (colorOf(near_nephew) == BLACK && colorOf(far_nephew) == BLACK) {
+                this->buffer << "[FIX_REMOVE] node=" << node << " case=black_nephews sibling=" << sibling;
+                this->log();
+                setColor(sibling, RED);
+                node = parent;
+                parent = node->parent;
+                continue;
+            }
+
+            if (colorOf(far_nephew) == BLACK) {
+                this->buffer << "[FIX_REMOVE] node=" << node << " case=near_red_nephew nephew=" << near_nephew;
+                this->log();
+                setColor(near_nephew, BLACK);
+                setColor(sibling, RED);
+                if (is_left) rotateRight(sibling); else rotateLeft(sibling);
+                sibling = is_left ? parent->right : parent->left;
+                far_nephew = is_left ? sibling->right : sibling->left;
+            }
+
+            this->buffer << "[FIX_REMOVE] node=" << node << " case=far_red_nephew nephew=" << far_nephew;
+            this->log();
+            setColor(sibling, parent->color);
+            setColor(parent, BLACK);
+            setColor(far_nephew, BLACK);
+            if (is_left) rotateLeft(parent); else rotateRight(parent);
+            node = root;
+        }
+        setColor(node, BLACK);
+    }
+
+    RBNode* findNode(const T& value) {
+        RBNode* current = root;
+        while (current) {
+            this->buffer << "[FIND] node=" << current << " searching=" << value;
+            if (value == current->data) {
+                this->buffer << " result=FOUND";
+                this->log();
+                return current;
+            }
+            bool go_left = value < current->data;
+            this->buffer << " direction=" << (go_left ? "left" : "right");
+            this->log();
+            current = go_left ? current->left : current->right;
+        }
+        return nullptr;
+    }
+
+    void destroy(RBNode* node) {
+        if (!node) return;
+        destroy(node->left);
+        destroy(node->right);
+        delete node;
+    }
+
+    void inorder(std::ostream& os, const RBNode* node, bool& first) const {
+        if (!node) return;
+        inorder(os, node->left, first);
+        os << (first ? "" : " ") << node->data;
+        first = false;
+        inorder(os, node->right, first);
+    }
+
+    void printNodeStructure(std::ostream& os, const RBNode* node, const std::string& prefix = "", bool isLast = true) const {
+        if (node == nullptr) {
+            os << prefix << (isLast ? "└── " : "├── ") << "null" << std::endl;
+            return;
+        }
+
+        os << prefix << (isLast ? "└── " : "├── ") << node->data
+           << (node->color == RED ? " (R)" : " (B)") << std::endl;
+
+        if (node->left != nullptr || node->right != nullptr) {
+            printNodeStructure(os, node->left, prefix + (isLast ? "    " : "│   "), node->right == nullptr);
+            printNodeStructure(os, node->right, prefix + (isLast ? "    " : "│   "), true);
+        }
+    }
+
+    int blackHeight(const RBNode* node) const {
+        int height = 0;
+        for (; node; node = node->left) {
+            if (node->color == BLACK) height++;
+        }
+        return height;
+    }
+
+public:
+    explicit LogRBTree(std::ostream& os = std::cout)
+        : LogDatas(os), root(nullptr) {}
+
+    ~LogRBTree() override {
+        destroy(root);
+    }
+
+    LogRBTree(const LogRBTree&) = delete;
+    LogRBTree& operator=(const LogRBTree&) = delete;
+
+    bool exist_in_tree(const T& value) {
+        this->buffer << "[TREE_FIND] value=" << value;
+        this->log();
+
+        bool result = findNode(value) != nullptr;
+
+        this->buffer << "[TREE_FIND_RESULT] value=" << value << " found=" << (result ? "true" : "false");
+        this->log();
+        return result;
+    }
+
+    void insert(const T& value) {
+        this->buffer << "[TREE_INSERT] value=" << value;
+        this->log();
+
+        RBNode* parent = nullptr;
+        for (RBNode* current = root; current; ) {
+            parent = current;
+            bool go_left = value < current->data;
+            this->buffer << "[INSERT] node=" << current << " value=" << value
+                         << " direction=" << (go_left ? "left" : "right");
+            this->log();
+            current = go_left ? current->left : current->right;
+        }
+
+        RBNode* node = new RBNode(value);
+        this->buffer << "[NODE_CREATE] address=" << node << " value=" << value << " color=RED";
+        this->log();
+        if (!parent) {
+            replaceChild(nullptr, nullptr, node);
+        } else if (value < parent->data) {
+            setLeft(parent, node);
+        } else {
+            setRight(parent, node);
+        }
+        fixInsert(node);
+    }
+
+    bool remove(const T& value) {
+        this->buffer << "[TREE_REMOVE] value=" << value;
+        this->log();
+
+        RBNode* node = findNode(value);
+        if (!node) {
+            this->buffer << "[TREE_REMOVE_FAILED] value=" << value;
+            this->log();
+            return false;
+        }
+
+        // A node with two children takes its successor's value; the successor is removed instead
+        if (node->left && node->right) {
+            RBNode* successor = node->right;
+            while (successor->left) successor = successor->left;
+            this->buffer << "[FIND_SUCCESSOR] node=" << node << " result=" << successor;
+            this->log();
+            this->buffer << "[DATA_CHANGE] node=" << node << " old_value=" << node->data << " new_value=" << successor->data;
+            this->log();
+            node->data = successor->data;
+            node = successor;
+        }
+
+        RBNode* child = node->left ? node->left : node->right;
+        RBNode* parent = node->parent;
+        Color removed = node->color;
+        this->buffer << "[NODE_DELETE] address=" << node << " value=" << node->data
+                     << " color=" << colorName(removed) << " replacement=" << child;
+        this->log();
+        replaceChild(parent, node, child);
+        delete node;
+
+        if (removed == BLACK) {
+            fixRemove(child, parent);
+        }
+        return true;
+    }
+
+    // Writes the keys in order on one line
+    void inorder(std::ostream& os) const {
+        bool first = true;
+        inorder(os, root, first);
+        if (!first) os << std::endl;
+    }
+
+    int black_height() const {
+        return blackHeight(root);
+    }
+
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogRBTree Structure:" << std::endl;
+        if (root == nullptr) {
+            os << "└── (empty)" << std::endl;
+        } else {
+            printNodeStructure(os, root);
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_RB_TREE_HPP
@@ -0,0 +1,171 @@
+#ifndef LOG_SEGMENT_TREE_HPP
+#define LOG_SEGMENT_TREE_HPP
+
+#include <algorithm>
+#include <limits>
+#include <string>
+#include <vector>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Segment tree over a fixed size array, aggregating ranges with sum, min or max.
+// Nodes are numbered like a heap (root 1, children 2i and 2i+1) and logged by
+// number and range, so a visualizer can draw the tree without pointers.
+// Slots not set yet hold the identity of the function and print as "_".
+class LogSegmentTree : public LogDatas {
+public:
+    enum Function { SUM, MIN, MAX };
+
+private:
+    size_t n;
+    Function function;
+    std::vector<long long> tree;   // aggregate of each node, tree[1] is the whole array
+    std::vector<long long> values;
+    std::vector<bool> used;
+    size_t used_count;
+
+    long long identity() const {
+        switch (function) {
+            case MIN: return std::numeric_limits<long long>::max();
+            case MAX: return std::numeric_limits<long long>::min();
+            default: return 0;
+        }
+    }
+
+    long long combine(long long a, long long b) const {
+        switch (function) {
+            case MIN: return a < b ? a : b;
+            case MAX: return a > b ? a : b;
+            default: return a + b;
+        }
+    }
+
+    // Writes an aggregate, with the identity of min and max shown as "none"
+    std::string show(long long value) const {
+        if (function != SUM && value == identity()) return "none";
+        return std::to_string(value);
+    }
+
+    void logNode(const char* event, size_t node, size_t lo, size_t hi) {
+        this->buffer << "[" << event << "] node=" << node << " range=[" << lo << "," << hi << "]"
+                     << " value=" << show(tree[node]);
+        this->log();
+    }
+
+    void update(size_t node, size_t lo, size_t hi, size_t index, long long value) {
+        if (lo == hi) {
+            tree[node] = value;
+            logNode("NODE_UPDATE", node, lo, hi);
+            return;
+        }
+        size_t mid = lo + (hi - lo) / 2;
+        bool go_left = index <= mid;
+        this->buffer << "[TRAVERSE] node=" << node << " range=[" << lo << "," << hi << "]"
+                     << " direction=" << (go_left ? "left" : "right");
+        this->log();
+        if (go_left) update(2 * node, lo, mid, index, value);
+        else update(2 * node + 1, mid + 1, hi, index, value);
+        tree[node] = combine(tree[2 * node], tree[2 * node + 1]);
+        logNode("NODE_UPDATE", node, lo, hi);
+    }
+
+    long long query(size_t node, size_t lo, size_t hi, size_t from, size_t to) {
+        if (to < lo || hi < from) {
+            this->buffer << "[QUERY_SKIP] node=" << node << " range=[" << lo << "," << hi << "]";
+            this->log();
+            return identity();
+        }
+        if (from <= lo && hi <= to) {
+            logNode("QUERY_COVER", node, lo, hi);
+            return tree[node];
+        }
+        this->buffer << "[QUERY_SPLIT] node=" << node << " range=[" << lo << "," << hi << "]";
+        this->log();
+        size_t mid = lo + (hi - lo) / 2;
+        return combine(query(2 * node, lo, mid, from, to), query(2 * node + 1, mid + 1, hi, from, to));
+    }
+
+    void printNode(std::ostream& os, size_t node, size_t lo, size_t hi, const std::string& prefix, bool isLast) const {
+        os << prefix << (isLast ? "└── " : "├── ") << "[" << lo << "," << hi << "] " << show(tree[node]) << std::endl;
+        if (lo == hi) return;
+        size_t mid = lo + (hi - lo) / 2;
+        std::string child_prefix = prefix + (isLast ? "    " : "│   ");
+        printNode(os, 2 * node, lo, mid, child_prefix, false);
+        printNode(os, 2 * node + 1, mid + 1, hi, child_prefix, true);
+    }
+
+public:
+    LogSegmentTree(size_t size, Function f, std::ostream& os = std::cout)
+        : LogDatas(os), n(size), function(f), tree(4 * size, 0), values(size, 0),
+          used(size, false), used_count(0) {
+        std::fill(tree.begin(), tree.end(), identity());
+        std::fill(values.begin(), values.end(), identity());
+    }
+
+    // Sets the value at index; returns false if index is out of range
+    bool set(size_t index, long long value) {
+        if (index >= n) return false;
+        this->buffer << "[SEGTREE_SET] index=" << index << " value=" << value;
+        this->log();
+        if (!used[index]) {
+            used[index] = true;
+            used_count++;
+        }
+        values[index] = value;
+        update(1, 0, n - 1, index, value);
+        return true;
+    }
+
+    // Sets the first slot not set yet; returns its index, or -1 when every slot is set
+    long append(long long value) {
+        for (size_t i = 0; i < n; i++) {
+            if (!used[i]) {
+                set(i, value);
+                return static_cast<long>(i);
+            }
+        }
+        return -1;
+    }
+
+    // Aggregates values[from..to]; "none" for min and max over slots not set yet
+    std::string query(size_t from, size_t to) {
+        this->buffer << "[SEGTREE_QUERY] from=" << from << " to=" << to << " function=" << functionName();
+        this->log();
+        std::string result = show(query(1, 0, n - 1, from, to));
+        this->buffer << "[SEGTREE_QUERY_RESULT] from=" << from << " to=" << to << " value=" << result;
+        this->log();
+        return result;
+    }
+
+    size_t capacity() const { return n; }
+    size_t size() const { return used_count; }
+
+    const char* functionName() const {
+        switch (function) {
+            case MIN: return "min";
+            case MAX: return "max";
+            default: return "sum";
+        }
+    }
+
+    // "[3, _, 7]" with "_" for slots not set yet
+    void printArray(std::ostream& os) const {
+        os << "[";
+        for (size_t i = 0; i < n; i++) {
+            os << (i ? ", " : "");
+            if (used[i]) os << values[i];
+            else os << "_";
+        }
+        os << "]" << std::endl;
+    }
+
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogSegmentTree Structure (" << functionName() << "):" << std::endl;
+        printNode(os, 1, 0, n - 1, "", true);
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_SEGMENT_TREE_HPP
@@ -0,0 +1,200 @@
+#ifndef LOG_SKIP_LIST_HPP
+#define LOG_SKIP_LIST_HPP
+
+#include <vector>
+#include <random>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Skip list that logs every step of its searches and every link it changes.
+// Levels come from a fixed-seed generator, so replaying the same commands
+// builds the same list.
+//
+// Log events, one per line; nodes are named by value, or HEAD / NIL:
+//   [SKIP_INSERT] value=V level=L       insert starts; L is the new node's height
+//   [SKIP_FIND] value=V                 find starts
+//   [SKIP_REMOVE] value=V               remove starts
+//   [ADVANCE] level=L from=X to=Y       search moves right along level L
+//   [DESCEND] level=L at=X              search drops from level L to L-1 at X
+//   [NODE_CREATE] address=P value=V level=L
+//   [LINK] level=L prev=X node=V next=Y     node spliced in between X and Y
+//   [UNLINK] level=L prev=X node=V next=Y   node cut out from between X and Y
+//   [NODE_DELETE] address=P value=V
+//   [LEVEL_CHANGE] from=A to=B          height of the whole list changed
+//   [FIND_RESULT] value=V found=true|false
+//   [DUPLICATE] value=V                 insert of a present value, nothing changed
+template<typename T>
+class LogSkipList : public LogDatas {
+private:
+    struct SkipNode {
+        T data;
+        std::vector<SkipNode*> next; // next[i] is the successor on level i
+
+        SkipNode(const T& value, int height) : data(value), next(height, nullptr) {}
+    };
+
+    SkipNode* head;   // sentinel with max_level forward pointers
+    int max_level;
+    double probability;
+    int level;        // levels in use, at least 1
+    size_t count;
+    std::mt19937 rng;
+
+    std::string name(const SkipNode* node) const {
+        if (node == head) return "HEAD";
+        if (!node) return "NIL";
+        std::ostringstream oss;
+        oss << node->data;
+        return oss.str();
+    }
+
+    int randomLevel() {
+        std::bernoulli_distribution coin(probability);
+        int height = 1;
+        while (height < max_level && coin(rng)) height++;
+        return height;
+    }
+
+    // Finds the last node before value on every level, logging the path
+    std::vector<SkipNode*> search(const T& value) {
+        std::vector<SkipNode*> update(max_level, head);
+        SkipNode* node = head;
+        for (int i = level - 1; i >= 0; i--) {
+            while (node->next[i] && node->next[i]->data < value) {
+                this->buffer << "[ADVANCE] level=" << i << " from=" << name(node) << " to=" << name(node->next[i]);
+                this->log();
+                node = node->next[i];
+            }
+            update[i] = node;
+            if (i > 0) {
+                this->buffer << "[DESCEND] level=" << i << " at=" << name(node);
+                this->log();
+            }
+        }
+        return update;
+    }
+
+    void setLevel(int new_level) {
+        if (new_level == level) return;
+        this->buffer << "[LEVEL_CHANGE] from=" << level << " to=" << new_level;
+        this->log();
+        level = new_level;
+    }
+
+public:
+    LogSkipList(double p = 0.5, int levels = 16, std::ostream& os = std::cout)
+        : LogDatas(os), head(new SkipNode(T(), levels)), max_level(levels),
+          probability(p), level(1), count(0), rng(1) {}
+
+    ~LogSkipList() override {
+        SkipNode* node = head;
+        while (node) {
+            SkipNode* next = node->next[0];
+            delete node;
+            node = next;
+        }
+    }
+
+    LogSkipList(const LogSkipList&) = delete;
+    LogSkipList& operator=(const LogSkipList&) = delete;
+
+    // Returns false if the value was already present
+    bool insert(const T& value) {
+        int height = randomLevel();
+        this->buffer << "[SKIP_INSERT] value=" << value << " level=" << height;
+        this->log();
+
+        std::vector<SkipNode*> update = search(value);
+        SkipNode* found = update[0]->next[0];
+        if (found && found->data == value) {
+            this->buffer << "[DUPLICATE] value=" << value;
+            this->log();
+            return false;
+        }
+
+        SkipNode* node = new SkipNode(value, height);
+        this->buffer << "[NODE_CREATE] address=" << node << " value=" << value << " level=" << height;
+        this->log();
+        if (height > level) {
+            setLevel(height);
+        }
+        for (int i = 0; i < height; i++) {
+            node->next[i] = update[i]->next[i];
+            update[i]->next[i] = node;
+            this->buffer << "[LINK] level=" << i << " prev=" << name(update[i])
+                         << " node=" << value << " next=" << name(node->next[i]);
+            this->log();
+        }
+        count++;
+        return true;
+    }
+
+    bool find(const T& value) {
+        this->buffer << "[SKIP_FIND] value=" << value;
+        this->log();
+        std::vector<SkipNode*> update = search(value);
+        SkipNode* candidate = update[0]->next[0];
+        bool found = candidate && candidate->data == value;
+        this->buffer << "[FIND_RESULT] value=" << value << " found=" << (found ? "true" : "false");
+        this->log();
+        return found;
+    }
+
+    // Returns false if the value was not present
+    bool remove(const T& value) {
+        this->buffer << "[SKIP_REMOVE] value=" << value;
+        this->log();
+        std::vector<SkipNode*> update = search(value);
+        SkipNode* node = update[0]->next[0];
+        if (!node || node->data != value) {
+            this->buffer << "[FIND_RESULT] value=" << value << " found=false";
+            this->log();
+            return false;
+        }
+
+        for (int i = 0; i < static_cast<int>(node->next.size()); i++) {
+            update[i]->next[i] = node->next[i];
+            this->buffer << "[UNLINK] level=" << i << " prev=" << name(update[i])
+                         << " node=" << value << " next=" << name(node->next[i]);
+            this->log();
+        }
+        this->buffer << "[NODE_DELETE] address=" << node << " value=" << value;
+        this->log();
+        delete node;
+        count--;
+
+        int new_level = level;
+        while (new_level > 1 && !head->next[new_level - 1]) new_level--;
+        setLevel(new_level);
+        return true;
+    }
+
+    size_t size() const { return count; }
+    int height() const { return level; }
+    int maxLevel() const { return max_level; }
+    double prob() const { return probability; }
+
+    std::vector<T> values() const {
+        std::vector<T> out;
+        for (SkipNode* node = head->next[0]; node; node = node->next[0]) {
+            out.push_back(node->data);
+        }
+        return out;
+    }
+
+    // One line per level, top level first: "L1: HEAD -> 5 -> 20 -> NIL"
+    void printLevels(std::ostream& os = std::cout) const {
+        for (int i = level - 1; i >= 0; i--) {
+            os << "L" << i << ": HEAD";
+            for (SkipNode* node = head->next[i]; node; node = node->next[i]) {
+                os << " -> " << node->data;
+            }
+            os << " -> NIL" << std::endl;
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_SKIP_LIST_HPP
@@ -0,0 +1,271 @@
+#ifndef LOG_SPLAY_TREE_HPP
+#define LOG_SPLAY_TREE_HPP
+
+#include <algorithm>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Splay tree that logs every rotation of its bottom-up splay, so the way an
+// accessed node climbs to the root can be replayed step by step.
+// Finds splay too: the last node reached moves to the root even on a miss.
+template<typename T>
+class LogSplayTree : public LogDatas {
+private:
+    struct SplayNode {
+        T data;
+        SplayNode* left;
+        SplayNode* right;
+        SplayNode* parent;
+
+        explicit SplayNode(const T& value)
+            : data(value), left(nullptr), right(nullptr), parent(nullptr) {}
+    };
+
+    SplayNode* root;
+    size_t count;
+
+    void setLeft(SplayNode* parent, SplayNode* child) {
+        parent->left = child;
+        if (child) child->parent = parent;
+        this->buffer << "[POINTER_CHANGE] " << parent << ".left=" << child;
+        this->log();
+    }
+
+    void setRight(SplayNode* parent, SplayNode* child) {
+        parent->right = child;
+        if (child) child->parent = parent;
+        this->buffer << "[POINTER_CHANGE] " << parent << ".right=" << child;
+        this->log();
+    }
+
+    // Puts replacement where node hangs under parent (or at the root)
+    void replaceChild(SplayNode* parent, SplayNode* node, SplayNode* replacement) {
+        if (!parent) {
+            SplayNode* old_root = root;
+            root = replacement;
+            if (replacement) replacement->parent = nullptr;
+            this->buffer << "[ROOT_CHANGE] old=" << old_root << " new=" << root;
+            this->log();
+        } else if (parent->left == node) {
+            setLeft(parent, replacement);
+        } else {
+            setRight(parent, replacement);
+        }
+    }
+
+    void rotateLeft(SplayNode* node) {
+        SplayNode* pivot = node->right;
+        this->buffer << "[ROTATE_LEFT] node=" << node << " right=" << pivot << " right_left=" << pivot->left;
+        this->log();
+        SplayNode* parent = node->parent;
+        setRight(node, pivot->left);
+        replaceChild(parent, node, pivot);
+        setLeft(pivot, node);
+    }
+
+    void rotateRight(SplayNode* node) {
+        SplayNode* pivot = node->left;
+        this->buffer << "[ROTATE_RIGHT] node=" << node << " left=" << pivot << " left_right=" << pivot->right;
+        this->log();
+        SplayNode* parent = node->parent;
+        setLeft(node, pivot->right);
+        replaceChild(parent, node, pivot);
+        setRight(pivot, node);
+    }
+
+    // Rotates node over its parent
+    void rotateUp(SplayNode* node) {
+        if (node == node->parent->left) rotateRight(node->parent);
+        else rotateLeft(node->parent);
+    }
+
+    // Moves node to the root with zig, zig-zig and zig-zag steps
+    void splay(SplayNode* node) {
+        this->buffer << "[SPLAY_START] node=" << node << " value=" << node->data;
+        this->log();
+        while (node->parent) {
+            SplayNode* parent = node->parent;
+            SplayNode* grand = parent->parent;
+            if (!grand) {
+                this->buffer << "[SPLAY_STEP] node=" << node << " case=zig parent=" << parent;
+                this->log();
+                rotateUp(node);
+            } else if ((node == parent->left) == (parent == grand->left)) {
+                this->buffer << "[SPLAY_STEP] node=" << node << " case=zig_zig parent=" << parent << " grandparent=" << grand;
+                this->log();
+                rotateUp(parent);
+                rotateUp(node);
+            } else {
+                this->buffer << "[SPLAY_STEP] node=" << node << " case=zig_zag parent=" << parent << " grandparent=" << grand;
+                this->log();
+                rotateUp(node);
+                rotateUp(node);
+            }
+        }
+        this->buffer << "[SPLAY_END] root=" << root << " value=" << root->data;
+        this->log();
+    }
+
+    // Walks towards value, logging each step; returns the node holding it or the last node reached
+    SplayNode* descend(const T& value) {
+        SplayNode* last = nullptr;
+        for (SplayNode* current = root; current; ) {
+            last = current;
+            if (value == current->data) break;
+            bool go_left = value < current->data;
+            this->buffer << "[TRAVERSE] node=" << current << " value=" << current->data
+                         << " direction=" << (go_left ? "left" : "right");
+            this->log();
+            current = go_left ? current->left : current->right;
+        }
+        return last;
+    }
+
+    void destroy(SplayNode* node) {
+        if (!node) return;
+        destroy(node->left);
+        destroy(node->right);
+        delete node;
+    }
+
+    void inorder(std::ostream& os, const SplayNode* node, bool& first) const {
+        if (!node) return;
+        inorder(os, node->left, first);
+        os << (first ? "" : " ") << node->data;
+        first = false;
+        inorder(os, node->right, first);
+    }
+
+    size_t depth(const SplayNode* node) const {
+        if (!node) return 0;
+        return 1 + std::max(depth(node->left), depth(node->right));
+    }
+
+    void printNodeStructure(std::ostream& os, const SplayNode* node, const std::string& prefix = "", bool isLast = true) const {
+        if (node == nullptr) {
+            os << prefix << (isLast ? "└── " : "├── ") << "null" << std::endl;
+            return;
+        }
+
+        os << prefix << (isLast ? "└── " : "├── ") << node->data << std::endl;
+
+        if (node->left != nullptr || node->right != nullptr) {
+            printNodeStructure(os, node->left, prefix + (isLast ? "    " : "│   "), node->right == nullptr);
+            printNodeStructure(os, node->right, prefix + (isLast ? "    " : "│   "), true);
+        }
+    }
+
+public:
+    explicit LogSplayTree(std::ostream& os = std::cout)
+        : LogDatas(os), root(nullptr), count(0) {}
+
+    ~LogSplayTree() override {
+        destroy(root);
+    }
+
+    LogSplayTree(const LogSplayTree&) = delete;
+    LogSplayTree& operator=(const LogSplayTree&) = delete;
+
+    // Searches for value and splays the last node reached
+    bool find(const T& value) {
+        this->buffer << "[TREE_FIND] value=" << value;
+        this->log();
+        SplayNode* last = descend(value);
+        bool found = last && last->data == value;
+        if (last) splay(last);
+        this->buffer << "[TREE_FIND_RESULT] value=" << value << " found=" << (found ? "true" : "false");
+        this->log();
+        return found;
+    }
+
+    // Returns false if the value was already present; either way the tree is splayed
+    bool insert(const T& value) {
+        this->buffer << "[TREE_INSERT] value=" << value;
+        this->log();
+        SplayNode* parent = descend(value);
+        if (parent && parent->data == value) {
+            splay(parent);
+            return false;
+        }
+
+        SplayNode* node = new SplayNode(value);
+        this->buffer << "[NODE_CREATE] address=" << node << " value=" << value;
+        this->log();
+        if (!parent) {
+            replaceChild(nullptr, nullptr, node);
+        } else if (value < parent->data) {
+            setLeft(parent, node);
+        } else {
+            setRight(parent, node);
+        }
+        count++;
+        splay(node);
+        return true;
+    }
+
+    // Splays value to the root, then joins its two subtrees under the largest key on the left
+    bool remove(const T& value) {
+        this->buffer << "[TREE_REMOVE] value=" << value;
+        this->log();
+        SplayNode* node = descend(value);
+        if (!node) {
+            this->buffer << "[TREE_REMOVE_FAILED] value=" << value;
+            this->log();
+            return false;
+        }
+        splay(node);
+        if (node->data != value) {
+            this->buffer << "[TREE_REMOVE_FAILED] value=" << value;
+            this->log();
+            return false;
+        }
+
+        SplayNode* left = node->left;
+        SplayNode* right = node->right;
+        if (left) left->parent = nullptr;
+        if (right) right->parent = nullptr;
+        this->buffer << "[NODE_DELETE] address=" << node << " value=" << node->data
+                     << " left=" << left << " right=" << right;
+        this->log();
+        delete node;
+        count--;
+
+        if (!left) {
+            replaceChild(nullptr, nullptr, right);
+            return true;
+        }
+        replaceChild(nullptr, nullptr, left);
+        SplayNode* max = left;
+        while (max->right) max = max->right;
+        this->buffer << "[FIND_PREDECESSOR] result=" << max << " value=" << max->data;
+        this->log();
+        splay(max);
+        setRight(max, right);
+        return true;
+    }
+
+    size_t size() const { return count; }
+
+    size_t height() const { return depth(root); }
+
+    // Writes the keys in order on one line
+    void inorder(std::ostream& os) const {
+        bool first = true;
+        inorder(os, root, first);
+        if (!first) os << std::endl;
+    }
+
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogSplayTree Structure:" << std::endl;
+        if (root == nullptr) {
+            os << "└── (empty)" << std::endl;
+        } else {
+            printNodeStructure(os, root);
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_SPLAY_TREE_HPP
@@ -0,0 +1,230 @@
+#ifndef LOG_TREAP_HPP
+#define LOG_TREAP_HPP
+
+#include <algorithm>
+#include <cstdint>
+#include <random>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Treap: a binary search tree on keys that is also a max-heap on random
+// priorities. Every node gets its priority from a seeded mt19937, whose output
+// the standard fixes, so the same seed and inserts give the same tree anywhere.
+// Insert rotates the new node up while it outranks its parent; remove rotates
+// the node down towards its higher priority child until it is a leaf.
+template<typename T>
+class LogTreap : public LogDatas {
+private:
+    struct TreapNode {
+        T data;
+        uint32_t priority;
+        TreapNode* left;
+        TreapNode* right;
+
+        TreapNode(const T& value, uint32_t p)
+            : data(value), priority(p), left(nullptr), right(nullptr) {}
+    };
+
+    TreapNode* root;
+    size_t count;
+    uint32_t seed;
+    std::mt19937 rng;
+
+    // Logs ref taking its new value; ref is root or a child pointer of parent
+    void relink(TreapNode*& ref, TreapNode* parent, TreapNode* node) {
+        if (!parent) {
+            this->buffer << "[ROOT_CHANGE] old=" << root << " new=" << node;
+        } else {
+            this->buffer << "[POINTER_CHANGE] " << parent << (&ref == &parent->left ? ".left=" : ".right=") << node;
+        }
+        this->log();
+        ref = node;
+    }
+
+    // Rotates the left child of ref up into its place
+    void rotateRight(TreapNode*& ref, TreapNode* parent) {
+        TreapNode* node = ref;
+        TreapNode* pivot = node->left;
+        this->buffer << "[ROTATE_RIGHT] node=" << node << " priority=" << node->priority
+                     << " left=" << pivot << " left_priority=" << pivot->priority;
+        this->log();
+        relink(node->left, node, pivot->right);
+        relink(pivot->right, pivot, node);
+        relink(ref, parent, pivot);
+    }
+
+    // Rotates the right child of ref up into its place
+    void rotateLeft(TreapNode*& ref, TreapNode* parent) {
+        TreapNode* node = ref;
+        TreapNode* pivot = node->right;
+        this->buffer << "[ROTATE_LEFT] node=" << node << " priority=" << node->priority
+                     << " right=" << pivot << " right_priority=" << pivot->priority;
+        this->log();
+        relink(node->right, node, pivot->left);
+        relink(pivot->left, pivot, node);
+        relink(ref, parent, pivot);
+    }
+
+    bool insertAt(TreapNode*& ref, TreapNode* parent, const T& value) {
+        if (!ref) {
+            TreapNode* node = new TreapNode(value, rng());
+            this->buffer << "[NODE_CREATE] address=" << node << " value=" << value << " priority=" << node->priority;
+            this->log();
+            relink(ref, parent, node);
+            count++;
+            return true;
+        }
+        TreapNode* node = ref;
+        if (value == node->data) return false;
+
+        bool go_left = value < node->data;
+        this->buffer << "[TRAVERSE] node=" << node << " value=" << node->data
+                     << " direction=" << (go_left ? "left" : "right");
+        this->log();
+        TreapNode*& child = go_left ? node->left : node->right;
+        if (!insertAt(child, node, value)) return false;
+
+        // Restore the heap order on the way back up
+        if (child->priority > node->priority) {
+            this->buffer << "[HEAP_VIOLATION] node=" << child << " priority=" << child->priority
+                         << " parent=" << node << " parent_priority=" << node->priority;
+            this->log();
+            if (go_left) rotateRight(ref, parent);
+            else rotateLeft(ref, parent);
+        }
+        return true;
+    }
+
+    bool removeAt(TreapNode*& ref, TreapNode* parent, const T& value) {
+        TreapNode* node = ref;
+        if (!node) return false;
+        if (value != node->data) {
+            bool go_left = value < node->data;
+            this->buffer << "[TRAVERSE] node=" << node << " value=" << node->data
+                         << " direction=" << (go_left ? "left" : "right");
+            this->log();
+            return removeAt(go_left ? node->left : node->right, node, value);
+        }
+
+        if (!node->left || !node->right) {
+            TreapNode* child = node->left ? node->left : node->right;
+            this->buffer << "[NODE_DELETE] address=" << node << " value=" << node->data << " child=" << child;
+            this->log();
+            relink(ref, parent, child);
+            delete node;
+            count--;
+            return true;
+        }
+
+        // Rotate the higher priority child up, then keep removing below it
+        if (node->left->priority > node->right->priority) {
+            rotateRight(ref, parent);
+            return removeAt(ref->right, ref, value);
+        }
+        rotateLeft(ref, parent);
+        return removeAt(ref->left, ref, value);
+    }
+
+    void destroy(TreapNode* node) {
+        if (!node) return;
+        destroy(node->left);
+        destroy(node->right);
+        delete node;
+    }
+
+    void inorder(std::ostream& os, const TreapNode* node, bool& first) const {
+        if (!node) return;
+        inorder(os, node->left, first);
+        os << (first ? "" : " ") << node->data;
+        first = false;
+        inorder(os, node->right, first);
+    }
+
+    size_t depth(const TreapNode* node) const {
+        if (!node) return 0;
+        return 1 + std::max(depth(node->left), depth(node->right));
+    }
+
+    void printNodeStructure(std::ostream& os, const TreapNode* node, const std::string& prefix = "", bool isLast = true) const {
+        if (node == nullptr) {
+            os << prefix << (isLast ? "└── " : "├── ") << "null" << std::endl;
+            return;
+        }
+
+        os << prefix << (isLast ? "└── " : "├── ") << node->data << " (p=" << node->priority << ")" << std::endl;
+
+        if (node->left != nullptr || node->right != nullptr) {
+            printNodeStructure(os, node->left, prefix + (isLast ? "    " : "│   "), node->right == nullptr);
+            printNodeStructure(os, node->right, prefix + (isLast ? "    " : "│   "), true);
+        }
+    }
+
+public:
+    explicit LogTreap(uint32_t priority_seed, std::ostream& os = std::cout)
+        : LogDatas(os), root(nullptr), count(0), seed(priority_seed), rng(priority_seed) {}
+
+    ~LogTreap() override {
+        destroy(root);
+    }
+
+    LogTreap(const LogTreap&) = delete;
+    LogTreap& operator=(const LogTreap&) = delete;
+
+    // Returns false if the value was already present
+    bool insert(const T& value) {
+        this->buffer << "[TREAP_INSERT] value=" << value;
+        this->log();
+        return insertAt(root, nullptr, value);
+    }
+
+    // Returns false if the value was not found
+    bool remove(const T& value) {
+        this->buffer << "[TREAP_REMOVE] value=" << value;
+        this->log();
+        if (removeAt(root, nullptr, value)) return true;
+        this->buffer << "[TREAP_REMOVE_FAILED] value=" << value;
+        this->log();
+        return false;
+    }
+
+    bool find(const T& value) {
+        this->buffer << "[TREAP_FIND] value=" << value;
+        this->log();
+        const TreapNode* node = root;
+        while (node && node->data != value) {
+            bool go_left = value < node->data;
+            this->buffer << "[TRAVERSE] node=" << node << " value=" << node->data
+                         << " direction=" << (go_left ? "left" : "right");
+            this->log();
+            node = go_left ? node->left : node->right;
+        }
+        this->buffer << "[TREAP_FIND_RESULT] value=" << value << " found=" << (node ? "true" : "false");
+        this->log();
+        return node != nullptr;
+    }
+
+    size_t size() const { return count; }
+    size_t height() const { return depth(root); }
+    uint32_t prioritySeed() const { return seed; }
+
+    // Writes the keys in order on one line
+    void inorder(std::ostream& os) const {
+        bool first = true;
+        inorder(os, root, first);
+        if (!first) os << std::endl;
+    }
+
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogTreap Structure:" << std::endl;
+        if (root == nullptr) {
+            os << "└── (empty)" << std::endl;
+        } else {
+            printNodeStructure(os, root);
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_TREAP_HPP
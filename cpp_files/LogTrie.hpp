@@ -0,0 +1,198 @@
+#ifndef LOG_TRIE_HPP
+#define LOG_TRIE_HPP
+
+#include <map>
+#include <vector>
+#include <cctype>
+#include "LogDatas.hpp"
+
+namespace datas {
+
+// Prefix tree over strings that logs every node visited, created and deleted,
+// so insert, search and prefix walks can be followed character by character
+class LogTrie : public LogDatas {
+private:
+    struct TrieNode {
+        std::map<char, TrieNode*> children;
+        bool is_word = false;
+
+        ~TrieNode() {
+            for (auto& entry : children) delete entry.second;
+        }
+    };
+
+    TrieNode* root;
+    bool case_sensitive;
+    size_t words;
+
+    std::string fold(const std::string& word) const {
+        if (case_sensitive) return word;
+        std::string folded = word;
+        for (char& c : folded) c = static_cast<char>(std::tolower(static_cast<unsigned char>(c)));
+        return folded;
+    }
+
+    // Walks the path of word, logging each step; returns null if the path breaks
+    TrieNode* walk(const std::string& word) {
+        TrieNode* node = root;
+        for (size_t depth = 0; depth < word.size(); depth++) {
+            auto next = node->children.find(word[depth]);
+            this->buffer << "[TRAVERSE] node=" << node << " char=" << word[depth] << " depth=" << depth
+                         << " found=" << (next != node->children.end() ? "true" : "false");
+            this->log();
+            if (next == node->children.end()) return nullptr;
+            node = next->second;
+        }
+        return node;
+    }
+
+    void collect(const TrieNode* node, std::string& prefix, std::vector<std::string>& out) const {
+        if (node->is_word) out.push_back(prefix);
+        for (const auto& entry : node->children) {
+            prefix.push_back(entry.first);
+            collect(entry.second, prefix, out);
+            prefix.pop_back();
+        }
+    }
+
+    // Removes word below node; returns true if node became useless and was unlinked by the caller
+    bool removeBelow(TrieNode* node, const std::string& word, size_t depth) {
+        if (depth == word.size()) {
+            node->is_word = false;
+            this->buffer << "[UNMARK_END] node=" << node << " word=" << word;
+            this->log();
+            return node->children.empty();
+        }
+        auto next = node->children.find(word[depth]);
+        TrieNode* child = next->second;
+        if (removeBelow(child, word, depth + 1)) {
+            this->buffer << "[NODE_DELETE] address=" << child << " char=" << word[depth] << " parent=" << node;
+            this->log();
+            node->children.erase(next);
+            delete child;
+            return node != root && !node->is_word && node->children.empty();
+        }
+        return false;
+    }
+
+    void printNodeStructure(std::ostream& os, const TrieNode* node, char c, const std::string& prefix, bool isLast) const {
+        os << prefix << (isLast ? "└── " : "├── ") << c << (node->is_word ? " *" : "") << std::endl;
+        size_t i = 0;
+        for (const auto& entry : node->children) {
+            printNodeStructure(os, entry.second, entry.first, prefix + (isLast ? "    " : "│   "), ++i == node->children.size());
+        }
+    }
+
+public:
+    explicit LogTrie(bool sensitive = true, std::ostream& os = std::cout)
+        : LogDatas(os), root(new TrieNode()), case_sensitive(sensitive), words(0) {}
+
+    ~LogTrie() override {
+        delete root;
+    }
+
+    LogTrie(const LogTrie&) = delete;
+    LogTrie& operator=(const LogTrie&) = delete;
+
+    // Returns false if the word was already present
+    bool insert(const std::string& raw) {
+        std::string word = fold(raw);
+        this->buffer << "[TRIE_INSERT] word=" << word << " root=" << root;
+        this->log();
+
+        TrieNode* node = root;
+        for (size_t depth = 0; depth < word.size(); depth++) {
+            auto next = node->children.find(word[depth]);
+            if (next != node->children.end()) {
+                this->buffer << "[TRAVERSE] node=" << node << " char=" << word[depth] << " depth=" << depth << " found=true";
+                this->log();
+                node = next->second;
+                continue;
+            }
+            TrieNode* child = new TrieNode();
+            node->children[word[depth]] = child;
+            this->buffer << "[NODE_CREATE] address=" << child << " char=" << word[depth] << " parent=" << node << " depth=" << depth;
+            this->log();
+            node = child;
+        }
+
+        if (node->is_word) {
+            this->buffer << "[TRIE_INSERT_DUPLICATE] word=" << word << " node=" << node;
+            this->log();
+            return false;
+        }
+        node->is_word = true;
+        words++;
+        this->buffer << "[MARK_END] node=" << node << " word=" << word;
+        this->log();
+        return true;
+    }
+
+    bool contains(const std::string& raw) {
+        std::string word = fold(raw);
+        this->buffer << "[TRIE_FIND] word=" << word;
+        this->log();
+        TrieNode* node = walk(word);
+        bool found = node && node->is_word;
+        this->buffer << "[TRIE_FIND_RESULT] word=" << word << " found=" << (found ? "true" : "false");
+        this->log();
+        return found;
+    }
+
+    // Returns false if the word was not present
+    bool remove(const std::string& raw) {
+        std::string word = fold(raw);
+        this->buffer << "[TRIE_REMOVE] word=" << word;
+        this->log();
+        TrieNode* node = walk(word);
+        if (!node || !node->is_word) {
+            this->buffer << "[TRIE_REMOVE_FAILED] word=" << word;
+            this->log();
+            return false;
+        }
+        removeBelow(root, word, 0);
+        words--;
+        return true;
+    }
+
+    // Lists the words starting with prefix, in lexicographic order
+    std::vector<std::string> withPrefix(const std::string& raw) {
+        std::string prefix = fold(raw);
+        this->buffer << "[TRIE_PREFIX] prefix=" << prefix;
+        this->log();
+        std::vector<std::string> out;
+        TrieNode* node = walk(prefix);
+        if (node) collect(node, prefix, out);
+        this->buffer << "[TRIE_PREFIX_RESULT] prefix=" << prefix << " count=" << out.size();
+        this->log();
+        return out;
+    }
+
+    std::vector<std::string> allWords() const {
+        std::vector<std::string> out;
+        std::string prefix;
+        collect(root, prefix, out);
+        return out;
+    }
+
+    size_t size() const { return words; }
+
+    bool is_case_sensitive() const { return case_sensitive; }
+
+    // Draws the tree; '*' marks nodes that end a word
+    void printTreeStructure(std::ostream& os = std::cout) const {
+        os << "LogTrie Structure:" << std::endl;
+        if (root->children.empty()) {
+            os << "└── (empty)" << std::endl;
+            return;
+        }
+        size_t i = 0;
+        for (const auto& entry : root->children) {
+            printNodeStructure(os, entry.second, entry.first, "", ++i == root->children.size());
+        }
+    }
+};
+
+} // namespace datas
+
+#endif // LOG_TRIE_HPP
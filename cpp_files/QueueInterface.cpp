@@ -0,0 +1,111 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogCircularDeque.hpp"
+
+class QueueInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogCircularDeque<int>> queue;
+    size_t capacity;
+
+protected:
+    std::string title() const override { return "Queue"; }
+
+    std::string readyLine() const override {
+        return "READY type=QUEUE capacity=" + std::to_string(capacity);
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>     - Add a value at the back (alias: enqueue, push)\n";
+        *program_out << "  dequeue            - Remove the value at the front (alias: pop)\n";
+        *program_out << "  peek               - Show the value at the front (alias: front)\n";
+        *program_out << "  print              - Display the values, front first\n";
+        *program_out << "  buffer             - Display the circular buffer slots\n";
+        *program_out << "  size               - Show how many values are queued\n";
+        *program_out << "  status             - Show queue status\n";
+    }
+
+    void initStructure() override {
+        queue = std::make_unique<datas::LogCircularDeque<int>>(capacity, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=QUEUE capacity=" << capacity << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert" || command == "enqueue" || command == "push") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (queue->pushBack(value)) {
+                *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << queue->size() << std::endl;
+            } else {
+                *program_out << "INSERT_FULL value=" << value << " capacity=" << queue->capacity() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "dequeue" || command == "pop") {
+            size_t mark = logMark();
+            if (queue->popFront(value)) {
+                *program_out << "DEQUEUE_SUCCESS value=" << value << " new_size=" << queue->size() << std::endl;
+            } else {
+                *program_out << "DEQUEUE_EMPTY size=0" << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "peek" || command == "front") {
+            if (queue->front(value)) {
+                *program_out << "PEEK_RESULT value=" << value << " size=" << queue->size() << std::endl;
+            } else {
+                *program_out << "PEEK_EMPTY size=0" << std::endl;
+            }
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "QUEUE_START" << std::endl;
+            queue->printItems(*program_out);
+            *program_out << "QUEUE_END" << std::endl;
+        }
+        else if (command == "buffer") {
+            *program_out << "BUFFER_START" << std::endl;
+            queue->printSlots(*program_out);
+            *program_out << "BUFFER_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << queue->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS queue_size=" << queue->size() << " type=QUEUE capacity=" << queue->capacity()
+                         << " head=" << queue->headIndex() << " tail=" << queue->tailIndex() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    QueueInterface(size_t cap, bool interactive = true)
+        : DataInterface(interactive), capacity(cap) {}
+};
+
+int main(int argc, char* argv[]) {
+    int capacity = 8;
+    return runDataInterface(argc, argv, "queueInterface 1.0",
+        "  --capacity <n>        Slots in the circular buffer (default: 8, 1 to 1024)\n",
+        [&](bool interactive) {
+            return std::make_unique<QueueInterface>(static_cast<size_t>(capacity), interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--capacity" && i + 1 < argc) {
+                capacity = std::atoi(argv[++i]);
+                if (capacity < 1 || capacity > 1024) {
+                    std::cerr << "Error: Capacity must be between 1 and 1024" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
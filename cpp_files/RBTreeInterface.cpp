@@ -0,0 +1,123 @@
+#include "DataInterface.hpp"
+#include "LogRBTree.hpp"
+
+class RBTreeInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogRBTree<int>> tree;
+    int tree_size;
+
+protected:
+    std::string title() const override { return "Red-Black Tree"; }
+
+    std::string readyLine() const override { return "READY type=RB"; }
+
+    void printCommands() override {
+        *program_out << "  insert <value>  - Insert a value\n";
+        *program_out << "  remove <value>  - Remove a value\n";
+        *program_out << "  find <value>    - Search for a value\n";
+        *program_out << "  print           - Display the tree (inorder)\n";
+        *program_out << "  structure       - Display tree structure with colors\n";
+        *program_out << "  size            - Show tree size\n";
+        *program_out << "  status          - Show tree status\n";
+    }
+
+    void initStructure() override {
+        tree = std::make_unique<datas::LogRBTree<int>>(log_stream);
+        tree_size = 0;
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=RB size=" << tree_size << std::endl;
+    }
+
+    void insertValue(int value) {
+        if (tree->exist_in_tree(value)) {
+            *program_out << "INSERT_DUPLICATE value=" << value << " size=" << tree_size << std::endl;
+            return;
+        }
+
+        size_t mark = logMark();
+        tree->insert(value);
+        tree_size++;
+        *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << tree_size << std::endl;
+        forwardLogs(mark);
+    }
+
+    void removeValue(int value) {
+        if (!tree->exist_in_tree(value)) {
+            *program_out << "REMOVE_NOT_FOUND value=" << value << " size=" << tree_size << std::endl;
+            return;
+        }
+
+        size_t mark = logMark();
+        if (tree->remove(value)) {
+            tree_size--;
+            *program_out << "REMOVE_SUCCESS value=" << value << " new_size=" << tree_size << std::endl;
+        } else {
+            *program_out << "REMOVE_FAILED value=" << value << " size=" << tree_size << std::endl;
+        }
+        forwardLogs(mark);
+    }
+
+    void findValue(int value) {
+        size_t mark = logMark();
+        bool found = tree->exist_in_tree(value);
+        *program_out << "FIND_RESULT value=" << value << " found=" << (found ? "true" : "false") << std::endl;
+        forwardLogs(mark);
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert") {
+            if (iss >> value) {
+                insertValue(value);
+            } else {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+            }
+        }
+        else if (command == "remove") {
+            if (iss >> value) {
+                removeValue(value);
+            } else {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<value>" << std::endl;
+            }
+        }
+        else if (command == "find" || command == "search") {
+            if (iss >> value) {
+                findValue(value);
+            } else {
+                *program_out << "ERROR invalid_find_syntax usage=find_<value>" << std::endl;
+            }
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "TREE_INORDER_START" << std::endl;
+            tree->inorder(*program_out);
+            *program_out << "TREE_INORDER_END" << std::endl;
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            tree->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << tree_size << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS tree_size=" << tree_size << " type=RB black_height=" << tree->black_height() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    explicit RBTreeInterface(bool interactive = true)
+        : DataInterface(interactive), tree_size(0) {}
+};
+
+int main(int argc, char* argv[]) {
+    return runDataInterface(argc, argv, "rbtreeInterface 1.0", "",
+        [](bool interactive) { return std::make_unique<RBTreeInterface>(interactive); },
+        [](const std::string&, int&) { return true; });
+}
@@ -0,0 +1,144 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogSegmentTree.hpp"
+
+class SegmentTreeInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogSegmentTree> tree;
+    size_t array_size;
+    datas::LogSegmentTree::Function function;
+
+    bool readRange(std::istringstream& iss, size_t& from, size_t& to) {
+        long long a, b;
+        if (!(iss >> a >> b) || a < 0 || b < a || static_cast<size_t>(b) >= tree->capacity()) return false;
+        from = static_cast<size_t>(a);
+        to = static_cast<size_t>(b);
+        return true;
+    }
+
+protected:
+    std::string title() const override { return "Segment Tree"; }
+
+    std::string readyLine() const override {
+        return std::string("READY type=SEGTREE size=") + std::to_string(array_size) + " function=" + tree->functionName();
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>       - Set the first slot not set yet\n";
+        *program_out << "  set <i> <value>      - Set the value at index i\n";
+        *program_out << "  query <l> <r>        - Aggregate the values at indexes l to r\n";
+        *program_out << "  print                - Display the array\n";
+        *program_out << "  structure            - Display tree structure with node aggregates\n";
+        *program_out << "  size                 - Show how many slots are set\n";
+        *program_out << "  status               - Show tree status\n";
+    }
+
+    void initStructure() override {
+        tree = std::make_unique<datas::LogSegmentTree>(array_size, function, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=SEGTREE size=0 capacity=" << array_size
+                     << " function=" << tree->functionName() << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        long long value;
+        if (command == "insert") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            long index = tree->append(value);
+            if (index >= 0) {
+                *program_out << "INSERT_SUCCESS value=" << value << " index=" << index << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "ERROR array_full capacity=" << tree->capacity() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "set" || command == "update") {
+            long long index;
+            if (!(iss >> index >> value)) {
+                *program_out << "ERROR invalid_set_syntax usage=set_<index>_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (index >= 0 && tree->set(static_cast<size_t>(index), value)) {
+                *program_out << "SET_SUCCESS index=" << index << " value=" << value << " size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "ERROR index_out_of_range index=" << index << " capacity=" << tree->capacity() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "query") {
+            size_t from, to;
+            if (!readRange(iss, from, to)) {
+                *program_out << "ERROR invalid_query_syntax usage=query_<l>_<r> capacity=" << tree->capacity() << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            std::string result = tree->query(from, to);
+            *program_out << "QUERY_RESULT from=" << from << " to=" << to << " function=" << tree->functionName()
+                         << " value=" << result << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "ARRAY_START" << std::endl;
+            tree->printArray(*program_out);
+            *program_out << "ARRAY_END" << std::endl;
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            tree->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << tree->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS segtree_size=" << tree->size() << " type=SEGTREE capacity=" << tree->capacity()
+                         << " function=" << tree->functionName() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    SegmentTreeInterface(size_t size, datas::LogSegmentTree::Function f, bool interactive = true)
+        : DataInterface(interactive), array_size(size), function(f) {}
+};
+
+int main(int argc, char* argv[]) {
+    int size = 16;
+    datas::LogSegmentTree::Function function = datas::LogSegmentTree::SUM;
+    return runDataInterface(argc, argv, "segtreeInterface 1.0",
+        "  --size <n>                Array size (default: 16, 1 to 1024)\n"
+        "  --function <sum|min|max>  Aggregate of a range (default: sum)\n",
+        [&](bool interactive) {
+            return std::make_unique<SegmentTreeInterface>(static_cast<size_t>(size), function, interactive);
+        },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--size" && i + 1 < argc) {
+                size = std::atoi(argv[++i]);
+                if (size < 1 || size > 1024) {
+                    std::cerr << "Error: Size must be between 1 and 1024" << std::endl;
+                    return false;
+                }
+            }
+            else if (arg == "--function" && i + 1 < argc) {
+                std::string name = argv[++i];
+                if (name != "sum" && name != "min" && name != "max") {
+                    std::cerr << "Error: Function must be sum, min or max" << std::endl;
+                    return false;
+                }
+                function = name == "min" ? datas::LogSegmentTree::MIN
+                         : name == "max" ? datas::LogSegmentTree::MAX
+                         : datas::LogSegmentTree::SUM;
+            }
+            return true;
+        });
+}
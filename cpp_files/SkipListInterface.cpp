@@ -0,0 +1,134 @@
+#include <cstdlib>
+#include "DataInterface.hpp"
+#include "LogSkipList.hpp"
+
+class SkipListInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogSkipList<int>> list;
+    double probability;
+    int max_level;
+
+protected:
+    std::string title() const override { return "Skip List"; }
+
+    std::string readyLine() const override {
+        std::ostringstream oss;
+        oss << "READY type=SKIPLIST probability=" << probability << " max_level=" << max_level;
+        return oss.str();
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>  - Insert a value\n";
+        *program_out << "  remove <value>  - Remove a value\n";
+        *program_out << "  find <value>    - Search for a value\n";
+        *program_out << "  print           - Display every level of the list\n";
+        *program_out << "  inorder         - Display values in order\n";
+        *program_out << "  size            - Show list size\n";
+        *program_out << "  status          - Show list status\n";
+    }
+
+    void initStructure() override {
+        list = std::make_unique<datas::LogSkipList<int>>(probability, max_level, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=SKIPLIST probability=" << probability
+                     << " max_level=" << max_level << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (list->insert(value)) {
+                *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << list->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << value << " size=" << list->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (list->remove(value)) {
+                *program_out << "REMOVE_SUCCESS value=" << value << " new_size=" << list->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << value << " size=" << list->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool found = list->find(value);
+            *program_out << "FIND_RESULT value=" << value << " found=" << (found ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "SKIPLIST_START" << std::endl;
+            list->printLevels(*program_out);
+            *program_out << "SKIPLIST_END" << std::endl;
+        }
+        else if (command == "inorder") {
+            *program_out << "SKIPLIST_INORDER_START" << std::endl;
+            *program_out << "[";
+            std::vector<int> values = list->values();
+            for (size_t i = 0; i < values.size(); i++) {
+                *program_out << (i ? ", " : "") << values[i];
+            }
+            *program_out << "]" << std::endl;
+            *program_out << "SKIPLIST_INORDER_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << list->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS list_size=" << list->size() << " type=SKIPLIST level=" << list->height()
+                         << " max_level=" << max_level << " probability=" << probability << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    SkipListInterface(double p, int levels, bool interactive = true)
+        : DataInterface(interactive), probability(p), max_level(levels) {}
+};
+
+int main(int argc, char* argv[]) {
+    double probability = 0.5;
+    int max_level = 16;
+    return runDataInterface(argc, argv, "skiplistInterface 1.0",
+        "  --probability <p>     Chance a node grows one more level (default: 0.5, 0 < p < 1)\n"
+        "  --max-level <n>       Highest level a node can reach (default: 16, 1 to 32)\n",
+        [&](bool interactive) { return std::make_unique<SkipListInterface>(probability, max_level, interactive); },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--probability" && i + 1 < argc) {
+                probability = std::atof(argv[++i]);
+                if (probability <= 0 || probability >= 1) {
+                    std::cerr << "Error: Probability must be between 0 and 1" << std::endl;
+                    return false;
+                }
+            }
+            else if (arg == "--max-level" && i + 1 < argc) {
+                max_level = std::atoi(argv[++i]);
+                if (max_level < 1 || max_level > 32) {
+                    std::cerr << "Error: Max level must be between 1 and 32" << std::endl;
+                    return false;
+                }
+            }
+            return true;
+        });
+}
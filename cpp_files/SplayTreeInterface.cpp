@@ -0,0 +1,100 @@
+#include "DataInterface.hpp"
+#include "LogSplayTree.hpp"
+
+class SplayTreeInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogSplayTree<int>> tree;
+
+protected:
+    std::string title() const override { return "Splay Tree"; }
+
+    std::string readyLine() const override { return "READY type=SPLAY"; }
+
+    void printCommands() override {
+        *program_out << "  insert <value>  - Insert a value and splay it to the root\n";
+        *program_out << "  remove <value>  - Remove a value\n";
+        *program_out << "  find <value>    - Search for a value and splay the last node reached\n";
+        *program_out << "  print           - Display the tree (inorder)\n";
+        *program_out << "  structure       - Display tree structure\n";
+        *program_out << "  size            - Show tree size\n";
+        *program_out << "  status          - Show tree status\n";
+    }
+
+    void initStructure() override {
+        tree = std::make_unique<datas::LogSplayTree<int>>(log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=SPLAY size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (tree->insert(value)) {
+                *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << value << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (tree->remove(value)) {
+                *program_out << "REMOVE_SUCCESS value=" << value << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << value << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool found = tree->find(value);
+            *program_out << "FIND_RESULT value=" << value << " found=" << (found ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "TREE_INORDER_START" << std::endl;
+            tree->inorder(*program_out);
+            *program_out << "TREE_INORDER_END" << std::endl;
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            tree->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << tree->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS tree_size=" << tree->size() << " type=SPLAY height=" << tree->height() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    explicit SplayTreeInterface(bool interactive = true)
+        : DataInterface(interactive) {}
+};
+
+int main(int argc, char* argv[]) {
+    return runDataInterface(argc, argv, "splaytreeInterface 1.0", "",
+        [](bool interactive) { return std::make_unique<SplayTreeInterface>(interactive); },
+        [](const std::string&, int&) { return true; });
+}
@@ -0,0 +1,126 @@
+#include <random>
+#include "DataInterface.hpp"
+#include "LogTreap.hpp"
+
+class TreapInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogTreap<int>> tree;
+    uint32_t seed;
+
+protected:
+    std::string title() const override { return "Treap"; }
+
+    std::string readyLine() const override {
+        return "READY type=TREAP seed=" + std::to_string(seed);
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <value>  - Insert a value with a random priority\n";
+        *program_out << "  remove <value>  - Remove a value\n";
+        *program_out << "  find <value>    - Search for a value\n";
+        *program_out << "  print           - Display the tree (inorder)\n";
+        *program_out << "  structure       - Display tree structure with priorities\n";
+        *program_out << "  size            - Show tree size\n";
+        *program_out << "  status          - Show tree status\n";
+    }
+
+    // Restarts the priority sequence too, so init followed by the same inserts rebuilds the same tree
+    void initStructure() override {
+        tree = std::make_unique<datas::LogTreap<int>>(seed, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=TREAP seed=" << seed << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        int value;
+        if (command == "insert") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (tree->insert(value)) {
+                *program_out << "INSERT_SUCCESS value=" << value << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << value << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (tree->remove(value)) {
+                *program_out << "REMOVE_SUCCESS value=" << value << " new_size=" << tree->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << value << " size=" << tree->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> value)) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<value>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool found = tree->find(value);
+            *program_out << "FIND_RESULT value=" << value << " found=" << (found ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            *program_out << "TREE_INORDER_START" << std::endl;
+            tree->inorder(*program_out);
+            *program_out << "TREE_INORDER_END" << std::endl;
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            tree->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << tree->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS tree_size=" << tree->size() << " type=TREAP height=" << tree->height()
+                         << " seed=" << seed << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    TreapInterface(uint32_t priority_seed, bool interactive = true)
+        : DataInterface(interactive), seed(priority_seed) {}
+};
+
+int main(int argc, char* argv[]) {
+    // Without --seed the priorities still come from a seed, reported in READY so a run can be repeated
+    uint32_t seed = std::random_device{}();
+    return runDataInterface(argc, argv, "treapInterface 1.0",
+        "  --seed <n>            Seed of the node priorities (default: random, 0 to 4294967295)\n",
+        [&](bool interactive) { return std::make_unique<TreapInterface>(seed, interactive); },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--seed" && i + 1 < argc) {
+                std::string raw = argv[++i];
+                size_t used = 0;
+                unsigned long long parsed = 0;
+                try {
+                    parsed = std::stoull(raw, &used);
+                } catch (const std::exception&) {
+                    used = 0;
+                }
+                if (raw.empty() || used != raw.size() || raw[0] == '-' || parsed > 4294967295ULL) {
+                    std::cerr << "Error: Seed must be an integer between 0 and 4294967295" << std::endl;
+                    return false;
+                }
+                seed = static_cast<uint32_t>(parsed);
+            }
+            return true;
+        });
+}
@@ -0,0 +1,151 @@
+#include <iomanip>
+#include "DataInterface.hpp"
+#include "LogTrie.hpp"
+
+// Words are read with std::quoted, so a key holding spaces arrives as "two words";
+// words are written back quoted when they would not survive as one field
+static std::string wireWord(const std::string& word) {
+    bool plain = !word.empty();
+    for (char c : word) {
+        if (std::isspace(static_cast<unsigned char>(c)) || c == '"' || c == '\\' || c == ',' || c == '[' || c == ']') {
+            plain = false;
+        }
+    }
+    if (plain) return word;
+    std::ostringstream quoted;
+    quoted << std::quoted(word);
+    return quoted.str();
+}
+
+class TrieInterface : public DataInterface {
+private:
+    std::unique_ptr<datas::LogTrie> trie;
+    bool case_sensitive;
+
+    const char* caseName() const {
+        return case_sensitive ? "sensitive" : "insensitive";
+    }
+
+    void printWords(const char* start, const char* end, const std::vector<std::string>& words) {
+        *program_out << start << std::endl;
+        for (const std::string& word : words) {
+            *program_out << wireWord(word) << std::endl;
+        }
+        *program_out << end << std::endl;
+    }
+
+protected:
+    std::string title() const override { return "Trie"; }
+
+    std::string readyLine() const override {
+        return std::string("READY type=TRIE case=") + caseName();
+    }
+
+    void printCommands() override {
+        *program_out << "  insert <word>   - Insert a word\n";
+        *program_out << "  remove <word>   - Remove a word\n";
+        *program_out << "  find <word>     - Search for a word\n";
+        *program_out << "  prefix <prefix> - List words starting with prefix\n";
+        *program_out << "  print           - List all words\n";
+        *program_out << "  structure       - Display trie structure\n";
+        *program_out << "  size            - Show number of words\n";
+        *program_out << "  status          - Show trie status\n";
+    }
+
+    void initStructure() override {
+        trie = std::make_unique<datas::LogTrie>(case_sensitive, log_stream);
+        log_stream.str("");
+        log_stream.clear();
+
+        *program_out << "INIT_SUCCESS type=TRIE case=" << caseName() << " size=0" << std::endl;
+    }
+
+    bool handleCommand(const std::string& command, std::istringstream& iss) override {
+        std::string word;
+        if (command == "insert") {
+            if (!(iss >> std::quoted(word))) {
+                *program_out << "ERROR invalid_insert_syntax usage=insert_<word>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (trie->insert(word)) {
+                *program_out << "INSERT_SUCCESS value=" << wireWord(word) << " new_size=" << trie->size() << std::endl;
+            } else {
+                *program_out << "INSERT_DUPLICATE value=" << wireWord(word) << " size=" << trie->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "remove") {
+            if (!(iss >> std::quoted(word))) {
+                *program_out << "ERROR invalid_remove_syntax usage=remove_<word>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            if (trie->remove(word)) {
+                *program_out << "REMOVE_SUCCESS value=" << wireWord(word) << " new_size=" << trie->size() << std::endl;
+            } else {
+                *program_out << "REMOVE_NOT_FOUND value=" << wireWord(word) << " size=" << trie->size() << std::endl;
+            }
+            forwardLogs(mark);
+        }
+        else if (command == "find" || command == "search") {
+            if (!(iss >> std::quoted(word))) {
+                *program_out << "ERROR invalid_find_syntax usage=find_<word>" << std::endl;
+                return true;
+            }
+            size_t mark = logMark();
+            bool found = trie->contains(word);
+            *program_out << "FIND_RESULT value=" << wireWord(word) << " found=" << (found ? "true" : "false") << std::endl;
+            forwardLogs(mark);
+        }
+        else if (command == "prefix") {
+            // An empty prefix lists every word
+            iss >> std::quoted(word);
+            size_t mark = logMark();
+            std::vector<std::string> words = trie->withPrefix(word);
+            *program_out << "PREFIX_RESULT prefix=" << wireWord(word) << " count=" << words.size() << std::endl;
+            printWords("PREFIX_START", "PREFIX_END", words);
+            forwardLogs(mark);
+        }
+        else if (command == "print" || command == "show") {
+            printWords("TRIE_WORDS_START", "TRIE_WORDS_END", trie->allWords());
+        }
+        else if (command == "structure") {
+            *program_out << "TREE_STRUCTURE_START" << std::endl;
+            trie->printTreeStructure(*program_out);
+            *program_out << "TREE_STRUCTURE_END" << std::endl;
+        }
+        else if (command == "size") {
+            *program_out << "SIZE " << trie->size() << std::endl;
+        }
+        else if (command == "status") {
+            *program_out << "STATUS trie_size=" << trie->size() << " type=TRIE case=" << caseName() << std::endl;
+        }
+        else {
+            return false;
+        }
+        return true;
+    }
+
+public:
+    explicit TrieInterface(bool sensitive, bool interactive = true)
+        : DataInterface(interactive), case_sensitive(sensitive) {}
+};
+
+int main(int argc, char* argv[]) {
+    bool case_sensitive = true;
+    return runDataInterface(argc, argv, "trieInterface 1.0",
+        "  --case <sensitive|insensitive>  Letter case handling (default: sensitive)\n",
+        [&](bool interactive) { return std::make_unique<TrieInterface>(case_sensitive, interactive); },
+        [&](const std::string& arg, int& i) {
+            if (arg == "--case" && i + 1 < argc) {
+                std::string mode = argv[++i];
+                if (mode != "sensitive" && mode != "insensitive") {
+                    std::cerr << "Error: Case must be sensitive or insensitive" << std::endl;
+                    return false;
+                }
+                case_sensitive = mode == "sensitive";
+            }
+            return true;
+        });
+}
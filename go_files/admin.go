@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed static/dashboard.html
+var dashboardPage []byte
+
+// sessionInfo is the admin view of a live session
+type sessionInfo struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Flags           string    `json:"flags"`
+	Started         time.Time `json:"started"`
+	Paused          bool      `json:"paused"`
+	Hibernated      bool      `json:"hibernated"`
+	JournalPosition int       `json:"journal_position"`
+	JournalLength   int       `json:"journal_length"`
+	Processes       int       `json:"processes"`
+	Viewers         int       `json:"viewers"`
+	BytesSent       int64     `json:"bytes_sent"`
+	BandwidthMode   string    `json:"bandwidth_mode"`
+	DebugProtocol   bool      `json:"debug_protocol"`
+	// Simulated network conditions set by an instructor; zero when off
+	SimulatedLatencyMS int64 `json:"simulated_latency_ms"`
+	SimulatedJitterMS  int64 `json:"simulated_jitter_ms"`
+}
+
+// info returns the admin view of the session
+func (s *Session) info() sessionInfo {
+	s.pauseMu.Lock()
+	paused := s.paused
+	s.pauseMu.Unlock()
+	s.procMu.Lock()
+	generation := s.generation
+	hibernated := s.hibernated
+	s.procMu.Unlock()
+	position, length := s.journal.status()
+	network := s.networkSettings()
+	viewers := 0
+	if s.hub != nil {
+		viewers = s.hub.count()
+	}
+
+	return sessionInfo{
+		ID:              s.ID,
+		Type:            s.DataType,
+		Flags:           s.Flags,
+		Started:         s.Started,
+		Paused:          paused,
+		Hibernated:      hibernated,
+		JournalPosition: position,
+		JournalLength:   length,
+		Processes:       generation,
+		Viewers:         viewers,
+		BytesSent:       s.bytesSent(),
+		BandwidthMode:   s.bandwidthMode(),
+		DebugProtocol:   s.inspector != nil,
+
+		SimulatedLatencyMS: network.LatencyMS,
+		SimulatedJitterMS:  network.JitterMS,
+	}
+}
+
+// requireAdmin rejects requests without the configured admin token
+// The token is accepted as a Bearer header or a token query parameter
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorized(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminAuthorized reports whether a request carries the configured admin token
+// Without an admin_token no request is an admin, so the admin routes stay closed
+func adminAuthorized(r *http.Request) bool {
+	if config.AdminToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) == 1
+}
+
+// handleAdminSessions serves GET /admin/sessions
+func handleAdminSessions(w http.ResponseWriter, r *http.Request) {
+	infos := []sessionInfo{}
+	for _, s := range listSessions() {
+		infos = append(infos, s.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	writeJSON(w, infos)
+}
+
+// handleAdminStats serves GET /admin/stats
+func handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, metrics.snapshot())
+}
+
+// handleDashboard serves the embedded GET /admin/dashboard page
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardPage)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
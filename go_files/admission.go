@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+)
+
+// ErrServerBusy is returned when a new session would run past config.MaxProcesses
+var ErrServerBusy = fmt.Errorf("%w: too many interface processes running", ErrCapacity)
+
+// cppProcesses counts the process slots taken: C++ interface processes starting or running
+var cppProcesses atomic.Int64
+
+// busyEnvelope is the error envelope of ErrServerBusy, telling the client when to retry
+type busyEnvelope struct {
+	errorEnvelope
+	RetryAfter int `json:"retry_after"` // seconds
+}
+
+// newBusyEnvelope wraps ErrServerBusy for clients
+func newBusyEnvelope() busyEnvelope {
+	return busyEnvelope{newErrorEnvelope(ErrServerBusy), config.BusyRetryAfterSeconds}
+}
+
+// admitSession refuses a new session on engine while the C++ processes are at the cap
+// It answers early, before a WebSocket upgrade; the slot itself is taken when the process
+// starts, so sessions admitted together still cannot run past the cap
+func admitSession(engine string) error {
+	if engine == "" {
+		engine = config.Engine
+	}
+	if engine != engineCpp || config.MaxProcesses <= 0 {
+		return nil
+	}
+	if cppProcesses.Load() >= int64(config.MaxProcesses) {
+		return ErrServerBusy
+	}
+	return nil
+}
+
+// acquireProcessSlot takes a slot for a C++ interface process about to start, failing with
+// ErrServerBusy when config.MaxProcesses are taken
+func acquireProcessSlot() error {
+	for {
+		taken := cppProcesses.Load()
+		if config.MaxProcesses > 0 && taken >= int64(config.MaxProcesses) {
+			return ErrServerBusy
+		}
+		if cppProcesses.CompareAndSwap(taken, taken+1) {
+			return nil
+		}
+	}
+}
+
+// releaseProcessSlot frees the slot of a C++ interface process that exited or never started
+func releaseProcessSlot() {
+	cppProcesses.Add(-1)
+}
+
+// startInterfaceCommand starts a C++ interface command in a process slot; every interface
+// process running a structure starts here. The returned wait waits for the command and
+// frees the slot
+func startInterfaceCommand(cmd *exec.Cmd) (func() error, error) {
+	if err := acquireProcessSlot(); err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		releaseProcessSlot()
+		return nil, err
+	}
+	return func() error {
+		defer releaseProcessSlot()
+		return cmd.Wait()
+	}, nil
+}
+
+// runInterfaceCommand runs a C++ interface command to its end in a process slot
+func runInterfaceCommand(cmd *exec.Cmd) error {
+	wait, err := startInterfaceCommand(cmd)
+	if err != nil {
+		return err
+	}
+	return wait()
+}
+
+// httpBusy answers a refused session request with 429 and Retry-After
+func httpBusy(w http.ResponseWriter) {
+	recordError(ErrServerBusy)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Retry-After", strconv.Itoa(config.BusyRetryAfterSeconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	sendJSONValue(w, newBusyEnvelope())
+}
+
+// sendBusy tells a raw TCP client the server is busy before it is disconnected
+func sendBusy(writer io.Writer) error {
+	recordError(ErrServerBusy)
+	return sendJSONValue(writer, newBusyEnvelope())
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+const (
+	// loginCookie holds the login session token of a signed-in user
+	loginCookie = "datas_login"
+	// stateCookie carries the OAuth2 state between /login and /callback
+	stateCookie = "datas_oauth_state"
+	// loginTTL is how long a login session lasts
+	loginTTL = 7 * 24 * time.Hour
+	// oauthTimeout bounds the code exchange and user info request
+	oauthTimeout = 10 * time.Second
+)
+
+// ErrUnknownProvider is returned for login providers that are not configured
+var ErrUnknownProvider = errors.New("unknown login provider")
+
+// User is an identity established through an OAuth2 provider
+type User struct {
+	ID       string `json:"id"` // "<provider>:<provider user id>", stored as owner of trees and sessions
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+}
+
+// oauthProvider describes how to log in with one identity provider
+type oauthProvider struct {
+	endpoint oauth2.Endpoint
+	scopes   []string
+	userURL  string
+	// parseUser extracts the identity from the provider's user info response
+	parseUser func(data []byte) (*User, error)
+}
+
+var oauthProviders = map[string]oauthProvider{
+	"github": {
+		endpoint:  endpoints.GitHub,
+		scopes:    []string{"read:user"},
+		userURL:   "https://api.github.com/user",
+		parseUser: parseGitHubUser,
+	},
+	"google": {
+		endpoint:  endpoints.Google,
+		scopes:    []string{"openid", "email", "profile"},
+		userURL:   "https://openidconnect.googleapis.com/v1/userinfo",
+		parseUser: parseGoogleUser,
+	},
+}
+
+// oauthConfig returns the OAuth2 client settings of a configured provider
+func oauthConfig(name string) (*oauth2.Config, oauthProvider, error) {
+	provider, ok := oauthProviders[name]
+	client, configured := config.OAuth[name]
+	if !ok || !configured {
+		return nil, provider, ErrUnknownProvider
+	}
+	return &oauth2.Config{
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+		Endpoint:     provider.endpoint,
+		Scopes:       provider.scopes,
+		RedirectURL:  config.PublicURL + "/callback?provider=" + name,
+	}, provider, nil
+}
+
+// handleLogin serves GET /login?provider=github|google and redirects to the provider
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("provider")
+	cfg, _, err := oauthConfig(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state := newImportToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/callback",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, cfg.AuthCodeURL(state), http.StatusFound)
+}
+
+// handleCallback serves GET /callback: exchanges the code and signs the user in
+func handleCallback(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("provider")
+	cfg, provider, err := oauthConfig(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state, err := r.Cookie(stateCookie)
+	if err != nil || state.Value == "" || state.Value != r.URL.Query().Get("state") {
+		http.Error(w, "Invalid OAuth state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/callback", MaxAge: -1})
+
+	ctx, cancel := context.WithTimeout(r.Context(), oauthTimeout)
+	defer cancel()
+	token, err := cfg.Exchange(ctx, r.URL.Query().Get("code"))
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	user, err := fetchUser(ctx, cfg, token, provider)
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	user.Provider = name
+	user.ID = name + ":" + user.ID
+
+	if err := store.saveUser(user); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	loginToken := newImportToken()
+	login := &LoginRecord{Token: loginToken, User: user, Expires: time.Now().Add(loginTTL)}
+	if err := store.saveLogin(login); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    loginToken,
+		Path:     "/",
+		MaxAge:   int(loginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	fmt.Printf("User %s (%s) logged in\n", user.ID, user.Name)
+	http.Redirect(w, r, "/me", http.StatusFound)
+}
+
+// handleLogout serves POST /logout
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(loginCookie); err == nil {
+		store.deleteLogin(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMe serves GET /me: the signed-in user with their saved trees and sessions
+func handleMe(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	if user == nil {
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+
+	trees, err := listSavedTrees()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	records, err := store.listSessions()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	owned := struct {
+		*User
+		Trees     []string `json:"trees"`
+		Sessions  []string `json:"sessions"`
+		BytesSent int64    `json:"bytes_sent"` // by all of the user's sessions
+	}{User: user, Trees: []string{}, Sessions: []string{}, BytesSent: ownerBytesSent(user.ID, records)}
+	for _, tree := range trees {
+		if tree.Owner == user.ID {
+			owned.Trees = append(owned.Trees, tree.Name)
+		}
+	}
+	for _, rec := range records {
+		if rec.Owner == user.ID {
+			owned.Sessions = append(owned.Sessions, rec.ID)
+		}
+	}
+	writeJSON(w, owned)
+}
+
+// currentUser returns the user signed in on the request, or nil
+func currentUser(r *http.Request) *User {
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil {
+		return nil
+	}
+	login, err := store.loadLogin(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	if time.Now().After(login.Expires) {
+		store.deleteLogin(login.Token)
+		return nil
+	}
+	return login.User
+}
+
+// currentUserID returns the ID of the signed-in user, or "" for anonymous requests
+func currentUserID(r *http.Request) string {
+	if user := currentUser(r); user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// fetchUser requests the user info of the token's owner from the provider
+func fetchUser(ctx context.Context, cfg *oauth2.Config, token *oauth2.Token, provider oauthProvider) (*User, error) {
+	resp, err := cfg.Client(ctx, token).Get(provider.userURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user info: %s", resp.Status)
+	}
+	var data json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return provider.parseUser(data)
+}
+
+// parseGitHubUser reads a GitHub /user response
+func parseGitHubUser(data []byte) (*User, error) {
+	var info struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	if info.ID == 0 {
+		return nil, errors.New("user info without id")
+	}
+	return &User{ID: strconv.FormatInt(info.ID, 10), Name: info.Login}, nil
+}
+
+// parseGoogleUser reads a Google OpenID Connect userinfo response
+func parseGoogleUser(data []byte) (*User, error) {
+	var info struct {
+		Sub   string `json:"sub"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	if info.Sub == "" {
+		return nil, errors.New("user info without sub")
+	}
+	return &User{ID: info.Sub, Name: info.Email}, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Values of config.SlowClientPolicy
+const (
+	// slowClientDropLogs drops the tree logs a client cannot keep up with and
+	// disconnects it only when a program message does not fit its queue
+	slowClientDropLogs = "drop_logs"
+	// slowClientDisconnect disconnects a client as soon as its queue is full
+	slowClientDisconnect = "disconnect"
+)
+
+// closeClientTooSlow is the WebSocket close code of a client disconnected by backpressure
+const closeClientTooSlow = 4005
+
+// ErrClientTooSlow is returned for a client whose outbound queue overflowed or whose write timed out
+var ErrClientTooSlow = fmt.Errorf("%w: client cannot keep up with the output", ErrClientGone)
+
+// deadlineWriter is a client connection whose writes can time out
+// Clients attached through one get an outbound queue in the fanout
+type deadlineWriter interface {
+	io.Writer
+	SetWriteDeadline(t time.Time) error
+}
+
+// writeTimeout is the deadline of one write to a client connection; 0 disables it
+func writeTimeout() time.Duration {
+	return time.Duration(config.WriteTimeoutSeconds) * time.Second
+}
+
+// deadlineConn is a raw TCP client whose every write has the client write timeout
+type deadlineConn struct {
+	net.Conn
+}
+
+// Write implements io.Writer
+func (c deadlineConn) Write(p []byte) (int, error) {
+	if timeout := writeTimeout(); timeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	return c.Conn.Write(p)
+}
+
+// outboundMessage is one message waiting in a client's queue
+type outboundMessage struct {
+	data []byte
+	gap  int // log lines dropped right before this message
+}
+
+// gapNotice tells a client how many log lines it missed
+type gapNotice struct {
+	Type    string `json:"type"` // always "gap"
+	Stream  string `json:"stream"`
+	Dropped int    `json:"dropped"`
+}
+
+// outboundQueue decouples one client connection from the session output: the fanout
+// queues messages without blocking and a goroutine writes them to the socket
+// Everything but the channel is guarded by the fanout's mutex
+type outboundQueue struct {
+	socket   io.Writer
+	messages chan outboundMessage
+	dropped  int  // log lines dropped since the last queued message
+	closed   bool // messages is closed; the writer finishes what is queued
+	failed   atomic.Bool
+	done     chan struct{} // closed once the writer returned
+
+	// code and reason close the socket once the queue is flushed; code 0 leaves it open
+	code   int
+	reason string
+}
+
+// newOutboundQueue starts the writer of a client connection
+func newOutboundQueue(socket io.Writer) *outboundQueue {
+	q := &outboundQueue{
+		socket:   socket,
+		messages: make(chan outboundMessage, config.ClientQueueMessages),
+		done:     make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// push queues a copy of p, a message in the client's encoding; droppable messages that do not fit are dropped under the
+// drop_logs policy, anything else that does not fit disconnects the client
+func (q *outboundQueue) push(p []byte, droppable bool) (int, error) {
+	if q.closed || q.failed.Load() {
+		return 0, ErrClientTooSlow
+	}
+	select {
+	case q.messages <- outboundMessage{data: append([]byte(nil), p...), gap: q.dropped}:
+		q.dropped = 0
+		return len(p), nil
+	default:
+	}
+	if droppable && config.SlowClientPolicy != slowClientDisconnect {
+		q.dropped++
+		metrics.logLinesDropped.Add(1)
+		return 0, nil
+	}
+	q.fail()
+	return 0, ErrClientTooSlow
+}
+
+// fail disconnects a client that fell behind; the writer discards what is still queued
+// The socket is closed from another goroutine as a blocked write holds its lock
+func (q *outboundQueue) fail() {
+	if q.failed.Swap(true) {
+		return
+	}
+	metrics.slowClients.Add(1)
+	go closeSocket(q.socket, closeClientTooSlow, "client too slow")
+}
+
+// close stops taking messages; the writer sends what is queued, then closes the socket
+// with code when it is not 0
+func (q *outboundQueue) close(code int, reason string) {
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.code, q.reason = code, reason
+	close(q.messages)
+}
+
+// run writes queued messages until the queue is closed
+func (q *outboundQueue) run() {
+	defer close(q.done)
+	for message := range q.messages {
+		if q.failed.Load() {
+			continue
+		}
+		if message.gap > 0 {
+			sendJSONValue(q.socket, gapNotice{Type: "gap", Stream: "log", Dropped: message.gap})
+		}
+		if _, err := q.socket.Write(message.data); err != nil {
+			q.fail()
+		}
+	}
+	if q.code != 0 && !q.failed.Load() {
+		closeSocket(q.socket, q.code, q.reason)
+	}
+}
+
+// flush waits for the writer to send what is queued, at most the write timeout per message
+func (q *outboundQueue) flush() {
+	limit := writeTimeout() * time.Duration(max(len(q.messages), 1))
+	if limit <= 0 {
+		<-q.done
+		return
+	}
+	select {
+	case <-q.done:
+	case <-time.After(limit):
+		q.fail()
+	}
+}
+
+// fanoutLogWriter writes tree log lines to a fanout, which may drop them for slow clients
+type fanoutLogWriter struct {
+	fanout *clientFanout
+}
+
+// Write implements io.Writer
+func (w fanoutLogWriter) Write(p []byte) (int, error) {
+	return w.fanout.write(rawMessage(p), true)
+}
+
+// WriteMessage implements messageWriter
+func (w fanoutLogWriter) WriteMessage(m *outgoingMessage) (int, error) {
+	return w.fanout.write(m, true)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// bandwidthSampleThreshold is the share of the cap after which tree logs are sampled
+const bandwidthSampleThreshold = 0.8
+
+// Bandwidth modes of a session, from full output to logs suppressed
+const (
+	bandwidthFull     int32 = iota
+	bandwidthSampling       // only one in BandwidthSampleRate tree log lines is sent
+	bandwidthCapped         // tree logs are dropped; program and server messages still go out
+)
+
+var bandwidthModeNames = map[int32]string{
+	bandwidthFull:     "full",
+	bandwidthSampling: "sampling",
+	bandwidthCapped:   "capped",
+}
+
+// bandwidthUsage tracks what a session sent to its clients against the optional cap
+type bandwidthUsage struct {
+	mode     atomic.Int32
+	logLines atomic.Int64 // tree log lines seen while sampling
+}
+
+// bytesSent returns how many bytes the session wrote to its clients, counted per client
+func (s *Session) bytesSent() int64 {
+	return s.clients.sent.Load()
+}
+
+// bandwidthMode returns the name of the session's current bandwidth mode
+func (s *Session) bandwidthMode() string {
+	return bandwidthModeNames[s.bandwidth.mode.Load()]
+}
+
+// allowLog decides whether a tree log line fits the session's bandwidth cap
+// Tree logs are by far the bulk of the traffic, so they are thinned out first
+func (s *Session) allowLog() bool {
+	limit := config.BandwidthCapBytes
+	if limit <= 0 {
+		return true
+	}
+
+	used := s.bytesSent()
+	mode := bandwidthFull
+	switch {
+	case used >= limit:
+		mode = bandwidthCapped
+	case float64(used) >= float64(limit)*bandwidthSampleThreshold:
+		mode = bandwidthSampling
+	}
+	if previous := s.bandwidth.mode.Swap(mode); previous != mode {
+		s.reply(fmt.Sprintf("BANDWIDTH mode=%s used=%d cap=%d", bandwidthModeNames[mode], used, limit))
+	}
+
+	switch mode {
+	case bandwidthCapped:
+		return false
+	case bandwidthSampling:
+		rate := int64(max(config.BandwidthSampleRate, 1))
+		return (s.bandwidth.logLines.Add(1)-1)%rate == 0
+	default:
+		return true
+	}
+}
+
+// ownerBytesSent totals the bytes sent by a user's sessions, stored and live
+func ownerBytesSent(owner string, records []*SessionRecord) int64 {
+	var total int64
+	live := make(map[string]bool)
+	for _, session := range listSessions() {
+		if session.Owner == owner {
+			total += session.bytesSent()
+			live[session.ID] = true
+		}
+	}
+	for _, rec := range records {
+		if rec.Owner == owner && !live[rec.ID] {
+			total += rec.BytesSent
+		}
+	}
+	return total
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bolt buckets, one per record kind
+var (
+	sessionsBucket = []byte("sessions")
+	treesBucket    = []byte("trees")
+	usersBucket    = []byte("users")
+	loginsBucket   = []byte("logins")
+	// searchBucket indexes session ops: searchTerm + "\x00" + session ID, with empty values
+	searchBucket = []byte("search")
+)
+
+// boltStore keeps all records in a single BoltDB file
+type boltStore struct {
+	db *bolt.DB
+}
+
+// openBoltStore opens (or creates) the database file and its buckets
+func openBoltStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{sessionsBucket, treesBucket, usersBucket, loginsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		// Databases from before the index get it built from their records
+		if tx.Bucket(searchBucket) != nil {
+			return nil
+		}
+		index, err := tx.CreateBucket(searchBucket)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(sessionsBucket).ForEach(func(key, data []byte) error {
+			var rec SessionRecord
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return err
+			}
+			return indexSession(index, &rec, nil)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+// put stores a record as JSON
+func (b *boltStore) put(bucket []byte, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), data)
+	})
+}
+
+// get loads a record, returning ErrRecordNotFound if it does not exist
+func (b *boltStore) get(bucket []byte, key string, v any) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data == nil {
+			return ErrRecordNotFound
+		}
+		return json.Unmarshal(data, v)
+	})
+}
+
+// each decodes every record of a bucket with decode
+func (b *boltStore) each(bucket []byte, decode func(data []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(_, data []byte) error {
+			return decode(data)
+		})
+	})
+}
+
+// saveSession stores the record and moves its index entries to its current ops
+func (b *boltStore) saveSession(rec *SessionRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		var old SessionRecord
+		if previous := sessions.Get([]byte(rec.ID)); previous != nil {
+			if err := json.Unmarshal(previous, &old); err != nil {
+				return err
+			}
+		}
+		if err := indexSession(tx.Bucket(searchBucket), rec, old.Ops); err != nil {
+			return err
+		}
+		return sessions.Put([]byte(rec.ID), data)
+	})
+}
+
+// indexSession replaces the index entries of the record's previous ops with those of its ops
+func indexSession(index *bolt.Bucket, rec *SessionRecord, previous []string) error {
+	entry := func(term string) []byte {
+		return []byte(term + "\x00" + rec.ID)
+	}
+	current := searchTerms(rec.Ops)
+	for _, term := range searchTerms(previous) {
+		if !slices.Contains(current, term) {
+			if err := index.Delete(entry(term)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, term := range current {
+		if err := index.Put(entry(term), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchSessions reads the session IDs filed under term
+func (b *boltStore) searchSessions(term string) ([]string, error) {
+	prefix := []byte(term + "\x00")
+	IDs := []string{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(searchBucket).Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			ID := string(key[len(prefix):])
+			// A key of one op may itself hold the separator; the ID never does
+			if !strings.Contains(ID, "\x00") {
+				IDs = append(IDs, ID)
+			}
+		}
+		return nil
+	})
+	return IDs, err
+}
+
+func (b *boltStore) loadSession(ID string) (*SessionRecord, error) {
+	var rec SessionRecord
+	if err := b.get(sessionsBucket, ID, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (b *boltStore) listSessions() ([]*SessionRecord, error) {
+	records := []*SessionRecord{}
+	err := b.each(sessionsBucket, func(data []byte) error {
+		var rec SessionRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		records = append(records, &rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortSessionRecords(records)
+	return records, nil
+}
+
+func (b *boltStore) saveTree(tree *SavedTree) error {
+	return b.put(treesBucket, tree.Name, tree)
+}
+
+func (b *boltStore) loadTree(name string) (*SavedTree, error) {
+	var tree SavedTree
+	err := b.get(treesBucket, name, &tree)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil, ErrSavedTreeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tree, nil
+}
+
+func (b *boltStore) listTrees() ([]*SavedTree, error) {
+	trees := []*SavedTree{}
+	err := b.each(treesBucket, func(data []byte) error {
+		var tree SavedTree
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return err
+		}
+		trees = append(trees, &tree)
+		return nil
+	})
+	return trees, err
+}
+
+func (b *boltStore) saveUser(user *User) error {
+	return b.put(usersBucket, user.ID, user)
+}
+
+func (b *boltStore) saveLogin(login *LoginRecord) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(loginsBucket)
+
+		// Drop expired logins so the bucket does not grow forever
+		var expired [][]byte
+		bucket.ForEach(func(key, data []byte) error {
+			var old LoginRecord
+			if json.Unmarshal(data, &old) == nil && time.Now().After(old.Expires) {
+				expired = append(expired, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		for _, key := range expired {
+			bucket.Delete(key)
+		}
+
+		return bucket.Put([]byte(login.Token), data)
+	})
+}
+
+func (b *boltStore) loadLogin(token string) (*LoginRecord, error) {
+	var login LoginRecord
+	if err := b.get(loginsBucket, token, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+func (b *boltStore) deleteLogin(token string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(loginsBucket).Delete([]byte(token))
+	})
+}
+
+func (b *boltStore) close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrBroadcastEnded is returned when watching a broadcast whose presenter is gone
+var ErrBroadcastEnded = errors.New("broadcast has ended")
+
+// ErrNotBroadcasting is returned when watching a session that was not started with broadcast=1
+var ErrNotBroadcasting = errors.New("session is not broadcasting")
+
+// viewerBufferSize is how many messages a viewer may lag behind before it is dropped
+const viewerBufferSize = 256
+
+// broadcastHub fans a presenter session's output out to any number of viewers
+// Each viewer gets its own buffered channel so a slow one never stalls the session
+type broadcastHub struct {
+	mu      sync.Mutex
+	viewers map[int]chan *outgoingMessage
+	nextID  int
+	ended   bool
+}
+
+// newBroadcastHub creates a hub with no viewers
+func newBroadcastHub() *broadcastHub {
+	return &broadcastHub{viewers: make(map[int]chan *outgoingMessage)}
+}
+
+// Write publishes one JSON line to every viewer
+func (h *broadcastHub) Write(p []byte) (int, error) {
+	h.WriteMessage(rawMessage(p))
+	return len(p), nil
+}
+
+// WriteMessage publishes one message to every viewer, dropping viewers that fell behind;
+// each viewer gets it in its own encoding
+func (h *broadcastHub) WriteMessage(message *outgoingMessage) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, ch := range h.viewers {
+		select {
+		case ch <- message:
+		default:
+			close(ch)
+			delete(h.viewers, id)
+		}
+	}
+	return 0, nil
+}
+
+// subscribe registers a viewer and returns the channel its messages arrive on
+func (h *broadcastHub) subscribe() (int, <-chan *outgoingMessage, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ended {
+		return 0, nil, ErrBroadcastEnded
+	}
+	h.nextID++
+	ch := make(chan *outgoingMessage, viewerBufferSize)
+	h.viewers[h.nextID] = ch
+	return h.nextID, ch, nil
+}
+
+// unsubscribe removes a viewer
+func (h *broadcastHub) unsubscribe(id int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ch, ok := h.viewers[id]; ok {
+		close(ch)
+		delete(h.viewers, id)
+	}
+}
+
+// count returns the number of connected viewers
+func (h *broadcastHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.viewers)
+}
+
+// hasEnded reports whether the presenter session is over
+func (h *broadcastHub) hasEnded() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ended
+}
+
+// end disconnects every viewer and refuses new ones
+func (h *broadcastHub) end() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ended = true
+	for id, ch := range h.viewers {
+		close(ch)
+		delete(h.viewers, id)
+	}
+}
+
+// startBroadcast attaches a hub to the session output
+// Must be called before the session is registered
+func (s *Session) startBroadcast() error {
+	s.hub = newBroadcastHub()
+	_, err := s.clients.add(s.hub, true)
+	return err
+}
+
+// stopBroadcast disconnects all viewers of the session
+func (s *Session) stopBroadcast() {
+	if s.hub != nil {
+		s.hub.end()
+	}
+}
+
+// watchBroadcast streams a presenter session's output to one viewer socket
+// Anything the viewer sends is discarded
+func watchBroadcast(session *Session, clientID string, socket io.ReadWriter) {
+	id, messages, err := session.hub.subscribe()
+	if err != nil {
+		fmt.Printf("[Client %s] Could not watch session %s: %v\n", clientID, session.ID, err)
+		return
+	}
+	defer session.hub.unsubscribe(id)
+	fmt.Printf("[Client %s] Watching session %s\n", clientID, session.ID)
+	sendJSONMessage(socket, "server", fmt.Sprintf("WATCHING session=%s viewers=%d", session.ID, session.hub.count()))
+
+	// Drain and discard input until the viewer leaves
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		io.Copy(io.Discard, socket)
+	}()
+
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				if session.hub.hasEnded() {
+					sendJSONMessage(socket, "server", "BROADCAST_ENDED session="+session.ID)
+					code, reason := session.closeFrame()
+					closeSocket(socket, code, reason)
+				} else {
+					sendJSONMessage(socket, "server", "ERROR viewer_too_slow")
+				}
+				fmt.Printf("[Client %s] Stopped watching session %s\n", clientID, session.ID)
+				return
+			}
+			if _, err := message.writeTo(socket); err != nil {
+				return
+			}
+		case <-inputDone:
+			fmt.Printf("[Client %s] Stopped watching session %s\n", clientID, session.ID)
+			return
+		}
+	}
+}
+
+// handleWatchClient attaches a lightweight viewer WebSocket to a broadcasting session
+func handleWatchClient(w http.ResponseWriter, r *http.Request, ID string) {
+	session, ok := lookupSession(ID)
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	if session.hub == nil {
+		httpError(w, ErrNotBroadcasting)
+		return
+	}
+
+	conn, err := upgradeClient(w, r, nil)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+
+	defer conn.Close()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s (watch: %s)\n", clientID, clientAddr(r), session.ID)
+	watchBroadcast(session, clientID, conn)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// canaryTolerance is how much higher than the stable failure rate the canary's may be
+const canaryTolerance = 0.05
+
+// ErrNoCanary is returned for data types without a canary in progress
+var ErrNoCanary = errors.New("no canary for this data type")
+
+// canaryTrack counts the sessions of one version while a canary runs
+type canaryTrack struct {
+	Sessions int `json:"sessions"` // sessions that ended
+	Crashes  int `json:"crashes"`
+	// Violations counts sessions whose process broke an invariant, e.g. reported a wrong size
+	Violations int `json:"violations"`
+	Failures   int `json:"failures"` // sessions with a crash or a violation
+}
+
+// failureRate is the share of ended sessions that failed
+func (t canaryTrack) failureRate() float64 {
+	if t.Sessions == 0 {
+		return 0
+	}
+	return float64(t.Failures) / float64(t.Sessions)
+}
+
+// canaryRelease is an updated binary serving a share of the new sessions of
+// one data type next to the stable binary it may replace
+type canaryRelease struct {
+	Name       string      `json:"name"`
+	Release    string      `json:"release"`
+	Version    string      `json:"version"`
+	Executable string      `json:"executable"` // the canary binary
+	Stable     string      `json:"stable"`     // the registered executable
+	Percent    int         `json:"percent"`
+	Started    time.Time   `json:"started"`
+	Canary     canaryTrack `json:"canary"`
+	Control    canaryTrack `json:"stable_track"`
+	done       bool        // promoted or rolled back
+}
+
+var (
+	canaryMu sync.Mutex
+	// canaries holds the canary in progress of each data type
+	canaries = make(map[string]*canaryRelease)
+)
+
+// startCanary puts a staged binary in front of a share of new sessions
+func startCanary(ds DataStructure, release, version, staged string) (*canaryRelease, error) {
+	canaryMu.Lock()
+	defer canaryMu.Unlock()
+	if previous, ok := canaries[ds.Name]; ok {
+		previous.done = true
+		os.Remove(previous.Executable)
+	}
+	canary := &canaryRelease{
+		Name:       ds.Name,
+		Release:    release,
+		Version:    version,
+		Executable: ds.Executable + ".canary",
+		Stable:     ds.Executable,
+		Percent:    config.CanaryPercent,
+		Started:    time.Now(),
+	}
+	if err := os.Rename(staged, canary.Executable); err != nil {
+		return nil, err
+	}
+	canaries[ds.Name] = canary
+	fmt.Printf("Canary %s %s serving %d%% of new %s sessions\n", release, version, canary.Percent, ds.Name)
+	return canary, nil
+}
+
+// joinCanary decides whether a new session of a data type runs the canary binary
+// Returns the release the session's outcome counts towards, or nil when no canary runs
+func joinCanary(ds string) (*canaryRelease, bool) {
+	canaryMu.Lock()
+	defer canaryMu.Unlock()
+	canary, ok := canaries[ds]
+	if !ok {
+		return nil, false
+	}
+	return canary, rand.Intn(100) < canary.Percent
+}
+
+// executable returns the binary a session's processes run: the canary while it
+// lasts, then whatever the registered executable became
+func (s *Session) executable() string {
+	if s.onCanary {
+		canaryMu.Lock()
+		defer canaryMu.Unlock()
+		if !s.canary.done {
+			return s.canary.Executable
+		}
+	}
+	return interfaceExecutable(s.DataType)
+}
+
+// recordCanaryOutcome counts an ended session towards its canary and judges the
+// canary once it has seen enough sessions
+func (s *Session) recordCanaryOutcome(crashed bool) {
+	if s.canary == nil {
+		return
+	}
+	violated := s.ops.invariantViolations() > 0
+
+	canaryMu.Lock()
+	defer canaryMu.Unlock()
+	canary := s.canary
+	if canary.done {
+		return
+	}
+	track := &canary.Control
+	if s.onCanary {
+		track = &canary.Canary
+	}
+	track.Sessions++
+	if crashed {
+		track.Crashes++
+	}
+	if violated {
+		track.Violations++
+	}
+	if crashed || violated {
+		track.Failures++
+	}
+
+	if !s.onCanary || canary.Canary.Sessions < config.CanaryMinSessions {
+		return
+	}
+	if canary.Canary.failureRate() > canary.Control.failureRate()+canaryTolerance {
+		canary.rollbackLocked("canary failure rate %.2f above stable %.2f",
+			canary.Canary.failureRate(), canary.Control.failureRate())
+		return
+	}
+	if err := canary.promoteLocked(); err != nil {
+		fmt.Printf("Canary %s of %s could not be promoted: %v\n", canary.Release, canary.Name, err)
+	}
+}
+
+// promoteLocked makes the canary the stable binary; canaryMu is held
+func (c *canaryRelease) promoteLocked() error {
+	if err := os.Rename(c.Executable, c.Stable); err != nil {
+		return err
+	}
+	c.done = true
+	delete(canaries, c.Name)
+	metrics.canaryPromotions.Add(1)
+	if err := recordRelease(c.Release); err != nil {
+		fmt.Printf("Recording release %s failed: %v\n", c.Release, err)
+	}
+	fmt.Printf("Canary %s of %s promoted after %d sessions (failure rate %.2f, stable %.2f)\n",
+		c.Release, c.Name, c.Canary.Sessions, c.Canary.failureRate(), c.Control.failureRate())
+	go checkInterfaces()
+	return nil
+}
+
+// rollbackLocked discards the canary binary; canaryMu is held
+// Sessions still running it move to the stable binary on their next restart
+func (c *canaryRelease) rollbackLocked(reason string, args ...any) {
+	c.done = true
+	delete(canaries, c.Name)
+	os.Remove(c.Executable)
+	metrics.canaryRollbacks.Add(1)
+	fmt.Printf("Canary %s of %s rolled back: %s\n", c.Release, c.Name, fmt.Sprintf(reason, args...))
+}
+
+// canaryStatuses lists the canaries in progress by data type
+func canaryStatuses() []canaryRelease {
+	canaryMu.Lock()
+	defer canaryMu.Unlock()
+	list := make([]canaryRelease, 0, len(canaries))
+	for _, canary := range canaries {
+		list = append(list, *canary)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// handleCanaries serves GET /admin/canaries
+func handleCanaries(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, canaryStatuses())
+}
+
+// handleCanaryDecision serves POST /admin/canaries/{name}/promote and /rollback,
+// ending a canary before it has seen enough sessions
+func handleCanaryDecision(w http.ResponseWriter, r *http.Request) {
+	canaryMu.Lock()
+	defer canaryMu.Unlock()
+	canary, ok := canaries[r.PathValue("name")]
+	if !ok {
+		httpError(w, ErrNoCanary)
+		return
+	}
+	status := *canary
+	if strings.HasSuffix(r.URL.Path, "/promote") {
+		if err := canary.promoteLocked(); err != nil {
+			httpError(w, err)
+			return
+		}
+	} else {
+		canary.rollbackLocked("requested by an admin")
+	}
+	writeJSON(w, status)
+}
@@ -0,0 +1,114 @@
+package main
+
+import "net/http"
+
+// capabilityParam is one query parameter a session of a structure accepts
+type capabilityParam struct {
+	Name string `json:"name"`
+	// Type is "enum", "integer", "number" or, for comma-separated lists, "integer_list" or "number_list"
+	Type     string   `json:"type"`
+	Values   []string `json:"values,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	MaxItems int      `json:"max_items,omitempty"`
+	Default  string   `json:"default,omitempty"`
+	Required bool     `json:"required,omitempty"`
+}
+
+// structureCapabilities describes what a client may ask of one data structure
+type structureCapabilities struct {
+	Name     string            `json:"name"`
+	KeyType  string            `json:"key_type"`
+	KeyArity int               `json:"key_arity"`
+	Engines  []string          `json:"engines"`
+	Snapshot bool              `json:"snapshot"`
+	Params   []capabilityParam `json:"params"`
+}
+
+// capabilities is the document served by GET /capabilities
+type capabilities struct {
+	ServerVersion   string                  `json:"server_version"`
+	ProtocolVersion int                     `json:"protocol_version"`
+	Subprotocols    []string                `json:"subprotocols"` // WebSocket subprotocols /session negotiates
+	Encodings       []string                `json:"encodings"`    // values of encoding= on /session
+	DefaultEngine   string                  `json:"default_engine"`
+	DataStructures  []structureCapabilities `json:"data_structures"`
+}
+
+// capabilitiesCache is the encoded document, rebuilt with the structure metadata
+var capabilitiesCache *cachedMetadata
+
+// param describes a registry flag the way a form needs it
+func (flag DataStructureFlag) param() capabilityParam {
+	param := capabilityParam{
+		Name:     flag.Param,
+		Values:   flag.Values,
+		MaxItems: flag.MaxItems,
+		Default:  flag.Default,
+		Required: flag.Required,
+	}
+	if len(flag.Values) > 0 {
+		param.Type = "enum"
+		return param
+	}
+	param.Type = "integer"
+	if flag.Float {
+		param.Type = "number"
+	}
+	if flag.List {
+		param.Type += "_list"
+	}
+	low := flag.Min
+	param.Min = &low
+	if flag.Max != 0 {
+		high := flag.Max
+		param.Max = &high
+	}
+	return param
+}
+
+// buildCapabilities describes every registered structure from the registry
+func buildCapabilities() capabilities {
+	doc := capabilities{
+		ServerVersion:   serverVersion,
+		ProtocolVersion: protocolVersion,
+		Subprotocols:    upgrader.Subprotocols,
+		Encodings:       messageEncodings,
+		DefaultEngine:   config.Engine,
+		DataStructures:  []structureCapabilities{},
+	}
+	for i := range config.DataStructures {
+		ds := &config.DataStructures[i]
+		structure := structureCapabilities{
+			Name:     ds.Name,
+			KeyType:  ds.keyType(),
+			KeyArity: ds.keyArity(),
+			Engines:  []string{engineCpp},
+			Params:   []capabilityParam{},
+		}
+		if _, ok := goEngines[ds.model()]; ok {
+			structure.Engines = append(structure.Engines, engineGo)
+		}
+		_, structure.Snapshot = snapshotSpecs[ds.Name]
+		for _, flag := range ds.Flags {
+			structure.Params = append(structure.Params, flag.param())
+		}
+		doc.DataStructures = append(doc.DataStructures, structure)
+	}
+	return doc
+}
+
+// handleCapabilities serves GET /capabilities: the structures, their session
+// parameters and the protocol version, for front-ends building forms
+func handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	metadataMu.RLock()
+	cached := capabilitiesCache
+	metadataMu.RUnlock()
+	if cached == nil {
+		refreshMetadata()
+		metadataMu.RLock()
+		cached = capabilitiesCache
+		metadataMu.RUnlock()
+	}
+	serveMetadata(w, r, cached)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const (
+	// defaultChallengeKeys is how many keys a challenge target holds without a count
+	defaultChallengeKeys = 7
+	// maxChallengeKeys caps the keys of a challenge target
+	maxChallengeKeys = 30
+	// challengeKeyRange is the range challenge keys are drawn from, small enough to read
+	challengeKeyRange = 100
+)
+
+// ErrNoChallenge is returned for challenge subcommands while no challenge is running
+var ErrNoChallenge = errors.New("no challenge running")
+
+// structureChallenge is a target structure a student must reproduce with their own operations
+type structureChallenge struct {
+	target  []string // dump of the target structure, as the snapshot command prints it
+	keys    int
+	seed    int64
+	minimal int // length of the shortest known script building target
+}
+
+// challengeState is the running challenge of a session
+type challengeState struct {
+	mu      sync.Mutex
+	current *structureChallenge
+}
+
+// cmdChallenge runs the challenge subcommands
+// Usage: challenge [keys] [seed] | challenge check | challenge show | challenge end
+func cmdChallenge(session *Session, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "check":
+			return session.checkChallenge()
+		case "show":
+			challenge, err := session.challenges.get()
+			if err != nil {
+				return err
+			}
+			return session.presentChallenge(challenge)
+		case "end":
+			challenge, err := session.challenges.get()
+			if err != nil {
+				return err
+			}
+			session.challenges.set(nil)
+			return session.reply(fmt.Sprintf("CHALLENGE_END seed=%d minimal_ops=%d", challenge.seed, challenge.minimal))
+		}
+	}
+	if len(args) > 2 {
+		return &ValidationError{"usage=challenge_[keys]_[seed]|check|show|end"}
+	}
+
+	keys := defaultChallengeKeys
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > maxChallengeKeys {
+			return &ValidationError{fmt.Sprintf("Invalid key count. Must be integer between 1 and %d", maxChallengeKeys)}
+		}
+		keys = n
+	}
+	var seed int64
+	if len(args) == 2 {
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return &ValidationError{"Invalid seed. Must be integer"}
+		}
+		seed = n
+	} else {
+		seed = session.genSeed()
+	}
+
+	challenge, err := newChallenge(session.DataType, session.Flags, keys, seed)
+	if err != nil {
+		return err
+	}
+	session.challenges.set(challenge)
+	return session.presentChallenge(challenge)
+}
+
+// newChallenge builds a target by replaying a seeded script on the Go model: the keys
+// inserted in random order, with some keys inserted and removed again so the shape
+// is not always the one plain inserts give. The script is then shrunk to the shortest
+// one still building the target, whose length is the known minimum
+func newChallenge(ds, flags string, keys int, seed int64) (*structureChallenge, error) {
+	if _, _, err := goEngineFor(ds); err != nil {
+		return nil, err
+	}
+	if _, ok := snapshotSpecs[ds]; !ok {
+		return nil, ErrSnapshotUnsupported
+	}
+	keyType := keyInt
+	if registered, ok := lookupDataStructure(ds); ok {
+		keyType = registered.keyType()
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	drawn := rng.Perm(challengeKeyRange)
+	decoys := keys / 3
+	var script []string
+	for i, key := range drawn[:keys+decoys] {
+		script = append(script, "insert "+formatKey(keyType, key))
+		if i >= keys {
+			// Decoys are removed again; the shuffle places the remove anywhere after the insert
+			script = append(script, "remove "+formatKey(keyType, key))
+		}
+	}
+	rng.Shuffle(len(script), func(i, j int) { script[i], script[j] = script[j], script[i] })
+	script = insertsBeforeRemoves(script)
+
+	target, err := modelDump(ds, flags, script)
+	if err != nil {
+		return nil, err
+	}
+	builds := func(candidate []string) bool {
+		dump, err := modelDump(ds, flags, candidate)
+		return err == nil && slices.Equal(dump, target)
+	}
+	return &structureChallenge{
+		target:  target,
+		keys:    keys,
+		seed:    seed,
+		minimal: len(minimizeScript(script, builds)),
+	}, nil
+}
+
+// insertsBeforeRemoves moves every remove after the insert of its key, keeping the
+// order of everything else
+func insertsBeforeRemoves(script []string) []string {
+	inserted := make(map[string]bool)
+	var ordered, waiting []string
+	for _, line := range script {
+		key := line[len(commandName(line))+1:]
+		if commandName(line) == "insert" {
+			inserted[key] = true
+			ordered = append(ordered, line)
+			for i := 0; i < len(waiting); i++ {
+				if waiting[i][len("remove "):] == key {
+					ordered = append(ordered, waiting[i])
+					waiting = slices.Delete(waiting, i, i+1)
+					i--
+				}
+			}
+			continue
+		}
+		if inserted[key] {
+			ordered = append(ordered, line)
+		} else {
+			waiting = append(waiting, line)
+		}
+	}
+	return ordered
+}
+
+// modelDump replays ops on the Go model of a structure and returns its structure dump
+func modelDump(ds, flags string, ops []string) ([]string, error) {
+	return dumpStructure(context.Background(), ds, engineGo, flags, ops)
+}
+
+// presentChallenge sends the target to the client
+func (s *Session) presentChallenge(challenge *structureChallenge) error {
+	s.reply(fmt.Sprintf("CHALLENGE_START type=%s keys=%d seed=%d minimal_ops=%d", s.DataType, challenge.keys, challenge.seed, challenge.minimal))
+	s.reply("CHALLENGE_TARGET_START")
+	for _, line := range challenge.target {
+		s.reply(line)
+	}
+	return s.reply("CHALLENGE_TARGET_END")
+}
+
+// checkChallenge verifies the session's structure against the target: the applied
+// journal is replayed on the Go model, so the check holds whichever engine runs the
+// session. The student's solution is the operations since the last init
+func (s *Session) checkChallenge() error {
+	challenge, err := s.challenges.get()
+	if err != nil {
+		return err
+	}
+	ops := s.journal.applied()
+	dump, err := modelDump(s.DataType, s.Flags, ops)
+	if err != nil {
+		return err
+	}
+
+	used := 0
+	for _, line := range ops {
+		if commandName(line) == "init" {
+			used = 0
+		} else {
+			used++
+		}
+	}
+	if !slices.Equal(dump, challenge.target) {
+		return s.reply(fmt.Sprintf("CHALLENGE_RESULT solved=false ops=%d minimal_ops=%d missing_lines=%d extra_lines=%d",
+			used, challenge.minimal, len(lineDifference(challenge.target, dump)), len(lineDifference(dump, challenge.target))))
+	}
+
+	record := used < challenge.minimal
+	if record {
+		s.challenges.mu.Lock()
+		challenge.minimal = used
+		s.challenges.mu.Unlock()
+	}
+	return s.reply(fmt.Sprintf("CHALLENGE_RESULT solved=true ops=%d minimal_ops=%d new_minimum=%t", used, challenge.minimal, record))
+}
+
+// get returns the running challenge
+func (c *challengeState) get() (*structureChallenge, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil {
+		return nil, ErrNoChallenge
+	}
+	return c.current, nil
+}
+
+// set replaces the running challenge; nil ends it
+func (c *challengeState) set(challenge *structureChallenge) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = challenge
+}
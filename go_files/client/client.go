@@ -0,0 +1,289 @@
+// Package client drives DATAS server sessions over the WebSocket protocol
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrClosed is returned when sending on a client whose connection is gone
+var ErrClosed = errors.New("client connection closed")
+
+// Message is one JSON message received from the server
+// Type is "program", "log" or "server" for text messages; structured
+// messages (fork, preview, protocol_error, ...) keep their full payload in Raw
+type Message struct {
+	Type    string          `json:"type"`
+	Content string          `json:"message"`
+	Raw     json.RawMessage `json:"-"`
+}
+
+// Option configures Connect
+type Option func(*settings)
+
+type settings struct {
+	query     url.Values
+	header    http.Header
+	onProgram func(string)
+	onLog     func(string)
+	onServer  func(string)
+	onMessage func(Message)
+}
+
+// WithType selects the data structure of the session (default "btree")
+func WithType(dataType string) Option {
+	return func(s *settings) { s.query.Set("type", dataType) }
+}
+
+// WithOrder sets the B-tree order
+func WithOrder(order int) Option {
+	return func(s *settings) { s.query.Set("order", strconv.Itoa(order)) }
+}
+
+// WithQuery adds a session query parameter, such as load, import or broadcast
+func WithQuery(key, value string) Option {
+	return func(s *settings) { s.query.Set(key, value) }
+}
+
+// WithHeader adds a header to the WebSocket handshake, e.g. a login cookie
+func WithHeader(key, value string) Option {
+	return func(s *settings) { s.header.Add(key, value) }
+}
+
+// OnProgram registers a callback for program output lines
+func OnProgram(fn func(line string)) Option {
+	return func(s *settings) { s.onProgram = fn }
+}
+
+// OnLog registers a callback for tree log lines
+func OnLog(fn func(line string)) Option {
+	return func(s *settings) { s.onLog = fn }
+}
+
+// OnServer registers a callback for server-generated lines (SAVE_SUCCESS, ERROR ...)
+func OnServer(fn func(line string)) Option {
+	return func(s *settings) { s.onServer = fn }
+}
+
+// OnMessage registers a callback receiving every message, including structured ones
+func OnMessage(fn func(msg Message)) Option {
+	return func(s *settings) { s.onMessage = fn }
+}
+
+// Client is a connected session
+// Callbacks run on the client's read goroutine, one message at a time
+type Client struct {
+	conn     *websocket.Conn
+	settings settings
+
+	writeMu sync.Mutex
+	closing atomic.Bool // Close was called; the read error that follows is expected
+
+	sessionID string
+	joinCode  string
+
+	done chan struct{}
+	err  error // why the read loop stopped, set before done closes
+}
+
+// Connect opens a session on the server at baseURL (e.g. "ws://localhost:8080")
+// It returns once the server announced the session ID
+func Connect(ctx context.Context, baseURL string, opts ...Option) (*Client, error) {
+	s := settings{query: url.Values{"type": {"btree"}}, header: http.Header{}}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	target, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/session")
+	if err != nil {
+		return nil, err
+	}
+	target.RawQuery = s.query.Encode()
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, target.String(), s.header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("connect: %w (%s)", err, resp.Status)
+		}
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	c := &Client{conn: conn, settings: s, done: make(chan struct{})}
+	if err := c.awaitSession(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// awaitSession reads messages until the SESSION announcement, delivering them to the callbacks
+func (c *Client) awaitSession(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	for {
+		msg, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		c.deliver(msg)
+		if msg.Type == "server" && strings.HasPrefix(msg.Content, "SESSION ") {
+			fields := parseFields(msg.Content)
+			c.sessionID, c.joinCode = fields["id"], fields["join_code"]
+			return nil
+		}
+	}
+}
+
+// readLoop delivers messages to the callbacks until the connection closes
+func (c *Client) readLoop() {
+	defer close(c.done)
+	for {
+		msg, err := c.read()
+		if err != nil {
+			if !c.closing.Load() {
+				c.err = err
+			}
+			return
+		}
+		c.deliver(msg)
+	}
+}
+
+// read receives and decodes one message
+func (c *Client) read() (Message, error) {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return Message{}, err
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Message{}, fmt.Errorf("decode message: %w", err)
+	}
+	msg.Raw = json.RawMessage(strings.TrimSpace(string(data)))
+	return msg, nil
+}
+
+// deliver passes a message to the matching callbacks
+func (c *Client) deliver(msg Message) {
+	if c.settings.onMessage != nil {
+		c.settings.onMessage(msg)
+	}
+	var fn func(string)
+	switch msg.Type {
+	case "program":
+		fn = c.settings.onProgram
+	case "log":
+		fn = c.settings.onLog
+	case "server":
+		fn = c.settings.onServer
+	}
+	if fn != nil {
+		fn(msg.Content)
+	}
+}
+
+// SessionID returns the ID the server gave the session
+func (c *Client) SessionID() string {
+	return c.sessionID
+}
+
+// JoinCode returns the code other clients can join the session with
+func (c *Client) JoinCode() string {
+	return c.joinCode
+}
+
+// Send writes one raw command line, e.g. "print" or "save mytree"
+func (c *Client) Send(line string) error {
+	if strings.ContainsAny(line, "\r\n") {
+		return errors.New("command must be a single line")
+	}
+	return c.write([]byte(line))
+}
+
+// SendOp writes a JSON op message, e.g. map[string]any{"op": "fork"}
+func (c *Client) SendOp(op any) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+// Insert adds a value to the structure
+func (c *Client) Insert(value int) error {
+	return c.Send("insert " + strconv.Itoa(value))
+}
+
+// Delete removes a value from the structure
+func (c *Client) Delete(value int) error {
+	return c.Send("remove " + strconv.Itoa(value))
+}
+
+// Find looks a value up; the result arrives as program output
+func (c *Client) Find(value int) error {
+	return c.Send("find " + strconv.Itoa(value))
+}
+
+// write sends one line as a WebSocket message; the server splits input on newlines
+func (c *Client) write(data []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, append(data, '\n'))
+}
+
+// Done returns a channel that closes when the connection ends
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended once Done is closed; nil after Close
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the session
+func (c *Client) Close() error {
+	c.closing.Store(true)
+	c.writeMu.Lock()
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMu.Unlock()
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// parseFields reads the key=value tokens of a server line
+func parseFields(line string) map[string]string {
+	fields := make(map[string]string)
+	for _, token := range strings.Fields(line) {
+		if key, value, ok := strings.Cut(token, "="); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrSessionPrivate is returned when a client without admin rights looks into an instructor's clone
+var ErrSessionPrivate = errors.New("session is private")
+
+// auditEvent is one admin action on another user's session, appended to config.AuditLogPath
+type auditEvent struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`  // user ID of the signed-in admin, or "admin" for the token
+	Action  string    `json:"action"` // e.g. "clone"
+	Session string    `json:"session"`
+	Target  string    `json:"target,omitempty"` // session created by the action
+	Owner   string    `json:"owner,omitempty"`  // owner of Session
+	Remote  string    `json:"remote"`
+}
+
+// auditMu serializes appends to the audit log
+var auditMu sync.Mutex
+
+// audit appends an event to the audit log
+func audit(event auditEvent) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(config.AuditLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readAudit returns the events in the audit log, oldest first
+func readAudit() ([]auditEvent, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	events := []auditEvent{}
+	f, err := os.Open(config.AuditLogPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return events, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event auditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue // a line cut short by a crash
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// adminActor names the admin making a request for the audit log
+func adminActor(r *http.Request) string {
+	if ID := currentUserID(r); ID != "" {
+		return ID
+	}
+	return "admin"
+}
+
+// visibleTo reports whether a request may look into the session without joining it
+func (s *Session) visibleTo(r *http.Request) bool {
+	return !s.Private || adminAuthorized(r)
+}
+
+// visibleTo reports whether a request may look into a stored session: an instructor's
+// clone only to admins, like the live session
+func (rec *SessionRecord) visibleTo(r *http.Request) bool {
+	return rec.ClonedBy == "" || adminAuthorized(r)
+}
+
+// handleCloneSession serves POST /admin/sessions/{id}/clone
+// It copies the live session's applied journal into a private session record for the
+// instructor, leaving the student's session untouched; the clone opens with /session?fork=
+func handleCloneSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	actor := adminActor(r)
+	position, _ := session.journal.status()
+
+	// Keep the student's record current so the clone can be compared with it
+	session.saveRecord()
+
+	clone := &SessionRecord{
+		ID:           genID(),
+		Type:         session.DataType,
+		Flags:        session.Flags,
+		Engine:       session.Engine,
+		Language:     session.Language,
+		Owner:        currentUserID(r),
+		ClonedBy:     actor,
+		Seed:         session.Seed,
+		Parent:       session.ID,
+		ForkPosition: position,
+		Created:      time.Now(),
+		Ops:          session.journal.applied(),
+	}
+	// The clone is only stored once its audit event is written, so no clone goes unaudited
+	event := auditEvent{
+		Time:    clone.Created,
+		Actor:   actor,
+		Action:  "clone",
+		Session: session.ID,
+		Target:  clone.ID,
+		Owner:   session.Owner,
+		Remote:  clientAddr(r),
+	}
+	if err := audit(event); err != nil {
+		logError(session.ID, "writing audit log", err)
+		httpError(w, err)
+		return
+	}
+	if err := store.saveSession(clone); err != nil {
+		httpError(w, err)
+		return
+	}
+	fmt.Printf("[Client %s] Cloned by %s into %s at %d\n", session.ID, actor, clone.ID, position)
+
+	writeJSON(w, ForkResult{
+		Type:     "clone",
+		Session:  clone.ID,
+		Parent:   session.ID,
+		Position: position,
+		URL:      "/session?fork=" + clone.ID,
+	})
+}
+
+// handleAudit serves GET /admin/audit
+func handleAudit(w http.ResponseWriter, r *http.Request) {
+	events, err := readAudit()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, events)
+}
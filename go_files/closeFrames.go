@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Teardown reasons of sessions ended from outside their clients and process
+const (
+	killedReason  = "killed by admin"
+	evictedReason = "evicted"
+)
+
+// WebSocket close codes sent to the clients of an ending session
+// (4000-4999 is application defined)
+const (
+	closeSessionKilled  = 4003 // an admin ended the session
+	closeSessionEvicted = 4004 // the session hibernated too long and was evicted
+)
+
+// closeFrame returns the close code and reason sent to clients of a session that
+// ended for reason; the text stays well under the 123 bytes a close frame allows
+func closeFrame(reason string) (int, string) {
+	switch reason {
+	case "process ended", "clients left":
+		return websocket.CloseNormalClosure, "session ended"
+	case shutdownReason:
+		return websocket.CloseGoingAway, "server shutting down"
+	case "process crashed":
+		return websocket.CloseInternalServerErr, "interface process crashed"
+	case "setup failed":
+		return websocket.CloseInternalServerErr, "session setup failed"
+	case killedReason:
+		return closeSessionKilled, "session ended by an administrator"
+	case evictedReason:
+		return closeSessionEvicted, "session evicted after hibernating"
+	}
+	return websocket.CloseNormalClosure, reason
+}
+
+// closeFrame returns the close code and reason for the session's clients
+// Only valid once closed is closed or its hubs have ended
+func (s *Session) closeFrame() (int, string) {
+	return closeFrame(s.endReason)
+}
+
+// closeSocket ends a client connection, with a close frame when it is a WebSocket
+func closeSocket(socket any, code int, reason string) {
+	switch socket := socket.(type) {
+	case interface{ CloseWithCode(int, string) error }:
+		socket.CloseWithCode(code, reason)
+	case io.Closer:
+		socket.Close()
+	}
+}
+
+// closeAll ends the connection of every attached client that can be closed, once
+// the messages queued for it are sent
+func (f *clientFanout) closeAll(code int, reason string) {
+	f.mu.Lock()
+	var queues []*outboundQueue
+	for _, client := range f.writers {
+		if client.queue != nil {
+			client.queue.close(code, reason)
+			queues = append(queues, client.queue)
+		} else {
+			closeSocket(client.writer, code, reason)
+		}
+	}
+	f.mu.Unlock()
+	for _, q := range queues {
+		q.flush()
+	}
+}
+
+// killResult is the response of POST /admin/sessions/{id}/kill
+type killResult struct {
+	Session string `json:"session"`
+	// Ending is false when the session was already ending on its own
+	Ending bool `json:"ending"`
+}
+
+// handleKillSession serves POST /admin/sessions/{id}/kill, ending a session and
+// closing its clients with closeSessionKilled
+func handleKillSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	actor := adminActor(r)
+	result := killResult{Session: session.ID}
+	select {
+	case session.ended <- sessionEnd{message: "Killed by " + actor, reason: killedReason}:
+		result.Ending = true
+	default:
+	}
+	event := auditEvent{
+		Time:    time.Now(),
+		Actor:   actor,
+		Action:  "kill",
+		Session: session.ID,
+		Owner:   session.Owner,
+		Remote:  clientAddr(r),
+	}
+	if err := audit(event); err != nil {
+		logError(session.ID, "writing audit log", err)
+	}
+	fmt.Printf("[Client %s] Killed by %s\n", session.ID, actor)
+	writeJSON(w, result)
+}
@@ -0,0 +1,233 @@
+// datas-cli is an interactive terminal client for the DATAS server
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"datasServer/client"
+)
+
+// ANSI colors per message type
+const (
+	colorReset   = "\033[0m"
+	colorProgram = "\033[32m" // green
+	colorLog     = "\033[90m" // gray
+	colorServer  = "\033[36m" // cyan
+	colorError   = "\033[31m" // red
+)
+
+const prompt = "datas> "
+
+// session is the connection the prompt sends commands to
+type session interface {
+	Send(line string) error
+	Close() error
+}
+
+// printer writes server messages without garbling the prompt
+type printer struct {
+	mu       sync.Mutex
+	color    bool
+	showLogs bool
+}
+
+// print shows one message and redraws the prompt
+func (p *printer) print(msgType, content string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if msgType == "log" && !p.showLogs {
+		return
+	}
+
+	label, color := msgType, colorServer
+	switch {
+	case msgType == "program":
+		color = colorProgram
+	case msgType == "log":
+		color = colorLog
+	case strings.HasPrefix(content, "ERROR") || msgType == "error" || msgType == "protocol_error":
+		color = colorError
+	}
+	if p.color {
+		fmt.Printf("\r%s[%s] %s%s\n%s", color, label, content, colorReset, prompt)
+	} else {
+		fmt.Printf("\r[%s] %s\n%s", label, content, prompt)
+	}
+}
+
+// message shows a decoded server message, structured ones as compact JSON
+func (p *printer) message(msgType, content string, raw []byte) {
+	switch msgType {
+	case "program", "log", "server":
+		p.print(msgType, content)
+	default:
+		p.print(msgType, strings.TrimSpace(string(raw)))
+	}
+}
+
+func main() {
+	wsURL := flag.String("ws", "ws://localhost:8080", "server WebSocket base URL")
+	tcpAddr := flag.String("tcp", "", "connect to the raw TCP port instead (e.g. localhost:9000)")
+	dataType := flag.String("type", "btree", "data structure type (WebSocket only)")
+	order := flag.Int("order", 0, "B-tree order (WebSocket only)")
+	engine := flag.String("engine", "", "engine to run the structure on: cpp or go (WebSocket only)")
+	load := flag.String("load", "", "saved tree to load (WebSocket only)")
+	join := flag.String("join", "", "join code of a running session (WebSocket only)")
+	noColor := flag.Bool("no-color", false, "disable colored output")
+	hideLogs := flag.Bool("hide-logs", false, "start with tree logs hidden")
+	flag.Parse()
+
+	out := &printer{color: !*noColor && useColor(), showLogs: !*hideLogs}
+
+	var conn session
+	var done <-chan struct{}
+	var err error
+	if *tcpAddr != "" {
+		conn, done, err = dialTCP(*tcpAddr, out)
+	} else {
+		opts := []client.Option{
+			client.WithType(*dataType),
+			client.OnMessage(func(msg client.Message) { out.message(msg.Type, msg.Content, msg.Raw) }),
+		}
+		if *order > 0 {
+			opts = append(opts, client.WithOrder(*order))
+		}
+		if *engine != "" {
+			opts = append(opts, client.WithQuery("engine", *engine))
+		}
+		if *load != "" {
+			opts = append(opts, client.WithQuery("load", *load))
+		}
+		if *join != "" {
+			opts = append(opts, client.WithQuery("join", *join))
+		}
+		var c *client.Client
+		c, err = client.Connect(context.Background(), *wsURL, opts...)
+		if err == nil {
+			conn, done = c, c.Done()
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	fmt.Println("Connected. Type :help for local commands, :quit to exit.")
+	lines := readPrompt()
+	for {
+		select {
+		case <-done:
+			fmt.Println("\nConnection closed by server")
+			return
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Println()
+				return
+			}
+			if !runLine(line, conn, out) {
+				return
+			}
+		}
+	}
+}
+
+// runLine handles one prompt line; returns false to quit
+func runLine(line string, conn session, out *printer) bool {
+	line = strings.TrimSpace(line)
+	switch line {
+	case "":
+	case ":quit", ":q":
+		return false
+	case ":help":
+		fmt.Println("  Any other line is sent to the server, e.g. insert 5, remove 5, print, save name")
+		fmt.Println("  :logs on|off  show or hide tree logs")
+		fmt.Println("  :quit         leave the session")
+	case ":logs on", ":logs off":
+		out.mu.Lock()
+		out.showLogs = line == ":logs on"
+		out.mu.Unlock()
+	default:
+		if err := conn.Send(line); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return false
+		}
+		// Give the first reply a moment so it lands before the next prompt
+		time.Sleep(50 * time.Millisecond)
+		return true
+	}
+	fmt.Print(prompt)
+	return true
+}
+
+// readPrompt reads stdin lines in the background, showing the prompt first
+func readPrompt() <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		fmt.Print(prompt)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// useColor reports whether stdout is a terminal that wants color
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// tcpSession is a session on the raw TCP port, which speaks newline separated lines
+type tcpSession struct {
+	conn net.Conn
+}
+
+func (t *tcpSession) Send(line string) error {
+	_, err := io.WriteString(t.conn, line+"\n")
+	return err
+}
+
+func (t *tcpSession) Close() error {
+	return t.conn.Close()
+}
+
+// dialTCP connects to the raw TCP port and prints its messages
+func dialTCP(addr string, out *printer) (session, <-chan struct{}, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var msg struct {
+				Type    string `json:"type"`
+				Content string `json:"message"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				out.print("raw", scanner.Text())
+				continue
+			}
+			out.message(msg.Type, msg.Content, scanner.Bytes())
+		}
+	}()
+	return &tcpSession{conn: conn}, done, nil
+}
@@ -0,0 +1,348 @@
+// integration runs the server end to end against the mock interface, without the C++ toolchain
+// It builds the server and cmd/mockinterface into a scratch directory, starts the server
+// on free ports and checks the message flow of a few scenarios over WebSocket and raw TCP
+//
+// Run it from the module root:
+//
+//	go run ./cmd/integration
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"datasServer/client"
+)
+
+// crashValue is the key the mock interface crashes on during the crash scenario
+const crashValue = 666
+
+// expectTimeout bounds how long a scenario waits for one expected message
+const expectTimeout = 5 * time.Second
+
+// harness is a running server under test
+type harness struct {
+	dir      string
+	httpAddr string
+	tcpAddr  string
+	server   *exec.Cmd
+	logPath  string
+}
+
+// scenario is one end-to-end check
+type scenario struct {
+	name string
+	run  func(h *harness) error
+}
+
+var scenarios = []scenario{
+	{"websocket session", testWebSocketSession},
+	{"raw tcp session", testTCPSession},
+	{"save and load", testSaveAndLoad},
+	{"process crash", testProcessCrash},
+	{"http error envelope", testHTTPErrorEnvelope},
+}
+
+func main() {
+	src := flag.String("src", ".", "module root to build the server from")
+	keep := flag.Bool("keep", false, "keep the scratch directory for inspection")
+	flag.Parse()
+
+	h, err := startHarness(*src)
+	if err != nil {
+		fmt.Println("Setup failed:", err)
+		os.Exit(1)
+	}
+	failed := 0
+	for _, s := range scenarios {
+		if err := s.run(h); err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", s.name, err)
+		} else {
+			fmt.Printf("PASS %s\n", s.name)
+		}
+	}
+	h.stop()
+
+	if failed > 0 {
+		fmt.Printf("%d of %d scenarios failed; server log: %s\n", failed, len(scenarios), h.logPath)
+		os.Exit(1)
+	}
+	if !*keep {
+		os.RemoveAll(h.dir)
+	} else {
+		fmt.Println("Scratch directory:", h.dir)
+	}
+}
+
+// startHarness builds the binaries and starts the server in a scratch directory
+func startHarness(src string) (*harness, error) {
+	dir, err := os.MkdirTemp("", "datas-integration-")
+	if err != nil {
+		return nil, err
+	}
+	h := &harness{dir: dir, logPath: filepath.Join(dir, "server.log")}
+
+	builds := [][]string{
+		{"build", "-o", filepath.Join(dir, "server"), "."},
+		{"build", "-o", filepath.Join(dir, "btreeInterface.exe"), "./cmd/mockinterface"},
+	}
+	for _, args := range builds {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = src
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	httpPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	tcpPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	h.httpAddr, h.tcpAddr = "127.0.0.1:"+httpPort, "127.0.0.1:"+tcpPort
+
+	cfg, _ := json.Marshal(map[string]any{
+		"http_port":     httpPort,
+		"tcp_port":      tcpPort,
+		"id_state_file": "",
+		"storage":       "file",
+		"storage_path":  "store",
+	})
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), cfg, 0644); err != nil {
+		return nil, err
+	}
+
+	logFile, err := os.Create(h.logPath)
+	if err != nil {
+		return nil, err
+	}
+	h.server = exec.Command("./server", "-config", "config.json")
+	h.server.Dir = dir
+	h.server.Env = append(os.Environ(), fmt.Sprintf("MOCK_CRASH_ON=%d", crashValue))
+	h.server.Stdout, h.server.Stderr = logFile, logFile
+	if err := h.server.Start(); err != nil {
+		return nil, err
+	}
+	if err := h.waitReady(); err != nil {
+		h.stop()
+		return nil, err
+	}
+	return h, nil
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer ln.Close()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	return port, err
+}
+
+// waitReady polls the readiness endpoint until the server answers
+func (h *harness) waitReady() error {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get("http://" + h.httpAddr + "/internal/ready")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return errors.New("server did not become ready")
+}
+
+// stop interrupts the server and waits for it to exit
+func (h *harness) stop() {
+	h.server.Process.Signal(os.Interrupt)
+	done := make(chan struct{})
+	go func() { h.server.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		h.server.Process.Kill()
+	}
+}
+
+// connect opens a WebSocket session whose messages arrive on the returned channel
+func (h *harness) connect(opts ...client.Option) (*client.Client, <-chan client.Message, error) {
+	messages := make(chan client.Message, 256)
+	opts = append(opts, client.OnMessage(func(msg client.Message) { messages <- msg }))
+	ctx, cancel := context.WithTimeout(context.Background(), expectTimeout)
+	defer cancel()
+	c, err := client.Connect(ctx, "ws://"+h.httpAddr, opts...)
+	return c, messages, err
+}
+
+// expect waits for a message of the given type whose content starts with prefix
+func expect(messages <-chan client.Message, msgType, prefix string) (client.Message, error) {
+	timeout := time.After(expectTimeout)
+	for {
+		select {
+		case msg := <-messages:
+			if msg.Type == msgType && strings.HasPrefix(msg.Content, prefix) {
+				return msg, nil
+			}
+		case <-timeout:
+			return client.Message{}, fmt.Errorf("no %s message starting with %q", msgType, prefix)
+		}
+	}
+}
+
+// expectAll waits for each expectation in order
+func expectAll(messages <-chan client.Message, msgType string, prefixes ...string) error {
+	for _, prefix := range prefixes {
+		if _, err := expect(messages, msgType, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func testWebSocketSession(h *harness) error {
+	c, messages, err := h.connect(client.WithType("btree"))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if c.SessionID() == "" || c.JoinCode() == "" {
+		return errors.New("session announced without id or join code")
+	}
+
+	for _, v := range []int{5, 3, 8} {
+		c.Insert(v)
+	}
+	c.Find(3)
+	c.Send("print")
+	if err := expectAll(messages, "program",
+		"INSERT_SUCCESS value=5 new_size=1",
+		"INSERT_SUCCESS value=3 new_size=2",
+		"INSERT_SUCCESS value=8 new_size=3",
+		"FIND_RESULT value=3 found=true",
+		"TREE_START", "[3, 5, 8]", "TREE_END",
+	); err != nil {
+		return err
+	}
+	_, err = expect(messages, "log", "[TREE_FIND] value=3")
+	return err
+}
+
+func testTCPSession(h *harness) error {
+	conn, err := net.DialTimeout("tcp", h.tcpAddr, expectTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	messages := make(chan client.Message, 256)
+	go func() {
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var msg client.Message
+			if json.Unmarshal(scanner.Bytes(), &msg) == nil {
+				messages <- msg
+			}
+		}
+	}()
+
+	if _, err := expect(messages, "server", "SESSION "); err != nil {
+		return err
+	}
+	io.WriteString(conn, "insert 42\nremove 7\n")
+	return expectAll(messages, "program", "INSERT_SUCCESS value=42 new_size=1", "REMOVE_NOT_FOUND value=7 size=1")
+}
+
+func testSaveAndLoad(h *harness) error {
+	c, messages, err := h.connect(client.WithType("btree"))
+	if err != nil {
+		return err
+	}
+	for _, v := range []int{10, 20} {
+		c.Insert(v)
+	}
+	c.Send("save integration_tree")
+	_, err = expect(messages, "server", "SAVE_SUCCESS name=integration_tree")
+	c.Close()
+	if err != nil {
+		return err
+	}
+
+	c, messages, err = h.connect(client.WithType("btree"), client.WithQuery("load", "integration_tree"))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if _, err := expect(messages, "server", "REPLAY_DONE ops=2"); err != nil {
+		return err
+	}
+	c.Send("print")
+	return expectAll(messages, "program", "TREE_START", "[10, 20]")
+}
+
+func testProcessCrash(h *harness) error {
+	c, messages, err := h.connect(client.WithType("btree"))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	c.Insert(1)
+	c.Insert(crashValue)
+
+	msg, err := expect(messages, "error", "interface process crashed")
+	if err != nil {
+		return err
+	}
+	var envelope struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(msg.Raw, &envelope); err != nil || envelope.Code != "process_crashed" {
+		return fmt.Errorf("unexpected crash envelope %s", msg.Raw)
+	}
+
+	select {
+	case <-c.Done():
+		return nil
+	case <-time.After(expectTimeout):
+		return errors.New("connection stayed open after the crash")
+	}
+}
+
+func testHTTPErrorEnvelope(h *harness) error {
+	resp, err := http.Get("http://" + h.httpAddr + "/session?type=heap")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var envelope struct {
+		Type string `json:"type"`
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusBadRequest || envelope.Type != "error" || envelope.Code != "invalid_request" {
+		return fmt.Errorf("got %s with %+v", resp.Status, envelope)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// mockinterface is a stand-in for btreeInterface.exe used by integration tests
+// It speaks the same command line, stdin and output protocol as the C++ interface,
+// but keeps the keys in a sorted slice and logs without pointers, so its output is deterministic
+//
+// Build it next to the server as btreeInterface.exe:
+//
+//	go build -o btreeInterface.exe ./cmd/mockinterface
+//
+// Set MOCK_CRASH_ON=<value> to make inserting that value exit like a segfault
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// crashExitCode is the status a shell reports for a process killed by SIGSEGV
+const crashExitCode = 139
+
+// mockTree mirrors the BTreeInterface state the server can observe
+type mockTree struct {
+	keys  []int
+	order int
+	logs  strings.Builder // operation log kept for the logs command
+
+	program io.Writer
+	treeLog io.Writer
+	crashOn *int
+}
+
+// logf writes one tree log line and keeps it for the logs command
+func (t *mockTree) logf(format string, args ...any) {
+	line := fmt.Sprintf(format, args...) + "\n"
+	t.logs.WriteString(line)
+	io.WriteString(t.treeLog, line)
+}
+
+// say writes one program output line
+func (t *mockTree) say(format string, args ...any) {
+	fmt.Fprintf(t.program, format+"\n", args...)
+}
+
+// search returns the index of value and whether it is present
+func (t *mockTree) search(value int) (int, bool) {
+	i := sort.SearchInts(t.keys, value)
+	return i, i < len(t.keys) && t.keys[i] == value
+}
+
+func (t *mockTree) init(order int) {
+	t.order = order
+	t.keys = nil
+	t.logs.Reset()
+	t.say("INIT_SUCCESS order=%d size=0", order)
+}
+
+func (t *mockTree) insert(value int) {
+	if t.crashOn != nil && *t.crashOn == value {
+		os.Exit(crashExitCode)
+	}
+	t.logf("[TREE_INSERT] value=%d", value)
+	i, _ := t.search(value)
+	t.keys = append(t.keys, 0)
+	copy(t.keys[i+1:], t.keys[i:])
+	t.keys[i] = value
+	t.logf("[TREE_INSERT_COMPLETE] value=%d index=%d", value, i)
+	t.say("INSERT_SUCCESS value=%d new_size=%d", value, len(t.keys))
+}
+
+func (t *mockTree) remove(value int) {
+	i, found := t.search(value)
+	if !found {
+		t.say("REMOVE_NOT_FOUND value=%d size=%d", value, len(t.keys))
+		return
+	}
+	t.logf("[TREE_REMOVE] value=%d", value)
+	t.keys = append(t.keys[:i], t.keys[i+1:]...)
+	t.logf("[TREE_REMOVE_COMPLETE] value=%d index=%d", value, i)
+	t.say("REMOVE_SUCCESS value=%d new_size=%d", value, len(t.keys))
+}
+
+func (t *mockTree) find(value int) {
+	i, found := t.search(value)
+	t.logf("[TREE_FIND] value=%d index=%d found=%t", value, i, found)
+	t.say("FIND_RESULT value=%d found=%t", value, found)
+}
+
+// print dumps the keys as one node, the way the C++ tree prints a single leaf
+func (t *mockTree) print() {
+	keys := make([]string, len(t.keys))
+	for i, key := range t.keys {
+		keys[i] = strconv.Itoa(key)
+	}
+	t.say("TREE_START")
+	t.say("[%s]", strings.Join(keys, ", "))
+	t.say("TREE_END")
+}
+
+// process runs one command line; returns false on quit
+func (t *mockTree) process(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return true
+	}
+	command := fields[0]
+	if command == "search" {
+		command = "find"
+	}
+	value, err := 0, strconv.ErrSyntax
+	if len(fields) > 1 {
+		value, err = strconv.Atoi(fields[1])
+	}
+
+	switch command {
+	case "quit", "exit", "q":
+		t.say("GOODBYE")
+		return false
+	case "help", "menu":
+	case "insert", "remove", "find":
+		if err != nil {
+			t.say("ERROR invalid_%s_syntax usage=%s_<value>", command, command)
+			return true
+		}
+		switch command {
+		case "insert":
+			t.insert(value)
+		case "remove":
+			t.remove(value)
+		default:
+			t.find(value)
+		}
+	case "print", "show":
+		t.print()
+	case "size":
+		t.say("SIZE %d", len(t.keys))
+	case "order":
+		t.say("ORDER %d", t.order)
+	case "status":
+		t.say("STATUS tree_size=%d order=%d root=initialized", len(t.keys), t.order)
+	case "logs":
+		if t.logs.Len() == 0 {
+			t.say("LOGS_EMPTY")
+		} else {
+			t.say("LOGS_START")
+			io.WriteString(t.program, t.logs.String())
+			t.say("LOGS_END")
+		}
+	case "clear_logs":
+		t.logs.Reset()
+		t.say("LOGS_CLEARED")
+	case "init":
+		if err != nil || value < 3 {
+			t.say("ERROR invalid_init_syntax usage=init_<order> order_must_be_>=3")
+			return true
+		}
+		t.init(value)
+	default:
+		t.say("ERROR unknown_command=%s use_help_for_commands", command)
+	}
+	return true
+}
+
+// openOutput resolves an output destination the way the C++ interface does
+func openOutput(name string) io.Writer {
+	switch name {
+	case "stdout", "-":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	case "null", "/dev/null":
+		return io.Discard
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: Could not open output file:", name)
+		return os.Stdout
+	}
+	return f
+}
+
+func main() {
+	order, batch := 4, false
+	programOut, treeLogOut := "stdout", "stdout"
+	args := os.Args[1:]
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--order" && i+1 < len(args):
+			i++
+			order, _ = strconv.Atoi(args[i])
+			if order < 3 {
+				fmt.Fprintln(os.Stderr, "Error: Order must be >= 3")
+				os.Exit(1)
+			}
+		case args[i] == "--batch":
+			batch = true
+		case args[i] == "--program-out" && i+1 < len(args):
+			i++
+			programOut = args[i]
+		case args[i] == "--tree-log-out" && i+1 < len(args):
+			i++
+			treeLogOut = args[i]
+		}
+	}
+
+	t := &mockTree{program: openOutput(programOut), treeLog: openOutput(treeLogOut)}
+	if raw := os.Getenv("MOCK_CRASH_ON"); raw != "" {
+		if value, err := strconv.Atoi(raw); err == nil {
+			t.crashOn = &value
+		}
+	}
+
+	t.init(order)
+	if batch {
+		t.say("READY order=%d", order)
+	} else {
+		t.say("BTree Interface Started (order=%d)", order)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if !t.process(scanner.Text()) {
+			break
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// maxConcurrentCompares bounds the comparisons replaying at once; each runs two processes,
+// which also take process slots under config.MaxProcesses
+const maxConcurrentCompares = 2
+
+// comparesRunning counts comparisons replaying
+var comparesRunning atomic.Int64
+
+// forkOutcome is one side of a fork comparison
+type forkOutcome struct {
+	Session   string   `json:"session"`
+	Divergent []string `json:"divergent_ops"` // ops applied after the shared prefix
+	Structure []string `json:"structure"`     // dump of the resulting structure
+	Unique    []string `json:"unique_lines"`  // structure lines the other side lacks
+}
+
+// ForkComparison reports how two sessions of a lineage ended up differing
+type ForkComparison struct {
+	Ancestor  string      `json:"ancestor"` // nearest session both descend from; empty if unrelated
+	SharedOps int         `json:"shared_ops"`
+	Identical bool        `json:"identical"` // both structures dump the same
+	A         forkOutcome `json:"a"`
+	B         forkOutcome `json:"b"`
+}
+
+// handleCompareForks serves GET /session/{id}/compare/{other}
+func handleCompareForks(w http.ResponseWriter, r *http.Request) {
+	var records [2]*SessionRecord
+	for i, ID := range []string{r.PathValue("id"), r.PathValue("other")} {
+		rec, err := currentRecord(ID)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		if !rec.visibleTo(r) {
+			httpError(w, ErrSessionPrivate)
+			return
+		}
+		if err := admitSession(rec.Engine); err != nil {
+			httpBusy(w)
+			return
+		}
+		records[i] = rec
+	}
+
+	if comparesRunning.Add(1) > maxConcurrentCompares {
+		comparesRunning.Add(-1)
+		httpBusy(w)
+		return
+	}
+	defer comparesRunning.Add(-1)
+
+	comparison, err := compareForks(records[0], records[1])
+	if errors.Is(err, ErrServerBusy) {
+		httpBusy(w)
+		return
+	}
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, comparison)
+}
+
+// compareForks replays both sessions in throwaway processes and diffs their end states
+func compareForks(recA, recB *SessionRecord) (*ForkComparison, error) {
+	if recA.Type != recB.Type || recA.Flags != recB.Flags {
+		return nil, &ValidationError{"Sessions have different types or flags"}
+	}
+
+	ancestor, err := commonAncestor(recA, recB)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := 0
+	for shared < len(recA.Ops) && shared < len(recB.Ops) && recA.Ops[shared] == recB.Ops[shared] {
+		shared++
+	}
+
+	structureA, structureB, err := dumpBoth(recA, recB)
+	if err != nil {
+		return nil, err
+	}
+	uniqueA, uniqueB := lineDifference(structureA, structureB), lineDifference(structureB, structureA)
+
+	return &ForkComparison{
+		Ancestor:  ancestor,
+		SharedOps: shared,
+		Identical: len(uniqueA) == 0 && len(uniqueB) == 0 && len(structureA) == len(structureB),
+		A: forkOutcome{
+			Session:   recA.ID,
+			Divergent: append([]string{}, recA.Ops[shared:]...),
+			Structure: structureA,
+			Unique:    uniqueA,
+		},
+		B: forkOutcome{
+			Session:   recB.ID,
+			Divergent: append([]string{}, recB.Ops[shared:]...),
+			Structure: structureB,
+			Unique:    uniqueB,
+		},
+	}, nil
+}
+
+// currentRecord loads a session record, using the live journal when the session is running
+func currentRecord(ID string) (*SessionRecord, error) {
+	rec, err := store.loadSession(ID)
+	if err != nil {
+		return nil, err
+	}
+	if session, ok := lookupSession(ID); ok {
+		rec.Ops = session.journal.applied()
+	}
+	return rec, nil
+}
+
+// lineage returns a session ID followed by the IDs of its parents, nearest first
+func lineage(rec *SessionRecord) ([]string, error) {
+	chain := []string{rec.ID}
+	seen := map[string]bool{rec.ID: true}
+	for parent := rec.Parent; parent != "" && !seen[parent]; {
+		chain = append(chain, parent)
+		seen[parent] = true
+		next, err := store.loadSession(parent)
+		if errors.Is(err, ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent = next.Parent
+	}
+	return chain, nil
+}
+
+// commonAncestor returns the nearest session both records descend from (or are)
+func commonAncestor(a, b *SessionRecord) (string, error) {
+	chainA, err := lineage(a)
+	if err != nil {
+		return "", err
+	}
+	chainB, err := lineage(b)
+	if err != nil {
+		return "", err
+	}
+	inB := make(map[string]bool, len(chainB))
+	for _, ID := range chainB {
+		inB[ID] = true
+	}
+	for _, ID := range chainA {
+		if inB[ID] {
+			return ID, nil
+		}
+	}
+	return "", nil
+}
+
+// dumpBoth replays both records at once; the first failure cancels the other
+// replay, and both processes have exited when it returns
+func dumpBoth(recA, recB *SessionRecord) ([]string, []string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var structures [2][]string
+	var first error // the cause; the cancelled side fails after it
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i, rec := range []*SessionRecord{recA, recB} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			structure, err := dumpStructure(ctx, rec.Type, rec.Engine, rec.Flags, rec.Ops)
+			if err != nil {
+				once.Do(func() { first = err })
+				cancel()
+				return
+			}
+			structures[i] = structure
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		return nil, nil, first
+	}
+	return structures[0], structures[1], nil
+}
+
+// dumpStructure replays ops in a throwaway process and returns its structure dump
+func dumpStructure(ctx context.Context, ds, engine, flags string, ops []string) ([]string, error) {
+	spec, ok := snapshotSpecs[ds]
+	if !ok {
+		return nil, ErrSnapshotUnsupported
+	}
+	program, _, err := runHeadless(ctx, ds, engine, flags, append(append([]string{}, ops...), spec.command))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	inside := false
+	for _, line := range program {
+		switch {
+		case line == spec.start:
+			inside = true
+		case line == spec.end:
+			inside = false
+		case inside:
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// lineDifference returns the lines of a not matched by a line of b, keeping their order
+func lineDifference(a, b []string) []string {
+	remaining := make(map[string]int, len(b))
+	for _, line := range b {
+		remaining[line]++
+	}
+	diff := []string{}
+	for _, line := range a {
+		if remaining[line] > 0 {
+			remaining[line]--
+			continue
+		}
+		diff = append(diff, line)
+	}
+	return diff
+}
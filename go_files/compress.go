@@ -0,0 +1,158 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressMinSize is the smallest body worth compressing; smaller ones are sent as is
+const compressMinSize = 1024
+
+// compressibleTypes are the content types compressed; anything else passes through
+var compressibleTypes = []string{"application/json", "application/yaml", "text/"}
+
+// gzipWriters reuses gzip writers across responses
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// negotiateEncoding picks the response encoding from an Accept-Encoding header
+// Only gzip is offered; "" sends the body unencoded
+func negotiateEncoding(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		return "gzip"
+	}
+	return ""
+}
+
+// compressWriter holds a response back until it knows whether compressing it pays off:
+// once compressMinSize bytes are written or the handler flushes, as streams do
+type compressWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer // nil when the body is sent as is
+}
+
+// WriteHeader records the status until the encoding is decided
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+// Write implements io.Writer
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.decided {
+		if c.gz != nil {
+			return c.gz.Write(p)
+		}
+		return c.ResponseWriter.Write(p)
+	}
+	c.buf = append(c.buf, p...)
+	if len(c.buf) >= compressMinSize {
+		if err := c.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush implements http.Flusher, sending what the handler wrote so far
+func (c *compressWriter) Flush() {
+	if !c.decided {
+		c.decide(true)
+	}
+	if c.gz != nil {
+		c.gz.Flush()
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// decide sends the headers, compressed when worth it and the response allows it,
+// and then the body held back so far
+func (c *compressWriter) decide(worth bool) error {
+	c.decided = true
+	header := c.ResponseWriter.Header()
+	status := c.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if worth && status == http.StatusOK && header.Get("Content-Encoding") == "" && compressibleType(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// The encoded body is another representation, no longer byte-identical
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		c.gz = gzipWriters.Get().(*gzip.Writer)
+		c.gz.Reset(c.ResponseWriter)
+	}
+	c.ResponseWriter.WriteHeader(status)
+	held := c.buf
+	c.buf = nil
+	if len(held) == 0 {
+		return nil
+	}
+	var err error
+	if c.gz != nil {
+		_, err = c.gz.Write(held)
+	} else {
+		_, err = c.ResponseWriter.Write(held)
+	}
+	return err
+}
+
+// close sends a body that stayed small and ends the compressed stream
+func (c *compressWriter) close() {
+	if !c.decided {
+		if c.status == 0 && len(c.buf) == 0 {
+			return // nothing written: let net/http answer as usual
+		}
+		c.decide(false)
+	}
+	if c.gz != nil {
+		c.gz.Close()
+		gzipWriters.Put(c.gz)
+		c.gz = nil
+	}
+}
+
+// compressibleType reports whether a content type is worth compressing
+func compressibleType(contentType string) bool {
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressed gzips the responses of a handler for clients accepting it
+// Meant for dumps, exports and streams; WebSocket upgrades must not go through it
+func compressed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || negotiateEncoding(r.Header.Get("Accept-Encoding")) == "" {
+			next(w, r)
+			return
+		}
+		writer := &compressWriter{ResponseWriter: w}
+		defer writer.close()
+		next(writer, r)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Config holds server settings loaded from an optional JSON file
+type Config struct {
+	// HTTPPort and TCPPort are where the WebSocket/HTTP and raw TCP servers listen
+	HTTPPort string `json:"http_port"`
+	TCPPort  string `json:"tcp_port"`
+	// GRPCPort serves the gRPC API of proto/datas.proto; empty disables it
+	GRPCPort string `json:"grpc_port"`
+	// UnixSocket serves the raw TCP protocol on this socket path too; empty disables it
+	UnixSocket string `json:"unix_socket"`
+	// RawTCPFraming lets raw TCP clients switch to length-prefixed frames by opening with
+	// the framing preface; each connection then waits up to 200ms for it before being served
+	RawTCPFraming bool `json:"raw_tcp_framing"`
+	// WebTransportPort serves the experimental WebTransport endpoint on this UDP port; it needs
+	// TLSCertFile, as QUIC is always encrypted. Empty disables it
+	WebTransportPort string `json:"webtransport_port"`
+
+	// AdminToken protects the /admin endpoints and other admin-only routes; empty disables them
+	AdminToken string `json:"admin_token"`
+
+	// IDStrategy selects session ID generation: "ulid" (default) or "sequential" (debug only:
+	// spectate links, event streams and exports are keyed by ID, so sequential IDs are guessable)
+	IDStrategy string `json:"id_strategy"`
+	// IDStateFile persists the sequential counter across restarts; empty disables it
+	IDStateFile string `json:"id_state_file"`
+	// IDNamespace prefixes every ID this server generates, e.g. with the name of the tenant it
+	// serves; one namespace per server, of letters, digits, '_' and '-' as IDs name FIFO paths
+	IDNamespace string `json:"id_namespace"`
+
+	// InviteSecret signs session invite and demo tokens; empty uses a random key per run
+	// and disables demo links, which are meant to outlive a restart
+	InviteSecret string `json:"invite_secret"`
+
+	// TLSCertFile and TLSKeyFile serve HTTPS, with HTTP/2 negotiated through ALPN, and TLS
+	// on the raw TCP port
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// TCPClientCAFile makes raw TCP clients present a certificate signed by one of its PEM CAs;
+	// the TCP port only speaks TLS when TLSCertFile is set
+	TCPClientCAFile string `json:"tcp_client_ca_file"`
+	// H2C enables cleartext HTTP/2 for clients listed in TrustedProxies
+	H2C bool `json:"h2c"`
+	// TrustedProxies lists IP addresses or CIDR ranges of reverse proxies in front of the server;
+	// only their X-Forwarded-For and X-Real-IP headers are believed
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ProxyProtocol expects a PROXY v1 or v2 header on raw TCP connections from TrustedProxies
+	// and logs the client address it carries
+	ProxyProtocol bool `json:"proxy_protocol"`
+	// AllowedOrigins lists the browser origins allowed to open WebSockets and, with CORS, to call
+	// the HTTP endpoints: exact origins, wildcard patterns ("https://*.example.com",
+	// "http://localhost:*") or "*" to allow any. Pages served from the server's own host and
+	// clients sending no Origin are always allowed; the default, empty, allows nothing else
+	AllowedOrigins []string `json:"allowed_origins"`
+	// CORS sends CORS headers to AllowedOrigins and answers their preflight requests
+	CORS bool `json:"cors"`
+
+	// OAuth holds the client credentials of each enabled login provider ("github", "google")
+	OAuth map[string]OAuthClient `json:"oauth"`
+	// PublicURL is the externally visible base URL, used for OAuth2 redirects
+	PublicURL string `json:"public_url"`
+
+	// Storage selects where records are persisted: "bolt" (a single database file) or "file" (JSON files)
+	Storage string `json:"storage"`
+	// StoragePath is the database file for bolt, or the directory for file
+	StoragePath string `json:"storage_path"`
+	// AuditLogPath is the JSON lines file recording admin actions on users' sessions, e.g. clones
+	AuditLogPath string `json:"audit_log_path"`
+
+	// BandwidthCapBytes limits what one session may send to its clients; 0 is unlimited
+	// Past 80% of the cap tree logs are sampled, at the cap they are dropped
+	BandwidthCapBytes int64 `json:"bandwidth_cap_bytes"`
+	// BandwidthSampleRate keeps one in this many tree log lines while sampling
+	BandwidthSampleRate int `json:"bandwidth_sample_rate"`
+
+	// DataStructures registers the structures clients can open with ?type=; replaces the defaults when set
+	DataStructures []DataStructure `json:"data_structures"`
+	// Engine runs sessions that do not pick one with ?engine=: "cpp" (the C++ executables)
+	// or "go" (in-process ports, for hosts where the executables are not built)
+	Engine string `json:"engine"`
+
+	// MaxValueBytes limits the value an insert may attach to its key
+	MaxValueBytes int `json:"max_value_bytes"`
+
+	// MaxProcesses caps the C++ interface processes running at once; past it new sessions
+	// are refused with 429 (HTTP) or a busy message (TCP). 0 is unlimited
+	MaxProcesses int `json:"max_processes"`
+	// BusyRetryAfterSeconds is the Retry-After sent to clients refused for MaxProcesses
+	BusyRetryAfterSeconds int `json:"busy_retry_after_seconds"`
+
+	// ClientQueueMessages bounds the messages waiting to be written to one client connection;
+	// 0 writes to clients directly, so a slow one blocks the session output
+	ClientQueueMessages int `json:"client_queue_messages"`
+	// WriteTimeoutSeconds is the deadline of one write to a client; 0 waits forever
+	WriteTimeoutSeconds int `json:"write_timeout_seconds"`
+	// SlowClientPolicy handles a client whose queue is full: "drop_logs" skips tree log lines
+	// and sends a gap notice, disconnecting only when a program message does not fit;
+	// "disconnect" disconnects it right away
+	SlowClientPolicy string `json:"slow_client_policy"`
+
+	// WSReadBufferSize and WSWriteBufferSize size each WebSocket connection's I/O buffers in bytes;
+	// a write buffer as large as a typical dump sends it in one frame
+	WSReadBufferSize  int `json:"ws_read_buffer_size"`
+	WSWriteBufferSize int `json:"ws_write_buffer_size"`
+	// WSHandshakeTimeoutSeconds bounds a WebSocket upgrade; 0 waits forever
+	WSHandshakeTimeoutSeconds int `json:"ws_handshake_timeout_seconds"`
+	// WSMaxMessageBytes closes a WebSocket whose client sends a larger message; 0 means no limit
+	WSMaxMessageBytes int64 `json:"ws_max_message_bytes"`
+
+	// LogCoalesceMillis batches each session's tree log lines over this interval into one
+	// "logs" message; 0 sends every line on its own unless the client asks with ?coalesce_ms=
+	LogCoalesceMillis int `json:"log_coalesce_ms"`
+
+	// LogVerbosity is the tree log verbosity sessions start with: "quiet", "normal" or
+	// "trace" (every line); clients change it with the verbosity control message
+	LogVerbosity string `json:"log_verbosity"`
+
+	// DeltaKeyframeInterval is how many delta messages a deltas=1 session sends between
+	// keyframes carrying the whole structure
+	DeltaKeyframeInterval int `json:"delta_keyframe_interval"`
+
+	// MaxProtocolViolations disconnects a client after this many rejected messages; 0 never disconnects
+	MaxProtocolViolations int `json:"max_protocol_violations"`
+
+	// IdleHibernateSeconds stops the process of a session whose clients were silent this long;
+	// the next command restores it from the journal. 0 keeps processes running
+	IdleHibernateSeconds int `json:"idle_hibernate_seconds"`
+	// HibernationStorageBytes caps the snapshots of hibernated sessions; past it the least
+	// recently used are ended, their records kept. 0 is unlimited
+	HibernationStorageBytes int64 `json:"hibernation_storage_bytes"`
+	// MaxConcurrentRestores bounds hibernated sessions restoring at once; 0 is unlimited
+	MaxConcurrentRestores int `json:"max_concurrent_restores"`
+
+	// RelayGraceSeconds is how long a relay=1 session waits for its client to resume before ending
+	RelayGraceSeconds int `json:"relay_grace_seconds"`
+	// RelayBacklogMessages is how many sent messages a relay keeps to replay on resume
+	RelayBacklogMessages int `json:"relay_backlog_messages"`
+
+	// DrainTimeoutSeconds is how long /internal/prestop waits for sessions to end by default,
+	// and how long a stop signal lets them run before they are ended
+	DrainTimeoutSeconds int `json:"drain_timeout_seconds"`
+	// ShutdownWebhookURL receives the shutdown report as a JSON POST; empty only logs it
+	ShutdownWebhookURL string `json:"shutdown_webhook_url"`
+	// Webhooks receive JSON POSTs on session start, session end and process crashes
+	Webhooks []WebhookConfig `json:"webhooks"`
+	// MQTT bridges session streams to a broker; disabled unless a broker is set
+	MQTT MQTTConfig `json:"mqtt"`
+	// Coordinator enables Lease-based leader election between replicas in Kubernetes
+	Coordinator CoordinatorConfig `json:"coordinator"`
+
+	// TemplateHosts lists the hosts template bundles may be imported from; empty disables URL imports
+	TemplateHosts []string `json:"template_hosts"`
+
+	// UpdateManifestURL is the release manifest POST /admin/interfaces/update installs binaries from;
+	// UpdatePublicKey is the base64 Ed25519 key its signature (<url>.sig) must verify with.
+	// The updater is disabled unless both are set
+	UpdateManifestURL string `json:"update_manifest_url"`
+	UpdatePublicKey   string `json:"update_public_key"`
+	// UpdateReleasePath records the last promoted release; manifests not newer than it are refused
+	UpdateReleasePath string `json:"update_release_path"`
+	// CanaryPercent routes this share of new sessions to updated binaries before they replace
+	// the stable ones; 0 replaces them at once
+	CanaryPercent int `json:"canary_percent"`
+	// CanaryMinSessions is how many canary sessions must end before the canary is promoted,
+	// or rolled back when its crash and invariant violation rate is worse than the stable one
+	CanaryMinSessions int `json:"canary_min_sessions"`
+
+	// OutputProgramWeight and OutputLogWeight share a session's writer between its FIFOs:
+	// while both have lines waiting, this many program lines are sent per turn for each
+	// turn of log lines, so replies stay prompt when the host is saturated
+	OutputProgramWeight int `json:"output_program_weight"`
+	OutputLogWeight     int `json:"output_log_weight"`
+
+	// WorkloadDir holds the YAML workload files gen and /api/v1/workloads read by name
+	WorkloadDir string `json:"workload_dir"`
+}
+
+// OAuthClient is an application registered with an OAuth2 provider
+type OAuthClient struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// CoordinatorConfig selects the Kubernetes Lease used to elect one active coordinator
+type CoordinatorConfig struct {
+	Enabled   bool   `json:"enabled"`
+	LeaseName string `json:"lease_name"`
+	Identity  string `json:"identity"` // defaults to the pod hostname
+}
+
+// WebhookConfig is an endpoint notified of session lifecycle events
+type WebhookConfig struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // session.started, session.ended, process.crashed; empty sends all
+	Secret string   `json:"secret"` // signs each body as X-Datas-Signature: sha256=<hex HMAC>
+}
+
+// MQTTConfig selects the broker session messages are published to
+type MQTTConfig struct {
+	Broker   string `json:"broker"`    // tcp://host:1883, or ssl://host:8883 for TLS
+	ClientID string `json:"client_id"` // defaults to datas-<hostname>
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// TopicPrefix starts every topic: {prefix}/{sessionID}/{type}
+	TopicPrefix string `json:"topic_prefix"`
+	// Commands subscribes to {prefix}/{sessionID}/command and runs the lines published there;
+	// anyone the broker lets publish to it can then drive sessions
+	Commands bool `json:"commands"`
+}
+
+// config is the active server configuration
+var config = defaultConfig()
+
+// defaultConfig returns the settings used when no config file is given
+func defaultConfig() Config {
+	return Config{
+		HTTPPort:                  "8080",
+		TCPPort:                   "9000",
+		IDStrategy:                "ulid",
+		IDStateFile:               "id_state",
+		PublicURL:                 "http://localhost:8080",
+		Storage:                   "bolt",
+		Engine:                    engineCpp,
+		DataStructures:            defaultDataStructures(),
+		MaxValueBytes:             1024,
+		MaxProtocolViolations:     10,
+		BusyRetryAfterSeconds:     5,
+		ClientQueueMessages:       1024,
+		WriteTimeoutSeconds:       10,
+		WSReadBufferSize:          4096,
+		WSWriteBufferSize:         32 << 10,
+		WSHandshakeTimeoutSeconds: 10,
+		WSMaxMessageBytes:         64 << 10,
+		SlowClientPolicy:          slowClientDropLogs,
+		LogVerbosity:              verbosityTrace.String(),
+		DeltaKeyframeInterval:     50,
+		BandwidthSampleRate:       10,
+		DrainTimeoutSeconds:       30,
+		IdleHibernateSeconds:      600,
+		HibernationStorageBytes:   64 << 20,
+		MaxConcurrentRestores:     4,
+		CanaryMinSessions:         20,
+		WorkloadDir:               "workloads",
+		MQTT:                      MQTTConfig{TopicPrefix: "datas"},
+		OutputProgramWeight:       8,
+		OutputLogWeight:           1,
+		RelayGraceSeconds:         60,
+		RelayBacklogMessages:      1024,
+		Coordinator:               CoordinatorConfig{LeaseName: "datas-coordinator"},
+		StoragePath:               "datas.db",
+		AuditLogPath:              "audit.jsonl",
+		UpdateReleasePath:         "interfaces.release",
+	}
+}
+
+// loadConfig reads a JSON config file over the defaults
+func loadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cfg := defaultConfig()
+	// Decoding into the default entries would merge their fields into the configured ones
+	cfg.DataStructures = nil
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+	if len(cfg.DataStructures) == 0 {
+		cfg.DataStructures = defaultDataStructures()
+	}
+	if err := validateRegistry(cfg.DataStructures); err != nil {
+		return err
+	}
+	if _, err := parseVerbosity(cfg.LogVerbosity); err != nil {
+		return fmt.Errorf("log_verbosity: %w", err)
+	}
+	if cfg.SlowClientPolicy != slowClientDropLogs && cfg.SlowClientPolicy != slowClientDisconnect {
+		return fmt.Errorf("slow_client_policy must be %q or %q", slowClientDropLogs, slowClientDisconnect)
+	}
+	if cfg.WSReadBufferSize < 0 || cfg.WSWriteBufferSize < 0 || cfg.WSHandshakeTimeoutSeconds < 0 || cfg.WSMaxMessageBytes < 0 {
+		return fmt.Errorf("ws_read_buffer_size, ws_write_buffer_size, ws_handshake_timeout_seconds and ws_max_message_bytes cannot be negative")
+	}
+	if cfg.WebTransportPort != "" && cfg.TLSCertFile == "" {
+		return fmt.Errorf("webtransport_port requires tls_cert_file")
+	}
+	if cfg.TCPClientCAFile != "" && cfg.TLSCertFile == "" {
+		return fmt.Errorf("tcp_client_ca_file requires tls_cert_file")
+	}
+	if err := validateOrigins(cfg.AllowedOrigins); err != nil {
+		return err
+	}
+	if err := validateWebhooks(cfg.Webhooks); err != nil {
+		return err
+	}
+	if cfg.MQTT.Broker != "" {
+		if err := validateMQTTBroker(cfg.MQTT.Broker); err != nil {
+			return err
+		}
+		if cfg.MQTT.TopicPrefix == "" || strings.ContainsAny(cfg.MQTT.TopicPrefix, "+#") {
+			return fmt.Errorf("mqtt.topic_prefix must be set and cannot contain wildcards")
+		}
+	}
+	config = cfg
+	return nil
+}
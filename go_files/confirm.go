@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// confirmTimeout is how long a confirmation nonce stays valid
+const confirmTimeout = 30 * time.Second
+
+// ErrConfirmationInvalid is returned when a confirm op does not echo the pending nonce
+var ErrConfirmationInvalid = errors.New("invalid or expired confirmation nonce")
+
+// rangeDeletable lists the models whose interfaces remove single keys; keyed by model
+var rangeDeletable = map[string]bool{
+	"btree":      true,
+	"avltree":    true,
+	"rbtree":     true,
+	"trie":       true,
+	"skiplist":   true,
+	"hashtable":  true,
+	"linkedlist": true,
+	"splaytree":  true,
+	"treap":      true,
+	"bplustree":  true,
+}
+
+// pendingConfirmation is a destructive op waiting for the client to echo its nonce
+type pendingConfirmation struct {
+	op      string
+	nonce   string
+	expires time.Time
+	lines   []string // commands sent once confirmed
+}
+
+// confirmRequired asks the client to repeat a destructive op's nonce in a confirm op
+type confirmRequired struct {
+	Type      string `json:"type"` // always "confirm_required"
+	Op        string `json:"op"`
+	Nonce     string `json:"nonce"`
+	Keys      int    `json:"keys,omitempty"` // keys the op would remove, when known
+	ExpiresIn int    `json:"expires_in"`     // seconds
+}
+
+// requestConfirmation replaces any pending destructive op with a new one and sends its nonce
+func (s *Session) requestConfirmation(op string, lines []string, keys int) error {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	pending := &pendingConfirmation{
+		op:      op,
+		nonce:   hex.EncodeToString(buf),
+		expires: time.Now().Add(confirmTimeout),
+		lines:   lines,
+	}
+
+	s.confirmMu.Lock()
+	s.confirm = pending
+	s.confirmMu.Unlock()
+
+	return s.send(confirmRequired{
+		Type:      "confirm_required",
+		Op:        op,
+		Nonce:     pending.nonce,
+		Keys:      keys,
+		ExpiresIn: int(confirmTimeout / time.Second),
+	})
+}
+
+// takeConfirmation returns and clears the pending op if nonce matches it
+// A wrong nonce also clears it, so a stale or guessed nonce cannot be retried
+func (s *Session) takeConfirmation(nonce string) (*pendingConfirmation, error) {
+	s.confirmMu.Lock()
+	defer s.confirmMu.Unlock()
+	pending := s.confirm
+	s.confirm = nil
+	if pending == nil || nonce == "" || pending.nonce != nonce || time.Now().After(pending.expires) {
+		return nil, ErrConfirmationInvalid
+	}
+	return pending, nil
+}
+
+// opClear asks for confirmation before resetting the structure to empty
+func opClear(session *Session, msg clientMessage) error {
+	return session.requestConfirmation("clear", []string{"init"}, 0)
+}
+
+// opDeleteRange asks for confirmation before removing every key between from and to
+// The keys are listed now, so the confirm removes exactly what the client was told about
+func opDeleteRange(session *Session, msg clientMessage) error {
+	registered, ok := lookupDataStructure(session.DataType)
+	if !ok {
+		return ErrSnapshotUnsupported
+	}
+	spec, ok := keyDumps[registered.model()]
+	if !ok || !rangeDeletable[registered.model()] {
+		return &ValidationError{"delete_range is not supported for " + session.DataType}
+	}
+	keyType := registered.keyType()
+	from, to, err := rangeBounds(keyType, msg)
+	if err != nil {
+		return err
+	}
+
+	keys, err := session.keysInRange(spec, keyType, from, to)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return session.reply("DELETE_RANGE_DONE removed=0")
+	}
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		normalized, _ := normalizeKey(keyType, key.text)
+		lines[i] = "remove " + normalized
+	}
+	return session.requestConfirmation("delete_range", lines, len(keys))
+}
+
+// opConfirm runs the pending destructive op if the client echoed its nonce
+func opConfirm(session *Session, msg clientMessage) error {
+	pending, err := session.takeConfirmation(msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range pending.lines {
+		session.record(line)
+		if err := session.sendCommand(line); err != nil {
+			return err
+		}
+	}
+	switch pending.op {
+	case "delete_range":
+		return session.reply(fmt.Sprintf("DELETE_RANGE_DONE removed=%d", len(pending.lines)))
+	default:
+		return session.reply("CLEAR_DONE")
+	}
+}
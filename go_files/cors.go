@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	// corsAllowMethods and corsAllowHeaders are what preflighted browser requests may use
+	corsAllowMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders = "Authorization, Content-Type, If-None-Match, X-Join-Code"
+	// corsExposeHeaders are the response headers scripts on other origins may read
+	corsExposeHeaders = "Location, Retry-After, ETag, Content-Disposition"
+	// corsMaxAge is how long, in seconds, browsers may cache a preflight answer
+	corsMaxAge = "600"
+)
+
+// validateOrigins checks the allowed origin patterns
+func validateOrigins(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("allowed_origins: invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// originAllowed reports whether a browser origin matches config.AllowedOrigins: "*" matches
+// any origin, other entries are exact origins or wildcard patterns such as
+// "https://*.example.com" and "http://localhost:*"
+func originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range config.AllowedOrigins {
+		if pattern == "*" {
+			return true
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin is the WebSocket upgraders' CheckOrigin: clients that send no Origin are not
+// browsers and are accepted, as are browsers on the server's own host or an allowed origin
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || originAllowed(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// withCORS answers preflight requests and adds CORS headers to the responses of requests
+// from allowed origins; it does nothing unless config.CORS is set
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !config.CORS || origin == "" || !originAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		header := w.Header()
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			header.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			header.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		header.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// crashDir holds one JSON report per crashed session
+const crashDir = "crashes"
+
+// CrashReport records a C++ process failure and the script that led to it
+type CrashReport struct {
+	Session      string    `json:"session"`
+	Type         string    `json:"type"`
+	Flags        string    `json:"flags"`
+	Engine       string    `json:"engine,omitempty"`
+	Error        string    `json:"error"`
+	Time         time.Time `json:"time"`
+	Script       []string  `json:"script"`
+	Reproducible *bool     `json:"reproducible,omitempty"` // nil until the minimizer ran
+	Minimized    []string  `json:"minimized,omitempty"`
+	MinimizeRuns int       `json:"minimize_runs,omitempty"`
+}
+
+// path returns where the report is stored
+func (r *CrashReport) path() string {
+	return filepath.Join(crashDir, r.Session+"_"+r.Type+".json")
+}
+
+// save writes the report to the crash directory
+func (r *CrashReport) save() error {
+	if err := os.MkdirAll(crashDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path(), data, 0644)
+}
+
+// reportCrash writes a crash report for the session and starts minimizing its script
+func reportCrash(session *Session, exitErr error) {
+	metrics.crashes.Add(1)
+	report := &CrashReport{
+		Session: session.ID,
+		Type:    session.DataType,
+		Flags:   session.Flags,
+		Engine:  session.Engine,
+		Error:   exitErr.Error(),
+		Time:    time.Now(),
+		Script:  session.recordedScript(),
+	}
+	event := newWebhookEvent(webhookProcessCrashed, session)
+	event.Crash = report
+	event.publish()
+	if err := report.save(); err != nil {
+		fmt.Printf("[Client %s] Error writing crash report: %v\n", session.ID, err)
+		return
+	}
+	fmt.Printf("[Client %s] Crash report written to %s\n", session.ID, report.path())
+
+	go minimizeCrash(report)
+}
+
+// minimizeCrash reduces the report script to a minimal crashing one and attaches it
+func minimizeCrash(report *CrashReport) {
+	runs := 0
+	crashes := func(script []string) bool {
+		runs++
+		return scriptCrashes(report.Type, report.Engine, report.Flags, script)
+	}
+
+	reproducible := crashes(report.Script)
+	report.Reproducible = &reproducible
+	if reproducible {
+		report.Minimized = minimizeScript(report.Script, crashes)
+	}
+	report.MinimizeRuns = runs
+
+	if err := report.save(); err != nil {
+		fmt.Printf("[Client %s] Error updating crash report: %v\n", report.Session, err)
+		return
+	}
+	if reproducible {
+		fmt.Printf("[Client %s] Crash minimized from %d to %d commands (%d runs)\n",
+			report.Session, len(report.Script), len(report.Minimized), runs)
+	} else {
+		fmt.Printf("[Client %s] Crash could not be reproduced from its script\n", report.Session)
+	}
+}
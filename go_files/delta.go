@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// deltaStream turns the structure dumps taken after each mutating command into
+// delta messages, so clients redraw only what changed
+type deltaStream struct {
+	mu   sync.Mutex
+	seq  int
+	last []string // lines of the last dump sent, whole or as a delta
+	// sinceKeyframe counts deltas since the last keyframe, which is resent every
+	// config.DeltaKeyframeInterval deltas so a client that missed one recovers
+	sinceKeyframe int
+}
+
+// keyframeMessage carries a whole dump
+type keyframeMessage struct {
+	Type  string   `json:"type"` // always "keyframe"
+	Seq   int      `json:"seq"`
+	Lines []string `json:"lines"`
+}
+
+// deltaMessage replaces Removed lines of the dump with sequence Base, starting at
+// line At, with Added
+type deltaMessage struct {
+	Type    string   `json:"type"` // always "delta"
+	Seq     int      `json:"seq"`
+	Base    int      `json:"base"`
+	At      int      `json:"at"`
+	Removed int      `json:"removed"`
+	Added   []string `json:"added"`
+}
+
+// diffLines finds the one region where two dumps differ, after their common prefix
+// and suffix; an operation changes the dump of a structure around one place
+func diffLines(old, current []string) (at, removed int, added []string) {
+	for at < len(old) && at < len(current) && old[at] == current[at] {
+		at++
+	}
+	suffix := 0
+	for suffix < len(old)-at && suffix < len(current)-at && old[len(old)-1-suffix] == current[len(current)-1-suffix] {
+		suffix++
+	}
+	return at, len(old) - at - suffix, current[at : len(current)-suffix]
+}
+
+// next returns the message describing a new dump, or nil when nothing changed
+func (d *deltaStream) next(lines []string) any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.last != nil && slices.Equal(d.last, lines) {
+		return nil
+	}
+	d.seq++
+	base := d.last
+	d.last = lines
+	if base == nil || d.sinceKeyframe >= max(config.DeltaKeyframeInterval, 1) {
+		d.sinceKeyframe = 0
+		return keyframeMessage{Type: "keyframe", Seq: d.seq, Lines: lines}
+	}
+	d.sinceKeyframe++
+	at, removed, added := diffLines(base, lines)
+	return deltaMessage{Type: "delta", Seq: d.seq, Base: d.seq - 1, At: at, Removed: removed, Added: added}
+}
+
+// sendDelta dumps the structure and sends what changed since the last dump
+// Runs on the data queue, so the dump reflects every command sent before it
+func (s *Session) sendDelta() {
+	snapshot, err := s.takeSnapshot()
+	if err != nil {
+		logError(s.ID, "taking delta snapshot", err)
+		return
+	}
+	if message := s.deltas.next(snapshot.Lines); message != nil {
+		s.send(message)
+	}
+}
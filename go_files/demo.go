@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultDemoTTL is how long a demo link works when no ttl is requested
+	defaultDemoTTL = 7 * 24 * time.Hour
+	// maxDemoTTL caps the ttl of a demo link
+	maxDemoTTL = 90 * 24 * time.Hour
+	// defaultDemoSampleSize is how many sample keys a demo starts with
+	defaultDemoSampleSize = 15
+	// maxDemoSampleSize keeps demo sessions small enough to read at a glance
+	maxDemoSampleSize = 200
+)
+
+var (
+	// ErrDemoInvalid is returned for malformed or forged demo tokens
+	ErrDemoInvalid = errors.New("invalid demo link")
+	// ErrDemoExpired is returned for demo tokens past their expiry
+	ErrDemoExpired = errors.New("demo link expired")
+	// ErrDemoUnsigned is returned when demo links are requested without a configured
+	// invite_secret; links signed with the per-run key would break on the next restart
+	ErrDemoUnsigned = errors.New("demo links need an invite_secret")
+)
+
+// demoWords are the sample keys of structures keyed by strings
+var demoWords = []string{
+	"apple", "apply", "ape", "banana", "band", "bandana", "can", "candle", "candy", "cane",
+	"car", "card", "care", "cart", "dog", "door", "dot", "egg", "ego", "fig",
+	"fog", "grape", "grid", "hat", "hate", "ice", "icon", "jam", "jar", "kite",
+	"lemon", "lime", "mango", "map", "maple", "net", "note", "oak", "olive", "pea",
+	"peach", "pear", "plum", "rose", "tea", "team", "tear", "tree", "trie", "zebra",
+}
+
+// demoPayload is the signed content of a demo token; everything needed to
+// rebuild the same sample session is in the link, so it needs no storage
+type demoPayload struct {
+	Type    string `json:"t"`
+	Flags   string `json:"f,omitempty"`
+	Size    int    `json:"n"`
+	Seed    int64  `json:"s"`
+	Expires int64  `json:"e"` // unix seconds
+}
+
+// demoLinkRequest is the body of POST /api/v1/demo-link
+type demoLinkRequest struct {
+	Type       string            `json:"type"`
+	Params     map[string]string `json:"params"` // structure flags, as on /session
+	SampleSize int               `json:"sample_size"`
+	Seed       *int64            `json:"seed"` // random when omitted
+	TTL        string            `json:"ttl"`
+}
+
+// demoLinkResponse is returned by POST /api/v1/demo-link
+type demoLinkResponse struct {
+	Token      string    `json:"token"`
+	Type       string    `json:"type"`
+	Flags      string    `json:"flags,omitempty"`
+	SampleSize int       `json:"sample_size"`
+	Seed       int64     `json:"seed"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	URL        string    `json:"url"`
+}
+
+// signDemo creates a demo token; demo signatures are domain separated from
+// invite signatures so one token can never be used as the other
+func signDemo(payload demoPayload) string {
+	data, _ := json.Marshal(payload)
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + demoSignature(encoded)
+}
+
+// demoSignature is the HMAC-SHA256 of an encoded demo payload
+func demoSignature(encoded string) string {
+	mac := hmac.New(sha256.New, inviteSigningKey())
+	mac.Write([]byte("demo:" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyDemo checks a demo token's signature and expiry and returns its payload
+func verifyDemo(token string) (*demoPayload, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || subtle.ConstantTimeCompare([]byte(signature), []byte(demoSignature(encoded))) != 1 {
+		return nil, ErrDemoInvalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrDemoInvalid
+	}
+	var payload demoPayload
+	if err := json.Unmarshal(data, &payload); err != nil || !validateDataType(payload.Type) {
+		return nil, ErrDemoInvalid
+	}
+	if time.Now().Unix() >= payload.Expires {
+		return nil, ErrDemoExpired
+	}
+	return &payload, nil
+}
+
+// sampleOps returns the inserts that pre-populate a demo; the same payload
+// always gives the same keys, so every visitor sees the same structure
+func (p *demoPayload) sampleOps() []string {
+	ds, _ := lookupDataStructure(p.Type)
+	rng := rand.New(rand.NewSource(p.Seed))
+
+	var pool []string
+	switch ds.keyType() {
+	case keyString:
+		pool = append(pool, demoWords...)
+	case keyFloat:
+		for i := 1; i <= max(p.Size*4, 100); i++ {
+			pool = append(pool, strconv.FormatFloat(float64(i)/2, 'f', 1, 64))
+		}
+	default:
+		for i := 1; i <= max(p.Size*4, 100); i++ {
+			pool = append(pool, strconv.Itoa(i))
+		}
+	}
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	ops := make([]string, 0, p.Size)
+	for _, key := range pool[:min(p.Size, len(pool))] {
+		ops = append(ops, "insert "+key)
+	}
+	return ops
+}
+
+// handleCreateDemoLink serves POST /api/v1/demo-link: signs a public link that
+// starts a guest session of the chosen structure filled with sample data
+func handleCreateDemoLink(w http.ResponseWriter, r *http.Request) {
+	if config.InviteSecret == "" {
+		httpError(w, ErrDemoUnsigned)
+		return
+	}
+	var req demoLinkRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		httpError(w, &ValidationError{"Invalid JSON body"})
+		return
+	}
+	payload, err := req.payload()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if err := demoAvailable(payload.Type); err != nil {
+		httpError(w, err)
+		return
+	}
+
+	token := signDemo(*payload)
+	expires := time.Unix(payload.Expires, 0)
+	fmt.Printf("Created demo link for %s (%d keys, expires %s)\n", payload.Type, payload.Size, expires.Format(time.RFC3339))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(demoLinkResponse{
+		Token:      token,
+		Type:       payload.Type,
+		Flags:      payload.Flags,
+		SampleSize: payload.Size,
+		Seed:       payload.Seed,
+		ExpiresAt:  expires,
+		URL:        config.PublicURL + "/session?demo=" + token,
+	})
+}
+
+// payload validates the request and turns it into a demo payload
+func (req *demoLinkRequest) payload() (*demoPayload, error) {
+	if req.Type == "" {
+		return nil, &ValidationError{"Missing required field: type"}
+	}
+	if !validateDataType(req.Type) {
+		return nil, &ValidationError{"Invalid type. Supported types: " + strings.Join(dataStructureNames(), ", ")}
+	}
+	params := url.Values{}
+	for name, value := range req.Params {
+		params.Set(name, value)
+	}
+	flags, err := buildFlagsFromParams(req.Type, params)
+	if err != nil {
+		return nil, err
+	}
+
+	size := req.SampleSize
+	if size == 0 {
+		size = defaultDemoSampleSize
+	}
+	if size < 1 || size > maxDemoSampleSize {
+		return nil, &ValidationError{fmt.Sprintf("Invalid sample_size. Must be integer between 1 and %d", maxDemoSampleSize)}
+	}
+	// String keys come from demoWords, so a larger sample could not be filled
+	if ds, _ := lookupDataStructure(req.Type); ds.keyType() == keyString && size > len(demoWords) {
+		return nil, &ValidationError{fmt.Sprintf("Invalid sample_size. %s demos hold at most %d keys", req.Type, len(demoWords))}
+	}
+
+	ttl := defaultDemoTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 || parsed > maxDemoTTL {
+			return nil, &ValidationError{fmt.Sprintf("Invalid ttl. Must be a duration up to %s", maxDemoTTL)}
+		}
+		ttl = parsed
+	}
+
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	return &demoPayload{
+		Type:    req.Type,
+		Flags:   flags,
+		Size:    size,
+		Seed:    seed,
+		Expires: time.Now().Add(ttl).Unix(),
+	}, nil
+}
+
+// demoAvailable reports whether guest sessions of a data type can run on the default engine
+func demoAvailable(dataType string) error {
+	if config.Engine == engineCpp {
+		return interfaceAvailable(dataType)
+	}
+	_, _, err := goEngineFor(dataType)
+	return err
+}
+
+// handleDemoClient starts a guest session from a demo link, pre-populated with its sample data
+func handleDemoClient(w http.ResponseWriter, r *http.Request, token string) {
+	demo, err := verifyDemo(token)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if err := demoAvailable(demo.Type); err != nil {
+		httpError(w, err)
+		return
+	}
+	if err := admitSession(config.Engine); err != nil {
+		httpBusy(w)
+		return
+	}
+
+	conn, err := upgradeClient(w, r, nil)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+
+	defer conn.Close()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s (demo: %s, %d keys)\n", clientID, clientAddr(r), demo.Type, demo.Size)
+	runClientThread(clientID, demo.Type, demo.Flags, conn, &sessionSetup{replay: demo.sampleOps()})
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message encodings a WebSocket client may ask for with encoding= on the session endpoints.
+// Messages travel through a session as outgoingMessages holding the value sent, and each
+// client socket writes them in its own encoding: a value is encoded once per encoding
+// whatever the number of clients, straight from the value. MessagePack carries the same
+// fields as JSON in fewer bytes, which adds up on busy log streams. Commands from the
+// client stay text in either encoding.
+const (
+	encodingJSON    = "json"
+	encodingMsgpack = "msgpack"
+)
+
+// messageEncodings lists the encodings, the default first
+var messageEncodings = []string{encodingJSON, encodingMsgpack}
+
+// messageEncoder writes the messages of a session in one encoding
+type messageEncoder interface {
+	// Encode returns one message as written to a client
+	Encode(msg any) ([]byte, error)
+	// Transcode re-encodes a message that was written as a JSON line
+	Transcode(line []byte) ([]byte, error)
+	// WithSequence returns a copy of an encoded object message with a leading seq field
+	WithSequence(p []byte, seq int64) []byte
+	// FrameType is the WebSocket message type the messages are sent as
+	FrameType() int
+}
+
+// encodedSocket is a client socket that chose its encoding; other sockets get JSON
+type encodedSocket interface {
+	Encoder() messageEncoder
+}
+
+// messageWriter takes whole messages rather than their bytes: fanouts, relays and hubs,
+// which hand a message on to clients that may each want another encoding
+type messageWriter interface {
+	WriteMessage(m *outgoingMessage) (int, error)
+}
+
+// encoderOf returns the encoding a client socket writes
+func encoderOf(w io.Writer) messageEncoder {
+	if socket, ok := w.(encodedSocket); ok {
+		if encoder := socket.Encoder(); encoder != nil {
+			return encoder
+		}
+	}
+	return jsonEncoder{}
+}
+
+// parseEncoding validates an encoding parameter; empty is JSON
+func parseEncoding(name string) (string, error) {
+	switch name {
+	case "":
+		return encodingJSON, nil
+	case encodingJSON, encodingMsgpack:
+		return name, nil
+	}
+	return "", &ValidationError{"Unsupported encoding. Must be " + strings.Join(messageEncodings, " or ")}
+}
+
+// textEncodingOnly refuses a binary encoding on a transport that carries only text
+func textEncodingOnly(request *sessionRequest) error {
+	if request.encoding != encodingJSON {
+		return &ValidationError{"encoding=" + request.encoding + " is only supported over WebSocket"}
+	}
+	return nil
+}
+
+// newMessageEncoder returns the encoder of a validated encoding
+func newMessageEncoder(encoding string) messageEncoder {
+	if encoding == encodingMsgpack {
+		return msgpackEncoder{}
+	}
+	return jsonEncoder{}
+}
+
+// outgoingMessage is one message on its way to clients, with its encodings made so far
+type outgoingMessage struct {
+	value any    // the message; nil when it was written as bytes
+	line  []byte // the JSON line of a message written as bytes
+
+	mu      sync.Mutex
+	encoded []encodedMessage
+}
+
+// encodedMessage is a message in one encoding
+type encodedMessage struct {
+	encoder messageEncoder
+	data    []byte
+}
+
+// newOutgoingMessage wraps a value about to be sent
+func newOutgoingMessage(value any) *outgoingMessage {
+	return &outgoingMessage{value: value}
+}
+
+// rawMessage wraps a JSON line written to a session as bytes
+func rawMessage(line []byte) *outgoingMessage {
+	return &outgoingMessage{line: append([]byte(nil), line...)}
+}
+
+// encode returns the message in the encoding of encoder, encoding it on first use
+func (m *outgoingMessage) encode(encoder messageEncoder) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.encoded {
+		if e.encoder == encoder {
+			return e.data, nil
+		}
+	}
+	var data []byte
+	var err error
+	switch {
+	case m.line == nil:
+		data, err = encoder.Encode(m.value)
+	default:
+		data, err = encoder.Transcode(m.line)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.encoded = append(m.encoded, encodedMessage{encoder, data})
+	return data, nil
+}
+
+// writeTo writes the message to a socket in the socket's encoding
+func (m *outgoingMessage) writeTo(w io.Writer) (int, error) {
+	data, err := m.encode(encoderOf(w))
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(data)
+}
+
+// jsonEncoder writes each message as a JSON line in a text message, as the protocol always has
+type jsonEncoder struct{}
+
+// Encode implements messageEncoder
+func (jsonEncoder) Encode(msg any) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Transcode implements messageEncoder
+func (jsonEncoder) Transcode(line []byte) ([]byte, error) {
+	return line, nil
+}
+
+// WithSequence implements messageEncoder
+func (jsonEncoder) WithSequence(p []byte, seq int64) []byte {
+	if !bytes.HasPrefix(p, []byte("{")) {
+		return append([]byte(nil), p...)
+	}
+	prefix := `{"seq":` + strconv.FormatInt(seq, 10)
+	if !bytes.HasPrefix(p, []byte("{}")) {
+		prefix += ","
+	}
+	return append([]byte(prefix), p[1:]...)
+}
+
+// FrameType implements messageEncoder
+func (jsonEncoder) FrameType() int {
+	return websocket.TextMessage
+}
+
+// msgpackEncoder writes each message as one binary MessagePack message, with the fields
+// the JSON encoding has
+type msgpackEncoder struct{}
+
+// Encode implements messageEncoder
+func (msgpackEncoder) Encode(msg any) ([]byte, error) {
+	return appendMsgpack(nil, reflect.ValueOf(msg))
+}
+
+// Transcode implements messageEncoder
+func (msgpackEncoder) Transcode(line []byte) ([]byte, error) {
+	return appendMsgpackJSON(nil, line)
+}
+
+// WithSequence implements messageEncoder
+func (msgpackEncoder) WithSequence(p []byte, seq int64) []byte {
+	n, header, ok := msgpackMapHeader(p)
+	if !ok {
+		return append([]byte(nil), p...)
+	}
+	b := appendMsgpackHeader(nil, n+1, 0x80, 16, 0, 0xde, 0xdf)
+	b = appendMsgpackString(b, "seq")
+	b = appendMsgpackInt(b, seq)
+	return append(b, p[header:]...)
+}
+
+// FrameType implements messageEncoder
+func (msgpackEncoder) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonNumberType    = reflect.TypeFor[json.Number]()
+)
+
+// appendMsgpack appends the MessagePack encoding of v laid out as encoding/json lays out
+// JSON: struct fields under their json tags, map keys sorted, Marshalers as what they
+// marshal to. Byte slices become binary rather than base64 text
+func appendMsgpack(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return append(b, 0xc0), nil
+	}
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return append(b, 0xc0), nil
+	}
+	if v.Type() == jsonNumberType {
+		return appendMsgpackNumber(b, json.Number(v.String()))
+	}
+	if v.Kind() != reflect.Pointer && v.CanAddr() && reflect.PointerTo(v.Type()).Implements(jsonMarshalerType) {
+		v = v.Addr()
+	}
+	// Values reached through an unexported embedded struct cannot be handed to their methods
+	if v.CanInterface() && v.Type().Implements(jsonMarshalerType) {
+		data, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgpackJSON(b, data)
+	}
+	if v.CanInterface() && v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgpackString(b, string(text)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return appendMsgpack(b, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgpackInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := v.Uint(); n > math.MaxInt64 {
+			return binary.BigEndian.AppendUint64(append(b, 0xcf), n), nil
+		}
+		return appendMsgpackInt(b, int64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v.Float())), nil
+	case reflect.String:
+		return appendMsgpackString(b, v.String()), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b = appendMsgpackHeader(b, v.Len(), 0, 0, 0xc4, 0xc5, 0xc6)
+			return append(b, v.Bytes()...), nil
+		}
+		fallthrough
+	case reflect.Array:
+		b = appendMsgpackHeader(b, v.Len(), 0x90, 16, 0, 0xdc, 0xdd)
+		for i := range v.Len() {
+			var err error
+			if b, err = appendMsgpack(b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		return appendMsgpackMap(b, v)
+	case reflect.Struct:
+		return appendMsgpackStruct(b, v)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %s", v.Type())
+}
+
+// appendMsgpackMap appends a map with its keys as JSON writes them, sorted
+func appendMsgpackMap(b []byte, v reflect.Value) ([]byte, error) {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key, err := msgpackMapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	b = appendMsgpackHeader(b, len(entries), 0x80, 16, 0, 0xde, 0xdf)
+	for _, e := range entries {
+		b = appendMsgpackString(b, e.key)
+		var err error
+		if b, err = appendMsgpack(b, e.value); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// msgpackMapKey returns a map key as the string JSON uses for it
+func msgpackMapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.Type().Implements(textMarshalerType) {
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("msgpack: unsupported map key type %s", key.Type())
+}
+
+// appendMsgpackStruct appends a struct as a map of the fields JSON would write
+func appendMsgpackStruct(b []byte, v reflect.Value) ([]byte, error) {
+	fields := msgpackFields(v.Type())
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, ok := fieldByIndex(v, field.index)
+		if !ok || (field.omitEmpty && isEmptyValue(value)) {
+			continue
+		}
+		names = append(names, field.name)
+		values = append(values, value)
+	}
+
+	b = appendMsgpackHeader(b, len(values), 0x80, 16, 0, 0xde, 0xdf)
+	for i, value := range values {
+		b = appendMsgpackString(b, names[i])
+		var err error
+		if b, err = appendMsgpack(b, value); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// msgpackField is a struct field written to messages
+type msgpackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// msgpackFieldCache holds the fields of each struct type encoded so far
+var msgpackFieldCache sync.Map // reflect.Type → []msgpackField
+
+// msgpackFields returns the fields of a struct type JSON would write, in its order:
+// exported fields under their json tag names, with those of untagged embedded structs
+// promoted unless the outer struct has a field of the same name
+func msgpackFields(t reflect.Type) []msgpackField {
+	if cached, ok := msgpackFieldCache.Load(t); ok {
+		return cached.([]msgpackField)
+	}
+	fields := collectMsgpackFields(t, nil, map[string]bool{})
+	msgpackFieldCache.Store(t, fields)
+	return fields
+}
+
+// collectMsgpackFields lists the fields of t under index, skipping names already taken
+func collectMsgpackFields(t reflect.Type, index []int, taken map[string]bool) []msgpackField {
+	type candidate struct {
+		field    reflect.StructField
+		name     string
+		opts     string
+		embedded reflect.Type // struct type to promote fields from; nil for a plain field
+	}
+	var candidates []candidate
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				candidates = append(candidates, candidate{field: field, embedded: embedded})
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		candidates = append(candidates, candidate{field: field, name: name, opts: opts})
+	}
+
+	// Fields of this struct shadow promoted ones
+	own := map[string]bool{}
+	for _, c := range candidates {
+		if c.embedded == nil && !taken[c.name] {
+			own[c.name] = true
+		}
+	}
+	var fields []msgpackField
+	for _, c := range candidates {
+		fieldIndex := append(append([]int(nil), index...), c.field.Index...)
+		if c.embedded != nil {
+			shadowed := map[string]bool{}
+			for name := range taken {
+				shadowed[name] = true
+			}
+			for name := range own {
+				shadowed[name] = true
+			}
+			for _, promoted := range collectMsgpackFields(c.embedded, fieldIndex, shadowed) {
+				if !taken[promoted.name] {
+					taken[promoted.name] = true
+					fields = append(fields, promoted)
+				}
+			}
+			continue
+		}
+		if taken[c.name] {
+			continue
+		}
+		taken[c.name] = true
+		fields = append(fields, msgpackField{
+			name:      c.name,
+			index:     fieldIndex,
+			omitEmpty: strings.Contains(","+c.opts+",", ",omitempty,"),
+		})
+	}
+	return fields
+}
+
+// fieldByIndex returns a possibly promoted field; false when it sits behind a nil pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether omitempty leaves v out, as encoding/json decides it
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// appendMsgpackJSON appends the MessagePack encoding of a JSON document
+func appendMsgpackJSON(b []byte, data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return appendMsgpack(b, reflect.ValueOf(value))
+}
+
+// appendMsgpackNumber appends a JSON number as an integer when it is one, else a float
+func appendMsgpackNumber(b []byte, n json.Number) ([]byte, error) {
+	if i, err := n.Int64(); err == nil {
+		return appendMsgpackInt(b, i), nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f)), nil
+}
+
+// appendMsgpackString appends a string
+func appendMsgpackString(b []byte, s string) []byte {
+	b = appendMsgpackHeader(b, len(s), 0xa0, 32, 0xd9, 0xda, 0xdb)
+	return append(b, s...)
+}
+
+// appendMsgpackHeader appends the type and length of a string, binary, array or map: the
+// fixed form below fixedLimit, else the 8-bit form when there is one, then the 16- and
+// 32-bit forms
+func appendMsgpackHeader(b []byte, n int, fixed byte, fixedLimit int, code8, code16, code32 byte) []byte {
+	switch {
+	case n < fixedLimit:
+		return append(b, fixed|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+}
+
+// msgpackMapHeader returns the entry count of an encoded map and the length of its header
+func msgpackMapHeader(p []byte) (int, int, bool) {
+	switch {
+	case len(p) >= 1 && p[0]&0xf0 == 0x80:
+		return int(p[0] & 0x0f), 1, true
+	case len(p) >= 3 && p[0] == 0xde:
+		return int(binary.BigEndian.Uint16(p[1:])), 3, true
+	case len(p) >= 5 && p[0] == 0xdf:
+		return int(binary.BigEndian.Uint32(p[1:])), 5, true
+	}
+	return 0, 0, false
+}
+
+// appendMsgpackInt appends an integer in its shortest MessagePack form
+func appendMsgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		return append(b, byte(n))
+	case n >= -32 && n < 0:
+		return append(b, byte(n))
+	case n >= 0 && n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n >= 0 && n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n >= 0 && n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	case n >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(n))
+	case n >= math.MinInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackEmbedded is promoted into msgpackSample as encoding/json promotes it
+type msgpackEmbedded struct {
+	Shared string `json:"shared"`
+	Inner  int    `json:"inner"`
+}
+
+// msgpackSample covers the struct tag handling of appendMsgpack
+type msgpackSample struct {
+	msgpackEmbedded
+	Name     string          `json:"name"`
+	Shared   string          `json:"shared"` // shadows the embedded field
+	Skipped  string          `json:"-"`
+	Empty    string          `json:"empty,omitempty"`
+	Kept     int             `json:"kept,omitempty"`
+	Untagged bool            // encoded under its Go name
+	Nested   *msgpackSample  `json:"nested,omitempty"`
+	Raw      json.RawMessage `json:"raw,omitempty"`
+	private  string
+}
+
+// decodeMsgpack decodes p with the reference decoder and normalizes its numbers
+func decodeMsgpack(t *testing.T, p []byte) any {
+	t.Helper()
+	var value any
+	if err := msgpack.Unmarshal(p, &value); err != nil {
+		t.Fatalf("reference decoder rejected %x: %v", p, err)
+	}
+	return normalizeMsgpack(value)
+}
+
+// normalizeMsgpack turns every integer into int64, or uint64 past math.MaxInt64, so values
+// decode the same whichever integer width was chosen on the wire
+func normalizeMsgpack(value any) any {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return v.Uint()
+		}
+		return int64(v.Uint())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = normalizeMsgpack(v.Index(i).Interface())
+		}
+		return items
+	case reflect.Map:
+		entries := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			entries[iter.Key().String()] = normalizeMsgpack(iter.Value().Interface())
+		}
+		return entries
+	}
+	return value
+}
+
+func TestMsgpackEncode(t *testing.T) {
+	stamp := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value any
+		want  any // as normalizeMsgpack returns it
+	}{
+		{"nil", nil, nil},
+		{"true", true, true},
+		{"false", false, false},
+		{"fixint", 127, int64(127)},
+		{"uint8", 255, int64(255)},
+		{"uint16", 65535, int64(65535)},
+		{"uint32", int64(math.MaxUint32), int64(math.MaxUint32)},
+		{"uint64", int64(1) << 40, int64(1) << 40},
+		{"largest uint64", uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{"negative fixint", -32, int64(-32)},
+		{"int8", -128, int64(-128)},
+		{"int16", -32768, int64(-32768)},
+		{"int32", int64(math.MinInt32), int64(math.MinInt32)},
+		{"int64", int64(math.MinInt64), int64(math.MinInt64)},
+		{"float", 1.5, 1.5},
+		{"float32", float32(0.25), 0.25},
+		{"empty string", "", ""},
+		{"fixstr", strings.Repeat("a", 31), strings.Repeat("a", 31)},
+		{"str8", strings.Repeat("a", 32), strings.Repeat("a", 32)},
+		{"str16", strings.Repeat("a", 256), strings.Repeat("a", 256)},
+		{"str32", strings.Repeat("a", 65536), strings.Repeat("a", 65536)},
+		{"utf-8", "ünïcode ✓", "ünïcode ✓"},
+		{"bytes", []byte{0, 1, 255}, []byte{0, 1, 255}},
+		{"nil slice", []int(nil), nil},
+		{"slice", []int{1, -1, 300}, []any{int64(1), int64(-1), int64(300)}},
+		{"array16", make([]int, 16), func() []any {
+			items := make([]any, 16)
+			for i := range items {
+				items[i] = int64(0)
+			}
+			return items
+		}()},
+		{"array", [2]string{"a", "b"}, []any{"a", "b"}},
+		{"nil pointer", (*msgpackSample)(nil), nil},
+		{"nil map", map[string]int(nil), nil},
+		{"map", map[string]int{"b": 2, "a": 1}, map[string]any{"a": int64(1), "b": int64(2)}},
+		{"int keys", map[int]string{2: "b", 10: "j"}, map[string]any{"2": "b", "10": "j"}},
+		{"map16", sixteenKeys(), func() map[string]any {
+			entries := map[string]any{}
+			for key, value := range sixteenKeys() {
+				entries[key] = int64(value)
+			}
+			return entries
+		}()},
+		{"json number", json.Number("12"), int64(12)},
+		{"json float number", json.Number("1.25"), 1.25},
+		{"text marshaler", stamp, "2026-10-16T12:30:00Z"},
+		{"interface", []any{"x", 1, nil}, []any{"x", int64(1), nil}},
+		{"struct", msgpackSample{
+			msgpackEmbedded: msgpackEmbedded{Shared: "hidden", Inner: 7},
+			Name:            "n",
+			Shared:          "outer",
+			Skipped:         "s",
+			Untagged:        true,
+			Nested:          &msgpackSample{Name: "child", Kept: 3},
+			Raw:             json.RawMessage(`{"k":[1,2.5,"v"]}`),
+			private:         "p",
+		}, map[string]any{
+			"inner":    int64(7),
+			"name":     "n",
+			"shared":   "outer",
+			"Untagged": true,
+			"nested": map[string]any{
+				"inner":    int64(0),
+				"name":     "child",
+				"shared":   "",
+				"kept":     int64(3),
+				"Untagged": false,
+			},
+			"raw": map[string]any{"k": []any{int64(1), 2.5, "v"}},
+		}},
+		{"server message", Message{Type: "program", Content: "READY"}, map[string]any{"type": "program", "message": "READY"}},
+		{"unexported embedded struct", newBusyEnvelope(), map[string]any{
+			"type": "error", "code": "busy", "message": ErrServerBusy.Error(), "retry_after": int64(newBusyEnvelope().RetryAfter),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := msgpackEncoder{}.Encode(tt.value)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if got := decodeMsgpack(t, p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// sixteenKeys is the smallest map past the fixmap form
+func sixteenKeys() map[string]int {
+	entries := map[string]int{}
+	for i := range 16 {
+		entries[string(rune('a'+i))] = i
+	}
+	return entries
+}
+
+func TestMsgpackTranscode(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want any
+	}{
+		{"message", `{"type":"log","message":"[TREE] root=1"}` + "\n", map[string]any{"type": "log", "message": "[TREE] root=1"}},
+		{"numbers", `{"n":3,"f":0.5,"big":18446744073709551615}`, map[string]any{"n": int64(3), "f": 0.5, "big": float64(math.MaxUint64)}},
+		{"nested", `{"a":[true,null,{"b":"c"}]}`, map[string]any{"a": []any{true, nil, map[string]any{"b": "c"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := msgpackEncoder{}.Transcode([]byte(tt.line))
+			if err != nil {
+				t.Fatalf("Transcode: %v", err)
+			}
+			if got := decodeMsgpack(t, p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+	if _, err := (msgpackEncoder{}).Transcode([]byte("not json")); err == nil {
+		t.Error("Transcode accepted invalid JSON")
+	}
+}
+
+func TestMsgpackWithSequence(t *testing.T) {
+	tests := []struct {
+		name    string
+		message any
+		seq     int64
+	}{
+		{"fixmap", Message{Type: "program", Content: "READY"}, 1},
+		{"fixmap growing to map16", func() map[string]int { m := sixteenKeys(); delete(m, "p"); return m }(), 70000},
+		{"map16", sixteenKeys(), -3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := msgpackEncoder{}.Encode(tt.message)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			want := decodeMsgpack(t, p).(map[string]any)
+			want["seq"] = tt.seq
+			if got := decodeMsgpack(t, msgpackEncoder{}.WithSequence(p, tt.seq)); !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %#v, want %#v", got, want)
+			}
+		})
+	}
+}
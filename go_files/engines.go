@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Engines a session can run its data structure on
+const (
+	engineCpp = "cpp" // the C++ interface executable, fed through stdin and FIFOs
+	engineGo  = "go"  // an in-process Go port speaking the same protocol
+)
+
+// errEngineKilled ends a Go engine's command loop when its session stops it
+var errEngineKilled = errors.New("engine killed")
+
+// goEngine is an in-process data structure speaking the batch protocol of its C++ interface
+type goEngine interface {
+	// start initializes the structure and returns the line announcing batch mode
+	start() string
+	// handle runs one command; returns false on quit
+	handle(command string, args []string) bool
+}
+
+// goEngines builds each Go model from its key type and interface flags
+var goEngines = map[string]func(keyType, flags string, out *engineOutput) (goEngine, error){
+	"btree":   newGoBTree,
+	"avltree": newGoAVLTree,
+}
+
+// parseEngine validates the engine a client asked for; empty picks the configured default
+func parseEngine(name string) (string, error) {
+	if name == "" {
+		name = config.Engine
+	}
+	switch name {
+	case engineCpp, engineGo:
+		return name, nil
+	}
+	return "", &ValidationError{"Unsupported engine. Must be cpp or go"}
+}
+
+// processHandle is a running interface, whether a C++ process or a Go engine
+type processHandle struct {
+	stdin io.WriteCloser
+	wait  func() error // blocks until the interface exits; called once
+	kill  func()
+}
+
+// launchProcess starts the interface of a data type on the given engine
+// executable is the C++ binary to run; the Go engine ignores it
+func launchProcess(engine, ds, executable, flags, progFifo, logFifo string) (*processHandle, error) {
+	args, err := interfaceArgs(ds, flags, "--program-out", progFifo, "--tree-log-out", logFifo)
+	if err != nil {
+		return nil, err
+	}
+	if engine == engineGo {
+		return startGoProcess(ds, flags, progFifo, logFifo)
+	}
+	return startCppProcess(ds, executable, args, progFifo, logFifo)
+}
+
+// engineOutput is where an engine writes, plus the log history the logs command shows
+// Like the C++ interfaces, logs accumulate in a buffer and only the part produced
+// by a command is forwarded to the tree log stream
+type engineOutput struct {
+	program io.Writer
+	treeLog io.Writer
+	logs    strings.Builder
+	err     error // first failed write; the engine stops on it
+}
+
+// say writes one program output line
+func (o *engineOutput) say(format string, args ...any) {
+	if _, err := fmt.Fprintf(o.program, format+"\n", args...); err != nil && o.err == nil {
+		o.err = err
+	}
+}
+
+// logf appends one line to the log history
+func (o *engineOutput) logf(format string, args ...any) {
+	fmt.Fprintf(&o.logs, format+"\n", args...)
+}
+
+// mark returns the current end of the log history
+func (o *engineOutput) mark() int {
+	return o.logs.Len()
+}
+
+// forward sends the logs written since mark to the tree log stream
+func (o *engineOutput) forward(mark int) {
+	if logs := o.logs.String()[mark:]; logs != "" {
+		if _, err := io.WriteString(o.treeLog, logs); err != nil && o.err == nil {
+			o.err = err
+		}
+	}
+}
+
+// common handles the commands every interface shares; returns false if command is not one
+func (o *engineOutput) common(command string) bool {
+	switch command {
+	case "help", "menu":
+		// The menu is only printed in interactive mode
+	case "logs":
+		if o.logs.Len() == 0 {
+			o.say("LOGS_EMPTY")
+		} else {
+			o.say("LOGS_START")
+			io.WriteString(o.program, o.logs.String())
+			o.say("LOGS_END")
+		}
+	case "clear_logs":
+		o.logs.Reset()
+		o.say("LOGS_CLEARED")
+	default:
+		return false
+	}
+	return true
+}
+
+// engineAddresses hands out fake node addresses, so logs parsed by the front end
+// keep their 0x... format; addresses are never reused within a session
+type engineAddresses struct {
+	next uintptr
+}
+
+// alloc returns a fresh address
+func (a *engineAddresses) alloc() uintptr {
+	a.next++
+	return 0x1000 + a.next*0x40
+}
+
+// formatAddress prints an address the way C++ streams a pointer; null is 0
+func formatAddress(addr uintptr) string {
+	if addr == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("0x%x", addr)
+}
+
+// streamInt reads a leading integer from args the way `iss >> value` does
+func streamInt(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	var value int
+	if _, err := fmt.Sscan(args[0], &value); err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// goEngineFor returns the builder of a data type's Go engine
+func goEngineFor(ds string) (func(keyType, flags string, out *engineOutput) (goEngine, error), *DataStructure, error) {
+	registered, ok := lookupDataStructure(ds)
+	if !ok {
+		return nil, nil, &ValidationError{"Unsupported data type"}
+	}
+	build, ok := goEngines[registered.model()]
+	if !ok {
+		return nil, nil, &ValidationError{"No Go engine for data type " + ds + ". Use engine=cpp"}
+	}
+	return build, registered, nil
+}
+
+// newGoEngine builds the Go engine of a data type
+func newGoEngine(ds, flags string, out *engineOutput) (goEngine, error) {
+	build, registered, err := goEngineFor(ds)
+	if err != nil {
+		return nil, err
+	}
+	return build(registered.keyType(), flags, out)
+}
+
+// runGoEngine runs an engine over its input until quit or end of input
+// A panic inside the engine is reported like a crashed C++ process
+func runGoEngine(engine goEngine, out *engineOutput, stdin io.Reader) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: go engine panic: %v", ErrProcessCrashed, r)
+		}
+	}()
+
+	out.say("%s", engine.start())
+	scanner := bufio.NewScanner(stdin)
+	for out.err == nil && scanner.Scan() {
+		fields, err := splitCommand(scanner.Text())
+		if err != nil {
+			fields = strings.Fields(scanner.Text())
+		}
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if !engine.handle(fields[0], fields[1:]) {
+			break
+		}
+	}
+	if out.err != nil {
+		return fmt.Errorf("%w: %v", ErrClientGone, out.err)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, errEngineKilled) {
+		return err
+	}
+	return nil
+}
+
+// startGoProcess runs the Go engine of a data type in a goroutine that writes
+// the session FIFOs, so the rest of the session cannot tell it from a C++ process
+func startGoProcess(ds, flags, progFifo, logFifo string) (*processHandle, error) {
+	out := &engineOutput{}
+	engine, err := newGoEngine(ds, flags, out)
+	if err != nil {
+		return nil, err
+	}
+
+	stdinReader, stdinWriter := io.Pipe()
+	exited := make(chan error, 1)
+	go func() {
+		defer stdinReader.Close()
+		exited <- func() error {
+			// Open in the same order as the C++ interfaces: program output, then tree logs
+			program, err := os.OpenFile(progFifo, os.O_WRONLY, 0)
+			if err != nil {
+				return err
+			}
+			defer program.Close()
+			treeLog, err := os.OpenFile(logFifo, os.O_WRONLY, 0)
+			if err != nil {
+				return err
+			}
+			defer treeLog.Close()
+
+			out.program, out.treeLog = program, treeLog
+			return runGoEngine(engine, out, stdinReader)
+		}()
+	}()
+
+	return &processHandle{
+		stdin: stdinWriter,
+		wait:  func() error { return <-exited },
+		kill:  func() { stdinReader.CloseWithError(errEngineKilled) },
+	}, nil
+}
+
+// runGoHeadless is runHeadless for the Go engine, run synchronously in memory
+func runGoHeadless(ds, flags string, script []string) ([]string, []string, error) {
+	var program, treeLog bytes.Buffer
+	out := &engineOutput{program: &program, treeLog: &treeLog}
+	engine, err := newGoEngine(ds, flags, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = runGoEngine(engine, out, strings.NewReader(strings.Join(script, "\n")+"\n"))
+	return readLines(&program), readLines(&treeLog), err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// engineAddress matches the node addresses in engine output, which differ between engines
+var engineAddress = regexp.MustCompile(`0x[0-9a-f]+`)
+
+// normalizeAddresses numbers addresses in order of first appearance, so outputs of both
+// engines compare equal when they point at the same nodes
+func normalizeAddresses(lines []string) []string {
+	seen := map[string]string{}
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = engineAddress.ReplaceAllStringFunc(line, func(addr string) string {
+			if _, ok := seen[addr]; !ok {
+				seen[addr] = fmt.Sprintf("@%d", len(seen)+1)
+			}
+			return seen[addr]
+		})
+	}
+	return normalized
+}
+
+// TestGoEnginesMatchInterfaces runs the same scripts on each Go engine and on its C++
+// interface in $DATAS_INTERFACE_DIR, or the working directory, and compares their output
+func TestGoEnginesMatchInterfaces(t *testing.T) {
+	if dir := os.Getenv("DATAS_INTERFACE_DIR"); dir != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chdir(wd) })
+	}
+	var script []string
+	for _, key := range []int{50, 20, 80, 10, 30, 70, 90, 5, 15, 25, 35, 60, 75, 85, 95, 1, 2, 3} {
+		script = append(script, fmt.Sprintf("insert %d", key))
+	}
+	script = append(script, "insert 20", "find 35", "find 36", "print", "status",
+		"remove 50", "remove 1", "remove 2", "remove 3", "remove 99", "find 50", "print", "status",
+		"insert abc", "bogus", "logs", "clear_logs", "logs", "quit")
+
+	tests := []struct {
+		ds    string
+		flags string
+	}{
+		{"btree", ""},
+		{"btree", "--order 3"},
+		{"btree", "--order 5"},
+		{"avltree", ""},
+		{"avltree", "--duplicates allow"},
+		{"avltree", "--verbose-rotations on"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.ds+" "+tt.flags), func(t *testing.T) {
+			if _, err := os.Stat(interfaceExecutable(tt.ds)); err != nil {
+				t.Skipf("%s is not built", interfaceExecutable(tt.ds))
+			}
+			wantProgram, wantLog, err := runHeadless(context.Background(), tt.ds, engineCpp, tt.flags, script)
+			if err != nil {
+				t.Fatalf("C++ interface: %v", err)
+			}
+			if len(wantProgram) == 0 || len(wantLog) == 0 {
+				t.Fatal("C++ interface printed nothing")
+			}
+			program, log, err := runHeadless(context.Background(), tt.ds, engineGo, tt.flags, script)
+			if err != nil {
+				t.Fatalf("Go engine: %v", err)
+			}
+			compareLines(t, "program output", normalizeAddresses(program), normalizeAddresses(wantProgram))
+			compareLines(t, "tree log", normalizeAddresses(log), normalizeAddresses(wantLog))
+		})
+	}
+}
+
+// compareLines reports the first line where got differs from want
+func compareLines(t *testing.T, name string, got, want []string) {
+	t.Helper()
+	for i := 0; i < max(len(got), len(want)); i++ {
+		var gotLine, wantLine string
+		if i < len(got) {
+			gotLine = got[i]
+		}
+		if i < len(want) {
+			wantLine = want[i]
+		}
+		if gotLine != wantLine {
+			t.Errorf("%s line %d = %q, want %q", name, i+1, gotLine, wantLine)
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// serverVersion identifies the server build; set with -ldflags "-X main.serverVersion=..."
+var serverVersion = "dev"
+
+// protocolVersion is bumped whenever client messages or replies change incompatibly
+const protocolVersion = 1
+
+// sessionEnvironment is everything needed to reproduce a session from its transcript:
+// the exact interface binary, how it was started and what seeded its randomness
+type sessionEnvironment struct {
+	ServerVersion   string `json:"server_version"`
+	ProtocolVersion int    `json:"protocol_version"`
+	Engine          string `json:"engine"`
+	Binary          string `json:"binary"`
+	BinaryVersion   string `json:"binary_version,omitempty"`
+	BinarySHA256    string `json:"binary_sha256,omitempty"`
+	Flags           string `json:"flags"`
+	Seed            int64  `json:"seed"`
+}
+
+var (
+	binaryVersionsMu sync.Mutex
+	// binaryVersions caches --version answers by binary checksum, so restarts do not probe again
+	binaryVersions = make(map[string]string)
+)
+
+// newSessionSeed picks the seed of a session that did not ask for one
+func newSessionSeed() int64 {
+	for {
+		if seed := rand.Int63(); seed != 0 {
+			return seed
+		}
+	}
+}
+
+// environment describes the binary and settings the session's process runs with
+// A binary that cannot be read is still reported, without its version and checksum
+func (s *Session) environment() sessionEnvironment {
+	env := sessionEnvironment{
+		ServerVersion:   serverVersion,
+		ProtocolVersion: protocolVersion,
+		Engine:          s.Engine,
+		Flags:           s.Flags,
+		Seed:            s.Seed,
+	}
+	if s.Engine == engineGo {
+		env.Binary = "builtin:" + s.DataType
+		env.BinaryVersion = serverVersion
+		return env
+	}
+
+	env.Binary = s.executable()
+	checksum := fileSHA256(env.Binary)
+	if checksum == "" {
+		return env
+	}
+	env.BinarySHA256 = checksum
+
+	binaryVersionsMu.Lock()
+	version, ok := binaryVersions[checksum]
+	binaryVersionsMu.Unlock()
+	if !ok {
+		var err error
+		if version, err = interfaceVersion(env.Binary); err != nil {
+			return env
+		}
+		binaryVersionsMu.Lock()
+		binaryVersions[checksum] = version
+		binaryVersionsMu.Unlock()
+	}
+	env.BinaryVersion = version
+	return env
+}
+
+// String formats the environment as the key=value fields of a protocol message
+// Values with spaces are quoted so the line still splits on spaces
+func (env sessionEnvironment) String() string {
+	field := func(value string) string {
+		if value == "" || strings.ContainsAny(value, " \t\"") {
+			return strconv.Quote(value)
+		}
+		return value
+	}
+	return fmt.Sprintf("server_version=%s protocol_version=%d engine=%s binary=%s binary_version=%s binary_sha256=%s flags=%s seed=%d",
+		field(env.ServerVersion), env.ProtocolVersion, field(env.Engine), field(env.Binary),
+		field(env.BinaryVersion), field(env.BinarySHA256), field(env.Flags), env.Seed)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os/exec"
+	"sync/atomic"
+)
+
+// Error classes every failure is wrapped into, so logs, metrics, HTTP statuses
+// and the JSON error envelope describe it the same way
+var (
+	// ErrBinaryMissing is returned when the C++ interface of a data type is not installed
+	ErrBinaryMissing = errors.New("interface binary missing")
+	// ErrCapacity is returned when the server or a session cannot take more work
+	ErrCapacity = errors.New("capacity exceeded")
+	// ErrClientGone is returned when the clients of a session disconnected
+	ErrClientGone = errors.New("client disconnected")
+	// ErrProcessCrashed is returned when a C++ interface exited abnormally
+	ErrProcessCrashed = errors.New("interface process crashed")
+)
+
+// Error codes that are not tied to one sentinel
+const (
+	errorCodeInvalid  = "invalid_request" // any *ValidationError
+	errorCodeInternal = "internal"        // everything unclassified
+)
+
+// errorClass gives a family of errors its stable code and HTTP status
+type errorClass struct {
+	target error
+	code   string
+	status int
+}
+
+// errorClasses is checked in order; the first class an error wraps wins
+var errorClasses = []errorClass{
+	{ErrBinaryMissing, "binary_missing", http.StatusServiceUnavailable},
+	{ErrServerBusy, "busy", http.StatusTooManyRequests},
+	{ErrCapacity, "capacity", http.StatusServiceUnavailable},
+	{ErrDraining, "draining", http.StatusServiceUnavailable},
+	{ErrClientGone, "client_gone", http.StatusGone},
+	{ErrProcessCrashed, "process_crashed", http.StatusBadGateway},
+	{ErrSessionNotFound, "not_found", http.StatusNotFound},
+	{ErrUnknownJoinCode, "not_found", http.StatusNotFound},
+	{ErrRecordNotFound, "not_found", http.StatusNotFound},
+	{ErrSavedTreeNotFound, "not_found", http.StatusNotFound},
+	{ErrImportNotFound, "not_found", http.StatusNotFound},
+	{ErrNotBroadcasting, "not_found", http.StatusNotFound},
+	{ErrNotInspected, "not_found", http.StatusNotFound},
+	{ErrUnknownResumeToken, "not_found", http.StatusNotFound},
+	{ErrNoCanary, "not_found", http.StatusNotFound},
+	{ErrWorkloadNotFound, "not_found", http.StatusNotFound},
+	{ErrForkOpened, "conflict", http.StatusConflict},
+	{ErrTemplateExists, "conflict", http.StatusConflict},
+	{ErrTreeExists, "conflict", http.StatusConflict},
+	{ErrUpdateBusy, "conflict", http.StatusConflict},
+	{ErrReleaseNotNewer, "conflict", http.StatusConflict},
+	{ErrCaptureBusy, "conflict", http.StatusConflict},
+	{ErrCaptureTimeout, "timeout", http.StatusGatewayTimeout},
+	{ErrSnapshotUnsupported, "unsupported", http.StatusNotImplemented},
+	{ErrUpdaterDisabled, "unsupported", http.StatusNotImplemented},
+	{ErrDemoUnsigned, "unsupported", http.StatusNotImplemented},
+	{ErrHostNotAllowed, "forbidden", http.StatusForbidden},
+	{ErrJoinCodeMismatch, "forbidden", http.StatusForbidden},
+	{ErrSessionPrivate, "forbidden", http.StatusForbidden},
+	{ErrTreeNotOwned, "forbidden", http.StatusForbidden},
+	{ErrInviteExpired, "expired", http.StatusGone},
+	{ErrInviteInvalid, "forbidden", http.StatusForbidden},
+	{ErrDemoExpired, "expired", http.StatusGone},
+	{ErrDemoInvalid, "forbidden", http.StatusForbidden},
+	{ErrConfirmationInvalid, "forbidden", http.StatusForbidden},
+	{ErrChecksumMismatch, "checksum_mismatch", http.StatusUnprocessableEntity},
+	{ErrTemplateFetch, "upstream", http.StatusBadGateway},
+	{ErrArtifactFetch, "upstream", http.StatusBadGateway},
+	{ErrSignatureInvalid, "signature_invalid", http.StatusUnprocessableEntity},
+	{ErrSelftestFailed, "selftest_failed", http.StatusUnprocessableEntity},
+}
+
+// classifyError returns the code and HTTP status of an error
+func classifyError(err error) (string, int) {
+	for _, class := range errorClasses {
+		if errors.Is(err, class.target) {
+			return class.code, class.status
+		}
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return errorCodeInvalid, http.StatusBadRequest
+	}
+	return errorCodeInternal, http.StatusInternalServerError
+}
+
+// errorCode returns the stable code of an error
+func errorCode(err error) string {
+	code, _ := classifyError(err)
+	return code
+}
+
+// errorCounts counts surfaced errors per code for /metrics; filled once at startup
+var errorCounts = func() map[string]*atomic.Int64 {
+	counts := map[string]*atomic.Int64{
+		errorCodeInvalid:  new(atomic.Int64),
+		errorCodeInternal: new(atomic.Int64),
+	}
+	for _, class := range errorClasses {
+		if counts[class.code] == nil {
+			counts[class.code] = new(atomic.Int64)
+		}
+	}
+	return counts
+}()
+
+// recordError counts an error under its code and returns the code
+func recordError(err error) string {
+	code := errorCode(err)
+	errorCounts[code].Add(1)
+	return code
+}
+
+// errorEnvelope is the JSON shape of every error sent to clients, over HTTP or a session socket
+type errorEnvelope struct {
+	Type    string `json:"type"` // always "error"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newErrorEnvelope wraps an error for clients
+func newErrorEnvelope(err error) errorEnvelope {
+	return errorEnvelope{Type: "error", Code: errorCode(err), Message: err.Error()}
+}
+
+// httpError answers a request with the error's status and JSON envelope
+func httpError(w http.ResponseWriter, err error) {
+	recordError(err)
+	_, status := classifyError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newErrorEnvelope(err))
+}
+
+// sendError writes the JSON envelope of an error to a client socket
+func sendError(writer io.Writer, err error) error {
+	return sendJSONValue(writer, newErrorEnvelope(err))
+}
+
+// logError logs a session error with its code and counts it
+func logError(clientID, action string, err error) {
+	code := recordError(err)
+	fmt.Printf("[Client %s] Error %s: %v (code=%s)\n", clientID, action, err, code)
+}
+
+// processStartError classifies a failure to launch the C++ interface of a data type
+func processStartError(ds string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrBinaryMissing, interfaceExecutable(ds))
+	}
+	return err
+}
+
+// processExitError classifies the result of waiting on a C++ interface
+func processExitError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%w: %v", ErrProcessCrashed, err)
+	}
+	return err
+}
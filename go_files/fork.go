@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrForkOpened is returned when a fork was already opened by a client
+var ErrForkOpened = errors.New("fork already opened")
+
+// ForkResult is sent to the client after a successful fork
+type ForkResult struct {
+	Type     string `json:"type"` // "fork", or "clone" for an instructor's copy
+	Session  string `json:"session"`
+	Parent   string `json:"parent"`
+	Position int    `json:"position"`
+	URL      string `json:"url"`
+}
+
+// opFork snapshots the current structure into a new session record seeded from it
+// The client opens the fork with GET /session?fork=<id>&token=<fork token>
+func opFork(session *Session, msg clientMessage) error {
+	position, _ := session.journal.status()
+
+	// Keep the parent record current so the lineage has a shared ancestor to compare
+	session.saveRecord()
+
+	fork := &SessionRecord{
+		ID:           genID(),
+		Type:         session.DataType,
+		Flags:        session.Flags,
+		Engine:       session.Engine,
+		Language:     session.Language,
+		Owner:        session.Owner,
+		Seed:         session.Seed,
+		Parent:       session.ID,
+		ForkPosition: position,
+		Created:      time.Now(),
+		Ops:          session.journal.applied(),
+		ForkToken:    newForkToken(),
+	}
+	if err := store.saveSession(fork); err != nil {
+		return err
+	}
+
+	return session.send(ForkResult{
+		Type:     "fork",
+		Session:  fork.ID,
+		Parent:   session.ID,
+		Position: position,
+		URL:      "/session?fork=" + fork.ID + "&token=" + fork.ForkToken,
+	})
+}
+
+// newForkToken returns the secret a fork is opened with; fork IDs follow the session ID
+// strategy and may be guessable, so the ID alone does not open a fork
+func newForkToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// forkClaimMu makes checking and claiming a fork atomic
+var forkClaimMu sync.Mutex
+
+// claimFork marks a stored fork as started so it can only be opened once
+func claimFork(ID string) (*SessionRecord, error) {
+	forkClaimMu.Lock()
+	defer forkClaimMu.Unlock()
+
+	rec, err := store.loadSession(ID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Parent == "" || !rec.Started.IsZero() {
+		return nil, ErrForkOpened
+	}
+	rec.Started = time.Now()
+	return rec, store.saveSession(rec)
+}
+
+// cloneOpenableBy reports whether a request may open an instructor's clone: an admin or
+// the signed-in user who made it
+func cloneOpenableBy(rec *SessionRecord, r *http.Request) bool {
+	if adminAuthorized(r) {
+		return true
+	}
+	user := currentUserID(r)
+	return user != "" && rec.Owner == user
+}
+
+// forkOpenableBy reports whether a request may open a fork: clones by cloneOpenableBy,
+// other forks with the token of their fork URL, or by an admin or their owner
+func forkOpenableBy(rec *SessionRecord, r *http.Request) bool {
+	if rec.ClonedBy != "" {
+		return cloneOpenableBy(rec, r)
+	}
+	token := r.URL.Query().Get("token")
+	if rec.ForkToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(rec.ForkToken)) == 1 {
+		return true
+	}
+	return cloneOpenableBy(rec, r)
+}
+
+// handleForkClient starts a forked session seeded from its stored record
+func handleForkClient(w http.ResponseWriter, r *http.Request, ID string) {
+	// Refuse before claiming, so a busy server or another user does not use up the fork
+	if rec, err := store.loadSession(ID); err == nil {
+		if !forkOpenableBy(rec, r) {
+			httpError(w, ErrSessionPrivate)
+			return
+		}
+		if err := admitSession(rec.Engine); err != nil {
+			httpBusy(w)
+			return
+		}
+	}
+	rec, err := claimFork(ID)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	conn, err := upgradeClient(w, r, nil)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+
+	defer conn.Close()
+
+	fmt.Printf("[Client %s] Connected from %s (fork of %s at %d)\n",
+		rec.ID, clientAddr(r), rec.Parent, rec.ForkPosition)
+	runClientThread(rec.ID, rec.Type, rec.Flags, conn, &sessionSetup{replay: rec.Ops, record: rec, engine: rec.Engine, language: rec.Language, private: rec.ClonedBy != ""})
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientAddr returns the address of the client behind a request. Behind a trusted proxy it is
+// the last X-Forwarded-For hop not itself a trusted proxy, or X-Real-IP when nginx only sets
+// that; the headers of anyone else are ignored, since clients can send them too
+func clientAddr(r *http.Request) string {
+	if !trustedProxy(r.RemoteAddr) {
+		return r.RemoteAddr
+	}
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if i == 0 || !trustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(real) != nil {
+		return real
+	}
+	return r.RemoteAddr
+}
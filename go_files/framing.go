@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Length-prefixed framing on the raw TCP port, enabled by raw_tcp_framing: a client opens
+// with framedPreface, the server echoes it, and from then on every message either way is a
+// 4-byte big-endian length followed by that many bytes, so commands may hold any byte,
+// newlines included. Clients that open with anything else, or send nothing, keep
+// newline-separated lines.
+
+// framedPreface opens a length-prefixed connection; no command line starts with NUL
+const framedPreface = "\x00DATAS-LP/1\n"
+
+const (
+	// framedPrefaceWait is how long a silent client is given to send the preface before its
+	// connection is taken as line based
+	framedPrefaceWait = 200 * time.Millisecond
+	// framedPrefaceTimeout bounds the rest of the preface once its first byte arrived
+	framedPrefaceTimeout = 5 * time.Second
+	// maxFrameBytes bounds one client frame, as the line scanner bounds one line
+	maxFrameBytes = bufio.MaxScanTokenSize
+)
+
+// ErrFrameTooLarge is returned when a client announces a frame over maxFrameBytes
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// messageSplitter is a client socket whose input is not newline separated
+type messageSplitter interface {
+	Split() bufio.SplitFunc
+}
+
+// bufferedConn is a raw TCP connection whose first bytes were read looking for the preface
+type bufferedConn struct {
+	deadlineConn
+	reader *bufio.Reader
+}
+
+// Read implements io.Reader
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// framedConn is a raw TCP connection speaking length-prefixed frames; its frames are
+// taken apart by Split
+type framedConn struct {
+	bufferedConn
+}
+
+// Write implements io.Writer: p is one message and is sent as one frame, without the
+// newline that ends a JSON line; newlines inside the message stay in the frame
+func (c *framedConn) Write(p []byte) (int, error) {
+	message := bytes.TrimSuffix(p, []byte("\n"))
+	frame := make([]byte, 4, 4+len(message))
+	binary.BigEndian.PutUint32(frame, uint32(len(message)))
+	if _, err := c.bufferedConn.Write(append(frame, message...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Split returns the scanner split function yielding one frame per token
+func (c *framedConn) Split() bufio.SplitFunc {
+	return splitFrames
+}
+
+// splitFrames is a bufio.SplitFunc for length-prefixed frames
+func splitFrames(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) < 4 {
+		if atEOF && len(data) > 0 {
+			return 0, nil, fmt.Errorf("truncated frame header")
+		}
+		return 0, nil, nil
+	}
+	size := binary.BigEndian.Uint32(data)
+	if size > maxFrameBytes-4 {
+		return 0, nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+	end := 4 + int(size)
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, fmt.Errorf("truncated frame")
+		}
+		return 0, nil, nil
+	}
+	return end, data[4:end], nil
+}
+
+// negotiateFraming returns the socket a raw TCP client is served through: a framedConn
+// when framing is enabled and it opened with framedPreface, else a lineConn.
+// Without raw_tcp_framing nothing is read up front, so clients are served at once
+func negotiateFraming(conn net.Conn) (io.ReadWriter, error) {
+	buffered := bufferedConn{deadlineConn{conn}, bufio.NewReader(conn)}
+	if !config.RawTCPFraming {
+		return newLineConn(buffered), nil
+	}
+	conn.SetReadDeadline(time.Now().Add(framedPrefaceWait))
+	first, err := buffered.reader.Peek(1)
+	conn.SetReadDeadline(time.Time{})
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return newLineConn(buffered), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first[0] != framedPreface[0] {
+		return newLineConn(buffered), nil
+	}
+
+	conn.SetReadDeadline(time.Now().Add(framedPrefaceTimeout))
+	preface, err := buffered.reader.Peek(len(framedPreface))
+	conn.SetReadDeadline(time.Time{})
+	if err != nil || string(preface) != framedPreface {
+		return nil, errors.New("invalid framing preface")
+	}
+	buffered.reader.Discard(len(framedPreface))
+	if _, err := buffered.Write([]byte(framedPreface)); err != nil {
+		return nil, err
+	}
+	return &framedConn{buffered}, nil
+}
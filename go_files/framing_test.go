@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frame prefixes a message with its 4-byte big-endian length
+func frame(message string) string {
+	return string(binary.BigEndian.AppendUint32(nil, uint32(len(message)))) + message
+}
+
+// errTruncated marks the table cases expecting a truncated header or body
+var errTruncated = errors.New("truncated")
+
+func TestSplitFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		frames []string
+		err    error // nil, ErrFrameTooLarge, or errTruncated for the other scan errors
+	}{
+		{"empty", "", nil, nil},
+		{"one frame", frame("insert 5"), []string{"insert 5"}, nil},
+		{"several frames", frame("insert 5") + frame("delete 5") + frame("status"), []string{"insert 5", "delete 5", "status"}, nil},
+		{"newlines inside a frame", frame("insert a\nb\n"), []string{"insert a\nb\n"}, nil},
+		{"empty frame", frame("") + frame("status"), []string{"", "status"}, nil},
+		{"any bytes", frame("\x00\xff\r\n"), []string{"\x00\xff\r\n"}, nil},
+		{"largest frame", frame(strings.Repeat("x", maxFrameBytes-4)), []string{strings.Repeat("x", maxFrameBytes-4)}, nil},
+		{"too large", frame(strings.Repeat("x", maxFrameBytes-3)), nil, ErrFrameTooLarge},
+		{"huge length", "\xff\xff\xff\xff", nil, ErrFrameTooLarge},
+		{"truncated header", frame("status") + "\x00\x00", []string{"status"}, errTruncated},
+		{"truncated body", frame("status")[:6], nil, errTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(tt.input))
+			scanner.Buffer(make([]byte, 0, 64), maxFrameBytes)
+			scanner.Split(splitFrames)
+			var frames []string
+			for scanner.Scan() {
+				frames = append(frames, scanner.Text())
+			}
+			if len(frames) != len(tt.frames) {
+				t.Fatalf("got %d frames, want %d", len(frames), len(tt.frames))
+			}
+			for i := range frames {
+				if frames[i] != tt.frames[i] {
+					t.Errorf("frame %d = %q, want %q", i, frames[i], tt.frames[i])
+				}
+			}
+			err := scanner.Err()
+			switch {
+			case tt.err == nil && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err == errTruncated && (err == nil || !strings.Contains(err.Error(), "truncated")):
+				t.Errorf("got %v, want a truncated frame error", err)
+			case tt.err == ErrFrameTooLarge && !errors.Is(err, ErrFrameTooLarge):
+				t.Errorf("got %v, want ErrFrameTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestFramedConnWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"json line", `{"type":"program","message":"READY"}` + "\n", frame(`{"type":"program","message":"READY"}`)},
+		{"without newline", "status", frame("status")},
+		{"newlines inside", "a\nb\n\n", frame("a\nb\n")},
+		{"empty", "", frame("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			conn := &framedConn{bufferedConn{deadlineConn{server}, bufio.NewReader(server)}}
+			go func() {
+				n, err := conn.Write([]byte(tt.message))
+				if err != nil || n != len(tt.message) {
+					t.Errorf("Write = %d, %v; want %d, nil", n, err, len(tt.message))
+				}
+				server.Close()
+			}()
+			got, _ := io.ReadAll(client)
+			if string(got) != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateFraming(t *testing.T) {
+	defer func(enabled bool) { config.RawTCPFraming = enabled }(config.RawTCPFraming)
+	tests := []struct {
+		name    string
+		enabled bool
+		opening string
+		framed  bool
+		err     bool
+	}{
+		{"disabled", false, framedPreface, false, false},
+		{"preface", true, framedPreface, true, false},
+		{"line client", true, "insert 5\n", false, false},
+		{"silent client", true, "", false, false},
+		{"bad preface", true, "\x00DATAS-XX/1\n", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.RawTCPFraming = tt.enabled
+			server, client := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			echoed := make(chan []byte, 1)
+			go func() {
+				client.Write([]byte(tt.opening))
+				if tt.framed {
+					reply := make([]byte, len(framedPreface))
+					io.ReadFull(client, reply)
+					echoed <- reply
+				}
+			}()
+
+			socket, err := negotiateFraming(server)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := socket.(*framedConn); ok != tt.framed {
+				t.Fatalf("socket is %T, framed = %v", socket, tt.framed)
+			}
+			if tt.framed {
+				select {
+				case reply := <-echoed:
+					if !bytes.Equal(reply, []byte(framedPreface)) {
+						t.Errorf("echoed %q, want the preface", reply)
+					}
+				case <-time.After(time.Second):
+					t.Error("preface was not echoed")
+				}
+			}
+		})
+	}
+}
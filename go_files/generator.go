@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxGenKeys caps how many keys one gen command may produce
+	maxGenKeys = 100000
+	// genInsertsPerSecond is the rate at which generated inserts are fed to the process
+	genInsertsPerSecond = 100
+)
+
+// cmdGen generates N random keys (optionally from a fixed seed) and inserts them,
+// or runs a workload file from config.WorkloadDir
+// Usage: gen <count> [seed] | gen <workload> [seed]
+func cmdGen(session *Session, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return &ValidationError{"usage=gen_<count|workload>_[seed]"}
+	}
+	workload, err := genWorkload(session, args[0])
+	if err != nil {
+		return err
+	}
+	seed := workload.Seed
+	if seed == 0 {
+		seed = session.genSeed()
+	}
+	if len(args) == 2 {
+		if seed, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return &ValidationError{"Invalid seed. Must be integer"}
+		}
+	}
+
+	if !session.generating.CompareAndSwap(false, true) {
+		return &ValidationError{"A generator is already running"}
+	}
+	count := workload.Prefill + workload.Operations
+	if workload.Name == "" {
+		session.reply(fmt.Sprintf("GEN_START count=%d seed=%d", count, seed))
+	} else {
+		session.reply(fmt.Sprintf("GEN_START workload=%s count=%d seed=%d", workload.Name, count, seed))
+	}
+	go session.runGenerator(workload, seed)
+	return nil
+}
+
+// genSeed returns the seed of the session's next gen run without one; the n-th run
+// of a session always gets the same seed, so replaying its transcript regenerates the keys
+func (s *Session) genSeed() int64 {
+	return s.Seed + s.genRuns.Add(1) - 1
+}
+
+// genWorkload returns the workload a gen argument names; a count is a workload of
+// that many uniform inserts
+func genWorkload(session *Session, arg string) (*Workload, error) {
+	if count, err := strconv.Atoi(arg); err == nil {
+		if count < 1 || count > maxGenKeys {
+			return nil, &ValidationError{fmt.Sprintf("Invalid count. Must be integer between 1 and %d", maxGenKeys)}
+		}
+		workload := &Workload{Operations: count}
+		return workload, workload.validate()
+	}
+	workload, err := loadWorkload(arg)
+	if err != nil {
+		return nil, err
+	}
+	if workload.Type != "" && workload.Type != session.DataType {
+		return nil, &ValidationError{fmt.Sprintf("Workload %s is written for %s, not %s", workload.Name, workload.Type, session.DataType)}
+	}
+	return workload, nil
+}
+
+// runGenerator feeds the commands of a workload into the bulk queue at its rate
+func (s *Session) runGenerator(workload *Workload, seed int64) {
+	defer s.generating.Store(false)
+
+	keyType := keyInt
+	if registered, ok := lookupDataStructure(s.DataType); ok {
+		keyType = registered.keyType()
+	}
+	lines := workload.commands(seed, keyType)
+	ticker := time.NewTicker(time.Second / time.Duration(workload.Rate))
+	defer ticker.Stop()
+
+	for _, line := range lines {
+		select {
+		case <-ticker.C:
+		case <-s.inputClosed:
+			return
+		}
+
+		msg := clientMessage{Op: "command", Command: line}
+		select {
+		case s.bulkQueue <- msg:
+		case <-s.inputClosed:
+			return
+		}
+	}
+	if workload.Name == "" {
+		s.reply(fmt.Sprintf("GEN_DONE count=%d seed=%d", len(lines), seed))
+	} else {
+		s.reply(fmt.Sprintf("GEN_DONE workload=%s count=%d seed=%d", workload.Name, len(lines), seed))
+	}
+}
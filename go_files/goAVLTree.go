@@ -0,0 +1,493 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// avlNode is an AVL node of the Go engine, mirroring LogAVLTree<T>::LogAVLNode
+type avlNode[K engineKey] struct {
+	addr   uintptr
+	data   K
+	left   *avlNode[K]
+	right  *avlNode[K]
+	height int
+}
+
+// goAVLTree is a port of LogAVLTree and AVLTreeInterface: same algorithm, same log lines
+type goAVLTree[K engineKey] struct {
+	out   *engineOutput
+	addrs engineAddresses
+	root  *avlNode[K]
+	size  int
+
+	allowDuplicates  bool
+	initial          []K // inserted on every init
+	verboseRotations bool
+}
+
+// newGoAVLTree builds an AVL tree engine over the given key type from the avltree interface flags
+func newGoAVLTree(keyType, flags string, out *engineOutput) (goEngine, error) {
+	allowDuplicates, verboseRotations := false, false
+	var initial []string
+	args := strings.Fields(flags)
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			break
+		}
+		switch args[i] {
+		case "--duplicates":
+			i++
+			if args[i] != "reject" && args[i] != "allow" {
+				return nil, &ValidationError{"Invalid duplicates. Must be one of: reject, allow"}
+			}
+			allowDuplicates = args[i] == "allow"
+		case "--initial":
+			i++
+			initial = strings.Split(args[i], ",")
+		case "--verbose-rotations":
+			i++
+			if args[i] != "on" && args[i] != "off" {
+				return nil, &ValidationError{"Invalid verbose_rotations. Must be one of: on, off"}
+			}
+			verboseRotations = args[i] == "on"
+		}
+	}
+	switch keyType {
+	case keyFloat:
+		return buildGoAVLTree[float64](out, allowDuplicates, initial, verboseRotations)
+	case keyString:
+		return buildGoAVLTree[stringKey](out, allowDuplicates, initial, verboseRotations)
+	}
+	return buildGoAVLTree[int](out, allowDuplicates, initial, verboseRotations)
+}
+
+// buildGoAVLTree parses the initial values as keys of the tree
+func buildGoAVLTree[K engineKey](out *engineOutput, allowDuplicates bool, initial []string, verboseRotations bool) (goEngine, error) {
+	t := &goAVLTree[K]{out: out, allowDuplicates: allowDuplicates, verboseRotations: verboseRotations}
+	for _, item := range initial {
+		value, ok := parseEngineKey[K]([]string{item})
+		if !ok {
+			return nil, &ValidationError{"Invalid initial. Must be a comma-separated list of keys"}
+		}
+		t.initial = append(t.initial, value)
+	}
+	return t, nil
+}
+
+func (t *goAVLTree[K]) start() string {
+	t.init()
+	return "READY type=AVL"
+}
+
+func (t *goAVLTree[K]) init() {
+	t.root = nil
+	t.size = 0
+	t.out.logs.Reset()
+
+	// The initial values' logs are forwarded so clients see the tree form
+	mark := t.out.mark()
+	for _, value := range t.initial {
+		if !t.allowDuplicates && t.existInTree(value) {
+			continue
+		}
+		t.insert(value)
+		t.size++
+	}
+	t.out.say("INIT_SUCCESS type=AVL size=%d", t.size)
+	t.out.forward(mark)
+}
+
+func (t *goAVLTree[K]) handle(command string, args []string) bool {
+	value, ok := parseEngineKey[K](args)
+	switch command {
+	case "quit", "exit":
+		t.out.say("GOODBYE")
+		return false
+	case "insert":
+		if !ok {
+			t.out.say("ERROR invalid_insert_syntax usage=insert_<value>")
+			break
+		}
+		if !t.allowDuplicates && t.existInTree(value) {
+			t.out.say("INSERT_DUPLICATE value=%v size=%d", value, t.size)
+			break
+		}
+		mark := t.out.mark()
+		t.insert(value)
+		t.size++
+		t.out.say("INSERT_SUCCESS value=%v new_size=%d", value, t.size)
+		t.out.forward(mark)
+	case "remove":
+		if !ok {
+			t.out.say("ERROR invalid_remove_syntax usage=remove_<value>")
+			break
+		}
+		if !t.existInTree(value) {
+			t.out.say("REMOVE_NOT_FOUND value=%v size=%d", value, t.size)
+			break
+		}
+		mark := t.out.mark()
+		if t.remove(value) {
+			t.size--
+			t.out.say("REMOVE_SUCCESS value=%v new_size=%d", value, t.size)
+		} else {
+			t.out.say("REMOVE_FAILED value=%v size=%d", value, t.size)
+		}
+		t.out.forward(mark)
+	case "find", "search":
+		if !ok {
+			t.out.say("ERROR invalid_find_syntax usage=find_<value>")
+			break
+		}
+		mark := t.out.mark()
+		t.out.say("FIND_RESULT value=%v found=%t", value, t.existInTree(value))
+		t.out.forward(mark)
+	case "print", "show":
+		values := []string{}
+		t.inorder(t.root, &values)
+		t.out.say("TREE_INORDER_START")
+		if len(values) > 0 {
+			t.out.say("%s", strings.Join(values, " "))
+		}
+		t.out.say("TREE_INORDER_END")
+	case "structure":
+		t.out.say("TREE_STRUCTURE_START")
+		t.out.say("LogAVLTree Structure:")
+		if t.root == nil {
+			t.out.say("└── (empty)")
+		} else {
+			t.printNodeStructure(t.root, "", true)
+		}
+		t.out.say("TREE_STRUCTURE_END")
+	case "size":
+		t.out.say("SIZE %d", t.size)
+	case "status":
+		duplicates := "reject"
+		if t.allowDuplicates {
+			duplicates = "allow"
+		}
+		t.out.say("STATUS tree_size=%d type=AVL duplicates=%s", t.size, duplicates)
+	case "init":
+		t.init()
+	default:
+		if !t.out.common(command) {
+			t.out.say("ERROR unknown_command=%s use_help_for_commands", command)
+		}
+	}
+	return true
+}
+
+func (t *goAVLTree[K]) inorder(node *avlNode[K], values *[]string) {
+	if node == nil {
+		return
+	}
+	t.inorder(node.left, values)
+	*values = append(*values, fmt.Sprint(node.data))
+	t.inorder(node.right, values)
+}
+
+func (t *goAVLTree[K]) printNodeStructure(node *avlNode[K], prefix string, isLast bool) {
+	branch, indent := "├── ", "│   "
+	if isLast {
+		branch, indent = "└── ", "    "
+	}
+	if node == nil {
+		t.out.say("%s%snull", prefix, branch)
+		return
+	}
+	t.out.say("%s%s%v", prefix, branch, node.data)
+	if node.left != nil || node.right != nil {
+		t.printNodeStructure(node.left, prefix+indent, node.right == nil)
+		t.printNodeStructure(node.right, prefix+indent, true)
+	}
+}
+
+// addrOf returns the printable address of a possibly nil node
+func (n *avlNode[K]) addrOf() string {
+	if n == nil {
+		return formatAddress(0)
+	}
+	return formatAddress(n.addr)
+}
+
+func (n *avlNode[K]) heightOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *avlNode[K]) balanceFactor() int {
+	if n == nil {
+		return 0
+	}
+	return n.left.heightOf() - n.right.heightOf()
+}
+
+func (n *avlNode[K]) updateHeight() {
+	n.height = 1 + max(n.left.heightOf(), n.right.heightOf())
+}
+
+func (t *goAVLTree[K]) newNode(value K) *avlNode[K] {
+	return &avlNode[K]{addr: t.addrs.alloc(), data: value, height: 1}
+}
+
+func (t *goAVLTree[K]) rotateRight(node *avlNode[K]) *avlNode[K] {
+	var leftRight *avlNode[K]
+	if node.left != nil {
+		leftRight = node.left.right
+	}
+	t.out.logf("[ROTATE_RIGHT] node=%s left=%s left_right=%s", node.addrOf(), node.left.addrOf(), leftRight.addrOf())
+
+	newRoot := node.left
+	node.left = newRoot.right
+	newRoot.right = node
+	node.updateHeight()
+	newRoot.updateHeight()
+
+	t.out.logf("[POINTER_CHANGE] %s.left=%s", node.addrOf(), node.left.addrOf())
+	t.out.logf("[POINTER_CHANGE] %s.right=%s", newRoot.addrOf(), node.addrOf())
+	return newRoot
+}
+
+func (t *goAVLTree[K]) rotateLeft(node *avlNode[K]) *avlNode[K] {
+	var rightLeft *avlNode[K]
+	if node.right != nil {
+		rightLeft = node.right.left
+	}
+	t.out.logf("[ROTATE_LEFT] node=%s right=%s right_left=%s", node.addrOf(), node.right.addrOf(), rightLeft.addrOf())
+
+	newRoot := node.right
+	node.right = newRoot.left
+	newRoot.left = node
+	node.updateHeight()
+	newRoot.updateHeight()
+
+	t.out.logf("[POINTER_CHANGE] %s.right=%s", node.addrOf(), node.right.addrOf())
+	t.out.logf("[POINTER_CHANGE] %s.left=%s", newRoot.addrOf(), node.addrOf())
+	return newRoot
+}
+
+func (t *goAVLTree[K]) balance(node *avlNode[K]) *avlNode[K] {
+	node.updateHeight()
+	factor := node.balanceFactor()
+	if t.verboseRotations && (factor > 1 || factor < -1) {
+		rotationCase := "LL"
+		switch {
+		case factor > 1 && node.left.balanceFactor() < 0:
+			rotationCase = "LR"
+		case factor < -1 && node.right.balanceFactor() > 0:
+			rotationCase = "RL"
+		case factor < -1:
+			rotationCase = "RR"
+		}
+		t.out.logf("[IMBALANCE] node=%s value=%v balance=%d case=%s", node.addrOf(), node.data, factor, rotationCase)
+	}
+	if factor > 1 {
+		if node.left.balanceFactor() < 0 {
+			node.left = t.rotateLeft(node.left)
+		}
+		return t.rotateRight(node)
+	}
+	if factor < -1 {
+		if node.right.balanceFactor() > 0 {
+			node.right = t.rotateRight(node.right)
+		}
+		return t.rotateLeft(node)
+	}
+	return node
+}
+
+func (t *goAVLTree[K]) insertNode(node *avlNode[K], value K) *avlNode[K] {
+	if value < node.data {
+		t.out.logf("[INSERT] node=%s value=%v direction=left", node.addrOf(), value)
+		if node.left == nil {
+			node.left = t.newNode(value)
+			t.out.logf("[NODE_CREATE] address=%s value=%v", node.left.addrOf(), value)
+			t.out.logf("[POINTER_CHANGE] %s.left=%s", node.addrOf(), node.left.addrOf())
+		} else {
+			old := node.left
+			node.left = t.insertNode(old, value)
+			if old != node.left {
+				t.out.logf("[POINTER_CHANGE] %s.left=%s", node.addrOf(), node.left.addrOf())
+			}
+		}
+	} else {
+		t.out.logf("[INSERT] node=%s value=%v direction=right", node.addrOf(), value)
+		if node.right == nil {
+			node.right = t.newNode(value)
+			t.out.logf("[NODE_CREATE] address=%s value=%v", node.right.addrOf(), value)
+			t.out.logf("[POINTER_CHANGE] %s.right=%s", node.addrOf(), node.right.addrOf())
+		} else {
+			old := node.right
+			node.right = t.insertNode(old, value)
+			if old != node.right {
+				t.out.logf("[POINTER_CHANGE] %s.right=%s", node.addrOf(), node.right.addrOf())
+			}
+		}
+	}
+	return t.balance(node)
+}
+
+func (t *goAVLTree[K]) findNode(node *avlNode[K], value K) *avlNode[K] {
+	switch {
+	case value == node.data:
+		t.out.logf("[FIND] node=%s searching=%v result=FOUND", node.addrOf(), value)
+		return node
+	case value < node.data:
+		t.out.logf("[FIND] node=%s searching=%v direction=left", node.addrOf(), value)
+		if node.left == nil {
+			return nil
+		}
+		return t.findNode(node.left, value)
+	default:
+		t.out.logf("[FIND] node=%s searching=%v direction=right", node.addrOf(), value)
+		if node.right == nil {
+			return nil
+		}
+		return t.findNode(node.right, value)
+	}
+}
+
+// findNextLeft returns the inorder predecessor of node and its depth below node.left
+func (t *goAVLTree[K]) findNextLeft(node *avlNode[K], depth *int) *avlNode[K] {
+	t.out.logf("[FIND_PREDECESSOR] start=%s", node.addrOf())
+	result := node
+	if leaf := node.left; leaf != nil {
+		d := 0
+		for leaf.right != nil {
+			leaf = leaf.right
+			d++
+		}
+		*depth = d
+		result = leaf
+	}
+	t.out.logf("[FIND_PREDECESSOR] result=%s depth=%d", result.addrOf(), *depth)
+	return result
+}
+
+// findNextRight returns the inorder successor of node and its depth below node.right
+func (t *goAVLTree[K]) findNextRight(node *avlNode[K], depth *int) *avlNode[K] {
+	t.out.logf("[FIND_SUCCESSOR] start=%s", node.addrOf())
+	result := node
+	if leaf := node.right; leaf != nil {
+		d := 0
+		for leaf.left != nil {
+			leaf = leaf.left
+			d++
+		}
+		*depth = d
+		result = leaf
+	}
+	t.out.logf("[FIND_SUCCESSOR] result=%s depth=%d", result.addrOf(), *depth)
+	return result
+}
+
+// removeItem removes value below node and returns the new subtree root
+// found is false when value is missing; nothing was changed then
+func (t *goAVLTree[K]) removeItem(node *avlNode[K], value K) (*avlNode[K], bool) {
+	if node == nil {
+		return nil, false
+	}
+	t.out.logf("[REMOVE] node=%s searching=%v", node.addrOf(), value)
+
+	if value < node.data {
+		child, found := t.removeItem(node.left, value)
+		if !found {
+			return node, false
+		}
+		old := node.left
+		node.left = child
+		if old != child {
+			t.out.logf("[POINTER_CHANGE] %s.left=%s", node.addrOf(), node.left.addrOf())
+		}
+		return t.balance(node), true
+	}
+	if value > node.data {
+		child, found := t.removeItem(node.right, value)
+		if !found {
+			return node, false
+		}
+		old := node.right
+		node.right = child
+		if old != child {
+			t.out.logf("[POINTER_CHANGE] %s.right=%s", node.addrOf(), node.right.addrOf())
+		}
+		return t.balance(node), true
+	}
+
+	t.out.logf("[REMOVE_FOUND] node=%s value=%v", node.addrOf(), node.data)
+	switch {
+	case node.left == nil && node.right == nil:
+		t.out.logf("[NODE_DELETE] address=%s value=%v type=leaf", node.addrOf(), node.data)
+		return nil, true
+	case node.left == nil:
+		t.out.logf("[NODE_DELETE] address=%s value=%v type=right_only replacement=%s", node.addrOf(), node.data, node.right.addrOf())
+		return node.right, true
+	case node.right == nil:
+		t.out.logf("[NODE_DELETE] address=%s value=%v type=left_only replacement=%s", node.addrOf(), node.data, node.left.addrOf())
+		return node.left, true
+	}
+
+	depthLeft, depthRight := 0, 0
+	nextLeft := t.findNextLeft(node, &depthLeft)
+	nextRight := t.findNextRight(node, &depthRight)
+	oldValue := node.data
+	if depthLeft > depthRight {
+		node.data = nextLeft.data
+		t.out.logf("[DATA_CHANGE] node=%s old_value=%v new_value=%v", node.addrOf(), oldValue, node.data)
+		child, _ := t.removeItem(node.left, node.data)
+		old := node.left
+		node.left = child
+		if old != child {
+			t.out.logf("[POINTER_CHANGE] %s.left=%s", node.addrOf(), node.left.addrOf())
+		}
+	} else {
+		node.data = nextRight.data
+		t.out.logf("[DATA_CHANGE] node=%s old_value=%v new_value=%v", node.addrOf(), oldValue, node.data)
+		child, _ := t.removeItem(node.right, node.data)
+		old := node.right
+		node.right = child
+		if old != child {
+			t.out.logf("[POINTER_CHANGE] %s.right=%s", node.addrOf(), node.right.addrOf())
+		}
+	}
+	return t.balance(node), true
+}
+
+func (t *goAVLTree[K]) existInTree(value K) bool {
+	t.out.logf("[TREE_FIND] value=%v", value)
+	found := t.root != nil && t.findNode(t.root, value) != nil
+	t.out.logf("[TREE_FIND_RESULT] value=%v found=%t", value, found)
+	return found
+}
+
+func (t *goAVLTree[K]) insert(value K) {
+	t.out.logf("[TREE_INSERT] value=%v", value)
+	if t.root == nil {
+		t.root = t.newNode(value)
+		t.out.logf("[ROOT_CREATE] address=%s value=%v", t.root.addrOf(), value)
+		return
+	}
+	old := t.root
+	t.root = t.insertNode(t.root, value)
+	if old != t.root {
+		t.out.logf("[ROOT_CHANGE] old=%s new=%s", old.addrOf(), t.root.addrOf())
+	}
+}
+
+func (t *goAVLTree[K]) remove(value K) bool {
+	t.out.logf("[TREE_REMOVE] value=%v", value)
+	old := t.root
+	root, found := t.removeItem(t.root, value)
+	if !found {
+		t.out.logf("[TREE_REMOVE_FAILED] value=%v", value)
+		return false
+	}
+	t.root = root
+	if old != t.root {
+		t.out.logf("[ROOT_CHANGE] old=%s new=%s", old.addrOf(), t.root.addrOf())
+	}
+	return true
+}
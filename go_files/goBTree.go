@@ -0,0 +1,453 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bNode is a B-tree node of the Go engine, mirroring BTree<T>::BNode
+type bNode[K engineKey] struct {
+	addr     uintptr
+	isLeaf   bool
+	keys     []K
+	children []*bNode[K]
+}
+
+// goBTree is a port of LogBTree and BTreeInterface: same algorithm, same log lines
+type goBTree[K engineKey] struct {
+	out     *engineOutput
+	addrs   engineAddresses
+	order   int // max number of children a node can have
+	minKeys int // min number of keys in a non-root node
+	root    *bNode[K]
+	size    int
+}
+
+// newGoBTree builds a B-tree engine over the given key type from the btree interface flags
+func newGoBTree(keyType, flags string, out *engineOutput) (goEngine, error) {
+	order := 4
+	args := strings.Fields(flags)
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--order" && i+1 < len(args) {
+			i++
+			parsed, err := strconv.Atoi(args[i])
+			if err != nil || parsed < 3 {
+				return nil, &ValidationError{"Invalid order. Must be integer >= 3"}
+			}
+			order = parsed
+		}
+	}
+	switch keyType {
+	case keyFloat:
+		return &goBTree[float64]{out: out, order: order}, nil
+	case keyString:
+		return &goBTree[stringKey]{out: out, order: order}, nil
+	}
+	return &goBTree[int]{out: out, order: order}, nil
+}
+
+func (t *goBTree[K]) start() string {
+	t.init(t.order)
+	return fmt.Sprintf("READY order=%d", t.order)
+}
+
+func (t *goBTree[K]) init(order int) {
+	t.order = order
+	t.minKeys = (order+1)/2 - 1
+	t.root = t.newNode(true)
+	t.size = 0
+	t.out.logs.Reset()
+	t.out.say("INIT_SUCCESS order=%d size=%d", t.order, t.size)
+}
+
+func (t *goBTree[K]) handle(command string, args []string) bool {
+	value, ok := parseEngineKey[K](args)
+	switch command {
+	case "quit", "exit", "q":
+		t.out.say("GOODBYE")
+		return false
+	case "insert":
+		if !ok {
+			t.out.say("ERROR invalid_insert_syntax usage=insert_<value>")
+			break
+		}
+		mark := t.out.mark()
+		t.insert(value)
+		t.size++
+		t.out.say("INSERT_SUCCESS value=%v new_size=%d", value, t.size)
+		t.out.forward(mark)
+	case "remove":
+		if !ok {
+			t.out.say("ERROR invalid_remove_syntax usage=remove_<value>")
+			break
+		}
+		if !t.find(value) {
+			t.out.say("REMOVE_NOT_FOUND value=%v size=%d", value, t.size)
+			break
+		}
+		mark := t.out.mark()
+		t.remove(value)
+		t.size--
+		t.out.say("REMOVE_SUCCESS value=%v new_size=%d", value, t.size)
+		t.out.forward(mark)
+	case "find", "search":
+		if !ok {
+			t.out.say("ERROR invalid_find_syntax usage=find_<value>")
+			break
+		}
+		mark := t.out.mark()
+		t.out.say("FIND_RESULT value=%v found=%t", value, t.find(value))
+		t.out.forward(mark)
+	case "print", "show":
+		t.out.say("TREE_START")
+		t.printNode(t.root, 0)
+		t.out.say("TREE_END")
+	case "size":
+		t.out.say("SIZE %d", t.size)
+	case "order":
+		t.out.say("ORDER %d", t.order)
+	case "status":
+		t.out.say("STATUS tree_size=%d order=%d root=initialized", t.size, t.order)
+	case "init":
+		// A bare init empties the tree and keeps its order
+		order, ok := t.order, true
+		if len(args) > 0 {
+			order, ok = streamInt(args)
+		}
+		if !ok || order < 3 {
+			t.out.say("ERROR invalid_init_syntax usage=init_<order> order_must_be_>=3")
+			break
+		}
+		t.init(order)
+	default:
+		if !t.out.common(command) {
+			t.out.say("ERROR unknown_command=%s use_help_for_commands", command)
+		}
+	}
+	return true
+}
+
+// printNode prints a subtree indented four spaces per level, like print_bnode
+func (t *goBTree[K]) printNode(node *bNode[K], level int) {
+	t.out.say("%s[%s]", strings.Repeat(" ", level*4), joinKeys(node.keys, ", "))
+	if !node.isLeaf {
+		for _, child := range node.children {
+			t.printNode(child, level+1)
+		}
+	}
+}
+
+func (t *goBTree[K]) newNode(isLeaf bool) *bNode[K] {
+	return &bNode[K]{addr: t.addrs.alloc(), isLeaf: isLeaf}
+}
+
+// addrOf returns the printable address of a possibly nil node
+func (n *bNode[K]) addrOf() string {
+	if n == nil {
+		return formatAddress(0)
+	}
+	return formatAddress(n.addr)
+}
+
+func (t *goBTree[K]) logNodeState(node *bNode[K], context string) {
+	children := make([]string, len(node.children))
+	for i, child := range node.children {
+		children[i] = child.addrOf()
+	}
+	t.out.logf("[NODE_STATE] %s node=%s is_leaf=%t keys_count=%d children_count=%d keys=[%s] children=[%s]",
+		context, node.addrOf(), node.isLeaf, len(node.keys), len(node.children),
+		joinKeys(node.keys, ","), strings.Join(children, ","))
+}
+
+func (t *goBTree[K]) logParentChild(parent *bNode[K], index int, context string) {
+	if index >= len(parent.children) {
+		return
+	}
+	t.out.logf("[PARENT_CHILD] %s parent=%s child_index=%d child=%s",
+		context, parent.addrOf(), index, parent.children[index].addrOf())
+}
+
+func (t *goBTree[K]) keyIndex(node *bNode[K], value K) int {
+	idx := 0
+	for idx < len(node.keys) && node.keys[idx] < value {
+		idx++
+	}
+	t.out.logf("[find Index] search index for val=%v in node=%s: found index=%d", value, node.addrOf(), idx)
+	return idx
+}
+
+func (t *goBTree[K]) findVal(node *bNode[K], value K) bool {
+	idx := t.keyIndex(node, value)
+	if idx < len(node.keys) && node.keys[idx] == value {
+		return true
+	}
+	if node.isLeaf {
+		return false
+	}
+	return t.findVal(node.children[idx], value)
+}
+
+func (t *goBTree[K]) splitSibling(node *bNode[K]) (*bNode[K], K) {
+	t.out.logf("[Split Sibling] node=%s keys_size=%d", node.addrOf(), len(node.keys))
+	t.logNodeState(node, "BEFORE_SPLIT")
+
+	sibling := t.newNode(node.isLeaf)
+	mid := (t.order - 1) / 2
+	midVal := node.keys[mid]
+	sibling.keys = append([]K{}, node.keys[mid+1:]...)
+	node.keys = node.keys[:mid]
+	if !node.isLeaf {
+		sibling.children = append([]*bNode[K]{}, node.children[mid+1:]...)
+		node.children = node.children[:mid+1]
+	}
+
+	t.out.logf("[Split Result] original_node=%s new_sibling=%s mid_val=%v", node.addrOf(), sibling.addrOf(), midVal)
+	t.out.logf("[Split Keys] original_node=%s original_keys=[%s] new_sibling=%s new_keys=[%s]",
+		node.addrOf(), joinKeys(node.keys, ","), sibling.addrOf(), joinKeys(sibling.keys, ","))
+	t.logNodeState(node, "AFTER_SPLIT_ORIGINAL")
+	t.logNodeState(sibling, "AFTER_SPLIT_NEW")
+	return sibling, midVal
+}
+
+func (t *goBTree[K]) splitChild(node *bNode[K], index int) {
+	child := node.children[index]
+	t.out.logf("[Split Child] parent=%s child_index=%d child=%s", node.addrOf(), index, child.addrOf())
+	t.logNodeState(node, "PARENT_BEFORE_SPLIT")
+	t.logNodeState(child, "CHILD_BEFORE_SPLIT")
+
+	sibling, midVal := t.splitSibling(child)
+	node.children = insertAt(node.children, index+1, sibling)
+	node.keys = insertAt(node.keys, index, midVal)
+
+	t.out.logf("[Split Child Result] parent=%s left_child=%s right_child=%s promoted_key=%v",
+		node.addrOf(), node.children[index].addrOf(), node.children[index+1].addrOf(), node.keys[index])
+	t.logNodeState(node, "PARENT_AFTER_SPLIT")
+	t.logParentChild(node, index, "LEFT_CHILD_AFTER_SPLIT")
+	t.logParentChild(node, index+1, "RIGHT_CHILD_AFTER_SPLIT")
+}
+
+func (t *goBTree[K]) insertVal(node *bNode[K], value K) {
+	t.out.logf("[Insert Val] node=%s value=%v", node.addrOf(), value)
+	t.logNodeState(node, "BEFORE_INSERT")
+
+	idx := t.keyIndex(node, value)
+	if node.isLeaf {
+		t.out.logf("[Insert Leaf] node=%s inserting key=%v at index=%d", node.addrOf(), value, idx)
+		node.keys = insertAt(node.keys, idx, value)
+		t.logNodeState(node, "AFTER_INSERT_LEAF")
+		return
+	}
+
+	t.out.logf("[Insert Internal] node=%s going to child at index=%d child=%s",
+		node.addrOf(), idx, node.children[idx].addrOf())
+	t.logParentChild(node, idx, "INSERT_GOING_TO_CHILD")
+	if len(node.children[idx].keys) == t.order-1 {
+		t.out.logf("[Insert Split] child=%s is full, splitting before insertion", node.children[idx].addrOf())
+		t.splitChild(node, idx)
+		idx = t.keyIndex(node, value)
+		t.out.logf("[Insert After Split] new index=%d going to child=%s", idx, node.children[idx].addrOf())
+		t.logParentChild(node, idx, "INSERT_AFTER_SPLIT")
+	}
+	t.insertVal(node.children[idx], value)
+}
+
+func (t *goBTree[K]) mergeSiblings(node *bNode[K], idx int) {
+	left, right := node.children[idx], node.children[idx+1]
+	t.out.logf("[Merge Siblings] parent=%s left=%s right=%s key_to_merge=%v",
+		node.addrOf(), left.addrOf(), right.addrOf(), node.keys[idx])
+	t.logNodeState(node, "PARENT_BEFORE_MERGE")
+	t.logNodeState(left, "LEFT_BEFORE_MERGE")
+	t.logNodeState(right, "RIGHT_BEFORE_MERGE")
+
+	left.keys = append(append(left.keys, node.keys[idx]), right.keys...)
+	if !left.isLeaf {
+		left.children = append(left.children, right.children...)
+	}
+	node.keys = removeAt(node.keys, idx)
+	node.children = removeAt(node.children, idx+1)
+
+	t.out.logf("[Merge Result] merged_node=%s deleted_node=%s", left.addrOf(), right.addrOf())
+	t.logNodeState(node, "PARENT_AFTER_MERGE")
+	t.logNodeState(left, "MERGED_NODE")
+}
+
+func (t *goBTree[K]) borrowFromLeft(node *bNode[K], idx int) {
+	left, right := node.children[idx-1], node.children[idx]
+	t.out.logf("[Borrow Left] Move from left=%s key=%v to father=%s and move key=%v to right=%s",
+		left.addrOf(), left.keys[len(left.keys)-1], node.addrOf(), node.keys[idx-1], right.addrOf())
+	t.logNodeState(node, "PARENT_BEFORE_BORROW_LEFT")
+	t.logNodeState(left, "LEFT_BEFORE_BORROW")
+	t.logNodeState(right, "RIGHT_BEFORE_BORROW")
+	if !left.isLeaf && len(left.children) > 0 {
+		t.out.logf("[Borrow Left] Move child=%s to start of right", left.children[len(left.children)-1].addrOf())
+	}
+
+	right.keys = insertAt(right.keys, 0, node.keys[idx-1])
+	node.keys[idx-1] = left.keys[len(left.keys)-1]
+	left.keys = left.keys[:len(left.keys)-1]
+	if !right.isLeaf {
+		right.children = insertAt(right.children, 0, left.children[len(left.children)-1])
+		left.children = left.children[:len(left.children)-1]
+	}
+
+	t.logNodeState(node, "PARENT_AFTER_BORROW_LEFT")
+	t.logNodeState(left, "LEFT_AFTER_BORROW")
+	t.logNodeState(right, "RIGHT_AFTER_BORROW")
+}
+
+func (t *goBTree[K]) borrowFromRight(node *bNode[K], idx int) {
+	left, right := node.children[idx], node.children[idx+1]
+	t.out.logf("[Borrow Right] Move from right=%s key=%v to father=%s and move key=%v to left=%s",
+		right.addrOf(), right.keys[0], node.addrOf(), node.keys[idx], left.addrOf())
+	t.logNodeState(node, "PARENT_BEFORE_BORROW_RIGHT")
+	t.logNodeState(left, "LEFT_BEFORE_BORROW")
+	t.logNodeState(right, "RIGHT_BEFORE_BORROW")
+	if !left.isLeaf && len(right.children) > 0 {
+		t.out.logf("[Borrow Right] Move child=%s to end of left", right.children[0].addrOf())
+	}
+
+	left.keys = append(left.keys, node.keys[idx])
+	node.keys[idx] = right.keys[0]
+	right.keys = removeAt(right.keys, 0)
+	if !left.isLeaf {
+		left.children = append(left.children, right.children[0])
+		right.children = removeAt(right.children, 0)
+	}
+
+	t.logNodeState(node, "PARENT_AFTER_BORROW_RIGHT")
+	t.logNodeState(left, "LEFT_AFTER_BORROW")
+	t.logNodeState(right, "RIGHT_AFTER_BORROW")
+}
+
+func (t *goBTree[K]) fixChild(node *bNode[K], idx int) {
+	switch {
+	case len(node.children[idx].keys) >= t.minKeys:
+	case idx > 0 && len(node.children[idx-1].keys) > t.minKeys:
+		t.borrowFromLeft(node, idx)
+	case idx < len(node.children)-1 && len(node.children[idx+1].keys) > t.minKeys:
+		t.borrowFromRight(node, idx)
+	case idx < len(node.children)-1:
+		t.mergeSiblings(node, idx)
+	default:
+		t.mergeSiblings(node, idx-1)
+	}
+}
+
+func (t *goBTree[K]) findSuc(node *bNode[K]) K {
+	for !node.isLeaf {
+		node = node.children[0]
+	}
+	return node.keys[0]
+}
+
+func (t *goBTree[K]) findPred(node *bNode[K]) K {
+	for !node.isLeaf {
+		node = node.children[len(node.keys)]
+	}
+	return node.keys[len(node.keys)-1]
+}
+
+func (t *goBTree[K]) removeVal(node *bNode[K], value K) {
+	t.out.logf("[Remove Val] node=%s searching=%v", node.addrOf(), value)
+	t.logNodeState(node, "BEFORE_REMOVE")
+
+	idx := t.keyIndex(node, value)
+	if node.isLeaf {
+		if idx < len(node.keys) && node.keys[idx] == value {
+			t.out.logf("[Remove Leaf] node=%s removing key=%v at index=%d", node.addrOf(), value, idx)
+			node.keys = removeAt(node.keys, idx)
+			t.logNodeState(node, "AFTER_REMOVE_LEAF")
+		} else {
+			t.out.logf("[Remove Leaf] key=%v not found in leaf %s", value, node.addrOf())
+		}
+		return
+	}
+
+	if idx < len(node.keys) && node.keys[idx] == value {
+		t.out.logf("[Remove Internal Found] node=%s found key=%v at index=%d", node.addrOf(), value, idx)
+		victim, next := idx, value
+		switch {
+		case len(node.children[idx].keys) > t.minKeys:
+			t.out.logf("[Remove Use Pred] left child=%s has enough keys, finding predecessor", node.children[idx].addrOf())
+			next = t.findPred(node.children[idx])
+			t.out.logf("[Remove Pred Found] predecessor=%v replacing key=%v in node=%s", next, value, node.addrOf())
+			node.keys[idx] = next
+		case len(node.children[idx+1].keys) > t.minKeys:
+			t.out.logf("[Remove Use Succ] right child=%s has enough keys, finding successor", node.children[idx+1].addrOf())
+			next = t.findSuc(node.children[idx+1])
+			t.out.logf("[Remove Succ Found] successor=%v replacing key=%v in node=%s", next, value, node.addrOf())
+			node.keys[idx] = next
+			victim = idx + 1
+		default:
+			t.out.logf("[Remove Merge] both children have min keys, merging at index=%d", idx)
+			t.mergeSiblings(node, idx)
+		}
+		t.out.logf("[Remove Recurse] removing=%v from child=%s", next, node.children[victim].addrOf())
+		t.logParentChild(node, victim, "REMOVE_RECURSE_TO_CHILD")
+		t.removeVal(node.children[victim], next)
+		t.fixChild(node, victim)
+		t.logNodeState(node, "AFTER_REMOVE_FIX")
+		return
+	}
+
+	t.out.logf("[Remove Internal Miss] key=%v not at current level, going to child at index=%d child=%s",
+		value, idx, node.children[idx].addrOf())
+	t.logParentChild(node, idx, "REMOVE_GOING_TO_CHILD")
+	t.removeVal(node.children[idx], value)
+	t.fixChild(node, idx)
+	t.logNodeState(node, "AFTER_REMOVE_FIX")
+}
+
+func (t *goBTree[K]) insert(value K) {
+	t.out.logf("[TREE_INSERT] value=%v root=%s", value, t.root.addrOf())
+	t.logNodeState(t.root, "ROOT_BEFORE_INSERT")
+
+	if len(t.root.keys) == t.order-1 {
+		t.out.logf("[Root Split] root=%s is full, creating new root", t.root.addrOf())
+		newRoot := t.newNode(false)
+		sibling, midVal := t.splitSibling(t.root)
+		newRoot.keys = []K{midVal}
+		newRoot.children = []*bNode[K]{t.root, sibling}
+		t.root = newRoot
+	}
+	t.insertVal(t.root, value)
+
+	t.out.logf("[TREE_INSERT_COMPLETE] value=%v root=%s", value, t.root.addrOf())
+	t.logNodeState(t.root, "ROOT_AFTER_INSERT")
+}
+
+func (t *goBTree[K]) find(value K) bool {
+	t.out.logf("[TREE_FIND] value=%v root=%s", value, t.root.addrOf())
+	found := t.findVal(t.root, value)
+	t.out.logf("[TREE_FIND_RESULT] value=%v found=%t", value, found)
+	return found
+}
+
+func (t *goBTree[K]) remove(value K) {
+	t.out.logf("[TREE_REMOVE] value=%v root=%s", value, t.root.addrOf())
+	t.logNodeState(t.root, "ROOT_BEFORE_REMOVE")
+
+	if t.findVal(t.root, value) {
+		t.removeVal(t.root, value)
+		if !t.root.isLeaf && len(t.root.keys) == 0 {
+			t.root = t.root.children[0]
+		}
+	}
+
+	t.out.logf("[TREE_REMOVE_COMPLETE] value=%v root=%s", value, t.root.addrOf())
+	t.logNodeState(t.root, "ROOT_AFTER_REMOVE")
+}
+
+// insertAt inserts value at index i of a slice
+func insertAt[T any](s []T, i int, value T) []T {
+	s = append(s, value)
+	copy(s[i+1:], s[i:])
+	s[i] = value
+	return s
+}
+
+// removeAt removes index i of a slice
+func removeAt[T any](s []T, i int) []T {
+	return append(s[:i], s[i+1:]...)
+}
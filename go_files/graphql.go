@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// The GraphQL API is executed by github.com/graphql-go/graphql: gqlSchema implements
+// graphqlSchema, the schema published at GET /graphql/schema.graphql, type for type
+
+// gqlRequestKey is the context key of the HTTP request an operation arrived with
+type gqlRequestKey struct{}
+
+// gqlSubscriptionKey is the context key of the graphqlSubscription an operation starts
+type gqlSubscriptionKey struct{}
+
+// gqlRequest returns the HTTP request of an operation
+func gqlRequest(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(gqlRequestKey{}).(*http.Request)
+	return r
+}
+
+// gqlCodedError gives a resolver error its code in the extensions of the response
+type gqlCodedError struct {
+	error
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e gqlCodedError) Extensions() map[string]any {
+	return map[string]any{"code": errorCode(e.error)}
+}
+
+// gqlResolve adapts a resolver to graphql-go, giving its errors their code
+func gqlResolve(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		value, err := resolve(p)
+		if err != nil {
+			return nil, gqlCodedError{err}
+		}
+		return value, nil
+	}
+}
+
+// gqlSubscribe adapts a subscriber to graphql-go: unlike resolver errors, subscriber
+// errors are not located by the executor, so they are located here to keep their code
+func gqlSubscribe(subscribe graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		value, err := subscribe(p)
+		if err != nil {
+			recordError(err)
+			return nil, gqlerrors.NewLocatedError(gqlCodedError{err}, nil)
+		}
+		return value, nil
+	}
+}
+
+// gqlOperationType returns the type of the operation a request executes, "" when the
+// document does not parse or has no such operation
+func gqlOperationType(query, operationName string) string {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return ""
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if ok && (operationName == "" || operation.Name != nil && operation.Name.Value == operationName) {
+			return operation.Operation
+		}
+	}
+	return ""
+}
+
+var gqlStructureType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Structure",
+	Fields: graphql.Fields{
+		"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"keyType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"params":  &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+	},
+})
+
+var gqlSessionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Session",
+	Fields: graphql.Fields{
+		"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"type":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"flags":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"engine":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"startedAt":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"participants": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"spectators":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var gqlCommandResultType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CommandResult",
+	Fields: graphql.Fields{
+		"session":  &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"accepted": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var gqlFieldType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Field",
+	Fields: graphql.Fields{
+		"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var gqlTreeEventType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TreeEvent",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Message type: program, log, logs, server, error, keyframe, delta, ...",
+		},
+		"message": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Text of program, log, server and error messages",
+		},
+		"category": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Bracketed category of a log line, e.g. NODE_CREATE",
+		},
+		"fields": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gqlFieldType))),
+			Description: "key=value pairs of the message",
+		},
+		"json": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "The whole message, as a WebSocket client receives it",
+		},
+	},
+})
+
+var gqlParamType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "Param",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"value": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+// gqlSchema executes the operations of the API
+var gqlSchema = func() graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"structures": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gqlStructureType))),
+					Resolve: gqlResolve(resolveStructures),
+				},
+				"session": &graphql.Field{
+					Type:    gqlSessionType,
+					Args:    graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+					Resolve: gqlResolve(resolveSession),
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"sendCommand": &graphql.Field{
+					Type:        graphql.NewNonNull(gqlCommandResultType),
+					Description: "Sends one client message to a session, like a WebSocket message",
+					Args: graphql.FieldConfigArgument{
+						"session":  {Type: graphql.NewNonNull(graphql.ID)},
+						"joinCode": {Type: graphql.NewNonNull(graphql.String)},
+						"command":  {Type: graphql.NewNonNull(graphql.String)},
+					},
+					Resolve: gqlResolve(resolveSendCommand),
+				},
+			},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Subscription",
+			Fields: graphql.Fields{
+				"session": &graphql.Field{
+					Type:        graphql.NewNonNull(gqlTreeEventType),
+					Description: "Starts a session of type, or follows session id: as a participant with its joinCode, otherwise read-only",
+					Args: graphql.FieldConfigArgument{
+						"type":       {Type: graphql.String},
+						"params":     {Type: graphql.NewList(graphql.NewNonNull(gqlParamType))},
+						"engine":     {Type: graphql.String},
+						"seed":       {Type: graphql.Int},
+						"lang":       {Type: graphql.String},
+						"deltas":     {Type: graphql.Boolean},
+						"coalesceMs": {Type: graphql.Int},
+						"id":         {Type: graphql.ID},
+						"joinCode":   {Type: graphql.String},
+					},
+					Subscribe: gqlSubscribe(subscribeSession),
+					Resolve:   gqlResolve(resolveTreeEvent),
+				},
+			},
+		}),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+// resolveStructures resolves Query.structures
+func resolveStructures(p graphql.ResolveParams) (any, error) {
+	structures := []any{}
+	for _, name := range dataStructureNames() {
+		ds, ok := lookupDataStructure(name)
+		if !ok {
+			continue
+		}
+		params := []any{}
+		for _, flag := range ds.Flags {
+			params = append(params, flag.Param)
+		}
+		structures = append(structures, map[string]any{
+			"name":    ds.Name,
+			"keyType": ds.keyType(),
+			"params":  params,
+		})
+	}
+	return structures, nil
+}
+
+// resolveSession resolves Query.session
+func resolveSession(p graphql.ResolveParams) (any, error) {
+	ID, _ := p.Args["id"].(string)
+	session, ok := lookupSession(ID)
+	if !ok {
+		return nil, nil
+	}
+	if !session.visibleTo(gqlRequest(p.Context)) {
+		return nil, ErrSessionPrivate
+	}
+	participants, spectators := session.clients.count()
+	return map[string]any{
+		"id":           session.ID,
+		"type":         session.DataType,
+		"flags":        session.Flags,
+		"engine":       session.Engine,
+		"startedAt":    session.Started.UTC().Format(time.RFC3339),
+		"participants": participants,
+		"spectators":   spectators,
+	}, nil
+}
+
+// resolveSendCommand resolves Mutation.sendCommand
+func resolveSendCommand(p graphql.ResolveParams) (any, error) {
+	ID, _ := p.Args["session"].(string)
+	code, _ := p.Args["joinCode"].(string)
+	command, _ := p.Args["command"].(string)
+	session, ok := lookupSession(ID)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if subtle.ConstantTimeCompare([]byte(code), []byte(session.JoinCode)) != 1 {
+		return nil, ErrJoinCodeMismatch
+	}
+	accepted := 0
+	for _, line := range strings.Split(command, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if violation := session.dispatch(line); violation != nil {
+			return nil, &ValidationError{violation.Message}
+		}
+		accepted++
+	}
+	return map[string]any{"session": session.ID, "accepted": accepted}, nil
+}
+
+// subscribeSession starts Subscription.session: the session of the operation follows its
+// arguments, and each of its messages becomes an event
+func subscribeSession(p graphql.ResolveParams) (any, error) {
+	sub, ok := p.Context.Value(gqlSubscriptionKey{}).(*graphqlSubscription)
+	if !ok {
+		return nil, &ValidationError{"Subscriptions are served over WebSocket with the " + graphqlProtocol + " protocol"}
+	}
+	if len(p.Info.Operation.GetSelectionSet().Selections) != 1 {
+		return nil, &ValidationError{"A subscription must select exactly the session field"}
+	}
+	if err := sub.prepare(p.Args); err != nil {
+		return nil, err
+	}
+	go sub.run()
+	return sub.events, nil
+}
+
+// resolveTreeEvent resolves Subscription.session from one event of the subscription;
+// a subscription sent as a plain query has none
+func resolveTreeEvent(p graphql.ResolveParams) (any, error) {
+	if event, ok := p.Source.(map[string]any); ok && event != nil {
+		return event, nil
+	}
+	return nil, &ValidationError{"Subscriptions are served over WebSocket with the " + graphqlProtocol + " protocol"}
+}
+
+// treeEvent turns one session message into a TreeEvent
+func treeEvent(line string) map[string]any {
+	var message struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal([]byte(line), &message)
+	fields := []any{}
+	for _, word := range strings.Fields(message.Message) {
+		if key, value, ok := strings.Cut(word, "="); ok && key != "" {
+			fields = append(fields, map[string]any{"key": key, "value": strings.Trim(value, `"`)})
+		}
+	}
+	event := map[string]any{"type": message.Type, "message": nil, "category": nil, "fields": fields, "json": line}
+	if message.Message != "" {
+		event["message"] = message.Message
+	}
+	if category := logCategory(message.Message); category != "" {
+		event["category"] = category
+	}
+	return event
+}
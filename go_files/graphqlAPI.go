@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// graphqlSchema is the schema served at GET /graphql/schema.graphql; gqlSchema implements it
+const graphqlSchema = `type Query {
+  structures: [Structure!]!
+  session(id: ID!): Session
+}
+
+type Mutation {
+  "Sends one client message to a session, like a WebSocket message"
+  sendCommand(session: ID!, joinCode: String!, command: String!): CommandResult!
+}
+
+type Subscription {
+  "Starts a session of type, or follows session id: as a participant with its joinCode, otherwise read-only"
+  session(type: String, params: [Param!], engine: String, seed: Int, lang: String,
+    deltas: Boolean, coalesceMs: Int, id: ID, joinCode: String): TreeEvent!
+}
+
+input Param {
+  name: String!
+  value: String!
+}
+
+type Structure {
+  name: String!
+  keyType: String!
+  params: [String!]!
+}
+
+type Session {
+  id: ID!
+  type: String!
+  flags: String!
+  engine: String!
+  startedAt: String!
+  participants: Int!
+  spectators: Int!
+}
+
+type CommandResult {
+  session: ID!
+  accepted: Int!
+}
+
+type TreeEvent {
+  "Message type: program, log, logs, server, error, keyframe, delta, ..."
+  type: String!
+  "Text of program, log, server and error messages"
+  message: String
+  "Bracketed category of a log line, e.g. NODE_CREATE"
+  category: String
+  "key=value pairs of the message"
+  fields: [Field!]!
+  "The whole message, as a WebSocket client receives it"
+  json: String!
+}
+
+type Field {
+  key: String!
+  value: String!
+}
+`
+
+// graphqlProtocol is the WebSocket subprotocol of subscriptions
+const graphqlProtocol = "graphql-transport-ws"
+
+// graphqlInitTimeout is how long a subscription socket may wait before connection_init
+const graphqlInitTimeout = 10 * time.Second
+
+// Close codes of the graphql-transport-ws protocol
+const (
+	closeGraphQLInvalid      = 4400 // malformed message
+	closeGraphQLUnauthorized = 4401 // subscribe before connection_ack
+	closeGraphQLInitTimeout  = 4408
+	closeGraphQLDuplicateID  = 4409
+	closeGraphQLTooManyInits = 4429
+)
+
+var graphqlUpgrader = websocket.Upgrader{
+	CheckOrigin:  checkOrigin,
+	Subprotocols: []string{graphqlProtocol},
+}
+
+// graphqlRequest is the body of POST /graphql and the payload of a subscribe message
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+// params returns the graphql-go parameters of the request, executed in ctx
+func (req graphqlRequest) params(ctx context.Context) graphql.Params {
+	return graphql.Params{
+		Schema:         gqlSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	}
+}
+
+// handleGraphQL serves /graphql: queries and mutations as a JSON POST, subscriptions
+// over a graphql-transport-ws WebSocket
+func handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		handleGraphQLSocket(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		httpError(w, &ValidationError{"Send operations as a JSON POST, or subscribe over WebSocket"})
+		return
+	}
+	var req graphqlRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCommandBody)).Decode(&req); err != nil {
+		httpError(w, &ValidationError{"Invalid JSON body"})
+		return
+	}
+	writeJSON(w, graphql.Do(req.params(context.WithValue(r.Context(), gqlRequestKey{}, r))))
+}
+
+// handleGraphQLSchema serves GET /graphql/schema.graphql
+func handleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, graphqlSchema)
+}
+
+// graphqlMessage is a message of the graphql-transport-ws protocol
+type graphqlMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlSocket is a graphql-transport-ws connection carrying any number of operations
+type graphqlSocket struct {
+	conn  *WebSocketWrapper
+	r     *http.Request
+	mu    sync.Mutex // guards acked and subs
+	acked bool
+	subs  map[string]*graphqlSubscription
+}
+
+// handleGraphQLSocket serves a graphql-transport-ws connection until it closes
+func handleGraphQLSocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(&graphqlUpgrader, w, r)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+	conn := &WebSocketWrapper{Conn: ws}
+	defer conn.Close()
+	if ws.Subprotocol() != graphqlProtocol {
+		conn.CloseWithCode(websocket.CloseProtocolError, "subprotocol "+graphqlProtocol+" required")
+		return
+	}
+
+	s := &graphqlSocket{conn: conn, r: r, subs: map[string]*graphqlSubscription{}}
+	defer s.endAll()
+	ws.SetReadDeadline(time.Now().Add(graphqlInitTimeout))
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			if !s.isAcked() {
+				conn.CloseWithCode(closeGraphQLInitTimeout, "connection initialisation timeout")
+			}
+			return
+		}
+		var message graphqlMessage
+		if json.Unmarshal(data, &message) != nil || message.Type == "" {
+			conn.CloseWithCode(closeGraphQLInvalid, "invalid message")
+			return
+		}
+		if code, reason := s.handle(message); code != 0 {
+			conn.CloseWithCode(code, reason)
+			return
+		}
+	}
+}
+
+// handle processes one client message; a non-zero code closes the connection
+func (s *graphqlSocket) handle(message graphqlMessage) (int, string) {
+	switch message.Type {
+	case "connection_init":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.acked {
+			return closeGraphQLTooManyInits, "too many initialisation requests"
+		}
+		s.acked = true
+		s.conn.SetReadDeadline(time.Time{})
+		sendJSONValue(s.conn, graphqlMessage{Type: "connection_ack"})
+	case "ping":
+		sendJSONValue(s.conn, graphqlMessage{Type: "pong"})
+	case "pong":
+	case "subscribe":
+		if !s.isAcked() {
+			return closeGraphQLUnauthorized, "unauthorized"
+		}
+		var req graphqlRequest
+		if message.ID == "" || json.Unmarshal(message.Payload, &req) != nil {
+			return closeGraphQLInvalid, "invalid subscribe message"
+		}
+		return s.subscribe(message.ID, req)
+	case "complete":
+		s.mu.Lock()
+		sub := s.subs[message.ID]
+		delete(s.subs, message.ID)
+		s.mu.Unlock()
+		if sub != nil {
+			sub.complete()
+		}
+	default:
+		return closeGraphQLInvalid, "unknown message type " + message.Type
+	}
+	return 0, ""
+}
+
+// isAcked reports whether connection_init was acknowledged
+func (s *graphqlSocket) isAcked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acked
+}
+
+// subscribe starts an operation: queries and mutations answer once, subscriptions
+// stream the events of a session until it ends or the client completes them
+func (s *graphqlSocket) subscribe(ID string, req graphqlRequest) (int, string) {
+	s.mu.Lock()
+	if _, ok := s.subs[ID]; ok {
+		s.mu.Unlock()
+		return closeGraphQLDuplicateID, "subscriber for " + ID + " already exists"
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.WithValue(s.r.Context(), gqlRequestKey{}, s.r))
+	if gqlOperationType(req.Query, req.OperationName) != "subscription" {
+		defer cancel()
+		sendJSONValue(s.conn, graphqlNext{ID: ID, Type: "next", Payload: graphql.Do(req.params(ctx))})
+		sendJSONValue(s.conn, graphqlMessage{ID: ID, Type: "complete"})
+		return 0, ""
+	}
+
+	sub := &graphqlSubscription{
+		socket: s,
+		ID:     ID,
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan any),
+		done:   make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.subs[ID] = sub
+	s.mu.Unlock()
+	go sub.forward(graphql.Subscribe(req.params(context.WithValue(ctx, gqlSubscriptionKey{}, sub))))
+	return 0, ""
+}
+
+// endAll ends the subscriptions of a closed connection
+func (s *graphqlSocket) endAll() {
+	s.mu.Lock()
+	subs := s.subs
+	s.subs = map[string]*graphqlSubscription{}
+	s.mu.Unlock()
+	for _, sub := range subs {
+		sub.complete()
+	}
+}
+
+// graphqlNext is a next message with an operation result
+type graphqlNext struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"` // always "next"
+	Payload *graphql.Result `json:"payload"`
+}
+
+// graphqlSubscription is a Subscription.session operation, attached to its session
+// like a socket: every message written is handed to the executor as a TreeEvent
+type graphqlSubscription struct {
+	socket *graphqlSocket
+	ID     string
+	ctx    context.Context // canceled once the client completes the operation
+	cancel context.CancelFunc
+	events chan any // read by the executor, closed once the session ends
+	start  func(clientID string, sub *graphqlSubscription)
+	done   chan struct{} // closed with events
+
+	mu        sync.Mutex // guards ended and closeErr; held while an event is handed over
+	ended     bool
+	closeErr  error // why the session ended, when it did not end normally
+	completed atomic.Bool
+}
+
+// prepare validates the arguments of the subscription and prepares the session it follows
+func (sub *graphqlSubscription) prepare(args map[string]any) error {
+	r := sub.socket.r
+	if sessionID, _ := args["id"].(string); sessionID != "" {
+		session, ok := lookupSession(sessionID)
+		if !ok {
+			return ErrSessionNotFound
+		}
+		code, _ := args["joinCode"].(string)
+		if code != "" && subtle.ConstantTimeCompare([]byte(code), []byte(session.JoinCode)) != 1 {
+			return ErrJoinCodeMismatch
+		}
+		if code == "" && !session.visibleTo(r) {
+			return ErrSessionPrivate
+		}
+		sub.start = func(clientID string, sub *graphqlSubscription) {
+			if code != "" {
+				joinSession(session, clientID, sub)
+			} else {
+				spectateSession(session, clientID, sub)
+			}
+		}
+		return nil
+	}
+
+	if draining.Load() {
+		return ErrDraining
+	}
+	query, err := graphqlSessionQuery(args)
+	if err != nil {
+		return err
+	}
+	session := r.Clone(r.Context())
+	session.URL.RawQuery = query.Encode()
+	request, err := newSessionRequest(session)
+	if err != nil {
+		return err
+	}
+	sub.start = func(clientID string, sub *graphqlSubscription) {
+		fmt.Printf("[Client %s] Connected from %s over GraphQL (type: %s, flags: %s, engine: %s)\n",
+			clientID, clientAddr(r), request.dataType, request.flags, request.engine)
+		runClientThread(clientID, request.dataType, request.flags, sub, request.setup)
+	}
+	return nil
+}
+
+// graphqlSessionQuery turns the arguments of Subscription.session, already checked against
+// their types by the executor, into /session query parameters
+func graphqlSessionQuery(args map[string]any) (url.Values, error) {
+	query := url.Values{}
+	for arg, param := range map[string]string{"type": "type", "engine": "engine", "lang": "lang"} {
+		if value, _ := args[arg].(string); value != "" {
+			query.Set(param, value)
+		}
+	}
+	for arg, param := range map[string]string{"seed": "seed", "coalesceMs": "coalesce_ms"} {
+		if value, _ := args[arg].(int); value != 0 {
+			query.Set(param, strconv.Itoa(value))
+		}
+	}
+	if deltas, _ := args["deltas"].(bool); deltas {
+		query.Set("deltas", "1")
+	}
+	params, _ := args["params"].([]any)
+	for _, item := range params {
+		param, _ := item.(map[string]any)
+		name, _ := param["name"].(string)
+		value, _ := param["value"].(string)
+		if name == "" {
+			return nil, &ValidationError{"Param names cannot be empty"}
+		}
+		if _, reserved := query[name]; reserved {
+			return nil, &ValidationError{"Param " + name + " is an argument of its own"}
+		}
+		query.Set(name, value)
+	}
+	return query, nil
+}
+
+// run follows the session until it ends
+func (sub *graphqlSubscription) run() {
+	sub.start(genID(), sub)
+	sub.end()
+}
+
+// forward sends the results of the executor to the client until the operation ends
+func (sub *graphqlSubscription) forward(results chan *graphql.Result) {
+	defer sub.cancel()
+	refused := false
+	started := false
+	// Drained to the end, as the executor blocks on every result
+	for result := range results {
+		switch {
+		case refused:
+		case !started && result.Data == nil && result.HasErrors():
+			// Refused before it started: invalid, or the session could not start
+			refused = true
+			sub.cancel()
+			payload, _ := json.Marshal(result.Errors)
+			sendJSONValue(sub.socket.conn, graphqlMessage{ID: sub.ID, Type: "error", Payload: payload})
+		default:
+			started = true
+			sendJSONValue(sub.socket.conn, graphqlNext{ID: sub.ID, Type: "next", Payload: result})
+		}
+	}
+	sub.socket.mu.Lock()
+	if sub.socket.subs[sub.ID] == sub {
+		delete(sub.socket.subs, sub.ID)
+	}
+	sub.socket.mu.Unlock()
+	if refused || sub.completed.Load() {
+		return
+	}
+	sub.mu.Lock()
+	closeErr := sub.closeErr
+	sub.mu.Unlock()
+	if closeErr != nil {
+		sendJSONValue(sub.socket.conn, graphqlNext{ID: sub.ID, Type: "next", Payload: &graphql.Result{
+			Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(gqlerrors.NewLocatedError(closeErr, nil))},
+		}})
+	}
+	sendJSONValue(sub.socket.conn, graphqlMessage{ID: sub.ID, Type: "complete"})
+}
+
+// Write implements io.Writer: each line of p is handed to the executor as one TreeEvent
+func (sub *graphqlSubscription) Write(p []byte) (int, error) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.ended {
+		return 0, ErrClientGone
+	}
+	var timeout <-chan time.Time
+	if d := writeTimeout(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		select {
+		case sub.events <- treeEvent(line):
+		case <-sub.ctx.Done():
+			return 0, ErrClientGone
+		case <-timeout:
+			return 0, ErrClientTooSlow
+		}
+	}
+	return len(p), nil
+}
+
+// SetWriteDeadline lets the fanout give the subscription an outbound queue, as it does sockets
+func (sub *graphqlSubscription) SetWriteDeadline(t time.Time) error {
+	return sub.socket.conn.SetWriteDeadline(t)
+}
+
+// Read implements io.Reader: input arrives through Mutation.sendCommand, so it blocks
+// until the operation ends
+func (sub *graphqlSubscription) Read(p []byte) (int, error) {
+	select {
+	case <-sub.done:
+	case <-sub.ctx.Done():
+	}
+	return 0, io.EOF
+}
+
+// CloseWithCode ends the operation; a session that did not end normally reports why
+func (sub *graphqlSubscription) CloseWithCode(code int, reason string) error {
+	if code != websocket.CloseNormalClosure {
+		sub.mu.Lock()
+		sub.closeErr = graphqlCloseError{code, reason}
+		sub.mu.Unlock()
+	}
+	sub.end()
+	return nil
+}
+
+// Close ends the operation
+func (sub *graphqlSubscription) Close() error {
+	sub.end()
+	return nil
+}
+
+// end stops handing events over; the executor then finishes the operation
+func (sub *graphqlSubscription) end() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.ended {
+		sub.ended = true
+		close(sub.events)
+		close(sub.done)
+	}
+}
+
+// complete stops an operation the client completed or left; the client is not told
+func (sub *graphqlSubscription) complete() {
+	sub.completed.Store(true)
+	sub.cancel()
+}
+
+// graphqlCloseError is how a session following a subscription ended abnormally
+type graphqlCloseError struct {
+	code   int // WebSocket close code
+	reason string
+}
+
+func (e graphqlCloseError) Error() string {
+	return e.reason
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e graphqlCloseError) Extensions() map[string]any {
+	return map[string]any{"code": "session_closed", "close_code": e.code}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+)
+
+// TestGraphQLSchemaMatchesSDL checks gqlSchema against the published schema, field by field
+func TestGraphQLSchemaMatchesSDL(t *testing.T) {
+	document, err := parser.Parse(parser.ParseParams{Source: graphqlSchema})
+	if err != nil {
+		t.Fatalf("published schema does not parse: %v", err)
+	}
+	description := func(value *ast.StringValue) string {
+		if value == nil {
+			return ""
+		}
+		return value.Value
+	}
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.ObjectDefinition:
+			object, ok := gqlSchema.Type(definition.Name.Value).(*graphql.Object)
+			if !ok {
+				t.Errorf("type %s is not implemented", definition.Name.Value)
+				continue
+			}
+			fields := object.Fields()
+			if len(fields) != len(definition.Fields) {
+				t.Errorf("%s has %d fields, want %d", object.Name(), len(fields), len(definition.Fields))
+			}
+			for _, want := range definition.Fields {
+				name := object.Name() + "." + want.Name.Value
+				field, ok := fields[want.Name.Value]
+				if !ok {
+					t.Errorf("%s is not implemented", name)
+					continue
+				}
+				if got := field.Type.String(); got != printer.Print(want.Type) {
+					t.Errorf("%s is %s, want %s", name, got, printer.Print(want.Type))
+				}
+				if field.Description != description(want.Description) {
+					t.Errorf("%s is described %q, want %q", name, field.Description, description(want.Description))
+				}
+				if len(field.Args) != len(want.Arguments) {
+					t.Errorf("%s has %d arguments, want %d", name, len(field.Args), len(want.Arguments))
+				}
+				for _, wantArg := range want.Arguments {
+					found := false
+					for _, arg := range field.Args {
+						if arg.Name() == wantArg.Name.Value {
+							found = true
+							if got := arg.Type.String(); got != printer.Print(wantArg.Type) {
+								t.Errorf("%s(%s) is %s, want %s", name, arg.Name(), got, printer.Print(wantArg.Type))
+							}
+						}
+					}
+					if !found {
+						t.Errorf("%s lacks argument %s", name, wantArg.Name.Value)
+					}
+				}
+			}
+		case *ast.InputObjectDefinition:
+			input, ok := gqlSchema.Type(definition.Name.Value).(*graphql.InputObject)
+			if !ok {
+				t.Errorf("input %s is not implemented", definition.Name.Value)
+				continue
+			}
+			fields := input.Fields()
+			if len(fields) != len(definition.Fields) {
+				t.Errorf("%s has %d fields, want %d", input.Name(), len(fields), len(definition.Fields))
+			}
+			for _, want := range definition.Fields {
+				field, ok := fields[want.Name.Value]
+				if !ok || field.Type.String() != printer.Print(want.Type) {
+					t.Errorf("%s.%s is not implemented as %s", input.Name(), want.Name.Value, printer.Print(want.Type))
+				}
+			}
+		default:
+			t.Errorf("unexpected definition %T", definition)
+		}
+	}
+}
+
+// execGraphQL executes a document as POST /graphql does
+func execGraphQL(document string, variables map[string]any) map[string]any {
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	result := graphql.Do(graphqlRequest{Query: document, Variables: variables}.params(context.WithValue(r.Context(), gqlRequestKey{}, r)))
+	// Compare as a client reads the response
+	data, _ := json.Marshal(result)
+	var response map[string]any
+	json.Unmarshal(data, &response)
+	return response
+}
+
+func TestGraphQLExecute(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		data     string // JSON of the expected data; "" to skip the check
+		errors   []string
+	}{
+		{"fragment", "query { ...S } fragment S on Query { structures { name } }", "", nil},
+		{"inline fragment and directives", `{ ... on Query { structures @skip(if: true) { name } } }`, `{}`, nil},
+		{"introspection", `{ __type(name: "Field") { fields { name } } }`, `{"__type":{"fields":[{"name":"key"},{"name":"value"}]}}`, nil},
+		{"typename", "{ __typename }", `{"__typename":"Query"}`, nil},
+		{"unknown session", `{ session(id: "nope") { id } }`, `{"session":null}`, nil},
+		{"unknown field", "{ nope }", "", []string{`Cannot query field "nope" on type "Query".`}},
+		{"missing argument", "{ session { id } }", "", []string{`Field "session" argument "id" of type "ID!" is required but not provided.`}},
+		{"subscription over POST", `subscription { session(type: "btree") { type } }`, "", []string{"Subscriptions are served over WebSocket with the graphql-transport-ws protocol"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := execGraphQL(tt.document, nil)
+			var messages []string
+			errs, _ := response["errors"].([]any)
+			for _, err := range errs {
+				messages = append(messages, err.(map[string]any)["message"].(string))
+			}
+			if !reflect.DeepEqual(messages, tt.errors) {
+				t.Errorf("errors = %q, want %q", messages, tt.errors)
+			}
+			if tt.data != "" {
+				var want any
+				json.Unmarshal([]byte(tt.data), &want)
+				if !reflect.DeepEqual(response["data"], want) {
+					t.Errorf("data = %v, want %s", response["data"], tt.data)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQLStructures(t *testing.T) {
+	response := execGraphQL("{ structures { name keyType params } }", nil)
+	structures := response["data"].(map[string]any)["structures"].([]any)
+	if len(structures) != len(dataStructureNames()) {
+		t.Fatalf("got %d structures, want %d", len(structures), len(dataStructureNames()))
+	}
+	for _, item := range structures {
+		structure := item.(map[string]any)
+		if structure["name"] == "btree" {
+			if structure["keyType"] != "int" || !reflect.DeepEqual(structure["params"], []any{"order"}) {
+				t.Errorf("btree = %v", structure)
+			}
+			return
+		}
+	}
+	t.Error("btree is missing")
+}
+
+func TestGraphQLErrorCodes(t *testing.T) {
+	response := execGraphQL(`mutation ($id: ID!) { sendCommand(session: $id, joinCode: "x", command: "status") { accepted } }`,
+		map[string]any{"id": "nope"})
+	errs, _ := response["errors"].([]any)
+	if len(errs) != 1 {
+		t.Fatalf("got %v, want one error", response)
+	}
+	err := errs[0].(map[string]any)
+	if err["message"] != ErrSessionNotFound.Error() {
+		t.Errorf("message = %v, want %q", err["message"], ErrSessionNotFound)
+	}
+	if code := err["extensions"].(map[string]any)["code"]; code != errorCode(ErrSessionNotFound) {
+		t.Errorf("code = %v, want %s", code, errorCode(ErrSessionNotFound))
+	}
+}
+
+func TestGQLOperationType(t *testing.T) {
+	tests := []struct {
+		document      string
+		operationName string
+		want          string
+	}{
+		{"{ structures { name } }", "", "query"},
+		{"mutation { sendCommand }", "", "mutation"},
+		{"subscription S { session { type } }", "", "subscription"},
+		{"query A { a } subscription B { b }", "B", "subscription"},
+		{"query A { a }", "B", ""},
+		{"{ unclosed", "", ""},
+	}
+	for _, tt := range tests {
+		if got := gqlOperationType(tt.document, tt.operationName); got != tt.want {
+			t.Errorf("gqlOperationType(%q, %q) = %q, want %q", tt.document, tt.operationName, got, tt.want)
+		}
+	}
+}
+
+func TestGraphQLSessionQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  map[string]any
+		query string
+		valid bool
+	}{
+		{"none", map[string]any{}, "", true},
+		{"strings", map[string]any{"type": "btree", "engine": "go", "lang": "fr"}, "engine=go&lang=fr&type=btree", true},
+		{"numbers", map[string]any{"seed": 42, "coalesceMs": 10}, "coalesce_ms=10&seed=42", true},
+		{"zero numbers are unset", map[string]any{"seed": 0}, "", true},
+		{"deltas", map[string]any{"deltas": true}, "deltas=1", true},
+		{"params", map[string]any{"type": "btree", "params": []any{map[string]any{"name": "order", "value": "5"}}}, "order=5&type=btree", true},
+		{"empty param name", map[string]any{"params": []any{map[string]any{"name": "", "value": "5"}}}, "", false},
+		{"param shadowing an argument", map[string]any{"type": "btree", "params": []any{map[string]any{"name": "type", "value": "avltree"}}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := graphqlSessionQuery(tt.args)
+			if !tt.valid {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("got %v, %v; want a ValidationError", query, err)
+				}
+				return
+			}
+			if err != nil || query.Encode() != tt.query {
+				t.Errorf("got %q, %v; want %q", query.Encode(), err, tt.query)
+			}
+		})
+	}
+}
+
+func TestTreeEvent(t *testing.T) {
+	line := `{"type":"log","message":"[NODE_CREATE] key=5 label=\"a\""}`
+	want := map[string]any{
+		"type":     "log",
+		"message":  `[NODE_CREATE] key=5 label="a"`,
+		"category": "NODE_CREATE",
+		"fields":   []any{map[string]any{"key": "key", "value": "5"}, map[string]any{"key": "label", "value": "a"}},
+		"json":     line,
+	}
+	if got := treeEvent(line); !reflect.DeepEqual(got, want) {
+		t.Errorf("treeEvent = %v, want %v", got, want)
+	}
+}
+
+func TestGraphQLSubscription(t *testing.T) {
+	inSessionDir(t)
+	server := httptest.NewServer(http.HandlerFunc(handleGraphQL))
+	defer server.Close()
+	dialer := websocket.Dialer{Subprotocols: []string{graphqlProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	send := func(message string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// receive reads messages until one of type for operation ID
+	receive := func(ID, kind string) graphqlMessage {
+		for {
+			var message graphqlMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				t.Fatalf("waiting for %s %s: %v", kind, ID, err)
+			}
+			if message.ID == ID && message.Type == kind {
+				return message
+			}
+		}
+	}
+
+	send(`{"type":"connection_init"}`)
+	receive("", "connection_ack")
+	send(`{"id":"bad","type":"subscribe","payload":{"query":"subscription { session(type: \"nope\") { type } }"}}`)
+	if refused := receive("bad", "error"); !strings.Contains(string(refused.Payload), `"code":"`+errorCodeInvalid+`"`) {
+		t.Errorf("refusal %s lacks the error code", refused.Payload)
+	}
+
+	send(`{"id":"1","type":"subscribe","payload":{"query":"subscription { session(type: \"btree\", engine: \"go\") { type message } }"}}`)
+	for {
+		var next struct {
+			Payload struct {
+				Data struct {
+					Session struct {
+						Type    string `json:"type"`
+						Message string `json:"message"`
+					} `json:"session"`
+				} `json:"data"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal(receive("1", "next").Payload, &next.Payload); err != nil {
+			t.Fatal(err)
+		}
+		if event := next.Payload.Data.Session; event.Type == "program" && strings.HasPrefix(event.Message, "READY") {
+			break
+		}
+	}
+	send(`{"id":"1","type":"complete"}`)
+
+	// The socket keeps serving other operations
+	send(`{"id":"2","type":"subscribe","payload":{"query":"{ __typename }"}}`)
+	if next := receive("2", "next"); string(next.Payload) != `{"data":{"__typename":"Query"}}` {
+		t.Errorf("query answered %s", next.Payload)
+	}
+	receive("2", "complete")
+}
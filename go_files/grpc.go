@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasv1 "datasServer/proto"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC API of proto/datas.proto, served with grpc-go from the generated code in proto/
+
+// grpcMaxMessage bounds one Command; the WebSocket scanner takes lines up to 64KB too
+const grpcMaxMessage = 64 << 10
+
+// grpcMetadataPrefix marks the request metadata carrying /session query parameters
+const grpcMetadataPrefix = "datas-"
+
+// grpcStatusOf maps an error to the gRPC status code matching its HTTP status
+func grpcStatusOf(err error) codes.Code {
+	if errors.Is(err, errAdminRequired) {
+		return codes.Unauthenticated
+	}
+	_, status := classifyError(err)
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Internal
+}
+
+// grpcStatusOfClose maps the WebSocket close code a session ended with to a gRPC status code
+func grpcStatusOfClose(code int) codes.Code {
+	switch code {
+	case websocket.CloseNormalClosure:
+		return codes.OK
+	case websocket.CloseGoingAway:
+		return codes.Unavailable
+	case websocket.ClosePolicyViolation:
+		return codes.InvalidArgument
+	case websocket.CloseInternalServerErr:
+		return codes.Internal
+	case closeSessionKilled, closeSessionEvicted:
+		return codes.Aborted
+	case closeClientTooSlow:
+		return codes.ResourceExhausted
+	}
+	return codes.Unknown
+}
+
+// grpcStream is an OpenSession call attached to a session like a WebSocket: Commands
+// are read as input lines and every message written is sent as an Event
+type grpcStream struct {
+	mu       sync.Mutex // serializes events and guards deadline, stalled and status
+	stream   datasv1.Datas_OpenSessionServer
+	commands chan string // lines of the Commands received, closed when the client stops sending
+	pending  []byte      // input not yet read from the last Command
+	deadline time.Time   // of the next Send; zero for none
+	stalled  bool        // a Send missed its deadline and may still be blocked
+	done     chan struct{}
+	once     sync.Once
+
+	// status and message end the call once done is closed
+	status  codes.Code
+	message string
+}
+
+// newGRPCStream attaches to an OpenSession call and starts receiving its Commands
+func newGRPCStream(stream datasv1.Datas_OpenSessionServer) *grpcStream {
+	s := &grpcStream{stream: stream, commands: make(chan string), done: make(chan struct{})}
+	go s.receive()
+	return s
+}
+
+// receive queues the line of each Command until the client stops sending or the call ends
+func (s *grpcStream) receive() {
+	defer close(s.commands)
+	for {
+		command, err := s.stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			// An oversized or undecodable Command ends the call with the status Recv reports
+			if code := status.Code(err); code != codes.Canceled {
+				s.end(code, status.Convert(err).Message())
+			}
+			return
+		}
+		select {
+		case s.commands <- command.GetLine():
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Write implements io.Writer: each line of p is sent as one Event
+func (s *grpcStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return 0, ErrClientGone
+	default:
+	}
+	if s.stalled {
+		return 0, ErrClientTooSlow
+	}
+	if timeout := writeTimeout(); timeout > 0 {
+		s.deadline = time.Now().Add(timeout)
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		var envelope struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(line), &envelope)
+		if err := s.send(&datasv1.Event{Type: envelope.Type, Json: line}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// send sends one Event, giving up at the deadline: grpc-go has no write deadline, so a
+// Send blocked by flow control is left to return once the call ends
+func (s *grpcStream) send(event *datasv1.Event) error {
+	if s.deadline.IsZero() {
+		return s.stream.Send(event)
+	}
+	sent := make(chan error, 1)
+	go func() { sent <- s.stream.Send(event) }()
+	timer := time.NewTimer(time.Until(s.deadline))
+	defer timer.Stop()
+	select {
+	case err := <-sent:
+		return err
+	case <-timer.C:
+		s.stalled = true
+		return ErrClientTooSlow
+	}
+}
+
+// SetWriteDeadline lets the fanout give the call an outbound queue, as it does sockets
+func (s *grpcStream) SetWriteDeadline(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadline = t
+	return nil
+}
+
+// Read implements io.Reader: returns the line of each Command followed by a newline
+func (s *grpcStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		select {
+		case line, ok := <-s.commands:
+			if !ok {
+				return 0, io.EOF
+			}
+			s.pending = append([]byte(line), '\n')
+		case <-s.done:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// CloseWithCode ends the call with the gRPC status matching a WebSocket close code
+func (s *grpcStream) CloseWithCode(code int, reason string) error {
+	s.end(grpcStatusOfClose(code), reason)
+	return nil
+}
+
+// Close ends the call; a session that ends without a close code ends it with OK
+func (s *grpcStream) Close() error {
+	s.end(codes.OK, "")
+	return nil
+}
+
+// end records the status of the call, the first one set winning, and ends it
+// It does not take mu, which a stalled Write may hold
+func (s *grpcStream) end(code codes.Code, message string) {
+	s.once.Do(func() {
+		s.status, s.message = code, message
+		close(s.done)
+	})
+}
+
+// err is the status the call ends with, nil for OK
+func (s *grpcStream) err() error {
+	<-s.done
+	if s.status == codes.OK {
+		return nil
+	}
+	return status.Error(s.status, s.message)
+}
+
+// grpcError is the status of a call that could not start
+func grpcError(ctx context.Context, err error) error {
+	recordError(err)
+	if errors.Is(err, ErrServerBusy) {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(config.BusyRetryAfterSeconds)))
+	}
+	return status.Error(grpcStatusOf(err), err.Error())
+}
+
+// grpcSessionRequest turns the metadata of a call into a /session request: datas- entries
+// become its query and the others its headers, so authentication works as over HTTP
+func grpcSessionRequest(ctx context.Context) *http.Request {
+	md, _ := metadata.FromIncomingContext(ctx)
+	query := url.Values{}
+	header := http.Header{}
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		if name, ok := strings.CutPrefix(key, grpcMetadataPrefix); ok {
+			name = strings.ReplaceAll(name, "-", "_")
+			query[name] = append(query[name], values...)
+			continue
+		}
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/session?"+query.Encode(), nil)
+	r.Header = header
+	if authority := md.Get(":authority"); len(authority) > 0 {
+		r.Host = authority[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+	return r
+}
+
+// grpcServer implements the Datas service
+type grpcServer struct {
+	datasv1.UnimplementedDatasServer
+}
+
+// OpenSession runs a session for the call
+func (grpcServer) OpenSession(stream datasv1.Datas_OpenSessionServer) error {
+	ctx := stream.Context()
+	if draining.Load() {
+		return grpcError(ctx, ErrDraining)
+	}
+	r := grpcSessionRequest(ctx)
+	request, err := newSessionRequest(r)
+	if err != nil {
+		return grpcError(ctx, err)
+	}
+
+	s := newGRPCStream(stream)
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s over gRPC (type: %s, flags: %s, engine: %s)\n",
+		clientID, clientAddr(r), request.dataType, request.flags, request.engine)
+	runClientThread(clientID, request.dataType, request.flags, s, request.setup)
+	// A client that closed its send side was detached without a close code
+	s.Close()
+	return s.err()
+}
+
+// newGRPCServer builds the gRPC server: over TLS when the HTTP server has a certificate,
+// otherwise cleartext HTTP/2 as gRPC clients expect
+func newGRPCServer() (*grpc.Server, error) {
+	options := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(grpcMaxMessage),
+		grpc.MaxConcurrentStreams(http2MaxConcurrentStreams),
+	}
+	if config.TLSCertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	srv := grpc.NewServer(options...)
+	datasv1.RegisterDatasServer(srv, grpcServer{})
+	return srv, nil
+}
+
+// startGRPCServer serves the gRPC API on its own port until shutdown is requested
+// An empty port disables it
+func startGRPCServer(ctx context.Context, wg *sync.WaitGroup, port string) {
+	defer wg.Done()
+	if port == "" {
+		return
+	}
+	srv, err := newGRPCServer()
+	if err != nil {
+		fmt.Println("gRPC server configuration error:", err)
+		return
+	}
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		fmt.Println("gRPC server error:", err)
+		return
+	}
+	fmt.Printf("gRPC server listening on port %s\n", port)
+	go func() {
+		if err := srv.Serve(listener); err != nil {
+			fmt.Println("gRPC server error:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	srv.Stop()
+}
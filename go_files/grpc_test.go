@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	datasv1 "datasServer/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCStatusOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{&ValidationError{"Missing required parameter: type"}, codes.InvalidArgument},
+		{errAdminRequired, codes.Unauthenticated},
+		{ErrSessionPrivate, codes.PermissionDenied},
+		{ErrServerBusy, codes.ResourceExhausted},
+		{ErrDraining, codes.Unavailable},
+		{errors.New("unexpected"), codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := grpcStatusOf(tt.err); got != tt.want {
+			t.Errorf("grpcStatusOf(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGRPCSessionRequest(t *testing.T) {
+	md := metadata.Pairs(
+		"datas-type", "btree",
+		"datas-engine", "go",
+		"datas-log-coalesce", "10",
+		"authorization", "Bearer s3",
+	)
+	r := grpcSessionRequest(metadata.NewIncomingContext(context.Background(), md))
+	want := map[string][]string{"type": {"btree"}, "engine": {"go"}, "log_coalesce": {"10"}}
+	if got := map[string][]string(r.URL.Query()); !reflect.DeepEqual(got, want) {
+		t.Errorf("query = %v, want %v", got, want)
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer s3" {
+		t.Errorf("Authorization = %q, want the metadata entry", got)
+	}
+	if r.Header.Get("Datas-Type") != "" {
+		t.Error("datas- metadata was copied into the headers")
+	}
+}
+
+// dialGRPC serves the Datas service in memory and returns a client for it
+func dialGRPC(t *testing.T) datasv1.DatasClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	srv, err := newGRPCServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return datasv1.NewDatasClient(conn)
+}
+
+func TestGRPCOpenSessionRejected(t *testing.T) {
+	client := dialGRPC(t)
+	tests := []struct {
+		name    string
+		md      metadata.MD
+		code    codes.Code
+		message string
+	}{
+		{"missing type", metadata.MD{}, codes.InvalidArgument, "Missing required parameter: type"},
+		{"unknown type", metadata.Pairs("datas-type", "nope"), codes.InvalidArgument, "Invalid type"},
+		{"unknown engine", metadata.Pairs("datas-type", "btree", "datas-engine", "nope"), codes.InvalidArgument, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), tt.md), 5*time.Second)
+			defer cancel()
+			stream, err := client.OpenSession(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = stream.Recv()
+			if status.Code(err) != tt.code || !strings.HasPrefix(status.Convert(err).Message(), tt.message) {
+				t.Errorf("got %v, want %v %q", err, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+// inSessionDir runs the test in a directory with the fifos/ sessions create their FIFOs in
+func inSessionDir(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "fifos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// openGoSession opens a B-tree session on the Go engine
+func openGoSession(t *testing.T, client datasv1.DatasClient) datasv1.Datas_OpenSessionClient {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	ctx = metadata.AppendToOutgoingContext(ctx, "datas-type", "btree", "datas-engine", "go")
+	stream, err := client.OpenSession(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+// recvProgram receives events until a program message starting with prefix
+func recvProgram(t *testing.T, stream datasv1.Datas_OpenSessionClient, prefix string) {
+	t.Helper()
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", prefix, err)
+		}
+		if event.Type == "program" && strings.Contains(event.Json, `"message":"`+prefix) {
+			return
+		}
+	}
+}
+
+func TestGRPCSession(t *testing.T) {
+	inSessionDir(t)
+	stream := openGoSession(t, dialGRPC(t))
+	recvProgram(t, stream, "READY")
+	stream.Send(&datasv1.Command{Line: "insert 5"})
+	recvProgram(t, stream, "INSERT_SUCCESS value=5")
+	stream.Send(&datasv1.Command{Line: "status"})
+	recvProgram(t, stream, "STATUS tree_size=1")
+	// Closing the send side ends the session normally
+	stream.CloseSend()
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("ended with %v, want OK", err)
+		}
+	}
+}
+
+func TestGRPCOversizedCommand(t *testing.T) {
+	inSessionDir(t)
+	stream := openGoSession(t, dialGRPC(t))
+	recvProgram(t, stream, "READY")
+	stream.Send(&datasv1.Command{Line: strings.Repeat("x", grpcMaxMessage+1)})
+	var err error
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("ended with %v, want ResourceExhausted", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionProbeTimeout bounds how long an interface may take to answer --version
+const versionProbeTimeout = 2 * time.Second
+
+// interfaceHealth is the result of probing one registered interface executable
+type interfaceHealth struct {
+	Name       string    `json:"name"`
+	Executable string    `json:"executable"`
+	Available  bool      `json:"available"`
+	Version    string    `json:"version,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+	err        error
+}
+
+var (
+	healthMu sync.RWMutex
+	// interfaceStatus holds the latest probe of each data structure; empty until the first check
+	interfaceStatus = make(map[string]interfaceHealth)
+)
+
+// probeInterface checks that an executable exists, is executable and answers --version
+func probeInterface(ds DataStructure) interfaceHealth {
+	health := interfaceHealth{Name: ds.Name, Executable: ds.Executable, CheckedAt: time.Now()}
+	health.err = func() error {
+		info, err := os.Stat(ds.Executable)
+		if err != nil {
+			return processStartError(ds.Name, err)
+		}
+		if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+			return fmt.Errorf("%w: %s is not executable", ErrBinaryMissing, ds.Executable)
+		}
+		version, err := interfaceVersion(ds.Executable)
+		health.Version = version
+		return err
+	}()
+
+	health.Available = health.err == nil
+	if health.err != nil {
+		health.Error = health.err.Error()
+	}
+	return health
+}
+
+// interfaceVersion runs an executable with --version and returns the first line it printed
+func interfaceVersion(executable string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, executable, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s --version: %v", ErrBinaryMissing, executable, err)
+	}
+	version, _, _ := strings.Cut(string(out), "\n")
+	if version = strings.TrimSpace(version); version == "" {
+		return "", fmt.Errorf("%w: %s --version printed nothing", ErrBinaryMissing, executable)
+	}
+	return version, nil
+}
+
+// checkInterfaces probes every registered interface and logs the results
+func checkInterfaces() []interfaceHealth {
+	results := make([]interfaceHealth, len(config.DataStructures))
+	var wg sync.WaitGroup
+	for i, ds := range config.DataStructures {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeInterface(ds)
+		}()
+	}
+	wg.Wait()
+
+	status := make(map[string]interfaceHealth, len(results))
+	for _, health := range results {
+		status[health.Name] = health
+		if health.Available {
+			fmt.Printf("Interface %s: %s (%s)\n", health.Name, health.Version, health.Executable)
+		} else {
+			fmt.Printf("Interface %s unavailable: %s\n", health.Name, health.Error)
+		}
+	}
+	healthMu.Lock()
+	interfaceStatus = status
+	healthMu.Unlock()
+	refreshMetadata()
+	return results
+}
+
+// interfaceAvailable returns why a data type's interface cannot run, or nil
+// Structures that were never probed are checked on the spot
+func interfaceAvailable(ds string) error {
+	healthMu.RLock()
+	health, ok := interfaceStatus[ds]
+	healthMu.RUnlock()
+	if !ok {
+		registered, found := lookupDataStructure(ds)
+		if !found {
+			return fmt.Errorf("%w: %s", ErrBinaryMissing, interfaceExecutable(ds))
+		}
+		health = probeInterface(*registered)
+	}
+	return health.err
+}
+
+// interfaceStatuses lists the latest probes in registry order
+func interfaceStatuses() []interfaceHealth {
+	healthMu.RLock()
+	defer healthMu.RUnlock()
+	statuses := []interfaceHealth{}
+	for _, ds := range config.DataStructures {
+		if health, ok := interfaceStatus[ds.Name]; ok {
+			statuses = append(statuses, health)
+		}
+	}
+	return statuses
+}
+
+// handleInterfaces serves GET /admin/interfaces; POST re-probes, e.g. after installing a binary
+func handleInterfaces(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		writeJSON(w, checkInterfaces())
+		return
+	}
+	writeJSON(w, interfaceStatuses())
+}
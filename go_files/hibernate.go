@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// idleCheckInterval is how often a session checks whether it went idle
+const idleCheckInterval = 10 * time.Second
+
+// touch marks the session as used by its clients
+func (s *Session) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// idleFor returns how long the clients have been silent
+func (s *Session) idleFor() time.Duration {
+	return time.Since(time.Unix(0, s.lastActive.Load()))
+}
+
+// runIdleWatch hibernates the session each time its clients stay silent for the configured time
+func (s *Session) runIdleWatch() {
+	idle := time.Duration(config.IdleHibernateSeconds) * time.Second
+	ticker := time.NewTicker(min(idleCheckInterval, idle))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// A gen job is still feeding commands even though the client is quiet
+			if s.idleFor() < idle || s.generating.Load() {
+				continue
+			}
+			if err := s.hibernate(); err != nil {
+				logError(s.ID, "hibernating session", err)
+			}
+		case <-s.inputClosed:
+			return
+		}
+	}
+}
+
+// hibernate saves the session's journal to the store and stops its process
+// The session and its clients stay attached; the next command restores it
+// The journal is the snapshot: replaying it rebuilds the structure exactly
+func (s *Session) hibernate() error {
+	s.procMu.Lock()
+	defer s.procMu.Unlock()
+	if s.proc == nil || s.hibernated {
+		return nil
+	}
+
+	s.saveRecord()
+	s.snapshot = s.stored.Ops
+	s.proc.stop()
+	s.proc = nil
+	s.hibernated = true
+	metrics.sessionsHibernated.Add(1)
+
+	fmt.Printf("[Client %s] Hibernated after %s idle, %d ops saved\n", s.ID, s.idleFor().Round(time.Second), len(s.snapshot))
+	err := s.reply(fmt.Sprintf("HIBERNATED ops=%d", len(s.snapshot)))
+
+	// Evicting locks the victims' procMu, so it cannot run under ours
+	evict := hibernation.add(s)
+	go func() {
+		for _, victim := range evict {
+			victim.evictHibernated()
+		}
+	}()
+	return err
+}
+
+// wakeLocked starts a fresh process for a hibernated session and replays its snapshot
+// The command that woke it may already be journaled, so the live journal is not used
+// procMu must be held
+func (s *Session) wakeLocked() error {
+	restores.acquire(s)
+	defer restores.release()
+
+	ops := s.snapshot
+	hibernation.remove(s)
+	if err := s.startProcessLocked(); err != nil {
+		return err
+	}
+	metrics.sessionsRestored.Add(1)
+	s.scriptMu.Lock()
+	s.script = append([]string(nil), ops...)
+	s.scriptMu.Unlock()
+
+	for _, line := range ops {
+		s.ops.sent(line)
+		if _, err := io.WriteString(s.proc.stdin, line+"\n"); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("[Client %s] Restored from hibernation, %d ops replayed\n", s.ID, len(ops))
+	return s.reply(fmt.Sprintf("RESTORED ops=%d", len(ops)))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// hibernationLRU tracks hibernated sessions, least recently used first, and the
+// bytes their snapshots take in the store
+type hibernationLRU struct {
+	mu      sync.Mutex
+	order   *list.List // of *Session; front was used least recently
+	entries map[*Session]*list.Element
+	bytes   int64
+}
+
+var hibernation = &hibernationLRU{order: list.New(), entries: make(map[*Session]*list.Element)}
+
+// snapshotBytes is the stored size of a snapshot
+func snapshotBytes(ops []string) int64 {
+	var n int64
+	for _, line := range ops {
+		n += int64(len(line)) + 1
+	}
+	return n
+}
+
+// add tracks a newly hibernated session, ordered by when its clients were last active
+// It returns the sessions to evict so the snapshots fit the storage cap again
+func (h *hibernationLRU) add(s *Session) []*Session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.entries[s]; ok {
+		return nil
+	}
+	active := s.lastActive.Load()
+	at := h.order.Back()
+	for at != nil && at.Value.(*Session).lastActive.Load() > active {
+		at = at.Prev()
+	}
+	if at == nil {
+		h.entries[s] = h.order.PushFront(s)
+	} else {
+		h.entries[s] = h.order.InsertAfter(s, at)
+	}
+	h.bytes += snapshotBytes(s.snapshot)
+
+	var evict []*Session
+	limit := config.HibernationStorageBytes
+	for e := h.order.Front(); limit > 0 && h.bytes > limit && e != nil; e = h.order.Front() {
+		victim := e.Value.(*Session)
+		h.removeLocked(victim)
+		evict = append(evict, victim)
+	}
+	return evict
+}
+
+// remove stops tracking a session that woke up or ended
+func (h *hibernationLRU) remove(s *Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(s)
+}
+
+// removeLocked is remove with h.mu already held
+func (h *hibernationLRU) removeLocked(s *Session) {
+	e, ok := h.entries[s]
+	if !ok {
+		return
+	}
+	h.order.Remove(e)
+	delete(h.entries, s)
+	h.bytes -= snapshotBytes(s.snapshot)
+}
+
+// stats returns how many sessions are hibernated and the bytes of their snapshots
+func (h *hibernationLRU) stats() (int, int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.order.Len(), h.bytes
+}
+
+// evictHibernated ends a hibernated session to free snapshot storage
+// Its record, journal included, stays in the store
+func (s *Session) evictHibernated() {
+	s.procMu.Lock()
+	hibernated := s.hibernated
+	s.procMu.Unlock()
+	if !hibernated {
+		return // woke up since it was picked
+	}
+
+	metrics.hibernationEvictions.Add(1)
+	fmt.Printf("[Client %s] Evicted from hibernation to stay under the storage cap\n", s.ID)
+	s.reply("EVICTED reason=hibernation_storage_full")
+	select {
+	case s.ended <- sessionEnd{message: "Evicted from hibernation", reason: evictedReason}:
+	default:
+	}
+}
+
+// restoreGate bounds how many hibernated sessions restore at once
+// Under contention the most recently used waiting session goes first
+type restoreGate struct {
+	mu      sync.Mutex
+	active  int
+	waiting []*restoreWaiter
+}
+
+// restoreWaiter is a session queued for a restore slot
+type restoreWaiter struct {
+	session *Session
+	ready   chan struct{}
+}
+
+var restores = &restoreGate{}
+
+// acquire blocks until the session may restore
+func (g *restoreGate) acquire(s *Session) {
+	g.mu.Lock()
+	limit := config.MaxConcurrentRestores
+	if limit <= 0 || (g.active < limit && len(g.waiting) == 0) {
+		g.active++
+		g.mu.Unlock()
+		return
+	}
+	w := &restoreWaiter{session: s, ready: make(chan struct{})}
+	g.waiting = append(g.waiting, w)
+	g.mu.Unlock()
+	<-w.ready
+}
+
+// release hands the slot to the waiting session used most recently, if any
+func (g *restoreGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.waiting) == 0 {
+		g.active--
+		return
+	}
+	next := 0
+	for i, w := range g.waiting {
+		if w.session.lastActive.Load() > g.waiting[next].session.lastActive.Load() {
+			next = i
+		}
+	}
+	w := g.waiting[next]
+	g.waiting = append(g.waiting[:next], g.waiting[next+1:]...)
+	close(w.ready)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// http2MaxConcurrentStreams is how many requests one HTTP/2 connection may multiplex
+const http2MaxConcurrentStreams = 250
+
+// configureHTTP2 enables HTTP/2 on the HTTP server: over TLS through ALPN, and
+// optionally as cleartext h2c for trusted proxies. WebSocket upgrades keep using
+// HTTP/1.1 since the server never advertises extended CONNECT. Handlers never push.
+func configureHTTP2(srv *http.Server) error {
+	h2s := &http2.Server{MaxConcurrentStreams: http2MaxConcurrentStreams}
+	if err := http2.ConfigureServer(srv, h2s); err != nil {
+		return err
+	}
+	if config.H2C {
+		handler := srv.Handler
+		if handler == nil {
+			handler = http.DefaultServeMux
+		}
+		srv.Handler = trustedH2C(h2c.NewHandler(handler, h2s), handler)
+	}
+	return nil
+}
+
+// trustedH2C serves h2c only to clients in the trusted proxies list;
+// anyone else attempting h2c is answered over HTTP/1.1
+func trustedH2C(h2cHandler, plain http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trustedProxy(r.RemoteAddr) {
+			h2cHandler.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == "PRI" && r.URL.Path == "*" {
+			http.Error(w, "h2c not allowed", http.StatusForbidden)
+			return
+		}
+		if strings.EqualFold(r.Header.Get("Upgrade"), "h2c") {
+			r.Header.Del("Upgrade")
+			r.Header.Del("HTTP2-Settings")
+		}
+		plain.ServeHTTP(w, r)
+	})
+}
+
+// trustedProxy reports whether a remote address is in one of the trusted proxy networks
+// Entries are IP addresses or CIDR ranges
+func trustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range config.TrustedProxies {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(entry); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
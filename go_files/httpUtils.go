@@ -2,39 +2,48 @@ package main
 
 import (
 	"net/http"
-	"strconv"
+	"net/url"
+	"strings"
 )
 
-// validateDataType checks if the data structure type is valid
+// validateDataType checks if the data structure type is registered
 func validateDataType(dataType string) bool {
-	validTypes := map[string]bool{
-		"btree":   true,
-		"avltree": true,
-	}
-	return validTypes[dataType]
+	_, ok := lookupDataStructure(dataType)
+	return ok
 }
 
 // buildFlags creates command line flags based on data type and parameters
 func buildFlags(dataType string, r *http.Request) (string, error) {
-	switch dataType {
-	case "btree":
-		order := r.URL.Query().Get("order")
-		if order == "" {
-			return "", nil
-		}
-		// Validate order is a number >= 2
-		if orderInt, err := strconv.Atoi(order); err != nil || orderInt < 2 {
-			return "", &ValidationError{"Invalid order. Must be integer >= 2"}
-		}
-		return "--order " + order, nil
-
-	case "avltree":
-		// AVL tree doesn't need special flags for now
-		return "", nil
+	return buildFlagsFromParams(dataType, r.URL.Query())
+}
 
-	default:
+// buildFlagsFromParams creates command line flags from query-style parameters
+// Sessions keep their flags as the arguments joined by spaces; flagArgs splits them again
+func buildFlagsFromParams(dataType string, params url.Values) (string, error) {
+	ds, ok := lookupDataStructure(dataType)
+	if !ok {
 		return "", &ValidationError{"Unsupported data type"}
 	}
+	args, err := ds.buildFlags(params)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(args, " "), nil
+}
+
+// interfaceArgs returns the command line of a data type's C++ interface: the session's
+// flags, checked against the registry, followed by the given stream options and --batch
+func interfaceArgs(dataType, flags string, streams ...string) ([]string, error) {
+	ds, ok := lookupDataStructure(dataType)
+	if !ok {
+		return nil, &ValidationError{"Unsupported data type"}
+	}
+	args, err := ds.flagArgs(flags)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, streams...)
+	return append(args, "--batch"), nil
 }
 
 // ValidationError represents a validation error
@@ -56,7 +65,7 @@ func validateRequest(r *http.Request) (string, string, error) {
 
 	// Validate data structure type
 	if !validateDataType(dataType) {
-		return "", "", &ValidationError{"Invalid type. Supported types: btree, avltree"}
+		return "", "", &ValidationError{"Invalid type. Supported types: " + strings.Join(dataStructureNames(), ", ")}
 	}
 
 	// Build flags for the data type
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idGenerator produces unique session IDs
+type idGenerator interface {
+	next() string
+}
+
+// idGen is the active ID generator, chosen from config at startup
+var idGen idGenerator = &ulidIDs{}
+
+// newIDGenerator builds the generator described by the config
+func newIDGenerator(cfg Config) (idGenerator, error) {
+	var gen idGenerator
+	switch cfg.IDStrategy {
+	case "", "ulid":
+		gen = &ulidIDs{}
+	case "sequential":
+		seq := &sequentialIDs{statePath: cfg.IDStateFile}
+		if err := seq.load(); err != nil {
+			return nil, err
+		}
+		gen = seq
+	default:
+		return nil, fmt.Errorf("unknown id_strategy %q (use sequential or ulid)", cfg.IDStrategy)
+	}
+
+	if cfg.IDNamespace != "" {
+		if !validNamespace(cfg.IDNamespace) {
+			return nil, fmt.Errorf("id_namespace %q may only contain letters, digits, '_' and '-'", cfg.IDNamespace)
+		}
+		gen = &namespacedIDs{prefix: cfg.IDNamespace, inner: gen}
+	}
+	return gen, nil
+}
+
+// validNamespace reports whether a namespace is safe in IDs, which end up in URLs and FIFO paths
+func validNamespace(namespace string) bool {
+	for _, c := range namespace {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// sequentialIDs counts up from 1, persisting the counter so restarts never reuse an ID
+type sequentialIDs struct {
+	mu        sync.Mutex
+	counter   int64
+	statePath string // empty disables persistence (debug only)
+}
+
+// load restores the counter from the state file, if any
+func (g *sequentialIDs) load() error {
+	if g.statePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(g.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	counter, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ID state file %s: %w", g.statePath, err)
+	}
+	g.counter = counter
+	return nil
+}
+
+func (g *sequentialIDs) next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.counter++
+	if g.statePath != "" {
+		if err := os.WriteFile(g.statePath, []byte(strconv.FormatInt(g.counter, 10)), 0644); err != nil {
+			fmt.Println("Error persisting ID state:", err)
+		}
+	}
+	return fmt.Sprintf("%04d", g.counter)
+}
+
+// crockford is the ULID base32 alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidIDs generates monotonic ULIDs: 48-bit millisecond time + 80 random bits
+type ulidIDs struct {
+	mu     sync.Mutex
+	lastMs uint64
+	random [10]byte
+}
+
+func (g *ulidIDs) next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms > g.lastMs {
+		g.lastMs = ms
+		rand.Read(g.random[:])
+	} else {
+		// Same millisecond: increment the random part to stay sortable and unique
+		for i := len(g.random) - 1; i >= 0; i-- {
+			g.random[i]++
+			if g.random[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var raw [16]byte
+	binary.BigEndian.PutUint16(raw[0:2], uint16(g.lastMs>>32))
+	binary.BigEndian.PutUint32(raw[2:6], uint32(g.lastMs))
+	copy(raw[6:], g.random[:])
+	return encodeULID(raw)
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters
+func encodeULID(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// namespacedIDs prefixes another generator's IDs with the server's namespace
+type namespacedIDs struct {
+	prefix string
+	inner  idGenerator
+}
+
+func (g *namespacedIDs) next() string {
+	return g.prefix + "-" + g.inner.next()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSequentialIDsPersistAcrossRestarts(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.IDStrategy = "sequential"
+	cfg.IDStateFile = filepath.Join(t.TempDir(), "id_state")
+
+	seen := map[string]bool{}
+	for run := 0; run < 3; run++ {
+		gen, err := newIDGenerator(cfg)
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		for i := 0; i < 5; i++ {
+			ID := gen.next()
+			if seen[ID] {
+				t.Fatalf("run %d reused ID %s", run, ID)
+			}
+			seen[ID] = true
+		}
+	}
+	if !seen["0015"] {
+		t.Errorf("IDs %v do not count up to 0015", seen)
+	}
+	data, err := os.ReadFile(cfg.IDStateFile)
+	if err != nil || string(data) != "15" {
+		t.Errorf("state file holds %q, %v; want 15", data, err)
+	}
+}
+
+func TestSequentialIDsInvalidState(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.IDStrategy = "sequential"
+	cfg.IDStateFile = filepath.Join(t.TempDir(), "id_state")
+	if err := os.WriteFile(cfg.IDStateFile, []byte("not a number"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newIDGenerator(cfg); err == nil {
+		t.Error("a corrupt state file was accepted")
+	}
+}
+
+func TestULIDs(t *testing.T) {
+	gen, err := newIDGenerator(defaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gen.(*ulidIDs); !ok {
+		t.Fatalf("default generator is %T, want ULIDs", gen)
+	}
+	last := ""
+	for i := 0; i < 1000; i++ {
+		ID := gen.next()
+		if len(ID) != 26 || strings.Trim(ID, crockford) != "" {
+			t.Fatalf("%q is not a ULID", ID)
+		}
+		// Monotonic within and across milliseconds
+		if ID <= last {
+			t.Fatalf("%s does not sort after %s", ID, last)
+		}
+		last = ID
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	var raw [16]byte
+	if got := encodeULID(raw); got != strings.Repeat("0", 26) {
+		t.Errorf("zero encodes as %s", got)
+	}
+	for i := range raw {
+		raw[i] = 0xff
+	}
+	if got := encodeULID(raw); got != "7"+strings.Repeat("Z", 25) {
+		t.Errorf("all ones encode as %s", got)
+	}
+}
+
+func TestIDNamespace(t *testing.T) {
+	tests := []struct {
+		namespace string
+		valid     bool
+	}{
+		{"tenant-a", true},
+		{"Tenant_01", true},
+		{"a/b", false},
+		{"../x", false},
+		{"a b", false},
+		{"ünï", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.IDNamespace = tt.namespace
+			gen, err := newIDGenerator(cfg)
+			if !tt.valid {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ID := gen.next(); !strings.HasPrefix(ID, tt.namespace+"-") {
+				t.Errorf("%s is not prefixed with %s-", ID, tt.namespace)
+			}
+		})
+	}
+}
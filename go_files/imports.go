@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// importTTL is how long an uploaded dataset waits for its WebSocket
+	importTTL = 5 * time.Minute
+	// maxImportKeys caps the size of one bulk import
+	maxImportKeys = 100000
+	// maxImportBody caps the request body of a bulk import
+	maxImportBody = 8 << 20
+	// importProgressSteps is how many progress messages a bulk load emits
+	importProgressSteps = 20
+)
+
+// ErrImportNotFound is returned for unknown, expired or already used import tickets
+var ErrImportNotFound = errors.New("import not found or expired")
+
+// pendingImport is a dataset uploaded with POST /session, waiting for its session
+type pendingImport struct {
+	Type    string
+	Keys    []string // keys in wire form, checked against the structure's key type
+	Expires time.Time
+}
+
+var (
+	importsMu      sync.Mutex
+	pendingImports = make(map[string]*pendingImport)
+)
+
+// importResponse is returned to the client after a successful upload
+type importResponse struct {
+	Import     string    `json:"import"`
+	Type       string    `json:"type"`
+	Keys       int       `json:"keys"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	SessionURL string    `json:"session_url"`
+}
+
+// handleSessionImport serves POST /session: stores the keys and returns a ticket
+// The client then opens the WebSocket with ?import=<ticket> to start the session
+func handleSessionImport(w http.ResponseWriter, r *http.Request) {
+	dataType, _, err := validateRequest(r)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	registered, _ := lookupDataStructure(dataType)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+	keys, err := parseImportKeys(r, registered.keyType())
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	token := newImportToken()
+	pending := &pendingImport{Type: dataType, Keys: keys, Expires: time.Now().Add(importTTL)}
+
+	importsMu.Lock()
+	dropExpiredImports()
+	pendingImports[token] = pending
+	importsMu.Unlock()
+
+	query := r.URL.Query()
+	query.Set("import", token)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(importResponse{
+		Import:     token,
+		Type:       dataType,
+		Keys:       len(keys),
+		ExpiresAt:  pending.Expires,
+		SessionURL: "/session?" + query.Encode(),
+	})
+}
+
+// parseImportKeys reads keys from a JSON body ({"keys":[...]} or a bare array of numbers
+// or strings) or from a multipart form field/file named "keys"; there numeric keys are
+// whitespace or comma separated and string keys come one per line
+func parseImportKeys(r *http.Request, keyType string) ([]string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var raw []string
+	switch mediaType {
+	case "multipart/form-data":
+		text, err := multipartKeys(r)
+		if err != nil {
+			return nil, err
+		}
+		if keyType == keyString {
+			for _, line := range strings.Split(text, "\n") {
+				if line = strings.TrimSuffix(line, "\r"); line != "" {
+					raw = append(raw, line)
+				}
+			}
+			break
+		}
+		raw = strings.FieldsFunc(text, func(c rune) bool {
+			return c == ',' || c == ' ' || c == '\n' || c == '\r' || c == '\t'
+		})
+
+	default:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		var values []json.RawMessage
+		var wrapped struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		if err := json.Unmarshal(body, &values); err != nil {
+			if err := json.Unmarshal(body, &wrapped); err != nil {
+				return nil, &ValidationError{"Invalid JSON body. Expected {\"keys\":[...]} or an array of keys"}
+			}
+			values = wrapped.Keys
+		}
+		for _, value := range values {
+			var key string
+			if json.Unmarshal(value, &key) != nil {
+				key = string(value)
+			}
+			raw = append(raw, key)
+		}
+	}
+
+	keys := make([]string, 0, len(raw))
+	for _, key := range raw {
+		normalized, err := normalizeKey(keyType, key)
+		if err != nil {
+			return nil, &ValidationError{"Invalid key: " + key}
+		}
+		keys = append(keys, normalized)
+	}
+
+	if len(keys) == 0 {
+		return nil, &ValidationError{"No keys to import"}
+	}
+	if len(keys) > maxImportKeys {
+		return nil, &ValidationError{fmt.Sprintf("Too many keys. Maximum is %d", maxImportKeys)}
+	}
+	return keys, nil
+}
+
+// multipartKeys returns the "keys" form value or the content of the "keys" file
+func multipartKeys(r *http.Request) (string, error) {
+	if err := r.ParseMultipartForm(maxImportBody); err != nil {
+		return "", &ValidationError{"Invalid multipart body"}
+	}
+	if value := r.FormValue("keys"); value != "" {
+		return value, nil
+	}
+	file, _, err := r.FormFile("keys")
+	if err != nil {
+		return "", &ValidationError{"Missing multipart field: keys"}
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	return string(data), err
+}
+
+// claimImport removes and returns a pending import for the given data type
+func claimImport(token, dataType string) (*pendingImport, error) {
+	importsMu.Lock()
+	defer importsMu.Unlock()
+	dropExpiredImports()
+
+	pending, ok := pendingImports[token]
+	if !ok {
+		return nil, ErrImportNotFound
+	}
+	if pending.Type != dataType {
+		return nil, &ValidationError{"Import type does not match: " + pending.Type}
+	}
+	delete(pendingImports, token)
+	return pending, nil
+}
+
+// dropExpiredImports forgets uploads nobody claimed in time (importsMu must be held)
+func dropExpiredImports() {
+	now := time.Now()
+	for token, pending := range pendingImports {
+		if now.After(pending.Expires) {
+			delete(pendingImports, token)
+		}
+	}
+}
+
+// newImportToken returns a random unguessable ticket
+func newImportToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// bulkInsert inserts keys into the session, streaming progress to the client
+func (s *Session) bulkInsert(keys []string) error {
+	total := len(keys)
+	step := max(total/importProgressSteps, 1)
+
+	s.reply(fmt.Sprintf("IMPORT_START keys=%d", total))
+	for i, key := range keys {
+		line := "insert " + key
+		s.record(line)
+		if err := s.sendCommand(line); err != nil {
+			return err
+		}
+		if done := i + 1; done%step == 0 && done < total {
+			s.reply(fmt.Sprintf("IMPORT_PROGRESS done=%d total=%d", done, total))
+		}
+	}
+	s.reply(fmt.Sprintf("IMPORT_DONE keys=%d", total))
+	return nil
+}
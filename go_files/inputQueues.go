@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	// controlQueueSize bounds pending control messages per session
+	controlQueueSize = 64
+	// dataQueueSize bounds pipelined commands waiting for the C++ process
+	dataQueueSize = 4096
+	// bulkQueueSize bounds batch job commands; kept small so jobs block instead of
+	// building a backlog that interactive commands would have to wait behind
+	bulkQueueSize = 16
+)
+
+// dispatch parses a client line and routes it to the control or data queue
+// It never blocks, so control messages are not stuck behind a large batch of commands
+// Returns a violation if the line was rejected
+func (s *Session) dispatch(line string) *ProtocolViolation {
+	s.touch()
+	violation := s.enqueue(line)
+	if violation != nil {
+		s.inspect(inspectIn, "client", line, decisionRejected, violation.Code)
+	}
+	return violation
+}
+
+// enqueue validates a client line and puts it on its queue
+func (s *Session) enqueue(line string) *ProtocolViolation {
+	msg, violation := parseClientLine(line)
+	if violation != nil {
+		return violation
+	}
+
+	switch msg.class() {
+	case classControl:
+		s.inspect(inspectIn, "client", line, decisionValidated, "op="+msg.Op)
+		select {
+		case s.controlQueue <- msg:
+			s.inspect(inspectIn, "client", line, decisionQueued, "control")
+		default:
+			return &ProtocolViolation{violationQueueFull, "Control queue full", line}
+		}
+	default:
+		if _, ok := dataOps[msg.Op]; !ok && msg.Op != "command" {
+			return &ProtocolViolation{violationUnknownOp, "Unknown op: " + msg.Op, line}
+		}
+		s.inspect(inspectIn, "client", line, decisionValidated, "op="+msg.Op)
+		select {
+		case s.dataQueue <- msg:
+			s.inspect(inspectIn, "client", line, decisionQueued, "data")
+		default:
+			return &ProtocolViolation{violationQueueFull, "Data queue full", line}
+		}
+	}
+	return nil
+}
+
+// runControlQueue handles control messages as soon as they arrive
+func (s *Session) runControlQueue() {
+	for {
+		select {
+		case msg := <-s.controlQueue:
+			if err := controlOps[msg.Op](s, msg); err != nil {
+				s.reply(fmt.Sprintf("ERROR op=%s code=%s error=%s", msg.Op, recordError(err), err))
+			}
+		case <-s.inputClosed:
+			return
+		}
+	}
+}
+
+// runDataQueue feeds queued commands to the C++ process in order, honoring pause
+// Interactive commands from the client take priority over batch job commands
+func (s *Session) runDataQueue() {
+	for {
+		// Priority lane: drain interactive commands first
+		select {
+		case msg := <-s.dataQueue:
+			if !s.processData(msg) {
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case msg := <-s.dataQueue:
+			if !s.processData(msg) {
+				return
+			}
+		case msg := <-s.bulkQueue:
+			if !s.processData(msg) {
+				return
+			}
+		case <-s.inputClosed:
+			return
+		}
+	}
+}
+
+// processData runs one data message: a data op, a server command or a line for the C++ process
+// Returns false when the session can no longer accept commands
+func (s *Session) processData(msg clientMessage) bool {
+	if !s.waitWhilePaused() {
+		return false
+	}
+	if msg.Op != "command" {
+		s.inspect(inspectIn, "client", "op="+msg.Op, decisionHandled, "")
+		if err := dataOps[msg.Op](s, msg); err != nil {
+			s.reply(fmt.Sprintf("ERROR op=%s error=%s", msg.Op, err))
+		}
+		return true
+	}
+
+	line := msg.Command
+	if handleServerCommand(s, line) {
+		s.inspect(inspectIn, "client", line, decisionHandled, "server_command")
+		return true
+	}
+	mutating := false
+	if registered, ok := lookupDataStructure(s.DataType); ok {
+		normalized, err := registered.normalizeCommand(line)
+		if err != nil {
+			s.inspect(inspectIn, "client", line, decisionRejected, err.Error())
+			s.reply(fmt.Sprintf("ERROR command=%s error=%s", commandName(line), err))
+			return true
+		}
+		line = normalized
+		mutating = registered.mutates(commandName(line))
+	}
+	s.record(line)
+	if err := s.sendCommand(line); err != nil {
+		s.inspect(inspectIn, "client", line, decisionDropped, err.Error())
+		logError(s.ID, "writing to C++ process", err)
+		return false
+	}
+	s.inspect(inspectIn, "client", line, decisionForwarded, "")
+	if commandName(line) == "status" {
+		s.reply("JOURNAL " + s.journalStatus())
+	}
+	// Commands a batch job queued together share the delta sent after the last of them
+	if s.deltas != nil && mutating && len(s.bulkQueue) == 0 {
+		s.sendDelta()
+	}
+	return true
+}
+
+// setPaused pauses or resumes the data queue
+func (s *Session) setPaused(paused bool) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if paused == s.paused {
+		return
+	}
+	s.paused = paused
+	if paused {
+		s.resumed = make(chan struct{})
+	} else {
+		close(s.resumed)
+	}
+}
+
+// waitWhilePaused blocks until the session is resumed
+// Returns false if the session ended while paused
+func (s *Session) waitWhilePaused() bool {
+	s.pauseMu.Lock()
+	paused, resumed := s.paused, s.resumed
+	s.pauseMu.Unlock()
+	if !paused {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-s.inputClosed:
+		return false
+	}
+}
+
+// subscribe limits forwarding to the given output streams
+func (s *Session) subscribe(streams []string) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	s.unsubscribed = map[string]bool{"program": true, "log": true}
+	for _, stream := range streams {
+		delete(s.unsubscribed, stream)
+	}
+}
+
+// subscribed reports whether an output stream is forwarded to the client
+func (s *Session) subscribed(stream string) bool {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	return !s.unsubscribed[stream]
+}
+
+// consumeProgram decides whether a program line is kept from the client
+func (s *Session) consumeProgram(line string) bool {
+	s.ops.observe(line)
+	switch {
+	case s.observeProgram(line):
+		s.inspect(inspectOut, "program", line, decisionDropped, "captured")
+		return true
+	case !s.subscribed("program"):
+		s.inspect(inspectOut, "program", line, decisionDropped, "unsubscribed")
+		return true
+	}
+	s.inspect(inspectOut, "program", line, decisionSent, "")
+	return false
+}
+
+// consumeLog decides whether a log line is kept from the client
+func (s *Session) consumeLog(line string) bool {
+	if !s.subscribed("log") {
+		s.inspect(inspectOut, "log", line, decisionDropped, "unsubscribed")
+		return true
+	}
+	if !s.verbose(line) {
+		s.inspect(inspectOut, "log", line, decisionDropped, "verbosity_"+logVerbosity(s.verbosity.Load()).String())
+		return true
+	}
+	if !s.allowLog() {
+		s.inspect(inspectOut, "log", line, decisionDropped, "bandwidth_"+s.bandwidthMode())
+		return true
+	}
+	decision := decisionSent
+	if s.bandwidth.mode.Load() == bandwidthSampling {
+		decision = decisionSampled
+	}
+	s.inspect(inspectOut, "log", line, decision, "")
+	return false
+}
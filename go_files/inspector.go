@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrNotInspected is returned when inspecting a session not started with debug_protocol=true
+var ErrNotInspected = errors.New("session is not in protocol debug mode")
+
+// Directions of an inspected message
+const (
+	inspectIn  = "in"  // sent by a client
+	inspectOut = "out" // sent to the clients
+)
+
+// Handling decisions the inspector annotates messages with
+const (
+	decisionValidated = "validated" // parsed into a known message
+	decisionQueued    = "queued"    // waiting in the control or data queue
+	decisionRejected  = "rejected"  // refused; the detail says why
+	decisionHandled   = "handled"   // answered by the server without the process
+	decisionForwarded = "forwarded" // written to the process stdin
+	decisionSent      = "sent"
+	decisionSampled   = "sampled" // sent while the bandwidth cap thins out tree logs
+	decisionDropped   = "dropped" // kept from the clients; the detail says why
+)
+
+// inspectorEvent is one protocol message of a session as seen by the server
+type inspectorEvent struct {
+	Type      string    `json:"type"` // always "inspect"
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Stream    string    `json:"stream"` // client, server, program or log
+	Message   string    `json:"message"`
+	Decision  string    `json:"decision"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// inspect mirrors one message and its handling to the session's inspectors
+// A no-op unless the session was started with debug_protocol=true
+func (s *Session) inspect(direction, stream, message, decision, detail string) {
+	if s.inspector == nil {
+		return
+	}
+	s.inspector.WriteMessage(newOutgoingMessage(inspectorEvent{
+		Type:      "inspect",
+		Time:      time.Now(),
+		Direction: direction,
+		Stream:    stream,
+		Message:   message,
+		Decision:  decision,
+		Detail:    detail,
+	}))
+}
+
+// inspectValue mirrors a structured server message
+func (s *Session) inspectValue(v any, err error) {
+	if s.inspector == nil {
+		return
+	}
+	data, _ := json.Marshal(v)
+	decision, detail := sentDecision(err)
+	s.inspect(inspectOut, "server", string(data), decision, detail)
+}
+
+// sentDecision returns the decision of a message written to the clients, and why it was dropped
+func sentDecision(err error) (string, string) {
+	if err != nil {
+		return decisionDropped, errorCode(err)
+	}
+	return decisionSent, ""
+}
+
+// handleInspectClient serves GET /admin/sessions/{id}/inspect, a WebSocket streaming
+// every protocol message of a session in debug mode together with its handling
+func handleInspectClient(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	if session.inspector == nil {
+		httpError(w, ErrNotInspected)
+		return
+	}
+
+	conn, err := upgradeClient(w, r, nil)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+	defer conn.Close()
+
+	id, events, err := session.inspector.subscribe()
+	if err != nil {
+		sendError(conn, err)
+		return
+	}
+	defer session.inspector.unsubscribe(id)
+	clientID := genID()
+	fmt.Printf("[Client %s] Inspecting session %s\n", clientID, session.ID)
+	sendJSONMessage(conn, "server", "INSPECTING session="+session.ID)
+
+	for event := range events {
+		if _, err := event.writeTo(conn); err != nil {
+			return
+		}
+	}
+	if session.inspector.hasEnded() {
+		sendJSONMessage(conn, "server", "INSPECTOR_ENDED session="+session.ID)
+		conn.CloseWithCode(session.closeFrame())
+	} else {
+		sendJSONMessage(conn, "server", "ERROR inspector_too_slow")
+	}
+	fmt.Printf("[Client %s] Stopped inspecting session %s\n", clientID, session.ID)
+}
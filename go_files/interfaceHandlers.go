@@ -2,11 +2,11 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"os/exec"
+	"time"
 )
 
 // Message represents a structured message to send to client
@@ -17,120 +17,243 @@ type Message struct {
 
 // sendJSONMessage sends a structured JSON message to client
 func sendJSONMessage(writer io.Writer, msgType string, content string) error {
-	msg := Message{
+	return sendJSONValue(writer, Message{
 		Type:    msgType,
 		Content: content,
-	}
+	})
+}
 
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		return err
-	}
+// sendJSONValue sends any JSON-encodable value as one message to client, in the encoding
+// of the client socket; a session fanout encodes it once for each encoding its clients use
+func sendJSONValue(writer io.Writer, msg any) error {
+	return sendMessage(writer, newOutgoingMessage(msg))
+}
 
-	// Add newline for message separation
-	_, err = fmt.Fprintln(writer, string(jsonData))
+// sendMessage sends one message to a client socket or a session
+func sendMessage(writer io.Writer, m *outgoingMessage) error {
+	var n int
+	var err error
+	if w, ok := writer.(messageWriter); ok {
+		n, err = w.WriteMessage(m)
+	} else {
+		n, err = m.writeTo(writer)
+	}
+	if err == nil {
+		metrics.messagesSent.Add(1)
+		metrics.bytesSent.Add(int64(n))
+	}
 	return err
 }
 
 // --- Utility Functions ---
 
-// startCppProcess starts the C++ interface with given FIFOs
-func startCppProcess(ds, flags, progFifo, logFifo string, webSocket io.Reader) (*exec.Cmd, error) {
-	cmd := exec.Command("./"+ds+"Interface.exe",
-		flags,
-		"--program-out", progFifo,
-		"--tree-log-out", logFifo,
-		"--batch",
-	)
-	// For now: forward Go stdin → C++ stdin
-	cmd.Stdin = webSocket
-	return cmd, cmd.Start()
+// interfaceExecutable returns the path of the C++ interface for a data type
+func interfaceExecutable(ds string) string {
+	if registered, ok := lookupDataStructure(ds); ok {
+		return registered.Executable
+	}
+	return "./" + ds + "Interface.exe"
+}
+
+// startCppProcess starts a C++ interface binary of the data type with given FIFOs
+func startCppProcess(ds, executable string, args []string, progFifo, logFifo string) (*processHandle, error) {
+	cmd := exec.Command(executable, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	wait, err := startInterfaceCommand(cmd)
+	if err != nil {
+		stdin.Close()
+		return nil, processStartError(ds, err)
+	}
+	return &processHandle{
+		stdin: stdin,
+		wait:  wait,
+		kill:  func() { cmd.Process.Kill() },
+	}, nil
 }
 
-// forwardFifoJSON reads from FIFO and sends structured JSON messages
-// Returns a channel that closes when forwarding stops
-func forwardFifoJSON(fifo string, webSocket io.Writer, messageType string) <-chan struct{} {
+// forwardClientInput reads lines from the client and dispatches them to the session queues
+// Returns a channel that closes when the client stops sending
+func forwardClientInput(clientSocket io.ReadWriter, session *Session) <-chan struct{} {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		f, err := os.Open(fifo)
-		if err != nil {
-			fmt.Println("Error opening fifo:", fifo, err)
-			return
+		guard := &protocolGuard{clientSocket: clientSocket}
+		scanner := bufio.NewScanner(clientSocket)
+		if splitter, ok := clientSocket.(messageSplitter); ok {
+			scanner.Split(splitter.Split())
 		}
-		defer f.Close()
-		scanner := bufio.NewScanner(f)
 		for scanner.Scan() {
-			line := scanner.Text()
-			writeErr := sendJSONMessage(webSocket, messageType, line)
-			if writeErr != nil {
-				fmt.Printf("Client disconnected while writing %s output\n", messageType)
+			if violation := session.dispatch(scanner.Text()); violation != nil && !guard.report(violation) {
 				return
 			}
-			// If you want to debug, uncomment:
-			// fmt.Printf("[%s] %s\n", messageType, line)
 		}
 	}()
 	return done
 }
 
+// sessionSetup describes state restored into the process before client input is read
+type sessionSetup struct {
+	replay    []string       // commands replayed verbatim (saved trees)
+	bulkKeys  []string       // keys inserted with progress messages (bulk import)
+	record    *SessionRecord // existing store record (forks); nil creates one
+	broadcast bool           // fan output out to ?watch= viewers
+	owner     string         // signed-in user starting the session
+	engine    string         // engineCpp or engineGo; empty uses the configured default
+	language  string         // BCP 47 tag for exports; empty is English
+	seed      int64          // session seed; 0 picks one
+	relay     bool           // keep the session through reconnects with a resume token
+	private   bool           // an instructor's clone, hidden from spectators
+	// debugProtocol mirrors the session's protocol messages to admin inspectors
+	debugProtocol bool
+	logCoalesce   time.Duration // log batching interval; 0 uses config.LogCoalesceMillis
+	deltas        bool          // send keyframe and delta messages after mutating commands
+}
+
 // runClientThread manages one client session with its own FIFOs and process
-func runClientThread(ID string, ds string, flags string, clientSocket io.ReadWriter) {
+func runClientThread(ID string, ds string, flags string, clientSocket io.ReadWriter, setup *sessionSetup) {
 	fmt.Printf("[Client %s] Starting session\n", ID)
 
-	// Define fifo paths
-	progFifo := "fifos/" + ID + "_" + ds + "_program.fifo"
-	logFifo := "fifos/" + ID + "_" + ds + "_log.fifo"
-
-	// Create FIFOs
-	if err := makeFifo(progFifo); err != nil {
-		fmt.Printf("[Client %s] Error creating program FIFO: %v\n", ID, err)
-		return
+	// Attach the client first so it sees the process start and any setup progress;
+	// its commands wait in the data queue until setup is done
+	session := newSession(ID, ds, flags)
+	session.Engine = config.Engine
+	if setup != nil && setup.engine != "" {
+		session.Engine = setup.engine
 	}
-	if err := makeFifo(logFifo); err != nil {
-		fmt.Printf("[Client %s] Error creating log FIFO: %v\n", ID, err)
+	session.logCoalesce = time.Duration(config.LogCoalesceMillis) * time.Millisecond
+	if setup != nil {
+		if setup.logCoalesce > 0 {
+			session.logCoalesce = setup.logCoalesce
+		}
+		if setup.deltas {
+			session.deltas = &deltaStream{}
+		}
+		session.Language = setup.language
+		session.Private = setup.private
+		if setup.debugProtocol {
+			session.inspector = newBroadcastHub()
+		}
+	}
+	if session.Engine == engineCpp {
+		session.canary, session.onCanary = joinCanary(ds)
+	}
+	var relay *sessionRelay
+	if setup != nil && setup.relay {
+		var err error
+		if relay, err = session.startRelay(clientSocket); err != nil {
+			logError(ID, "attaching relay client", err)
+			return
+		}
+	} else if _, err := session.attach(clientSocket); err != nil {
+		logError(ID, "attaching client", err)
 		return
 	}
+	reason := "setup failed"
+	defer func() { session.teardown(reason) }()
 
-	// Start C++ interface
-	cmd, err := startCppProcess(ds, flags, progFifo, logFifo, clientSocket)
-	if err != nil {
-		fmt.Printf("[Client %s] Error starting C++ process: %v\n", ID, err)
+	// Start C++ interface and forward its FIFOs to the client
+	if err := session.startProcess(); err != nil {
+		logError(ID, "starting session", err)
+		if errors.Is(err, ErrServerBusy) {
+			sendBusy(clientSocket)
+		} else {
+			sendError(clientSocket, err)
+		}
 		return
 	}
+	metrics.sessionsStarted.Add(1)
 
-	// Forward FIFO → client socket as JSON messages
-	progDone := forwardFifoJSON(progFifo, clientSocket, "program")
-	logDone := forwardFifoJSON(logFifo, clientSocket, "log")
+	// Record the session in the store, keeping lineage of forks
+	if setup != nil && setup.record != nil {
+		session.stored = setup.record
+	} else {
+		session.stored = &SessionRecord{ID: ID, Type: ds, Flags: flags, Engine: session.Engine, Language: session.Language, Created: time.Now()}
+		if setup != nil {
+			session.stored.Owner = setup.owner
+		}
+	}
+	session.Owner = session.stored.Owner
+	if setup != nil && setup.seed != 0 {
+		session.stored.Seed = setup.seed
+	}
+	if session.stored.Seed == 0 {
+		session.stored.Seed = newSessionSeed()
+	}
+	session.Seed = session.stored.Seed
+	session.env = session.environment()
+	session.stored.Environment = &session.env
+	session.stored.Started = time.Now()
+	session.saveRecord()
 
-	// Monitor both C++ process and FIFO forwarding
-	processDone := make(chan error, 1)
-	go func() {
-		processDone <- cmd.Wait()
-	}()
+	// Presenter sessions publish their output to viewers
+	if setup != nil && setup.broadcast {
+		if err := session.startBroadcast(); err != nil {
+			logError(ID, "starting broadcast", err)
+			return
+		}
+	}
 
-	// Wait for ANY of these to finish
-	select {
-	case err := <-processDone:
-		if err != nil {
-			fmt.Printf("[Client %s] C++ process exited with error: %v\n", ID, err)
-		} else {
-			fmt.Printf("[Client %s] C++ process completed successfully\n", ID)
+	// Register the session so it can be reached outside the client socket
+	registerSession(session)
+	newWebhookEvent(webhookSessionStarted, session).publish()
+	session.publishToMQTT()
+	session.reply(fmt.Sprintf("SESSION id=%s join_code=%s %s", session.ID, session.JoinCode, session.env))
+	if session.hub != nil {
+		session.reply(fmt.Sprintf("BROADCAST session=%s url=/session?watch=%s", session.ID, session.ID))
+	}
+	if relay != nil {
+		session.reply(fmt.Sprintf("RELAY session=%s token=%s url=/session?resume=%s", session.ID, relay.token, relay.token))
+	}
+	if session.inspector != nil {
+		session.reply(fmt.Sprintf("INSPECTOR session=%s url=/admin/sessions/%s/inspect", session.ID, session.ID))
+	}
+	go session.runControlQueue()
+	if config.IdleHibernateSeconds > 0 {
+		go session.runIdleWatch()
+	}
+
+	// Restore any preloaded state before the clients take over
+	if setup != nil && len(setup.replay) > 0 {
+		if err := session.replay(setup.replay); err != nil {
+			logError(ID, "replaying preloaded commands", err)
+		}
+	}
+	if setup != nil && len(setup.bulkKeys) > 0 {
+		if err := session.bulkInsert(setup.bulkKeys); err != nil {
+			logError(ID, "during bulk import", err)
 		}
-	case <-progDone:
-		fmt.Printf("[Client %s] Program FIFO forwarding stopped (client likely disconnected)\n", ID)
-	case <-logDone:
-		fmt.Printf("[Client %s] Log FIFO forwarding stopped (client likely disconnected)\n", ID)
 	}
 
-	// Cleanup: kill process if still running
-	if cmd.Process != nil {
-		cmd.Process.Kill()
+	// Delta clients start from a keyframe of the restored state
+	if session.deltas != nil {
+		session.sendDelta()
 	}
 
-	// Clean up FIFOs
-	os.Remove(progFifo)
-	os.Remove(logFifo)
+	// Forward queued client commands → C++ stdin
+	go session.runDataQueue()
 
-	fmt.Printf("[Client %s] Session ended\n", ID)
+	// Wait for either the process or every client to finish
+	crashed := false
+	select {
+	case end := <-session.ended:
+		fmt.Printf("[Client %s] %s\n", ID, end.message)
+		reason = "process ended"
+		if end.reason != "" {
+			reason = end.reason
+		}
+		if errors.Is(end.err, ErrProcessCrashed) {
+			crashed = true
+			reason = "process crashed"
+			logError(ID, "running session", end.err)
+			session.send(newErrorEnvelope(end.err))
+			reportCrash(session, end.err)
+		}
+	case <-session.clients.empty:
+		fmt.Printf("[Client %s] Client input closed\n", ID)
+		reason = "clients left"
+	}
+	session.recordCanaryOutcome(crashed)
 }
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultInviteTTL is how long an invite is valid when no ttl is requested
+	defaultInviteTTL = time.Hour
+	// maxInviteTTL caps the ttl a client may request
+	maxInviteTTL = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInviteInvalid is returned for malformed or forged invite tokens
+	ErrInviteInvalid = errors.New("invalid invite")
+	// ErrInviteExpired is returned for invite tokens past their expiry
+	ErrInviteExpired = errors.New("invite expired")
+	// ErrJoinCodeMismatch is returned when an invite is requested without the session's join code
+	ErrJoinCodeMismatch = errors.New("invalid join code")
+)
+
+// Invite roles
+const (
+	roleEditor = "editor"
+	roleViewer = "viewer"
+)
+
+// invitePayload is the signed content of an invite token
+type invitePayload struct {
+	Session string `json:"s"`
+	Role    string `json:"r"`
+	Expires int64  `json:"e"` // unix seconds
+}
+
+// inviteResponse is returned by POST /session/{id}/invite
+type inviteResponse struct {
+	Token     string    `json:"token"`
+	Session   string    `json:"session"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+	URL       string    `json:"url"`
+}
+
+var (
+	inviteKeyOnce sync.Once
+	inviteKey     []byte
+)
+
+// inviteSigningKey returns the configured invite secret, or a random one
+// generated at startup (invites then do not survive a restart)
+func inviteSigningKey() []byte {
+	inviteKeyOnce.Do(func() {
+		if config.InviteSecret != "" {
+			inviteKey = []byte(config.InviteSecret)
+			return
+		}
+		inviteKey = make([]byte, 32)
+		rand.Read(inviteKey)
+	})
+	return inviteKey
+}
+
+// signInvite creates a token granting role on a session until expires
+func signInvite(sessionID, role string, expires time.Time) string {
+	payload, _ := json.Marshal(invitePayload{Session: sessionID, Role: role, Expires: expires.Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + inviteSignature(encoded)
+}
+
+// inviteSignature is the HMAC-SHA256 of an encoded payload
+func inviteSignature(encoded string) string {
+	mac := hmac.New(sha256.New, inviteSigningKey())
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyInvite checks an invite token's signature and expiry and returns its payload
+func verifyInvite(token string) (*invitePayload, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || subtle.ConstantTimeCompare([]byte(signature), []byte(inviteSignature(encoded))) != 1 {
+		return nil, ErrInviteInvalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInviteInvalid
+	}
+	var payload invitePayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInviteInvalid
+	}
+	if time.Now().Unix() >= payload.Expires {
+		return nil, ErrInviteExpired
+	}
+	return &payload, nil
+}
+
+// handleCreateInvite serves POST /session/{id}/invite?role=editor|viewer&ttl=30m
+// The caller proves membership with the session's join code (code parameter)
+func handleCreateInvite(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if subtle.ConstantTimeCompare([]byte(code), []byte(session.JoinCode)) != 1 {
+		httpError(w, ErrJoinCodeMismatch)
+		return
+	}
+
+	role := r.URL.Query().Get("role")
+	if role == "" {
+		role = roleViewer
+	}
+	if role != roleEditor && role != roleViewer {
+		httpError(w, &ValidationError{"Invalid role. Must be editor or viewer"})
+		return
+	}
+
+	ttl := defaultInviteTTL
+	if raw := r.URL.Query().Get("ttl"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 || parsed > maxInviteTTL {
+			httpError(w, &ValidationError{fmt.Sprintf("Invalid ttl. Must be a duration up to %s", maxInviteTTL)})
+			return
+		}
+		ttl = parsed
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	token := signInvite(session.ID, role, expires)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(inviteResponse{
+		Token:     token,
+		Session:   session.ID,
+		Role:      role,
+		ExpiresAt: expires,
+		URL:       "/session?invite=" + token,
+	})
+}
+
+// handleInviteClient attaches a WebSocket client to the session an invite grants access to
+func handleInviteClient(w http.ResponseWriter, r *http.Request, token string) {
+	invite, err := verifyInvite(token)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	session, ok := lookupSession(invite.Session)
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+
+	conn, err := upgradeClient(w, r, nil)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+
+	defer conn.Close()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s (invite: %s as %s)\n", clientID, clientAddr(r), session.ID, invite.Role)
+	if invite.Role == roleEditor {
+		joinSession(session, clientID, conn)
+	} else {
+		spectateSession(session, clientID, conn)
+	}
+}
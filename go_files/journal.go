@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ErrNothingToUndo is returned when the journal is at its start
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// ErrNothingToRedo is returned when the journal is at its end
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+// commandJournal is the ordered list of accepted state-changing commands of a session
+// position marks how many of them are applied to the current process
+type commandJournal struct {
+	mu       sync.Mutex
+	ops      []string
+	position int
+}
+
+// append records a newly applied command, discarding any undone ones after it
+func (j *commandJournal) append(line string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.ops = append(j.ops[:j.position], line)
+	j.position++
+}
+
+// applied returns a copy of the commands up to the current position
+func (j *commandJournal) applied() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.ops[:j.position]...)
+}
+
+// status returns the current position and the journal length
+func (j *commandJournal) status() (int, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.position, len(j.ops)
+}
+
+// undo steps back one command and returns the commands to replay
+func (j *commandJournal) undo() ([]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.position == 0 {
+		return nil, ErrNothingToUndo
+	}
+	j.position--
+	return append([]string(nil), j.ops[:j.position]...), nil
+}
+
+// redo steps forward one command and returns the commands to replay
+func (j *commandJournal) redo() ([]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.position == len(j.ops) {
+		return nil, ErrNothingToRedo
+	}
+	j.position++
+	return append([]string(nil), j.ops[:j.position]...), nil
+}
+
+// moveTo sets the position to n and returns the commands to replay
+func (j *commandJournal) moveTo(n int) ([]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if n < 0 || n > len(j.ops) {
+		return nil, &ValidationError{fmt.Sprintf("Invalid position. Must be between 0 and %d", len(j.ops))}
+	}
+	j.position = n
+	return append([]string(nil), j.ops[:n]...), nil
+}
+
+// journalStatus formats the journal position for status messages
+func (s *Session) journalStatus() string {
+	position, length := s.journal.status()
+	return fmt.Sprintf("position=%d length=%d", position, length)
+}
+
+// resetTo restarts the C++ process and replays ops into it without touching the journal
+func (s *Session) resetTo(ops []string, reason string) error {
+	s.reply("RESET reason=" + reason)
+	if err := s.restartProcess(); err != nil {
+		return err
+	}
+
+	// The script tracks what the current process has seen, for crash reports
+	s.scriptMu.Lock()
+	s.script = append([]string(nil), ops...)
+	s.scriptMu.Unlock()
+
+	for _, line := range ops {
+		if err := s.sendCommand(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cmdUndo reverts the last state-changing command
+func cmdUndo(session *Session, args []string) error {
+	ops, err := session.journal.undo()
+	if err != nil {
+		return err
+	}
+	if err := session.resetTo(ops, "undo"); err != nil {
+		return err
+	}
+	return session.reply("UNDO_SUCCESS " + session.journalStatus())
+}
+
+// cmdRedo reapplies the last undone command
+func cmdRedo(session *Session, args []string) error {
+	ops, err := session.journal.redo()
+	if err != nil {
+		return err
+	}
+	if err := session.resetTo(ops, "redo"); err != nil {
+		return err
+	}
+	return session.reply("REDO_SUCCESS " + session.journalStatus())
+}
+
+// cmdGoto restarts the process and replays the first N journaled commands
+// Usage: goto <N>
+func cmdGoto(session *Session, args []string) error {
+	if len(args) != 1 {
+		return &ValidationError{"usage=goto_<position>"}
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return &ValidationError{"Invalid position. Must be integer"}
+	}
+	ops, err := session.journal.moveTo(n)
+	if err != nil {
+		return err
+	}
+	if err := session.resetTo(ops, "goto"); err != nil {
+		return err
+	}
+	return session.reply("GOTO_SUCCESS " + session.journalStatus())
+}
+
+// cmdJournal reports the journal position
+func cmdJournal(session *Session, args []string) error {
+	return session.reply("JOURNAL " + session.journalStatus())
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	datasv1 "datasServer/proto"
+)
+
+func TestCommandJournal(t *testing.T) {
+	var j commandJournal
+	expect := func(ops []string, err error, want []string, position, length int) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ops, want) {
+			t.Errorf("replays %q, want %q", ops, want)
+		}
+		if p, l := j.status(); p != position || l != length {
+			t.Errorf("position=%d length=%d, want position=%d length=%d", p, l, position, length)
+		}
+	}
+
+	if _, err := j.undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("undo of an empty journal = %v", err)
+	}
+	j.append("insert 1")
+	j.append("insert 2")
+	j.append("insert 3")
+	ops, err := j.undo()
+	expect(ops, err, []string{"insert 1", "insert 2"}, 2, 3)
+	ops, err = j.undo()
+	expect(ops, err, []string{"insert 1"}, 1, 3)
+	ops, err = j.redo()
+	expect(ops, err, []string{"insert 1", "insert 2"}, 2, 3)
+	ops, err = j.moveTo(0)
+	expect(ops, err, nil, 0, 3)
+	ops, err = j.moveTo(3)
+	expect(ops, err, []string{"insert 1", "insert 2", "insert 3"}, 3, 3)
+	if _, err := j.redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("redo at the end = %v", err)
+	}
+	for _, n := range []int{-1, 4} {
+		if _, err := j.moveTo(n); err == nil {
+			t.Errorf("moveTo(%d) succeeded", n)
+		}
+	}
+
+	// A command applied after an undo discards the undone ones
+	j.moveTo(1)
+	j.append("remove 1")
+	if got := j.applied(); !reflect.DeepEqual(got, []string{"insert 1", "remove 1"}) {
+		t.Errorf("applied = %q", got)
+	}
+	if _, err := j.redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("redo after a new command = %v", err)
+	}
+}
+
+// recvServer receives events until a server message starting with prefix
+func recvServer(t *testing.T, stream datasv1.Datas_OpenSessionClient, prefix string) {
+	t.Helper()
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", prefix, err)
+		}
+		if event.Type == "server" && strings.Contains(event.Json, `"message":"`+prefix) {
+			return
+		}
+	}
+}
+
+// TestJournalSession moves a session through its journal and checks the replayed structure
+func TestJournalSession(t *testing.T) {
+	inSessionDir(t)
+	stream := openGoSession(t, dialGRPC(t))
+	recvProgram(t, stream, "READY")
+	for _, line := range []string{"insert 1", "insert 2", "insert 3"} {
+		stream.Send(&datasv1.Command{Line: line})
+		recvProgram(t, stream, "INSERT_SUCCESS")
+	}
+	steps := []struct {
+		command, reply, status string
+	}{
+		{"undo", "UNDO_SUCCESS position=2 length=3", "STATUS tree_size=2"},
+		{"redo", "REDO_SUCCESS position=3 length=3", "STATUS tree_size=3"},
+		{"goto 1", "GOTO_SUCCESS position=1 length=3", "STATUS tree_size=1"},
+		{"goto 9", "ERROR command=goto error=Invalid position", "STATUS tree_size=1"},
+		{"goto 0", "GOTO_SUCCESS position=0 length=3", "STATUS tree_size=0"},
+		{"undo", "ERROR command=undo error=" + ErrNothingToUndo.Error(), "STATUS tree_size=0"},
+		{"journal", "JOURNAL position=0 length=3", "STATUS tree_size=0"},
+	}
+	for _, step := range steps {
+		stream.Send(&datasv1.Command{Line: step.command})
+		recvServer(t, stream, step.reply)
+		stream.Send(&datasv1.Command{Line: "status"})
+		recvProgram(t, stream, step.status)
+	}
+
+	// A new command discards the undone ones
+	stream.Send(&datasv1.Command{Line: "insert 7"})
+	recvProgram(t, stream, "INSERT_SUCCESS")
+	stream.Send(&datasv1.Command{Line: "journal"})
+	recvServer(t, stream, "JOURNAL position=1 length=1")
+	stream.Send(&datasv1.Command{Line: "redo"})
+	recvServer(t, stream, "ERROR command=redo error="+ErrNothingToRedo.Error())
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Key types a data structure can declare in the registry
+const (
+	keyInt    = "int"
+	keyFloat  = "float"
+	keyString = "string"
+)
+
+// keyCommands take a key as their first argument
+var keyCommands = map[string]bool{
+	"insert": true,
+	"remove": true,
+	"find":   true,
+	"search": true,
+}
+
+// splitCommand splits a command line into fields like strings.Fields, except that a
+// field starting with a double quote runs to its closing quote and is unquoted with
+// Go escapes, so string keys may hold spaces, quotes and backslashes
+func splitCommand(line string) ([]string, error) {
+	var fields []string
+	rest := strings.TrimLeftFunc(line, unicode.IsSpace)
+	for rest != "" {
+		var field string
+		if rest[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, &ValidationError{"Unterminated or invalid quoted key"}
+			}
+			field, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+			if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+				return nil, &ValidationError{"Quoted key must be followed by a space"}
+			}
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			field, rest = rest[:end], rest[end:]
+		}
+		fields = append(fields, field)
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	return fields, nil
+}
+
+// quoteField returns the wire form of a string key or value: bare when it is one
+// plain word, otherwise double quoted with Go escapes; commas and brackets are
+// quoted too so keys can be read back from structure dumps
+func quoteField(field string) string {
+	if field == "" || strings.ContainsFunc(field, func(r rune) bool {
+		return strings.ContainsRune(`"\\,[]`, r) || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(field)
+	}
+	return field
+}
+
+// normalizeKey validates a key of the given type and returns its wire form
+func normalizeKey(keyType, raw string) (string, error) {
+	switch keyType {
+	case keyFloat:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return "", &ValidationError{"Invalid key. Must be a finite number: " + raw}
+		}
+		return strconv.FormatFloat(value, 'g', -1, 64), nil
+	case keyString:
+		return quoteField(raw), nil
+	default:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return "", &ValidationError{"Invalid key. Must be an integer: " + raw}
+		}
+		return strconv.Itoa(value), nil
+	}
+}
+
+// keyType returns the declared key type of a structure; int when unset
+func (ds *DataStructure) keyType() string {
+	if ds.KeyType == "" {
+		return keyInt
+	}
+	return ds.KeyType
+}
+
+// keyArity returns how many keys the structure's key commands take
+func (ds *DataStructure) keyArity() int {
+	return max(ds.KeyArity, 1)
+}
+
+// keyUsage describes the keys of a key command, e.g. "<int key>" or "<int key> <int key>"
+func (ds *DataStructure) keyUsage() string {
+	return strings.TrimSpace(strings.Repeat("<"+ds.keyType()+" key> ", ds.keyArity()))
+}
+
+// normalizeCommand validates the key of a key command, and the value of an insert,
+// and rewrites the line in its wire form; other commands pass through untouched
+// for the interface to judge
+func (ds *DataStructure) normalizeCommand(line string) (string, error) {
+	fields, err := splitCommand(line)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) == 0 || !keyCommands[fields[0]] {
+		return line, nil
+	}
+	keys := ds.keyArity()
+	if fields[0] == "insert" {
+		if len(fields) != keys+1 && len(fields) != keys+2 {
+			return "", &ValidationError{fmt.Sprintf("Usage: insert %s [value]", ds.keyUsage())}
+		}
+	} else if len(fields) != keys+1 {
+		return "", &ValidationError{fmt.Sprintf("Usage: %s %s", fields[0], ds.keyUsage())}
+	}
+	normalized := fields[0]
+	for _, raw := range fields[1 : keys+1] {
+		key, err := normalizeKey(ds.keyType(), raw)
+		if err != nil {
+			return "", err
+		}
+		normalized += " " + key
+	}
+	if len(fields) == keys+2 {
+		value := fields[keys+1]
+		if len(value) > config.MaxValueBytes {
+			return "", &ValidationError{fmt.Sprintf("Value too long. Maximum is %d bytes", config.MaxValueBytes)}
+		}
+		normalized += " " + quoteField(value)
+	}
+	return normalized, nil
+}
+
+// stringKey is a string key of a Go engine; it prints in its wire form so log
+// lines stay one field per key
+type stringKey string
+
+func (k stringKey) String() string {
+	return quoteField(string(k))
+}
+
+// engineKey is a key type a Go engine can be instantiated with
+type engineKey interface {
+	int | float64 | stringKey
+}
+
+// parseEngineKey reads the leading key of a command's arguments the way the
+// interface for that key type would
+func parseEngineKey[K engineKey](args []string) (K, bool) {
+	var key K
+	if len(args) == 0 {
+		return key, false
+	}
+	switch p := any(&key).(type) {
+	case *int:
+		value, ok := streamInt(args)
+		*p = value
+		return key, ok
+	case *float64:
+		_, err := fmt.Sscan(args[0], p)
+		return key, err == nil
+	case *stringKey:
+		*p = stringKey(args[0])
+	}
+	return key, true
+}
+
+// joinKeys formats keys the way the C++ loggers do
+func joinKeys[K cmp.Ordered](values []K, sep string) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line   string
+		fields []string
+		valid  bool
+	}{
+		{"insert 5", []string{"insert", "5"}, true},
+		{"  find\t7  ", []string{"find", "7"}, true},
+		{"", nil, true},
+		{`insert "a b" "say \"hi\""`, []string{"insert", "a b", `say "hi"`}, true},
+		{`insert "" x`, []string{"insert", "", "x"}, true},
+		{`insert "a\\b"`, []string{"insert", `a\b`}, true},
+		{`insert "unterminated`, nil, false},
+		{`insert "a"b`, nil, false},
+	}
+	for _, tt := range tests {
+		fields, err := splitCommand(tt.line)
+		if !tt.valid {
+			if _, ok := err.(*ValidationError); !ok {
+				t.Errorf("splitCommand(%q) = %q, %v; want a ValidationError", tt.line, fields, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("splitCommand(%q) = %q, %v; want %q", tt.line, fields, err, tt.fields)
+		}
+	}
+}
+
+func TestQuoteFieldRoundTrip(t *testing.T) {
+	for _, field := range []string{"plain", "", "two words", `"quoted"`, `back\slash`, "a,b", "[x]", "tab\there", "é"} {
+		fields, err := splitCommand("insert " + quoteField(field))
+		if err != nil || len(fields) != 2 || fields[1] != field {
+			t.Errorf("%q quoted as %s reads back as %q, %v", field, quoteField(field), fields, err)
+		}
+	}
+	if got := quoteField("plain"); got != "plain" {
+		t.Errorf("quoteField(plain) = %s, want it bare", got)
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		keyType, raw string
+		want         string
+		valid        bool
+	}{
+		{keyInt, "42", "42", true},
+		{keyInt, "+7", "7", true},
+		{keyInt, "-0", "0", true},
+		{keyInt, "1.5", "", false},
+		{keyInt, "x", "", false},
+		{"", "3", "3", true},
+		{keyFloat, "1.50", "1.5", true},
+		{keyFloat, "1e3", "1000", true},
+		{keyFloat, "NaN", "", false},
+		{keyFloat, "Inf", "", false},
+		{keyString, "apple", "apple", true},
+		{keyString, "a b", `"a b"`, true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeKey(tt.keyType, tt.raw)
+		if !tt.valid {
+			if _, ok := err.(*ValidationError); !ok {
+				t.Errorf("normalizeKey(%s, %q) = %q, %v; want a ValidationError", tt.keyType, tt.raw, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeKey(%s, %q) = %q, %v; want %q", tt.keyType, tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeCommand(t *testing.T) {
+	intKeys := &DataStructure{Name: "btree"}
+	floatKeys := &DataStructure{Name: "f", KeyType: keyFloat}
+	stringKeys := &DataStructure{Name: "s", KeyType: keyString}
+	pairs := &DataStructure{Name: "p", KeyArity: 2}
+	tests := []struct {
+		ds    *DataStructure
+		line  string
+		want  string
+		valid bool
+	}{
+		{intKeys, "insert 05", "insert 5", true},
+		{intKeys, "insert 5 payload", "insert 5 payload", true},
+		{intKeys, `insert 5 "a value"`, `insert 5 "a value"`, true},
+		{intKeys, "insert", "", false},
+		{intKeys, "insert 1 2 3", "", false},
+		{intKeys, "find 1 2", "", false},
+		{intKeys, "remove x", "", false},
+		{intKeys, "print  tree", "print  tree", true},
+		{intKeys, "insert 5 " + strings.Repeat("v", config.MaxValueBytes+1), "", false},
+		{floatKeys, "find 2.50", "find 2.5", true},
+		{stringKeys, `insert "big apple" red`, `insert "big apple" red`, true},
+		{stringKeys, `search "x"`, "search x", true},
+		{pairs, "insert 1 02", "insert 1 2", true},
+		{pairs, "insert 1 2 v", "insert 1 2 v", true},
+		{pairs, "find 1", "", false},
+	}
+	for _, tt := range tests {
+		got, err := tt.ds.normalizeCommand(tt.line)
+		if !tt.valid {
+			if _, ok := err.(*ValidationError); !ok {
+				t.Errorf("%s: normalizeCommand(%q) = %q, %v; want a ValidationError", tt.ds.Name, tt.line, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: normalizeCommand(%q) = %q, %v; want %q", tt.ds.Name, tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseEngineKey(t *testing.T) {
+	if key, ok := parseEngineKey[int]([]string{"12", "v"}); !ok || key != 12 {
+		t.Errorf("int key = %v, %v", key, ok)
+	}
+	if _, ok := parseEngineKey[int]([]string{"x"}); ok {
+		t.Error("x parsed as an int key")
+	}
+	if key, ok := parseEngineKey[float64]([]string{"2.5"}); !ok || key != 2.5 {
+		t.Errorf("float key = %v, %v", key, ok)
+	}
+	if key, ok := parseEngineKey[stringKey]([]string{"a b"}); !ok || key.String() != `"a b"` {
+		t.Errorf("string key = %v, %v", key, ok)
+	}
+	if _, ok := parseEngineKey[stringKey](nil); ok {
+		t.Error("missing key parsed")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Coordinator leader election runs on client-go's leaderelection over a
+// coordination.k8s.io/v1 Lease in the pod's namespace
+
+const (
+	// leaseDuration is how long a lease is valid without renewal
+	leaseDuration = 15 * time.Second
+	// leaseRenewDeadline is how long the leader keeps leading after renewals start failing
+	leaseRenewDeadline = 10 * time.Second
+	// leaseRetryPeriod is how often the lease is acquired or renewed
+	leaseRetryPeriod = 2 * time.Second
+)
+
+// serviceAccountDir holds the in-cluster API credentials mounted into every pod
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// leading is true while this replica holds the coordinator lease
+var leading atomic.Bool
+
+// newInClusterLeaseLock builds the lock on the named Lease from the pod's service account
+func newInClusterLeaseLock(name, identity string) (*resourcelock.LeaseLock, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+	if err != nil {
+		return nil, err
+	}
+	return newLeaseLock(client, strings.TrimSpace(string(namespace)), name, identity), nil
+}
+
+// newLeaseLock builds the lock on a Lease held under identity
+func newLeaseLock(client kubernetes.Interface, namespace, name, identity string) *resourcelock.LeaseLock {
+	return &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Client:     client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+}
+
+// runLeaderElection keeps trying to hold the coordinator lease until ctx is done
+// and closes done once the lease is released
+func runLeaderElection(ctx context.Context, lock *resourcelock.LeaseLock, done chan<- struct{}) {
+	defer close(done)
+	identity, name := lock.Identity(), lock.LeaseMeta.Name
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: leaseRenewDeadline,
+		RetryPeriod:   leaseRetryPeriod,
+		// Give up the lease on shutdown so another replica can take over without waiting for expiry
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				leading.Store(true)
+				fmt.Printf("Coordinator %s became leader of lease %s\n", identity, name)
+			},
+			OnStoppedLeading: func() {
+				if !leading.Swap(false) {
+					return
+				}
+				if ctx.Err() != nil {
+					fmt.Printf("Coordinator %s released lease %s\n", identity, name)
+				} else {
+					fmt.Printf("Coordinator %s lost lease %s\n", identity, name)
+				}
+			},
+		},
+	})
+	if err != nil {
+		fmt.Println("Leader election error:", err)
+		return
+	}
+	// Run returns when leadership is lost; keep competing for the lease until shutdown
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+}
+
+// startCoordinator joins the leader election when coordinator mode is enabled
+// Returns a channel that closes once the election stopped after ctx is done
+func startCoordinator(ctx context.Context) (<-chan struct{}, error) {
+	done := make(chan struct{})
+	if !config.Coordinator.Enabled {
+		close(done)
+		return done, nil
+	}
+	identity := config.Coordinator.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		identity = hostname
+	}
+	lock, err := newInClusterLeaseLock(config.Coordinator.LeaseName, identity)
+	if err != nil {
+		return nil, err
+	}
+	go runLeaderElection(ctx, lock, done)
+	return done, nil
+}
+
+// handleLeader serves /internal/leader: 200 on the active coordinator, 503 elsewhere
+// Used as the readiness probe of the coordinator Service so only the leader gets traffic
+func handleLeader(w http.ResponseWriter, r *http.Request) {
+	if !config.Coordinator.Enabled || !leading.Load() {
+		http.Error(w, "not leader", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "leader")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLeaderElection(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go runLeaderElection(ctx, newLeaseLock(client, "datas", "datas-coordinator", "replica-a"), done)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !leading.Load() {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("never became leader of a free lease")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	holder := func() string {
+		lease, err := client.CoordinationV1().Leases("datas").Get(context.Background(), "datas-coordinator", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lease.Spec.HolderIdentity == nil {
+			return ""
+		}
+		return *lease.Spec.HolderIdentity
+	}
+	if got := holder(); got != "replica-a" {
+		t.Errorf("lease held by %q, want replica-a", got)
+	}
+
+	// Shutting down releases the lease
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("election did not stop")
+	}
+	if leading.Load() {
+		t.Error("still leading after shutdown")
+	}
+	if got := holder(); got != "" {
+		t.Errorf("lease still held by %q after shutdown", got)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// drainPollInterval is how often a drain checks whether sessions are done
+const drainPollInterval = 200 * time.Millisecond
+
+// ErrDraining is returned when a new session is requested while the server drains
+var ErrDraining = errors.New("server is draining")
+
+// draining is set once the server stops accepting new sessions
+var draining atomic.Bool
+
+// drainStarted is when the drain began, in Unix nanoseconds; 0 before
+var drainStarted atomic.Int64
+
+// shutdownNotice warns a session's clients that the server is stopping
+type shutdownNotice struct {
+	Type     string    `json:"type"`     // always "shutdown"
+	Deadline time.Time `json:"deadline"` // sessions still running then are ended
+	Message  string    `json:"message"`
+}
+
+// drainResult reports how a drain ended
+type drainResult struct {
+	Drained   bool      `json:"drained"`   // every session ended before the deadline
+	Remaining int       `json:"remaining"` // sessions still running
+	Deadline  time.Time `json:"deadline"`
+}
+
+// drain stops new sessions, warns the live ones and waits for them to end until the deadline
+func drain(deadline time.Time) drainResult {
+	if !draining.Swap(true) {
+		drainStarted.Store(time.Now().UnixNano())
+		fmt.Printf("Draining: no new sessions, waiting until %s\n", deadline.Format(time.RFC3339))
+		notice := shutdownNotice{
+			Type:     "shutdown",
+			Deadline: deadline,
+			Message:  "The server is shutting down; save your work before the deadline",
+		}
+		for _, session := range listSessions() {
+			session.reply("DRAINING deadline=" + deadline.Format(time.RFC3339))
+			session.send(notice)
+		}
+	}
+
+	for sessionCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainPollInterval)
+	}
+	remaining := sessionCount()
+	return drainResult{Drained: remaining == 0, Remaining: remaining, Deadline: deadline}
+}
+
+// handlePreStop serves /internal/prestop for the Kubernetes preStop hook
+// It returns once sessions drained or the deadline passed; Kubernetes then sends SIGTERM
+func handlePreStop(w http.ResponseWriter, r *http.Request) {
+	timeout := time.Duration(config.DrainTimeoutSeconds) * time.Second
+	if raw := r.URL.Query().Get("deadline"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			httpError(w, &ValidationError{"Invalid deadline. Must be a duration"})
+			return
+		}
+		timeout = parsed
+	}
+
+	writeJSON(w, drain(time.Now().Add(timeout)))
+}
+
+// handleReady serves /internal/ready for the readiness probe: unready while draining
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	if draining.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Unicode isolates that keep numbers left-to-right inside right-to-left text
+const (
+	leftToRightIsolate  = "\u2066"
+	popDirectionIsolate = "\u2069"
+)
+
+// rtlLanguages are the base languages written right to left
+var rtlLanguages = map[string]bool{
+	"ar": true,
+	"fa": true,
+	"he": true,
+	"ur": true,
+	"yi": true,
+}
+
+// localeFormatter formats numbers and keys for people reading a session's exports
+// Raw protocol messages never go through it; they stay locale-neutral
+type localeFormatter struct {
+	tag     language.Tag
+	printer *message.Printer
+	rtl     bool
+}
+
+// parseLanguage validates a language tag such as "de" or "he-IL"; empty is English
+func parseLanguage(raw string) (language.Tag, error) {
+	if raw == "" {
+		return language.English, nil
+	}
+	tag, err := language.Parse(raw)
+	if err != nil {
+		return language.Und, &ValidationError{"Invalid lang. Must be a BCP 47 language tag such as en or he-IL"}
+	}
+	return tag, nil
+}
+
+// newLocaleFormatter returns the formatter of a language
+func newLocaleFormatter(tag language.Tag) *localeFormatter {
+	base, _ := tag.Base()
+	return &localeFormatter{
+		tag:     tag,
+		printer: message.NewPrinter(tag),
+		rtl:     rtlLanguages[base.String()],
+	}
+}
+
+// isolate keeps a number readable left to right in right-to-left locales
+func (f *localeFormatter) isolate(s string) string {
+	if !f.rtl || s == "" {
+		return s
+	}
+	return leftToRightIsolate + s + popDirectionIsolate
+}
+
+// integer formats a whole number with the locale's digit grouping
+func (f *localeFormatter) integer(n int64) string {
+	return f.isolate(f.printer.Sprintf("%d", n))
+}
+
+// decimal formats a number with the locale's decimal separator
+func (f *localeFormatter) decimal(x float64, precision int) string {
+	return f.isolate(f.printer.Sprintf("%.*f", precision, x))
+}
+
+// key formats a numeric key or count reported by an interface; anything else is kept
+func (f *localeFormatter) key(raw string) string {
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return raw
+	}
+	return f.integer(n)
+}
+
+// decimalComma reports whether the locale writes decimals with a comma, in which
+// case spreadsheets expect ';' between CSV fields
+func (f *localeFormatter) decimalComma() bool {
+	return strings.Contains(f.printer.Sprintf("%.1f", 1.5), ",")
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 )
 
 func clientHandle(req string) {
@@ -27,24 +29,86 @@ func clientHandle(req string) {
 }
 
 func main() {
+	configPath := flag.String("config", "", "path to a JSON config file")
+	flag.Parse()
+	if *configPath != "" {
+		if err := loadConfig(*configPath); err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
+		}
+	}
+	if config.AdminToken == "" {
+		fmt.Println("No admin_token configured: admin endpoints are disabled")
+	}
+	checkInterfaces()
+	gen, err := newIDGenerator(config)
+	if err != nil {
+		fmt.Println("Error configuring ID generation:", err)
+		os.Exit(1)
+	}
+	idGen = gen
+	db, err := openStore(config)
+	if err != nil {
+		fmt.Println("Error opening storage:", err)
+		os.Exit(1)
+	}
+	store = db
+	defer store.close()
+
 	// Context + waitgroup for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
 
+	coordinatorDone, err := startCoordinator(ctx)
+	if err != nil {
+		fmt.Println("Error starting coordinator:", err)
+		os.Exit(1)
+	}
+
 	// Start server
 	os.Mkdir("fifos", 0755)
 	wg.Add(1)
-	go startRawTcpServer(ctx, &wg, "9000")
-	go startHttpServer(ctx, &wg, "8080")
+	go startRawTcpServer(ctx, &wg, config.TCPPort)
+	wg.Add(1)
+	go startUnixServer(ctx, &wg, config.UnixSocket)
+	wg.Add(1)
+	go startHttpServer(ctx, &wg, config.HTTPPort)
+	wg.Add(1)
+	go startGRPCServer(ctx, &wg, config.GRPCPort)
+	wg.Add(1)
+	go startWebTransportServer(ctx, &wg, config.WebTransportPort)
+	wg.Add(1)
+	go startMQTTBridge(ctx, &wg)
 	// Wait for interrupt (Ctrl+C)
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	<-sig
-	fmt.Println("Signal received, shutting down...")
+	fmt.Println("Signal received, draining sessions...")
+	drained := make(chan drainResult, 1)
+	go func() {
+		drained <- drain(time.Now().Add(time.Duration(config.DrainTimeoutSeconds) * time.Second))
+	}()
+	select {
+	case result := <-drained:
+		if !result.Drained {
+			fmt.Printf("Drain deadline passed with %d sessions running\n", result.Remaining)
+		}
+	case <-sig:
+		fmt.Println("Second signal received, ending sessions now")
+	}
+	fmt.Println("Shutting down...")
+	report := terminateSessions()
 
 	// Cancel server context, wait for goroutines
 	cancel()
 	wg.Wait()
+	<-coordinatorDone
+	report.detectLeaks()
 	os.RemoveAll("fifos/")
-	fmt.Println("Server stopped cleanly.")
+	report.publish()
+	if report.Clean {
+		fmt.Println("Server stopped cleanly.")
+	} else {
+		fmt.Println("Server stopped; see the shutdown report for leaks and failed teardowns.")
+	}
 }
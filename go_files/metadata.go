@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// describeTimeout bounds how long an interface may take to answer --describe
+const describeTimeout = 2 * time.Second
+
+// commandGrammar is one command an interface accepts
+type commandGrammar struct {
+	Name     string `json:"name"`
+	Args     string `json:"args,omitempty"` // usage of the arguments, e.g. "<int key> [value]"
+	Mutating bool   `json:"mutating,omitempty"`
+}
+
+// structureMetadata is the command grammar and capabilities of one data structure
+type structureMetadata struct {
+	Name string `json:"name"`
+	// Source is "describe" when the binary described its own commands, "registry" otherwise
+	Source       string              `json:"source"`
+	BinarySHA256 string              `json:"binary_sha256,omitempty"`
+	KeyType      string              `json:"key_type"`
+	KeyArity     int                 `json:"key_arity"`
+	Params       []DataStructureFlag `json:"params"`
+	Commands     []commandGrammar    `json:"commands"`
+	Engines      []string            `json:"engines"`
+	Snapshot     bool                `json:"snapshot"`
+}
+
+// cachedMetadata is a metadata document encoded once, with the ETag it is served under
+type cachedMetadata struct {
+	meta     structureMetadata
+	body     []byte
+	etag     string
+	checksum string // binary checksum the grammar was generated from; empty if unreadable
+}
+
+var (
+	metadataMu sync.RWMutex
+	// metadataCache holds the metadata of each data structure, generated when interfaces are probed
+	metadataCache = make(map[string]*cachedMetadata)
+	// metadataIndex lists every structure's metadata in registry order
+	metadataIndex *cachedMetadata
+)
+
+// describeOutput is the first line an interface supporting --describe prints
+type describeOutput struct {
+	Commands []commandGrammar `json:"commands"`
+}
+
+// describeInterface asks an executable for its command grammar with --describe
+// Interfaces without the flag print their banner instead, which does not parse
+func describeInterface(executable string) ([]commandGrammar, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, executable, "--describe").Output()
+	if err != nil {
+		return nil, false
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	var described describeOutput
+	if json.Unmarshal([]byte(line), &described) != nil || len(described.Commands) == 0 {
+		return nil, false
+	}
+	return described.Commands, true
+}
+
+// registryGrammar is the grammar the server knows from the registry: the key commands,
+// init, the structure's own mutating commands, its dump command and status, which every
+// interface answers
+func registryGrammar(ds *DataStructure) []commandGrammar {
+	usage := ds.keyUsage()
+	commands := []commandGrammar{
+		{Name: "init", Mutating: true},
+		{Name: "insert", Args: usage + " [value]", Mutating: true},
+		{Name: "remove", Args: usage, Mutating: true},
+		{Name: "find", Args: usage, Mutating: ds.mutates("find")},
+		{Name: "search", Args: usage, Mutating: ds.mutates("search")},
+	}
+	add := func(command commandGrammar) {
+		if !slices.ContainsFunc(commands, func(c commandGrammar) bool { return c.Name == command.Name }) {
+			commands = append(commands, command)
+		}
+	}
+	for _, name := range ds.Mutating {
+		add(commandGrammar{Name: name, Mutating: true})
+	}
+	if spec, ok := snapshotSpecs[ds.Name]; ok {
+		add(commandGrammar{Name: spec.command})
+	}
+	add(commandGrammar{Name: "status"})
+	return commands
+}
+
+// buildMetadata generates the metadata of a structure whose binary has the given checksum
+func buildMetadata(ds *DataStructure, checksum string) *cachedMetadata {
+	meta := structureMetadata{
+		Name:         ds.Name,
+		Source:       "registry",
+		BinarySHA256: checksum,
+		KeyType:      ds.keyType(),
+		KeyArity:     ds.keyArity(),
+		Params:       ds.Flags,
+		Engines:      []string{engineCpp},
+	}
+	if meta.Params == nil {
+		meta.Params = []DataStructureFlag{}
+	}
+	if commands, ok := describeInterface(ds.Executable); ok {
+		meta.Source = "describe"
+		meta.Commands = commands
+	} else {
+		meta.Commands = registryGrammar(ds)
+	}
+	if _, ok := goEngines[ds.model()]; ok {
+		meta.Engines = append(meta.Engines, engineGo)
+	}
+	_, meta.Snapshot = snapshotSpecs[ds.Name]
+	return encodeMetadata(meta, checksum)
+}
+
+// encodeMetadata encodes a document and derives its ETag from the encoding
+func encodeMetadata(meta structureMetadata, checksum string) *cachedMetadata {
+	body := marshalMetadata(meta)
+	return &cachedMetadata{meta: meta, body: body, etag: bodyETag(body), checksum: checksum}
+}
+
+// marshalMetadata encodes a document without escaping the angle brackets of usages
+func marshalMetadata(v any) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+	return buf.Bytes()
+}
+
+// bodyETag is a strong ETag of a response body
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// refreshMetadata regenerates the metadata of every structure whose binary changed
+// since it was generated; unchanged binaries are not invoked again
+func refreshMetadata() {
+	metadataMu.RLock()
+	previous := metadataCache
+	metadataMu.RUnlock()
+
+	entries := make([]*cachedMetadata, len(config.DataStructures))
+	var wg sync.WaitGroup
+	for i := range config.DataStructures {
+		ds := &config.DataStructures[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checksum := fileSHA256(ds.Executable)
+			if cached, ok := previous[ds.Name]; ok && checksum != "" && cached.checksum == checksum {
+				entries[i] = cached
+				return
+			}
+			entries[i] = buildMetadata(ds, checksum)
+		}()
+	}
+	wg.Wait()
+
+	cache := make(map[string]*cachedMetadata, len(entries))
+	all := make([]structureMetadata, len(entries))
+	for i, entry := range entries {
+		cache[entry.meta.Name] = entry
+		all[i] = entry.meta
+	}
+	body := marshalMetadata(all)
+	capabilitiesBody := marshalMetadata(buildCapabilities())
+
+	metadataMu.Lock()
+	metadataCache = cache
+	metadataIndex = &cachedMetadata{body: body, etag: bodyETag(body)}
+	capabilitiesCache = &cachedMetadata{body: capabilitiesBody, etag: bodyETag(capabilitiesBody)}
+	metadataMu.Unlock()
+}
+
+// serveMetadata writes a cached document, or 304 when the client holds its ETag
+func serveMetadata(w http.ResponseWriter, r *http.Request, cached *cachedMetadata) {
+	w.Header().Set("ETag", cached.etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), cached.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(cached.body)
+}
+
+// etagMatches reports whether an If-None-Match header names etag; weak tags compare equal
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// handleStructures serves GET /structures: the metadata of every registered structure
+func handleStructures(w http.ResponseWriter, r *http.Request) {
+	metadataMu.RLock()
+	index := metadataIndex
+	metadataMu.RUnlock()
+	if index == nil {
+		refreshMetadata()
+		metadataMu.RLock()
+		index = metadataIndex
+		metadataMu.RUnlock()
+	}
+	serveMetadata(w, r, index)
+}
+
+// handleStructure serves GET /structures/{name}: the metadata of one structure
+func handleStructure(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := lookupDataStructure(name); !ok {
+		httpError(w, &ValidationError{"Unsupported data type"})
+		return
+	}
+	metadataMu.RLock()
+	cached, ok := metadataCache[name]
+	metadataMu.RUnlock()
+	if !ok {
+		refreshMetadata()
+		metadataMu.RLock()
+		cached = metadataCache[name]
+		metadataMu.RUnlock()
+	}
+	serveMetadata(w, r, cached)
+}
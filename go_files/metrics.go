@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// serverMetrics are process-wide counters exposed on /metrics and /admin/stats
+type serverMetrics struct {
+	sessionsStarted      atomic.Int64
+	commands             atomic.Int64 // command lines written to C++ processes
+	messagesSent         atomic.Int64 // JSON messages written to clients
+	bytesSent            atomic.Int64
+	crashes              atomic.Int64
+	processRestarts      atomic.Int64
+	sessionsHibernated   atomic.Int64 // idle processes stopped, counted once per hibernation
+	sessionsRestored     atomic.Int64 // hibernated sessions woken by a command
+	hibernationEvictions atomic.Int64 // hibernated sessions ended to respect the storage cap
+	protocolViolations   atomic.Int64 // rejected client messages
+	invariantViolations  atomic.Int64 // process answers that broke the size invariant
+	canaryPromotions     atomic.Int64
+	canaryRollbacks      atomic.Int64
+	programStarved       atomic.Int64 // program lines that waited past outputStarvationThreshold
+	logStarved           atomic.Int64 // log lines that waited past outputStarvationThreshold
+	logLinesDropped      atomic.Int64 // log lines not sent to a client whose queue was full
+	slowClients          atomic.Int64 // clients disconnected for falling behind the output
+}
+
+var metrics serverMetrics
+
+var serverStarted = time.Now()
+
+// metricsSnapshot is a point-in-time copy of the metrics
+type metricsSnapshot struct {
+	UptimeSeconds       float64 `json:"uptime_seconds"`
+	SessionsActive      int     `json:"sessions_active"`
+	SessionsStarted     int64   `json:"sessions_started"`
+	Commands            int64   `json:"commands"`
+	MessagesSent        int64   `json:"messages_sent"`
+	BytesSent           int64   `json:"bytes_sent"`
+	Crashes             int64   `json:"crashes"`
+	ProcessRestarts     int64   `json:"process_restarts"`
+	SessionsHibernated  int64   `json:"sessions_hibernated"`
+	SessionsRestored    int64   `json:"sessions_restored"`
+	HibernationEvicted  int64   `json:"hibernation_evictions"`
+	HibernatedSessions  int     `json:"hibernated_sessions"`
+	HibernatedBytes     int64   `json:"hibernated_bytes"`
+	ProtocolViolations  int64   `json:"protocol_violations"`
+	InvariantViolations int64   `json:"invariant_violations"`
+	CanaryPromotions    int64   `json:"canary_promotions"`
+	CanaryRollbacks     int64   `json:"canary_rollbacks"`
+	ProgramStarved      int64   `json:"program_lines_starved"`
+	LogStarved          int64   `json:"log_lines_starved"`
+	LogLinesDropped     int64   `json:"log_lines_dropped"`
+	SlowClients         int64   `json:"slow_clients_disconnected"`
+	Goroutines          int     `json:"goroutines"`
+
+	Errors map[string]int64 `json:"errors"` // by error code
+}
+
+// snapshot copies the current metric values
+func (m *serverMetrics) snapshot() metricsSnapshot {
+	hibernated, hibernatedBytes := hibernation.stats()
+	return metricsSnapshot{
+		UptimeSeconds:       time.Since(serverStarted).Seconds(),
+		SessionsActive:      sessionCount(),
+		SessionsStarted:     m.sessionsStarted.Load(),
+		Commands:            m.commands.Load(),
+		MessagesSent:        m.messagesSent.Load(),
+		BytesSent:           m.bytesSent.Load(),
+		Crashes:             m.crashes.Load(),
+		ProcessRestarts:     m.processRestarts.Load(),
+		SessionsHibernated:  m.sessionsHibernated.Load(),
+		SessionsRestored:    m.sessionsRestored.Load(),
+		HibernationEvicted:  m.hibernationEvictions.Load(),
+		HibernatedSessions:  hibernated,
+		HibernatedBytes:     hibernatedBytes,
+		ProtocolViolations:  m.protocolViolations.Load(),
+		InvariantViolations: m.invariantViolations.Load(),
+		CanaryPromotions:    m.canaryPromotions.Load(),
+		CanaryRollbacks:     m.canaryRollbacks.Load(),
+		ProgramStarved:      m.programStarved.Load(),
+		LogStarved:          m.logStarved.Load(),
+		LogLinesDropped:     m.logLinesDropped.Load(),
+		SlowClients:         m.slowClients.Load(),
+		Goroutines:          runtime.NumGoroutine(),
+		Errors:              errorCountsSnapshot(),
+	}
+}
+
+// errorCountsSnapshot copies the per-code error counters
+func errorCountsSnapshot() map[string]int64 {
+	counts := make(map[string]int64, len(errorCounts))
+	for code, count := range errorCounts {
+		counts[code] = count.Load()
+	}
+	return counts
+}
+
+// handleMetrics serves GET /metrics in the Prometheus text format
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	snap := metrics.snapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	writeMetric(w, "datas_uptime_seconds", "gauge", "Seconds since the server started", snap.UptimeSeconds)
+	writeMetric(w, "datas_sessions_active", "gauge", "Sessions with a running C++ process", snap.SessionsActive)
+	writeMetric(w, "datas_sessions_started_total", "counter", "Sessions started", snap.SessionsStarted)
+	writeMetric(w, "datas_commands_total", "counter", "Command lines written to C++ processes", snap.Commands)
+	writeMetric(w, "datas_messages_sent_total", "counter", "JSON messages written to clients", snap.MessagesSent)
+	writeMetric(w, "datas_bytes_sent_total", "counter", "Bytes written to clients", snap.BytesSent)
+	writeMetric(w, "datas_crashes_total", "counter", "C++ processes that exited with an error", snap.Crashes)
+	writeMetric(w, "datas_process_restarts_total", "counter", "C++ processes restarted within a session", snap.ProcessRestarts)
+	writeMetric(w, "datas_sessions_hibernated_total", "counter", "Idle session processes stopped until the next command", snap.SessionsHibernated)
+	writeMetric(w, "datas_sessions_restored_total", "counter", "Hibernated sessions restored by a command", snap.SessionsRestored)
+	writeMetric(w, "datas_hibernation_evictions_total", "counter", "Hibernated sessions ended to stay under the storage cap", snap.HibernationEvicted)
+	writeMetric(w, "datas_hibernated_sessions", "gauge", "Sessions hibernated right now", snap.HibernatedSessions)
+	writeMetric(w, "datas_hibernated_bytes", "gauge", "Bytes of the snapshots of hibernated sessions", snap.HibernatedBytes)
+	writeMetric(w, "datas_protocol_violations_total", "counter", "Client messages rejected as protocol violations", snap.ProtocolViolations)
+	writeMetric(w, "datas_invariant_violations_total", "counter", "Interface answers that reported an impossible size", snap.InvariantViolations)
+	writeMetric(w, "datas_canary_promotions_total", "counter", "Canary binaries promoted to stable", snap.CanaryPromotions)
+	writeMetric(w, "datas_canary_rollbacks_total", "counter", "Canary binaries rolled back", snap.CanaryRollbacks)
+	writeMetric(w, "datas_log_lines_dropped_total", "counter", "Log lines not sent to clients that could not keep up", snap.LogLinesDropped)
+	writeMetric(w, "datas_slow_clients_disconnected_total", "counter", "Clients disconnected for falling behind the output", snap.SlowClients)
+	writeMetric(w, "datas_goroutines", "gauge", "Live goroutines", snap.Goroutines)
+	fmt.Fprintf(w, "# HELP datas_output_starved_total Output lines that waited more than %s to be sent, by FIFO\n# TYPE datas_output_starved_total counter\n", outputStarvationThreshold)
+	fmt.Fprintf(w, "datas_output_starved_total{stream=\"program\"} %d\n", snap.ProgramStarved)
+	fmt.Fprintf(w, "datas_output_starved_total{stream=\"log\"} %d\n", snap.LogStarved)
+
+	fmt.Fprintf(w, "# HELP datas_errors_total Errors surfaced to clients or logs, by code\n# TYPE datas_errors_total counter\n")
+	codes := make([]string, 0, len(snap.Errors))
+	for code := range snap.Errors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "datas_errors_total{code=%q} %d\n", code, snap.Errors[code])
+	}
+
+	// Canary and stable sessions side by side, while a canary runs
+	canaries := canaryStatuses()
+	fmt.Fprintf(w, "# HELP datas_canary_sessions_total Ended sessions of data types with a canary, by version\n# TYPE datas_canary_sessions_total counter\n")
+	for _, canary := range canaries {
+		fmt.Fprintf(w, "datas_canary_sessions_total{type=%q,track=\"canary\"} %d\n", canary.Name, canary.Canary.Sessions)
+		fmt.Fprintf(w, "datas_canary_sessions_total{type=%q,track=\"stable\"} %d\n", canary.Name, canary.Control.Sessions)
+	}
+	fmt.Fprintf(w, "# HELP datas_canary_failures_total Those sessions that crashed or broke an invariant\n# TYPE datas_canary_failures_total counter\n")
+	for _, canary := range canaries {
+		fmt.Fprintf(w, "datas_canary_failures_total{type=%q,track=\"canary\"} %d\n", canary.Name, canary.Canary.Failures)
+		fmt.Fprintf(w, "datas_canary_failures_total{type=%q,track=\"stable\"} %d\n", canary.Name, canary.Control.Failures)
+	}
+}
+
+// writeMetric writes one metric with its HELP and TYPE lines
+func writeMetric(w http.ResponseWriter, name, kind, help string, value any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// replayTimeout bounds a single replay of a script against a fresh process
+	replayTimeout = 5 * time.Second
+	// maxMinimizeRuns caps how many replays one minimization may spend
+	maxMinimizeRuns = 500
+)
+
+// scriptCrashes replays a script against a fresh process in batch mode
+// Returns true if the process exited abnormally (a hang past the timeout is not a crash)
+func scriptCrashes(ds, engine, flags string, script []string) bool {
+	if engine == engineGo {
+		_, _, err := runGoHeadless(ds, flags, script)
+		return errors.Is(err, ErrProcessCrashed)
+	}
+
+	args, err := interfaceArgs(ds, flags, "--program-out", "null", "--tree-log-out", "null")
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, interfaceExecutable(ds), args...)
+	cmd.Stdin = strings.NewReader(strings.Join(script, "\n") + "\n")
+	err = processExitError(runInterfaceCommand(cmd))
+	return errors.Is(err, ErrProcessCrashed) && ctx.Err() == nil
+}
+
+// minimizeScript shrinks a crashing script to its shortest crashing prefix,
+// then removes every command not needed to reproduce the crash (delta debugging)
+func minimizeScript(script []string, crashes func([]string) bool) []string {
+	runs := 0
+	test := func(candidate []string) bool {
+		if runs >= maxMinimizeRuns {
+			return false
+		}
+		runs++
+		return crashes(candidate)
+	}
+
+	return ddmin(shortestCrashingPrefix(script, test), test)
+}
+
+// shortestCrashingPrefix binary searches the shortest prefix that still crashes
+func shortestCrashingPrefix(script []string, crashes func([]string) bool) []string {
+	lo, hi := 1, len(script)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if crashes(script[:mid]) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return script[:hi]
+}
+
+// ddmin is Zeller's delta debugging algorithm: it keeps splitting the script into
+// finer chunks and drops any chunk whose removal still reproduces the crash
+func ddmin(script []string, crashes func([]string) bool) []string {
+	n := 2
+	for len(script) >= 2 {
+		chunk := (len(script) + n - 1) / n
+		reduced := false
+
+		// Try each chunk on its own
+		for start := 0; start < len(script) && !reduced; start += chunk {
+			subset := script[start:min(start+chunk, len(script))]
+			if crashes(subset) {
+				script, n, reduced = subset, 2, true
+			}
+		}
+
+		// Try removing each chunk
+		for start := 0; start < len(script) && !reduced; start += chunk {
+			complement := append(append([]string{}, script[:start]...), script[min(start+chunk, len(script)):]...)
+			if crashes(complement) {
+				script, n, reduced = complement, max(n-1, 2), true
+			}
+		}
+
+		if !reduced {
+			if n >= len(script) {
+				break
+			}
+			n = min(2*n, len(script))
+		}
+	}
+	return script
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// The MQTT client of the session bridge, on eclipse/paho.mqtt.golang: QoS 0 publishes and
+// subscriptions over a clean session, reconnecting with backoff when the broker drops
+
+const (
+	// mqttKeepAlive is the keepalive announced to the broker
+	mqttKeepAlive = 60 * time.Second
+	// mqttTimeout bounds connecting, the CONNECT handshake and each packet written
+	mqttTimeout = 10 * time.Second
+	// mqttRetryMax caps the wait between reconnection attempts
+	mqttRetryMax = 30 * time.Second
+)
+
+// validateMQTTBroker checks a broker URL: tcp:// or mqtt:// for plain connections,
+// ssl://, tls:// or mqtts:// for TLS
+func validateMQTTBroker(broker string) error {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("mqtt.broker must be a URL such as tcp://host:1883")
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts":
+		return nil
+	}
+	return fmt.Errorf("mqtt.broker scheme must be tcp, mqtt, ssl, tls or mqtts")
+}
+
+// mqttBrokerURL returns a validated broker URL with its port, 1883 or 8883 for TLS
+// when it has none, as paho dials the host as given
+func mqttBrokerURL(broker string) string {
+	u, err := url.Parse(broker)
+	if err != nil || u.Port() != "" {
+		return broker
+	}
+	port := "1883"
+	if u.Scheme == "ssl" || u.Scheme == "tls" || u.Scheme == "mqtts" {
+		port = "8883"
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), port)
+	return u.String()
+}
+
+// newMQTTClient configures a client for the broker; onConnect runs after every
+// successful connection, the first one and each reconnection
+func newMQTTClient(cfg MQTTConfig, onConnect func(mqtt.Client)) mqtt.Client {
+	options := mqtt.NewClientOptions().
+		AddBroker(mqttBrokerURL(cfg.Broker)).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetCleanSession(true).
+		SetKeepAlive(mqttKeepAlive).
+		SetConnectTimeout(mqttTimeout).
+		SetWriteTimeout(mqttTimeout).
+		SetConnectRetry(true).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(mqttRetryMax).
+		SetOnConnectHandler(onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			fmt.Printf("MQTT bridge to %s failed: %v; reconnecting\n", cfg.Broker, err)
+		})
+	return mqtt.NewClient(options)
+}
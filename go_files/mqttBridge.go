@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// The MQTT bridge publishes every session's messages to {prefix}/{sessionID}/{type}
+// (program, log, server, error, ...) and, when enabled, feeds the lines published to
+// {prefix}/{sessionID}/command to the session
+
+const (
+	// mqttQueueMessages bounds the messages waiting for the broker; past it they are dropped,
+	// never holding up a session
+	mqttQueueMessages = 4096
+)
+
+// mqttMessage is a message on its way to the broker
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+var (
+	mqttOutbox  = make(chan mqttMessage, mqttQueueMessages)
+	mqttDropped atomic.Int64 // messages dropped since the bridge last reported it
+)
+
+// mqttSessionWriter is attached to a session like a spectator and queues each of its
+// messages for the broker
+type mqttSessionWriter struct {
+	topic string // {prefix}/{sessionID}
+}
+
+// Write implements io.Writer: each line of p is published under its message type
+func (w mqttSessionWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		var envelope struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal([]byte(line), &envelope) != nil || envelope.Type == "" {
+			continue
+		}
+		select {
+		case mqttOutbox <- mqttMessage{topic: w.topic + "/" + envelope.Type, payload: []byte(line)}:
+		default:
+			mqttDropped.Add(1)
+		}
+	}
+	return len(p), nil
+}
+
+// publishToMQTT attaches the bridge to the session output; private sessions are not published
+func (s *Session) publishToMQTT() {
+	if config.MQTT.Broker == "" || s.Private {
+		return
+	}
+	if _, err := s.clients.add(mqttSessionWriter{topic: config.MQTT.TopicPrefix + "/" + s.ID}, true); err != nil {
+		logError(s.ID, "attaching MQTT bridge", err)
+	}
+}
+
+// startMQTTBridge publishes queued messages to config.MQTT.Broker until ctx is done;
+// paho reconnects when the connection drops, and messages wait in the outbox meanwhile
+func startMQTTBridge(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	cfg := config.MQTT
+	if cfg.Broker == "" {
+		return
+	}
+	if cfg.ClientID == "" {
+		host, _ := os.Hostname()
+		cfg.ClientID = "datas-" + host
+	}
+
+	connected := make(chan struct{}, 1)
+	client := newMQTTClient(cfg, func(client mqtt.Client) {
+		fmt.Printf("MQTT bridge connected to %s\n", cfg.Broker)
+		if dropped := mqttDropped.Swap(0); dropped > 0 {
+			fmt.Printf("MQTT bridge dropped %d messages while the broker was unreachable\n", dropped)
+		}
+		// A clean session forgets subscriptions, so they are made again on every connection
+		if cfg.Commands {
+			go subscribeMQTTCommands(client, cfg.TopicPrefix)
+		}
+		select {
+		case connected <- struct{}{}:
+		default:
+		}
+	})
+	client.Connect()
+	defer client.Disconnect(uint(mqttTimeout / time.Millisecond))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-mqttOutbox:
+			// QoS 0 publishes made while paho reconnects are discarded, so wait for the connection
+			for !client.IsConnectionOpen() {
+				select {
+				case <-ctx.Done():
+					return
+				case <-connected:
+				}
+			}
+			token := client.Publish(msg.topic, 0, false, msg.payload)
+			if !token.WaitTimeout(mqttTimeout) || token.Error() != nil {
+				mqttDropped.Add(1)
+			}
+		}
+	}
+}
+
+// subscribeMQTTCommands subscribes to the command topics of every session
+func subscribeMQTTCommands(client mqtt.Client, prefix string) {
+	filter := prefix + "/+/command"
+	token := client.Subscribe(filter, 0, func(_ mqtt.Client, msg mqtt.Message) {
+		handleMQTTCommand(prefix, msg.Topic(), msg.Payload())
+	})
+	if !token.WaitTimeout(mqttTimeout) {
+		fmt.Println("MQTT bridge: command subscription timed out")
+		return
+	}
+	// A return code of 0x80 is a refused subscription
+	if err := token.Error(); err != nil || token.(*mqtt.SubscribeToken).Result()[filter] == 0x80 {
+		fmt.Println("MQTT bridge: broker refused the command subscription", err)
+	}
+}
+
+// handleMQTTCommand feeds the lines of a message published to {prefix}/{sessionID}/command
+// to the session; a rejected line is reported on {prefix}/{sessionID}/protocol_error and
+// ends the message
+func handleMQTTCommand(prefix, topic string, payload []byte) {
+	ID, prefixed := strings.CutPrefix(topic, prefix+"/")
+	ID, command := strings.CutSuffix(ID, "/command")
+	if !prefixed || !command || strings.Contains(ID, "/") {
+		return
+	}
+	session, ok := lookupSession(ID)
+	if !ok || session.Private {
+		fmt.Printf("MQTT bridge: command for unknown session %s ignored\n", ID)
+		return
+	}
+	for _, line := range strings.Split(string(payload), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if violation := session.dispatch(line); violation != nil {
+			metrics.protocolViolations.Add(1)
+			// Commands over MQTT have no connection to close, so violations are never counted
+			// against a limit
+			sendJSONValue(mqttSessionWriter{topic: prefix + "/" + ID}, protocolErrorMessage{
+				Type:       "protocol_error",
+				Code:       violation.Code,
+				Message:    violation.Message,
+				Snippet:    snippet(violation.Payload),
+				Violations: 1,
+			})
+			return
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestMQTTBrokerURL(t *testing.T) {
+	tests := []struct {
+		broker string
+		valid  bool
+		want   string
+	}{
+		{"tcp://broker", true, "tcp://broker:1883"},
+		{"mqtt://broker:1884", true, "mqtt://broker:1884"},
+		{"ssl://broker", true, "ssl://broker:8883"},
+		{"mqtts://[::1]", true, "mqtts://[::1]:8883"},
+		{"http://broker", false, ""},
+		{"broker:1883", false, ""},
+	}
+	for _, tt := range tests {
+		if err := validateMQTTBroker(tt.broker); (err == nil) != tt.valid {
+			t.Errorf("validateMQTTBroker(%q) = %v, want valid %v", tt.broker, err, tt.valid)
+			continue
+		}
+		if tt.valid {
+			if got := mqttBrokerURL(tt.broker); got != tt.want {
+				t.Errorf("mqttBrokerURL(%q) = %q, want %q", tt.broker, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxSimulatedLatency bounds the delay an instructor can add to a session
+	maxSimulatedLatency = 10 * time.Second
+	// netsimQueueSize is how many delayed messages may wait before writers block
+	netsimQueueSize = 4096
+)
+
+// delayedWrite is one output message held back until its due time
+type delayedWrite struct {
+	due       time.Time
+	message   *outgoingMessage
+	droppable bool // a tree log line, which slow clients may miss
+}
+
+// networkSimulation delays a session's output to mimic a slow, jittery network
+// Messages keep their order: a message is never due before the one queued ahead of it
+type networkSimulation struct {
+	mu      sync.Mutex
+	latency time.Duration
+	jitter  time.Duration
+	last    time.Time // due time of the last queued message
+	queue   chan delayedWrite
+	done    <-chan struct{} // closed when the session ends
+}
+
+// networkSettings is the JSON view of a session's simulated network
+type networkSettings struct {
+	Session   string `json:"session"`
+	LatencyMS int64  `json:"latency_ms"`
+	JitterMS  int64  `json:"jitter_ms"`
+}
+
+// delay returns how long the next message is held back
+func (n *networkSimulation) delay() time.Duration {
+	d := n.latency
+	if n.jitter > 0 {
+		d += time.Duration(rand.Int64N(int64(2*n.jitter)+1)) - n.jitter
+	}
+	return max(d, 0)
+}
+
+// enqueue schedules a message; blocks while the queue is full
+func (n *networkSimulation) enqueue(m *outgoingMessage, droppable bool) {
+	n.mu.Lock()
+	due := time.Now().Add(n.delay())
+	if due.Before(n.last) {
+		due = n.last
+	}
+	n.last = due
+	n.mu.Unlock()
+	select {
+	case n.queue <- delayedWrite{due: due, message: m, droppable: droppable}:
+	case <-n.done:
+	}
+}
+
+// run writes queued messages to the clients when they are due, until the session ends
+func (n *networkSimulation) run(f *clientFanout) {
+	for {
+		select {
+		case w := <-n.queue:
+			time.Sleep(time.Until(w.due))
+			f.writeNow(w.message, w.droppable)
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// setNetworkSimulation delays the session's output by latency ± jitter; zero turns it off
+// Once enabled, output keeps going through the delay queue so nothing overtakes queued messages
+func (s *Session) setNetworkSimulation(latency, jitter time.Duration) {
+	notice := fmt.Sprintf("NETWORK_SIMULATION latency_ms=%d jitter_ms=%d", latency.Milliseconds(), jitter.Milliseconds())
+	sim := s.clients.sim.Load()
+	if sim == nil {
+		// Announce before the first message is delayed
+		s.reply(notice)
+		notice = ""
+		created := &networkSimulation{queue: make(chan delayedWrite, netsimQueueSize), done: s.closed}
+		if s.clients.sim.CompareAndSwap(nil, created) {
+			go created.run(s.clients)
+		}
+		sim = s.clients.sim.Load()
+	}
+
+	sim.mu.Lock()
+	sim.latency, sim.jitter = latency, jitter
+	sim.mu.Unlock()
+	if notice != "" {
+		s.reply(notice)
+	}
+}
+
+// networkSettings returns the session's simulated latency and jitter
+func (s *Session) networkSettings() networkSettings {
+	settings := networkSettings{Session: s.ID}
+	if sim := s.clients.sim.Load(); sim != nil {
+		sim.mu.Lock()
+		settings.LatencyMS, settings.JitterMS = sim.latency.Milliseconds(), sim.jitter.Milliseconds()
+		sim.mu.Unlock()
+	}
+	return settings
+}
+
+// handleNetworkSimulation serves POST /admin/sessions/{id}/network?latency=300ms&jitter=100ms
+// Instructors use it to show how the visualization degrades on a poor network
+func handleNetworkSimulation(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+
+	var durations [2]time.Duration
+	for i, param := range []string{"latency", "jitter"} {
+		raw := r.URL.Query().Get(param)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 || parsed > maxSimulatedLatency {
+			httpError(w, &ValidationError{fmt.Sprintf("Invalid %s. Must be a duration up to %s", param, maxSimulatedLatency)})
+			return
+		}
+		durations[i] = parsed
+	}
+
+	session.setNetworkSimulation(durations[0], durations[1])
+	fmt.Printf("[Client %s] Simulated network set to latency=%s jitter=%s\n", session.ID, durations[0], durations[1])
+	writeJSON(w, session.networkSettings())
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxOpStats caps the rows kept per session; the oldest are dropped first
+const maxOpStats = 50000
+
+// silentCommands produce no program output in batch mode, so they never get a result line
+var silentCommands = map[string]bool{
+	"help": true,
+	"menu": true,
+}
+
+// opStat is one command run by a session's process and how it was answered
+type opStat struct {
+	Seq      int
+	Sent     time.Time
+	Op       string
+	Argument string
+	Latency  time.Duration // zero until answered
+	Result   string        // first token of the answer, e.g. INSERT_SUCCESS
+	Size     string        // structure size after the command, if reported
+	Height   string        // structure height after the command, if reported
+	answered bool
+}
+
+// opStats times every command from the write to stdin until its first program line
+// The interface answers commands in order, so pending commands form a FIFO
+type opStats struct {
+	mu       sync.Mutex
+	rows     []*opStat
+	pending  []*opStat
+	seq      int
+	blockEnd string // end marker of a multi-line answer being skipped, e.g. TREE_END
+
+	// size is the structure size the process last reported, checked against each
+	// insert and remove answer; violations counts answers that broke it
+	size       int
+	sizeKnown  bool
+	violations int
+}
+
+// sizeChanges is how each answer must move the reported size
+var sizeChanges = map[string]int{
+	"INSERT_SUCCESS":   1,
+	"INSERT_DUPLICATE": 0,
+	"REMOVE_SUCCESS":   -1,
+	"REMOVE_NOT_FOUND": 0,
+}
+
+// sent records a command written to the process
+func (o *opStats) sent(line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || silentCommands[fields[0]] {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.seq++
+	stat := &opStat{Seq: o.seq, Sent: time.Now(), Op: fields[0], Argument: strings.Join(fields[1:], " ")}
+	if len(o.rows) >= maxOpStats {
+		o.rows = o.rows[1:]
+	}
+	o.rows = append(o.rows, stat)
+	o.pending = append(o.pending, stat)
+}
+
+// observe matches a program line to the oldest unanswered command
+func (o *opStats) observe(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.blockEnd != "" {
+		if line == o.blockEnd {
+			o.blockEnd = ""
+		}
+		return
+	}
+	if strings.HasPrefix(line, "INIT_SUCCESS") {
+		o.size, o.sizeKnown = 0, true
+	}
+	if len(o.pending) == 0 {
+		return
+	}
+	// A fresh process announces itself before it reads the first command
+	if (strings.HasPrefix(line, "INIT_SUCCESS") || strings.HasPrefix(line, "READY")) && o.pending[0].Op != "init" {
+		return
+	}
+	stat := o.pending[0]
+	o.pending = o.pending[1:]
+
+	stat.answered = true
+	stat.Latency = time.Since(stat.Sent)
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		stat.Result = fields[0]
+	}
+	if strings.HasSuffix(stat.Result, "_START") {
+		o.blockEnd = strings.TrimSuffix(stat.Result, "_START") + "_END"
+	}
+	for i, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		switch {
+		case ok && (key == "new_size" || key == "size" || key == "tree_size"):
+			stat.Size = value
+		case ok && key == "height":
+			stat.Height = value
+		case !ok && field == "SIZE" && i+1 < len(fields):
+			stat.Size = fields[i+1]
+		}
+	}
+	o.checkSize(stat)
+}
+
+// checkSize counts an invariant violation when an answer reports a size its
+// result cannot lead to, e.g. an INSERT_SUCCESS that did not grow the structure
+func (o *opStats) checkSize(stat *opStat) {
+	size, err := strconv.Atoi(stat.Size)
+	if err != nil {
+		// A state change that did not report the size leaves it unknown
+		if mutatingCommands[stat.Op] {
+			o.sizeKnown = false
+		}
+		return
+	}
+	if change, tracked := sizeChanges[stat.Result]; tracked && o.sizeKnown && size != o.size+change {
+		o.violations++
+		metrics.invariantViolations.Add(1)
+	}
+	o.size, o.sizeKnown = size, true
+}
+
+// invariantViolations returns how many answers broke the size invariant
+func (o *opStats) invariantViolations() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.violations
+}
+
+// restarted forgets unanswered commands when the process is replaced
+func (o *opStats) restarted() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending = nil
+	o.blockEnd = ""
+	o.sizeKnown = false
+}
+
+// snapshot copies the recorded rows
+func (o *opStats) snapshot() []opStat {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	rows := make([]opStat, len(o.rows))
+	for i, stat := range o.rows {
+		rows[i] = *stat
+	}
+	return rows
+}
+
+// handleOpsCSV serves GET /sessions/{id}/ops.csv: one row per command, for spreadsheets
+// Numbers follow the session language, or ?lang= when given
+func handleOpsCSV(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	if !session.visibleTo(r) {
+		httpError(w, ErrSessionPrivate)
+		return
+	}
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = session.Language
+	}
+	tag, err := parseLanguage(lang)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	locale := newLocaleFormatter(tag)
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"session-%s-ops.csv\"", session.ID))
+	out := csv.NewWriter(w)
+	if locale.decimalComma() {
+		out.Comma = ';'
+	}
+	out.Write([]string{"seq", "timestamp", "op", "argument", "latency_ms", "result", "size", "height"})
+	for _, stat := range session.ops.snapshot() {
+		latency := ""
+		if stat.answered {
+			latency = locale.decimal(float64(stat.Latency.Microseconds())/1000, 3)
+		}
+		out.Write([]string{
+			strconv.Itoa(stat.Seq),
+			stat.Sent.Format(time.RFC3339Nano),
+			stat.Op,
+			locale.key(stat.Argument),
+			latency,
+			stat.Result,
+			locale.key(stat.Size),
+			locale.key(stat.Height),
+		})
+	}
+	out.Flush()
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// outputLaneSize is how many lines a FIFO reader may get ahead of the writer before it blocks
+	outputLaneSize = 256
+	// outputStarvationThreshold is how long a line may wait for the writer before it counts as starved
+	outputStarvationThreshold = 250 * time.Millisecond
+	// maxLogBatch is how many coalesced log lines are sent in one message at most
+	maxLogBatch = 256
+	// maxLogCoalesce bounds the coalescing interval a client may ask for
+	maxLogCoalesce = time.Second
+)
+
+// outputLine is one FIFO line waiting to be sent to the clients
+type outputLine struct {
+	text string
+	read time.Time
+}
+
+// outputLane is the queue of one FIFO in front of the shared writer
+type outputLane struct {
+	stream  string    // "program" or "log", the message type the lines are sent as
+	writer  io.Writer // where the lines are sent
+	weight  int       // lines sent per turn while both lanes have lines waiting
+	lines   chan outputLine
+	done    chan struct{} // closed once every line was sent, or the writer stopped
+	starved *atomic.Int64 // lines that waited past outputStarvationThreshold
+
+	// coalesce batches the lane's lines over this interval into one message; 0 sends each alone
+	coalesce time.Duration
+	batch    []string
+	batchDue time.Time // when the batch is sent, counted from its first line
+}
+
+// logBatch is the message carrying coalesced tree log lines, oldest first
+type logBatch struct {
+	Type  string   `json:"type"` // always "logs"
+	Lines []string `json:"lines"`
+}
+
+// forwardOutput reads both FIFOs of a process and writes their lines to the clients
+// from a single writer that serves the program lane first, so a flood of tree logs
+// on a saturated host cannot delay the replies. Lines claimed by consume are not sent.
+// Log lines are coalesced into logBatch messages when logCoalesce is not 0.
+// Returns channels that close when forwarding of the program and log FIFO stops
+func forwardOutput(progFifo, logFifo string, clients io.Writer, consumeProgram, consumeLog func(string) bool, logCoalesce time.Duration) (<-chan struct{}, <-chan struct{}) {
+	program := &outputLane{
+		stream:  "program",
+		writer:  clients,
+		weight:  max(config.OutputProgramWeight, 1),
+		lines:   make(chan outputLine, outputLaneSize),
+		done:    make(chan struct{}),
+		starved: &metrics.programStarved,
+	}
+	log := &outputLane{
+		stream:  "log",
+		writer:  clients,
+		weight:  max(config.OutputLogWeight, 1),
+		lines:   make(chan outputLine, outputLaneSize),
+		done:    make(chan struct{}),
+		starved: &metrics.logStarved,
+
+		coalesce: logCoalesce,
+	}
+
+	// Slow clients miss log lines rather than holding up the program lane
+	if fanout, ok := clients.(*clientFanout); ok {
+		log.writer = fanoutLogWriter{fanout}
+	}
+
+	stopped := make(chan struct{}) // closed when the writer can no longer reach the clients
+	go readFifoLines(progFifo, program.lines, consumeProgram, stopped)
+	go readFifoLines(logFifo, log.lines, consumeLog, stopped)
+	go writeOutput(program, log, stopped)
+	return program.done, log.done
+}
+
+// readFifoLines queues the lines of a FIFO until it closes or the writer stopped
+func readFifoLines(fifo string, lines chan<- outputLine, consume func(string) bool, stopped <-chan struct{}) {
+	defer close(lines)
+	f, err := os.Open(fifo)
+	if err != nil {
+		fmt.Println("Error opening fifo:", fifo, err)
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if consume != nil && consume(line) {
+			continue
+		}
+		select {
+		case lines <- outputLine{text: line, read: time.Now()}:
+		case <-stopped:
+			return
+		}
+	}
+}
+
+// writeOutput sends queued lines by weighted round robin: up to program.weight program
+// lines, then up to log.weight log lines, skipping a lane with nothing waiting
+func writeOutput(program, log *outputLane, stopped chan struct{}) {
+	defer func() {
+		for _, lane := range []*outputLane{program, log} {
+			select {
+			case <-lane.done:
+			default:
+				close(lane.done)
+			}
+		}
+	}()
+
+	// sent checks the result of a write; false once the clients are gone
+	sent := func(lane *outputLane, err error) bool {
+		if err != nil {
+			fmt.Printf("Client disconnected while writing %s output\n", lane.stream)
+			close(stopped)
+			return false
+		}
+		return true
+	}
+	// flush sends the lines batched on a lane as one message
+	flush := func(lane *outputLane) bool {
+		if len(lane.batch) == 0 {
+			return true
+		}
+		err := sendJSONValue(lane.writer, logBatch{Type: "logs", Lines: lane.batch})
+		lane.batch = nil
+		return sent(lane, err)
+	}
+	// send writes one line, or adds it to the lane's batch when the lane coalesces
+	send := func(lane *outputLane, line outputLine) bool {
+		if time.Since(line.read) > outputStarvationThreshold {
+			lane.starved.Add(1)
+		}
+		if lane.coalesce <= 0 {
+			return sent(lane, sendJSONMessage(lane.writer, lane.stream, line.text))
+		}
+		if len(lane.batch) == 0 {
+			lane.batchDue = time.Now().Add(lane.coalesce)
+		}
+		lane.batch = append(lane.batch, line.text)
+		if len(lane.batch) < maxLogBatch && time.Now().Before(lane.batchDue) {
+			return true
+		}
+		return flush(lane)
+	}
+	// received sends a line taken from a lane, or retires the lane when it closed
+	// Returns false once the clients are gone
+	received := func(lane *outputLane, line outputLine, open bool) bool {
+		if !open {
+			if !flush(lane) {
+				return false
+			}
+			close(lane.done)
+			lane.lines = nil
+			return true
+		}
+		return send(lane, line)
+	}
+
+	for program.lines != nil || log.lines != nil {
+		if len(log.batch) > 0 && !time.Now().Before(log.batchDue) && !flush(log) {
+			return
+		}
+		served := false
+		for _, lane := range []*outputLane{program, log} {
+		turn:
+			for i := 0; i < lane.weight && lane.lines != nil; i++ {
+				select {
+				case line, open := <-lane.lines:
+					if !received(lane, line, open) {
+						return
+					}
+					served = true
+				default:
+					break turn
+				}
+			}
+		}
+		if served {
+			continue
+		}
+
+		// Both lanes are empty: wait for whichever fills first (a nil lane never does),
+		// or until the pending log batch is due
+		var timer *time.Timer
+		var due <-chan time.Time
+		if len(log.batch) > 0 {
+			timer = time.NewTimer(time.Until(log.batchDue))
+			due = timer.C
+		}
+		var ok bool
+		select {
+		case line, open := <-program.lines:
+			ok = received(program, line, open)
+		case line, open := <-log.lines:
+			ok = received(log, line, open)
+		case <-due:
+			ok = flush(log)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if !ok {
+			return
+		}
+	}
+}
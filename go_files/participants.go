@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrSessionEmpty is returned when writing to or joining a session everyone has left
+var ErrSessionEmpty = fmt.Errorf("%w: no clients attached to session", ErrClientGone)
+
+// joinCodeAlphabet avoids characters that are easy to confuse when read aloud
+const joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// joinCodeLength is the number of characters in a join code
+const joinCodeLength = 6
+
+// fanoutClient is one client socket attached to a session
+type fanoutClient struct {
+	writer    io.Writer
+	spectator bool           // read-only; does not keep the session alive
+	queue     *outboundQueue // writes to a client connection; nil for hubs and relays
+}
+
+// clientFanout writes every message to all clients attached to a session
+type clientFanout struct {
+	mu           sync.Mutex
+	writers      map[int]fanoutClient
+	participants int // attached clients that are not spectators
+	nextID       int
+	left         bool          // the last participant left; the session is over
+	empty        chan struct{} // closed when the last participant leaves
+	sent         atomic.Int64  // bytes written, summed over clients
+
+	sim atomic.Pointer[networkSimulation] // delays output once an instructor enabled it
+}
+
+// newClientFanout creates an empty fanout
+func newClientFanout() *clientFanout {
+	return &clientFanout{
+		writers: make(map[int]fanoutClient),
+		empty:   make(chan struct{}),
+	}
+}
+
+// add attaches a client and returns its ID within the session
+func (f *clientFanout) add(w io.Writer, spectator bool) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.left {
+		return 0, ErrSessionEmpty
+	}
+	f.nextID++
+	client := fanoutClient{writer: w, spectator: spectator}
+	if conn, ok := w.(deadlineWriter); ok && config.ClientQueueMessages > 0 {
+		client.queue = newOutboundQueue(conn)
+	}
+	f.writers[f.nextID] = client
+	if !spectator {
+		f.participants++
+	}
+	return f.nextID, nil
+}
+
+// remove detaches a client and returns how many remain
+func (f *clientFanout) remove(id int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeLocked(id)
+	return len(f.writers)
+}
+
+// removeLocked detaches a client, closing empty when it was the last participant
+func (f *clientFanout) removeLocked(id int) {
+	client, ok := f.writers[id]
+	if !ok {
+		return
+	}
+	delete(f.writers, id)
+	if client.queue != nil {
+		client.queue.close(0, "")
+	}
+	if client.spectator {
+		return
+	}
+	f.participants--
+	if f.participants == 0 && !f.left {
+		f.left = true
+		close(f.empty)
+	}
+}
+
+// count returns the number of attached participants and spectators
+func (f *clientFanout) count() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.participants, len(f.writers) - f.participants
+}
+
+// Write implements io.Writer by sending p, a JSON line, to every client
+// Clients that fail are detached; it only fails once no client is left
+func (f *clientFanout) Write(p []byte) (int, error) {
+	return f.write(rawMessage(p), false)
+}
+
+// WriteMessage implements messageWriter by sending m to every client
+func (f *clientFanout) WriteMessage(m *outgoingMessage) (int, error) {
+	return f.write(m, false)
+}
+
+// write sends m to every client; droppable messages are skipped for clients whose
+// queue is full rather than disconnecting them
+func (f *clientFanout) write(m *outgoingMessage, droppable bool) (int, error) {
+	if sim := f.sim.Load(); sim != nil {
+		sim.enqueue(m, droppable)
+		return 0, nil
+	}
+	return f.writeNow(m, droppable)
+}
+
+// writeNow sends m to every client without any simulated delay, in each client's encoding
+// Client connections only get it queued, so a slow one does not hold up the others
+// Returns the size of the message as last encoded
+func (f *clientFanout) writeNow(m *outgoingMessage, droppable bool) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	size := 0
+	for id, client := range f.writers {
+		var n int
+		var err error
+		if w, ok := client.writer.(messageWriter); ok && client.queue == nil {
+			n, err = w.WriteMessage(m)
+		} else {
+			var data []byte
+			if data, err = m.encode(encoderOf(client.writer)); err == nil {
+				size = len(data)
+				if client.queue != nil {
+					n, err = client.queue.push(data, droppable)
+				} else {
+					n, err = client.writer.Write(data)
+				}
+			}
+		}
+		f.sent.Add(int64(n))
+		if err != nil {
+			f.removeLocked(id)
+		}
+	}
+	if len(f.writers) == 0 {
+		return 0, ErrSessionEmpty
+	}
+	return size, nil
+}
+
+// attach adds a client socket to the session and forwards its input
+// Returns a channel that closes when this client leaves
+func (s *Session) attach(socket io.ReadWriter) (<-chan struct{}, error) {
+	id, err := s.clients.add(socket, false)
+	if err != nil {
+		return nil, err
+	}
+
+	left := make(chan struct{})
+	inputDone := forwardClientInput(socket, s)
+	go func() {
+		defer close(left)
+		select {
+		case <-inputDone:
+		case <-s.closed:
+		}
+		s.clients.remove(id)
+	}()
+	return left, nil
+}
+
+// joinSession attaches an extra client to a running session until it leaves
+func joinSession(session *Session, clientID string, socket io.ReadWriter) {
+	left, err := session.attach(socket)
+	if err != nil {
+		fmt.Printf("[Client %s] Could not join session %s: %v\n", clientID, session.ID, err)
+		return
+	}
+	fmt.Printf("[Client %s] Joined session %s\n", clientID, session.ID)
+	participants, spectators := session.clients.count()
+	session.reply(fmt.Sprintf("JOINED participants=%d spectators=%d", participants, spectators))
+
+	<-left
+	fmt.Printf("[Client %s] Left session %s\n", clientID, session.ID)
+}
+
+// spectateSession attaches a read-only client that receives the session output
+// Anything it sends is discarded
+func spectateSession(session *Session, clientID string, socket io.ReadWriter) {
+	id, err := session.clients.add(socket, true)
+	if err != nil {
+		fmt.Printf("[Client %s] Could not spectate session %s: %v\n", clientID, session.ID, err)
+		return
+	}
+	defer session.clients.remove(id)
+	fmt.Printf("[Client %s] Spectating session %s\n", clientID, session.ID)
+	sendJSONMessage(socket, "server", "SPECTATING session="+session.ID)
+
+	// Drain and discard input until the spectator leaves
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		io.Copy(io.Discard, socket)
+	}()
+
+	select {
+	case <-inputDone:
+	case <-session.closed:
+	}
+	fmt.Printf("[Client %s] Stopped spectating session %s\n", clientID, session.ID)
+}
+
+// newJoinCode returns a random join code
+func newJoinCode() string {
+	buf := make([]byte, joinCodeLength)
+	rand.Read(buf)
+	for i, b := range buf {
+		buf[i] = joinCodeAlphabet[int(b)%len(joinCodeAlphabet)]
+	}
+	return string(buf)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// plainModeHandshake, sent as the first line on the raw TCP port, switches the connection
+// to plain text: each message is written as "[LABEL] text" rather than JSON, which reads
+// better in nc or telnet. Messages sent before it arrived stay JSON.
+const plainModeHandshake = "mode=plain"
+
+// plainLabels names the message types whose text is worth a short label
+var plainLabels = map[string]string{
+	"program": "OUT",
+	"log":     "LOG",
+	"server":  "SERVER",
+}
+
+// lineConn is a newline-separated raw TCP connection whose first line may be a handshake
+type lineConn struct {
+	bufferedConn
+	handshaken bool   // the first line was read; only the input goroutine reads
+	pending    []byte // the first line, when it was a command
+	plain      atomic.Bool
+}
+
+// newLineConn wraps a line-based connection; a first line already received is looked at
+// right away, so a client sending the handshake as it connects gets no JSON at all
+func newLineConn(buffered bufferedConn) *lineConn {
+	c := &lineConn{bufferedConn: buffered}
+	if ahead, _ := c.reader.Peek(c.reader.Buffered()); bytes.IndexByte(ahead, '\n') >= 0 {
+		c.handshake()
+	}
+	return c
+}
+
+// handshake reads the first line, switching to plain mode when it is the handshake
+func (c *lineConn) handshake() error {
+	c.handshaken = true
+	line, err := c.reader.ReadString('\n')
+	if strings.TrimSpace(line) == plainModeHandshake {
+		c.plain.Store(true)
+		sendJSONMessage(c, "server", "MODE plain")
+	} else {
+		c.pending = []byte(line)
+	}
+	return err
+}
+
+// Read implements io.Reader, taking the handshake out of the input
+func (c *lineConn) Read(p []byte) (int, error) {
+	if !c.handshaken {
+		if err := c.handshake(); err != nil && len(c.pending) == 0 {
+			return 0, err
+		}
+	}
+	if len(c.pending) > 0 {
+		n := copy(p, c.pending)
+		c.pending = c.pending[n:]
+		return n, nil
+	}
+	return c.reader.Read(p)
+}
+
+// Write implements io.Writer, turning each JSON line into a labeled text line in plain mode
+func (c *lineConn) Write(p []byte) (int, error) {
+	if !c.plain.Load() {
+		return c.bufferedConn.Write(p)
+	}
+	var text bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		text.WriteString(plainLine(line))
+		text.WriteByte('\n')
+	}
+	if _, err := c.bufferedConn.Write(text.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// plainLine renders one JSON message as text: "[OUT] ..." and "[LOG] ..." for process
+// output, "[ERROR] message (code)" for errors, and the JSON itself after its type otherwise
+func plainLine(line string) string {
+	var msg struct {
+		Type    string `json:"type"`
+		Message any    `json:"message"`
+		Code    string `json:"code"`
+	}
+	if json.Unmarshal([]byte(line), &msg) != nil || msg.Type == "" {
+		return line
+	}
+	text, isText := msg.Message.(string)
+	switch {
+	case isText && msg.Code != "":
+		return fmt.Sprintf("[%s] %s (%s)", strings.ToUpper(msg.Type), text, msg.Code)
+	case isText && plainLabels[msg.Type] != "":
+		return fmt.Sprintf("[%s] %s", plainLabels[msg.Type], text)
+	}
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(msg.Type), line)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// PreviewResult is sent to the client after a dry run of a command
+type PreviewResult struct {
+	Type    string   `json:"type"` // always "preview"
+	Command string   `json:"command"`
+	Program []string `json:"program"`
+	Log     []string `json:"log"`
+}
+
+// opPreview runs a command against a throwaway clone of the session structure
+// and returns the events it produced, leaving the real session untouched
+func opPreview(session *Session, msg clientMessage) error {
+	if strings.TrimSpace(msg.Command) == "" {
+		return &ValidationError{"Missing required field: command"}
+	}
+	if _, ok := serverCommands[commandName(msg.Command)]; ok {
+		return &ValidationError{"Server commands cannot be previewed"}
+	}
+
+	result, err := previewCommand(session.DataType, session.Engine, session.Flags, session.journal.applied(), msg.Command)
+	if err != nil {
+		return err
+	}
+	return session.send(result)
+}
+
+// previewCommand clones the state by replaying ops in a temporary process and
+// isolates the output of command by diffing against a run without it
+func previewCommand(ds, engine, flags string, ops []string, command string) (*PreviewResult, error) {
+	program, log, err := runAfter(context.Background(), ds, engine, flags, ops, []string{command})
+	if err != nil {
+		return nil, err
+	}
+	return &PreviewResult{Type: "preview", Command: command, Program: program, Log: log}, nil
+}
+
+// runAfter replays ops in a temporary process, runs script after them and returns
+// only the output of script, found by diffing against a run of ops alone
+func runAfter(ctx context.Context, ds, engine, flags string, ops, script []string) ([]string, []string, error) {
+	baseProgram, baseLog, err := runHeadless(ctx, ds, engine, flags, ops)
+	if err != nil {
+		return nil, nil, err
+	}
+	program, log, err := runHeadless(ctx, ds, engine, flags, append(append([]string{}, ops...), script...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Both runs are deterministic, so the base output is a prefix (up to pointer values)
+	return append([]string{}, program[min(len(baseProgram), len(program)):]...),
+		append([]string{}, log[min(len(baseLog), len(log)):]...), nil
+}
+
+// runHeadless runs a script against a fresh process with program output on stdout
+// and tree logs on stderr, returning both once the process exits
+// Cancelling ctx kills the process
+func runHeadless(ctx context.Context, ds, engine, flags string, script []string) ([]string, []string, error) {
+	if engine == "" {
+		engine = config.Engine
+	}
+	if engine == engineGo {
+		return runGoHeadless(ds, flags, script)
+	}
+	args, err := interfaceArgs(ds, flags, "--program-out", "stdout", "--tree-log-out", "stderr")
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, replayTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, interfaceExecutable(ds), args...)
+	cmd.Stdin = strings.NewReader(strings.Join(script, "\n") + "\n")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	wait, err := startInterfaceCommand(cmd)
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		return nil, nil, processStartError(ds, err)
+	}
+
+	var program, log []string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); program = readLines(stdout) }()
+	go func() { defer wg.Done(); log = readLines(stderr) }()
+	wg.Wait()
+
+	return program, log, processExitError(wait())
+}
+
+// readLines reads a stream to its end
+func readLines(r io.Reader) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
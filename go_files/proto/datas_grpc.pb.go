@@ -0,0 +1,128 @@
+// gRPC API of the DATAS server, served on config grpc_port.
+//
+// A call to OpenSession starts a session like GET /session does over WebSocket.
+// The session parameters are sent as request metadata named after the /session
+// query parameters with a "datas-" prefix, e.g. "datas-type: avltree",
+// "datas-engine: go", "datas-seed: 42". Each Command carries one client message.
+// Each Event carries one server message. Closing the send side ends the session.
+// The stream then finishes with the gRPC status matching the WebSocket close code.
+//
+// Regenerate the Go code after editing, from go_files:
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/datas.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: proto/datas.proto
+
+package datasv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Datas_OpenSession_FullMethodName = "/datas.v1.Datas/OpenSession"
+)
+
+// DatasClient is the client API for Datas service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DatasClient interface {
+	OpenSession(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Command, Event], error)
+}
+
+type datasClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDatasClient(cc grpc.ClientConnInterface) DatasClient {
+	return &datasClient{cc}
+}
+
+func (c *datasClient) OpenSession(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Command, Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Datas_ServiceDesc.Streams[0], Datas_OpenSession_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Command, Event]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Datas_OpenSessionClient = grpc.BidiStreamingClient[Command, Event]
+
+// DatasServer is the server API for Datas service.
+// All implementations must embed UnimplementedDatasServer
+// for forward compatibility.
+type DatasServer interface {
+	OpenSession(grpc.BidiStreamingServer[Command, Event]) error
+	mustEmbedUnimplementedDatasServer()
+}
+
+// UnimplementedDatasServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDatasServer struct{}
+
+func (UnimplementedDatasServer) OpenSession(grpc.BidiStreamingServer[Command, Event]) error {
+	return status.Errorf(codes.Unimplemented, "method OpenSession not implemented")
+}
+func (UnimplementedDatasServer) mustEmbedUnimplementedDatasServer() {}
+func (UnimplementedDatasServer) testEmbeddedByValue()               {}
+
+// UnsafeDatasServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DatasServer will
+// result in compilation errors.
+type UnsafeDatasServer interface {
+	mustEmbedUnimplementedDatasServer()
+}
+
+func RegisterDatasServer(s grpc.ServiceRegistrar, srv DatasServer) {
+	// If the following call pancis, it indicates UnimplementedDatasServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Datas_ServiceDesc, srv)
+}
+
+func _Datas_OpenSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DatasServer).OpenSession(&grpc.GenericServerStream[Command, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Datas_OpenSessionServer = grpc.BidiStreamingServer[Command, Event]
+
+// Datas_ServiceDesc is the grpc.ServiceDesc for Datas service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Datas_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "datas.v1.Datas",
+	HandlerType: (*DatasServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "OpenSession",
+			Handler:       _Datas_OpenSession_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/datas.proto",
+}
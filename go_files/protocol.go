@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// messageClass separates control messages from data commands
+type messageClass int
+
+const (
+	classData    messageClass = iota // ordered commands for the C++ process
+	classControl                     // session control, handled ahead of queued data
+)
+
+// clientMessage is one message received from the client
+// Plain text lines are data commands; JSON objects carry an op
+type clientMessage struct {
+	Op      string          `json:"op"`
+	Command string          `json:"command,omitempty"`
+	Streams []string        `json:"streams,omitempty"`
+	From    json.RawMessage `json:"from,omitempty"` // range bounds: numbers, or strings for string keys
+	To      json.RawMessage `json:"to,omitempty"`
+	Nonce   string          `json:"nonce,omitempty"` // echoed from confirm_required
+	// Type "control" names a control message by the setting it carries instead of an op
+	Type      string `json:"type,omitempty"`
+	Verbosity string `json:"verbosity,omitempty"`
+}
+
+// opHandler runs a JSON op for a session
+type opHandler func(session *Session, msg clientMessage) error
+
+// controlOps are handled immediately, ahead of any queued data
+var controlOps = map[string]opHandler{
+	"pause":     opPause,
+	"resume":    opResume,
+	"subscribe": opSubscribe,
+	"heartbeat": opHeartbeat,
+	"range":     opRange,
+	"verbosity": opVerbosity,
+}
+
+// dataOps are queued in order with the client's commands
+var dataOps = map[string]opHandler{
+	"preview":      opPreview,
+	"fork":         opFork,
+	"clear":        opClear,
+	"delete_range": opDeleteRange,
+	"confirm":      opConfirm,
+}
+
+// parseClientLine turns a raw client line into a message
+func parseClientLine(line string) (clientMessage, *ProtocolViolation) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		// Only framed connections can deliver one; the process would read it as several commands
+		if strings.ContainsAny(line, "\r\n") {
+			return clientMessage{}, &ProtocolViolation{violationMultilineCommand, "A command cannot span lines", line}
+		}
+		return clientMessage{Op: "command", Command: line}, nil
+	}
+
+	var msg clientMessage
+	if err := json.Unmarshal([]byte(trimmed), &msg); err != nil {
+		return msg, &ProtocolViolation{violationMalformedJSON, "Invalid JSON message", line}
+	}
+	if msg.Op == "" && msg.Type == "control" && msg.Verbosity != "" {
+		msg.Op = "verbosity"
+	}
+	if msg.Op == "" {
+		return msg, &ProtocolViolation{violationMissingOp, "Missing required field: op", line}
+	}
+	return msg, nil
+}
+
+// class returns which queue a message belongs to
+func (m clientMessage) class() messageClass {
+	if _, ok := controlOps[m.Op]; ok {
+		return classControl
+	}
+	return classData
+}
+
+// opPause stops feeding queued commands to the C++ process
+func opPause(session *Session, msg clientMessage) error {
+	session.setPaused(true)
+	return session.reply("PAUSED")
+}
+
+// opResume continues feeding queued commands
+func opResume(session *Session, msg clientMessage) error {
+	session.setPaused(false)
+	return session.reply("RESUMED")
+}
+
+// opSubscribe selects which output streams are forwarded to the client
+func opSubscribe(session *Session, msg clientMessage) error {
+	for _, stream := range msg.Streams {
+		if stream != "program" && stream != "log" {
+			return &ValidationError{"Unknown stream: " + stream}
+		}
+	}
+	session.subscribe(msg.Streams)
+	return session.reply("SUBSCRIBED streams=" + strings.Join(msg.Streams, ","))
+}
+
+// opHeartbeat answers a client liveness probe
+func opHeartbeat(session *Session, msg clientMessage) error {
+	return session.reply("HEARTBEAT_ACK")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HAProxy PROXY protocol v1 and v2 on the raw TCP listener: a load balancer listed in
+// TrustedProxies prefixes each connection with the address of the client it relays
+
+const (
+	// proxyHeaderTimeout bounds how long a trusted proxy may take to send the header
+	proxyHeaderTimeout = 5 * time.Second
+	// proxyV1MaxLength is the longest v1 header line, CRLF included
+	proxyV1MaxLength = 107
+)
+
+// proxyV2Signature starts every v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrProxyHeader is returned when a trusted proxy's connection has no valid PROXY header
+var ErrProxyHeader = errors.New("invalid PROXY protocol header")
+
+// proxiedConn is a connection relayed by a load balancer: it reports the client's address
+// and reads past the header
+type proxiedConn struct {
+	net.Conn
+	reader *bufio.Reader // holds any client bytes read along with the header
+	remote net.Addr
+}
+
+// Read implements io.Reader
+func (c *proxiedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// RemoteAddr returns the client's address as the proxy reported it
+func (c *proxiedConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// acceptProxyHeader reads the PROXY header of a connection from a trusted proxy;
+// other connections are returned unchanged
+func acceptProxyHeader(conn net.Conn) (net.Conn, error) {
+	if !config.ProxyProtocol || !trustedProxy(conn.RemoteAddr().String()) {
+		return conn, nil
+	}
+	conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	remote, err := readProxyHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	if remote == nil {
+		// UNKNOWN, LOCAL and non-IP headers: the connection is the proxy's own
+		remote = conn.RemoteAddr()
+	}
+	return &proxiedConn{Conn: conn, reader: reader, remote: remote}, nil
+}
+
+// readProxyHeader consumes a v1 or v2 header and returns the source address it carries,
+// nil when it carries none
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	start, err := r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyHeader, err)
+	}
+	switch {
+	case bytes.Equal(start, proxyV2Signature):
+		return readProxyV2(r)
+	case bytes.HasPrefix(start, []byte("PROXY ")):
+		return readProxyV1(r)
+	}
+	return nil, fmt.Errorf("%w: missing", ErrProxyHeader)
+}
+
+// readProxyV1 parses "PROXY TCP4|TCP6 <src> <dst> <sport> <dport>\r\n" or "PROXY UNKNOWN ...\r\n"
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == proxyV1MaxLength {
+			return nil, fmt.Errorf("%w: v1 line too long", ErrProxyHeader)
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrProxyHeader, err)
+		}
+		line = append(line, b)
+	}
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("%w: malformed v1 line", ErrProxyHeader)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, fmt.Errorf("%w: malformed v1 source address", ErrProxyHeader)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2 parses a binary v2 header
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyHeader, err)
+	}
+	version, command := header[12]>>4, header[12]&0x0f
+	if version != 2 || command > 1 {
+		return nil, fmt.Errorf("%w: unsupported v2 version or command", ErrProxyHeader)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyHeader, err)
+	}
+	if command == 0 {
+		// LOCAL: the proxy's own connection, such as a health check
+		return nil, nil
+	}
+	switch header[13] {
+	case 0x11: // TCP over IPv4: source, destination, source port, destination port
+		if len(body) < 12 {
+			return nil, fmt.Errorf("%w: truncated v2 IPv4 addresses", ErrProxyHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(body) < 36 {
+			return nil, fmt.Errorf("%w: truncated v2 IPv6 addresses", ErrProxyHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:]))}, nil
+	}
+	// UDP, Unix sockets and unspecified families carry no TCP client address
+	return nil, nil
+}
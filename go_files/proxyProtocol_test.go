@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// proxyV2Header builds a v2 header: command 0 is LOCAL, 1 is PROXY
+func proxyV2Header(command, family byte, body []byte) string {
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(body)))
+	return string(append(header, body...))
+}
+
+// proxyV2Addresses is the address block of a v2 header: source, destination, source port, destination port
+func proxyV2Addresses(src, dst net.IP, sport, dport uint16) []byte {
+	body := append(append([]byte{}, src...), dst...)
+	body = binary.BigEndian.AppendUint16(body, sport)
+	return binary.BigEndian.AppendUint16(body, dport)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v4 := proxyV2Addresses(net.IPv4(203, 0, 113, 7).To4(), net.IPv4(10, 0, 0, 1).To4(), 51000, 9000)
+	v6 := proxyV2Addresses(net.ParseIP("2001:db8::7"), net.ParseIP("2001:db8::1"), 51000, 9000)
+	tests := []struct {
+		name   string
+		input  string
+		remote string // "" when the header carries no address
+		err    bool
+	}{
+		{"v1 tcp4", "PROXY TCP4 203.0.113.7 10.0.0.1 51000 9000\r\n", "203.0.113.7:51000", false},
+		{"v1 tcp6", "PROXY TCP6 2001:db8::7 2001:db8::1 51000 9000\r\n", "[2001:db8::7]:51000", false},
+		{"v1 unknown", "PROXY UNKNOWN\r\n", "", false},
+		{"v1 unknown with addresses", "PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n", "", false},
+		{"v1 family mismatch", "PROXY TCP4 2001:db8::7 2001:db8::1 51000 9000\r\n", "", true},
+		{"v1 bad port", "PROXY TCP4 203.0.113.7 10.0.0.1 70000 9000\r\n", "", true},
+		{"v1 missing field", "PROXY TCP4 203.0.113.7 10.0.0.1 51000\r\n", "", true},
+		{"v1 bad protocol", "PROXY UDP4 203.0.113.7 10.0.0.1 51000 9000\r\n", "", true},
+		{"v1 without crlf", "PROXY TCP4 203.0.113.7 10.0.0.1 51000 9000\n", "", true},
+		{"v1 too long", "PROXY TCP4 " + strings.Repeat("1", proxyV1MaxLength) + "\r\n", "", true},
+		{"v2 tcp4", proxyV2Header(1, 0x11, v4), "203.0.113.7:51000", false},
+		{"v2 tcp6", proxyV2Header(1, 0x21, v6), "[2001:db8::7]:51000", false},
+		{"v2 tcp4 with tlvs", proxyV2Header(1, 0x11, append(v4, 0x04, 0x00, 0x01, 0xff)), "203.0.113.7:51000", false},
+		{"v2 local", proxyV2Header(0, 0x11, v4), "", false},
+		{"v2 udp", proxyV2Header(1, 0x12, v4), "", false},
+		{"v2 unspecified family", proxyV2Header(1, 0x00, nil), "", false},
+		{"v2 truncated ipv4", proxyV2Header(1, 0x11, v4[:8]), "", true},
+		{"v2 truncated ipv6", proxyV2Header(1, 0x21, v6[:20]), "", true},
+		{"v2 body shorter than length", proxyV2Header(1, 0x11, v4)[:20], "", true},
+		{"v2 bad command", proxyV2Header(2, 0x11, v4), "", true},
+		{"v2 bad version", strings.Replace(proxyV2Header(1, 0x11, v4), "\x21\x11", "\x11\x11", 1), "", true},
+		{"missing", "insert 5\r\ninsert 6\r\n", "", true},
+		{"short", "PROXY", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Client bytes follow a valid header and must be left unread
+			input := tt.input
+			if !tt.err {
+				input += "insert 5\n"
+			}
+			r := bufio.NewReader(strings.NewReader(input))
+			remote, err := readProxyHeader(r)
+			if tt.err {
+				if !errors.Is(err, ErrProxyHeader) {
+					t.Fatalf("got %v, %v; want ErrProxyHeader", remote, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := ""
+			if remote != nil {
+				got = remote.String()
+			}
+			if got != tt.remote {
+				t.Errorf("remote = %q, want %q", got, tt.remote)
+			}
+			rest, _ := io.ReadAll(r)
+			if string(rest) != "insert 5\n" {
+				t.Errorf("client bytes after the header = %q, want %q", rest, "insert 5\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rangeChunkSize bounds how many keys one range_chunk message carries
+const rangeChunkSize = 256
+
+// keyDumps make each structure list its keys, in any order; keyed by model
+var keyDumps = map[string]snapshotSpec{
+	"btree":      {command: "print", start: "TREE_START", end: "TREE_END"},
+	"avltree":    {command: "print", start: "TREE_INORDER_START", end: "TREE_INORDER_END"},
+	"rbtree":     {command: "print", start: "TREE_INORDER_START", end: "TREE_INORDER_END"},
+	"heap":       {command: "print", start: "HEAP_START", end: "HEAP_END"},
+	"trie":       {command: "print", start: "TRIE_WORDS_START", end: "TRIE_WORDS_END"},
+	"skiplist":   {command: "inorder", start: "SKIPLIST_INORDER_START", end: "SKIPLIST_INORDER_END"},
+	"hashtable":  {command: "keys", start: "KEYS_START", end: "KEYS_END"},
+	"linkedlist": {command: "print", start: "LIST_START", end: "LIST_END"},
+	"splaytree":  {command: "print", start: "TREE_INORDER_START", end: "TREE_INORDER_END"},
+	"treap":      {command: "print", start: "TREE_INORDER_START", end: "TREE_INORDER_END"},
+	"bplustree":  {command: "leaves", start: "LEAVES_START", end: "LEAVES_END"},
+}
+
+// rangeChunk is one part of the answer to a range op
+type rangeChunk struct {
+	Type  string `json:"type"` // always "range_chunk"
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+	Seq   int    `json:"seq"`
+	Keys  []any  `json:"keys"`
+	Total int    `json:"total"` // keys sent so far, this chunk included
+	Done  bool   `json:"done"`
+}
+
+// dumpFields splits a line of a key dump into keys, skipping the brackets, commas
+// and tree drawing around them; quoted keys are unquoted
+func dumpFields(line string) []string {
+	var fields []string
+	rest := line
+	for {
+		rest = strings.TrimLeft(rest, " \t,[]│├└─")
+		if rest == "" {
+			return fields
+		}
+		if rest[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err == nil {
+				field, _ := strconv.Unquote(quoted)
+				fields = append(fields, field)
+				rest = rest[len(quoted):]
+				continue
+			}
+		}
+		end := strings.IndexAny(rest, " \t,[]")
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = rest[end:]
+	}
+}
+
+// rangeKey is a key compared in the order of its structure's key type
+type rangeKey struct {
+	text   string
+	number float64
+}
+
+// parseRangeKey reads a key of the given type; JSON numbers and strings are both accepted
+func parseRangeKey(keyType string, raw json.RawMessage) (rangeKey, error) {
+	var text string
+	if json.Unmarshal(raw, &text) != nil {
+		text = string(raw)
+	}
+	if keyType == keyString {
+		return rangeKey{text: text}, nil
+	}
+	normalized, err := normalizeKey(keyType, text)
+	if err != nil {
+		return rangeKey{}, err
+	}
+	number, _ := strconv.ParseFloat(normalized, 64)
+	return rangeKey{text: normalized, number: number}, nil
+}
+
+// compareKeys orders two keys of the same type
+func compareKeys(keyType string, a, b rangeKey) int {
+	if keyType == keyString {
+		return strings.Compare(a.text, b.text)
+	}
+	return cmp.Compare(a.number, b.number)
+}
+
+// jsonKey is the JSON form of a key: a number unless the structure keys strings
+func (k rangeKey) jsonKey(keyType string) any {
+	if keyType == keyString {
+		return k.text
+	}
+	return json.Number(k.text)
+}
+
+// rangeBounds reads and checks the from and to fields of a range message
+func rangeBounds(keyType string, msg clientMessage) (rangeKey, rangeKey, error) {
+	if msg.From == nil || msg.To == nil {
+		return rangeKey{}, rangeKey{}, &ValidationError{"Missing required fields: from, to"}
+	}
+	from, err := parseRangeKey(keyType, msg.From)
+	if err != nil {
+		return rangeKey{}, rangeKey{}, err
+	}
+	to, err := parseRangeKey(keyType, msg.To)
+	if err != nil {
+		return rangeKey{}, rangeKey{}, err
+	}
+	if compareKeys(keyType, from, to) > 0 {
+		return rangeKey{}, rangeKey{}, &ValidationError{"Invalid range: from is after to"}
+	}
+	return from, to, nil
+}
+
+// keysInRange dumps the session's keys and returns those between from and to, sorted
+func (s *Session) keysInRange(spec snapshotSpec, keyType string, from, to rangeKey) ([]rangeKey, error) {
+	lines, err := s.captureOutput(spec.command, spec.start, spec.end, snapshotTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []rangeKey
+	for _, line := range lines {
+		for _, field := range dumpFields(line) {
+			key, err := parseRangeKey(keyType, json.RawMessage(strconv.Quote(field)))
+			if err != nil {
+				continue
+			}
+			if compareKeys(keyType, key, from) >= 0 && compareKeys(keyType, key, to) <= 0 {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.SortStableFunc(keys, func(a, b rangeKey) int { return compareKeys(keyType, a, b) })
+	return keys, nil
+}
+
+// opRange streams the keys between from and to, inclusive, in ascending order
+// The dump runs in the background so the control queue stays responsive
+func opRange(session *Session, msg clientMessage) error {
+	registered, ok := lookupDataStructure(session.DataType)
+	if !ok {
+		return ErrSnapshotUnsupported
+	}
+	spec, ok := keyDumps[registered.model()]
+	if !ok {
+		return ErrSnapshotUnsupported
+	}
+	keyType := registered.keyType()
+	from, to, err := rangeBounds(keyType, msg)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		keys, err := session.keysInRange(spec, keyType, from, to)
+		if err != nil {
+			session.reply(fmt.Sprintf("ERROR op=range code=%s error=%s", recordError(err), err))
+			return
+		}
+
+		chunk := rangeChunk{Type: "range_chunk", From: from.jsonKey(keyType), To: to.jsonKey(keyType)}
+		for start := 0; ; start += rangeChunkSize {
+			end := min(start+rangeChunkSize, len(keys))
+			chunk.Keys = make([]any, 0, end-start)
+			for _, key := range keys[start:end] {
+				chunk.Keys = append(chunk.Keys, key.jsonKey(keyType))
+			}
+			chunk.Total, chunk.Done = end, end == len(keys)
+			if err := session.send(chunk); err != nil || chunk.Done {
+				return
+			}
+			chunk.Seq++
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DataStructure registers a data structure and the interface executable that runs it
+// New structures are added in config.json; no Go code is needed unless they want a Go engine
+type DataStructure struct {
+	Name string `json:"name"` // value of ?type=
+	// Executable is the interface binary, started with --program-out, --tree-log-out and --batch
+	Executable string `json:"executable"`
+	// Flags lists the query parameters a client may set and the flags they become
+	Flags []DataStructureFlag `json:"flags"`
+	// DefaultFlags are passed when the client sets none of Flags
+	DefaultFlags string `json:"default_flags"`
+	// KeyType is int (default), float or string; keys are checked and quoted before
+	// they reach the interface
+	KeyType string `json:"key_type,omitempty"`
+	// Model is the Go engine implementing the structure; defaults to Name
+	Model string `json:"model,omitempty"`
+	// Mutating lists commands that change this structure besides the shared mutatingCommands,
+	// e.g. find on a splay tree; they are journaled so undo and restores replay them
+	Mutating []string `json:"mutating,omitempty"`
+	// KeyArity is how many keys the key commands take, e.g. 2 for an interval's
+	// low and high ends; 0 means 1
+	KeyArity int `json:"key_arity,omitempty"`
+	// Sizing names a flagSizers entry computing flags from the parameters, e.g. "bloom";
+	// flags without a Flag are then only its inputs
+	Sizing string `json:"sizing,omitempty"`
+}
+
+// DataStructureFlag maps a query parameter to a command line flag of the interface
+type DataStructureFlag struct {
+	Param string `json:"param"` // query parameter, e.g. "order"
+	Flag  string `json:"flag"`  // command line flag, e.g. "--order"; empty for Sizing inputs
+	// Values lists the accepted values; empty accepts any number from Min to Max
+	Values []string `json:"values,omitempty"`
+	Min    float64  `json:"min"`
+	Max    float64  `json:"max,omitempty"`   // 0 leaves the number unbounded above
+	Float  bool     `json:"float,omitempty"` // accept decimals instead of integers only
+	// Required rejects sessions that do not set the parameter, e.g. a cache capacity
+	Required bool `json:"required,omitempty"`
+	// List accepts a comma-separated list, each item checked like a single value
+	List     bool `json:"list,omitempty"`
+	MaxItems int  `json:"max_items,omitempty"` // 0 leaves the list length unbounded
+	// Default is what the interface uses when the parameter is not set; shown to clients only
+	Default string `json:"default,omitempty"`
+}
+
+// defaultDataStructures are the structures built from cpp_files
+func defaultDataStructures() []DataStructure {
+	return []DataStructure{
+		{
+			Name:       "btree",
+			Executable: "./btreeInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "order", Flag: "--order", Min: 3, Default: "4"}},
+		},
+		{
+			Name:       "avltree",
+			Executable: "./avltreeInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "duplicates", Flag: "--duplicates", Values: []string{"reject", "allow"}, Default: "reject"},
+				{Param: "initial", Flag: "--initial", List: true, MaxItems: 64, Min: math.MinInt32, Max: math.MaxInt32},
+				{Param: "verbose_rotations", Flag: "--verbose-rotations", Values: []string{"on", "off"}, Default: "off"},
+			},
+		},
+		{
+			Name:       "rbtree",
+			Executable: "./rbtreeInterface.exe",
+		},
+		{
+			Name:       "heap",
+			Executable: "./heapInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "kind", Flag: "--kind", Values: []string{"min", "max"}, Default: "min"},
+				{Param: "arity", Flag: "--arity", Min: 2, Default: "2"},
+			},
+		},
+		{
+			Name:       "trie",
+			Executable: "./trieInterface.exe",
+			KeyType:    keyString,
+			Flags:      []DataStructureFlag{{Param: "case", Flag: "--case", Values: []string{"sensitive", "insensitive"}, Default: "sensitive"}},
+		},
+		{
+			Name:       "skiplist",
+			Executable: "./skiplistInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "probability", Flag: "--probability", Float: true, Min: 0.01, Max: 0.99, Default: "0.5"},
+				{Param: "max_level", Flag: "--max-level", Min: 1, Max: 32, Default: "16"},
+			},
+		},
+		{
+			Name:       "hashtable",
+			Executable: "./hashtableInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "buckets", Flag: "--buckets", Min: 1, Max: 65536, Default: "8"},
+				{Param: "strategy", Flag: "--strategy", Values: []string{"chaining", "openaddr"}, Default: "chaining"},
+			},
+		},
+		{
+			Name:       "linkedlist",
+			Executable: "./linkedlistInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "kind", Flag: "--kind", Values: []string{"singly", "doubly"}, Default: "singly"}},
+		},
+		{
+			Name:       "splaytree",
+			Executable: "./splaytreeInterface.exe",
+			Mutating:   []string{"find", "search"},
+		},
+		{
+			Name:       "treap",
+			Executable: "./treapInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "seed", Flag: "--seed", Min: 0, Max: math.MaxUint32}},
+		},
+		{
+			Name:       "segtree",
+			Executable: "./segtreeInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "size", Flag: "--size", Min: 1, Max: 1024, Default: "16"},
+				{Param: "function", Flag: "--function", Values: []string{"sum", "min", "max"}, Default: "sum"},
+			},
+			Mutating: []string{"set", "update"},
+		},
+		{
+			Name:       "fenwick",
+			Executable: "./fenwickInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "size", Flag: "--size", Min: 1, Max: 1024, Default: "16"}},
+			Mutating:   []string{"set", "update", "add"},
+		},
+		{
+			Name:       "dsu",
+			Executable: "./dsuInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "path_compression", Flag: "--path-compression", Values: []string{"on", "off"}, Default: "on"},
+				{Param: "union_by_rank", Flag: "--union-by-rank", Values: []string{"on", "off"}, Default: "on"},
+			},
+			// Finds compress paths, so they are replayed to rebuild the same forest
+			Mutating: []string{"make", "union", "find", "search", "connected"},
+		},
+		{
+			Name:       "bplustree",
+			Executable: "./bplustreeInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "order", Flag: "--order", Min: 3, Default: "4"},
+				{Param: "leaf_links", Flag: "--leaf-links", Values: []string{"on", "off"}, Default: "on"},
+			},
+		},
+		{
+			Name:       "graph",
+			Executable: "./graphInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "directed", Flag: "--directed", Values: []string{"on", "off"}, Default: "off"}},
+			Mutating:   []string{"edge", "connect", "unedge", "disconnect"},
+		},
+		{
+			Name:       "lrucache",
+			Executable: "./lrucacheInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "capacity", Flag: "--capacity", Min: 1, Required: true}},
+			// A hit makes its key the most recent, which decides the next eviction
+			Mutating: []string{"find", "search", "access"},
+		},
+		{
+			Name:       "queue",
+			Executable: "./queueInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "capacity", Flag: "--capacity", Min: 1, Max: 1024, Default: "8"}},
+			Mutating:   []string{"enqueue", "dequeue"},
+		},
+		{
+			Name:       "deque",
+			Executable: "./dequeInterface.exe",
+			Flags:      []DataStructureFlag{{Param: "capacity", Flag: "--capacity", Min: 1, Max: 1024, Default: "8"}},
+			Mutating:   []string{"pop_front", "pop_back"},
+		},
+		{
+			Name:       "bloomfilter",
+			Executable: "./bloomfilterInterface.exe",
+			Flags: []DataStructureFlag{
+				{Param: "expected_items", Min: 1, Max: 100000, Default: "100"},
+				{Param: "fp_rate", Float: true, Min: 0.0001, Max: 0.5, Default: "0.01"},
+			},
+			Sizing: "bloom",
+		},
+		{
+			Name:       "intervaltree",
+			Executable: "./intervaltreeInterface.exe",
+			KeyArity:   2,
+		},
+	}
+}
+
+// flagSizer computes interface flags from parameters that are easier to choose
+type flagSizer struct {
+	// flags are the flags size emits; the command line accepts them like registered flags
+	flags []DataStructureFlag
+	// size runs after the parameters were validated and returns flag, value pairs
+	size func(params url.Values) []string
+}
+
+// flagSizers are keyed by DataStructure.Sizing
+var flagSizers = map[string]flagSizer{
+	"bloom": {
+		flags: []DataStructureFlag{
+			{Param: "bits", Flag: "--bits", Min: 8, Max: 4194304, Default: "959"},
+			{Param: "hashes", Flag: "--hashes", Min: 1, Max: 32, Default: "7"},
+		},
+		size: bloomSizing,
+	},
+}
+
+// bloomSizing turns expected_items n and fp_rate p into the bit count
+// m = -n ln p / (ln 2)^2 and hash count k = m/n ln 2 that minimize the
+// false-positive rate of n keys
+func bloomSizing(params url.Values) []string {
+	n, p := 100.0, 0.01
+	if value := params.Get("expected_items"); value != "" {
+		n, _ = strconv.ParseFloat(value, 64)
+	}
+	if value := params.Get("fp_rate"); value != "" {
+		p, _ = strconv.ParseFloat(value, 64)
+	}
+	bits := max(int(math.Ceil(-n*math.Log(p)/(math.Ln2*math.Ln2))), 8)
+	hashes := min(max(int(math.Round(float64(bits)/n*math.Ln2)), 1), 32)
+	return []string{"--bits", strconv.Itoa(bits), "--hashes", strconv.Itoa(hashes)}
+}
+
+// validateRegistry checks the configured structures before the server uses them
+func validateRegistry(structures []DataStructure) error {
+	seen := make(map[string]bool)
+	for _, ds := range structures {
+		switch {
+		case ds.Name == "":
+			return fmt.Errorf("data structure without a name")
+		case seen[ds.Name]:
+			return fmt.Errorf("data structure %q registered twice", ds.Name)
+		case ds.Executable == "":
+			return fmt.Errorf("data structure %q has no executable", ds.Name)
+		}
+		seen[ds.Name] = true
+		switch ds.KeyType {
+		case "", keyInt, keyFloat, keyString:
+		default:
+			return fmt.Errorf("data structure %q has unknown key type %q", ds.Name, ds.KeyType)
+		}
+		if ds.KeyArity < 0 {
+			return fmt.Errorf("data structure %q has a negative key arity", ds.Name)
+		}
+		if _, ok := flagSizers[ds.Sizing]; ds.Sizing != "" && !ok {
+			return fmt.Errorf("data structure %q has unknown sizing %q", ds.Name, ds.Sizing)
+		}
+		for _, flag := range ds.Flags {
+			sizingInput := flag.Flag == "" && ds.Sizing != ""
+			if flag.Param == "" || (!sizingInput && !strings.HasPrefix(flag.Flag, "-")) || (flag.Max != 0 && flag.Max < flag.Min) || flag.MaxItems < 0 {
+				return fmt.Errorf("data structure %q has an invalid flag %+v", ds.Name, flag)
+			}
+		}
+		if _, err := ds.flagArgs(ds.DefaultFlags); err != nil {
+			return fmt.Errorf("data structure %q has invalid default flags: %v", ds.Name, err)
+		}
+	}
+	return nil
+}
+
+// lookupDataStructure returns the registered structure with the given name
+func lookupDataStructure(name string) (*DataStructure, bool) {
+	for i := range config.DataStructures {
+		if config.DataStructures[i].Name == name {
+			return &config.DataStructures[i], true
+		}
+	}
+	return nil, false
+}
+
+// dataStructureNames lists the registered structures in config order
+func dataStructureNames() []string {
+	names := make([]string, len(config.DataStructures))
+	for i, ds := range config.DataStructures {
+		names[i] = ds.Name
+	}
+	return names
+}
+
+// model returns the name of the Go engine implementing the structure
+func (ds *DataStructure) model() string {
+	if ds.Model == "" {
+		return ds.Name
+	}
+	return ds.Model
+}
+
+// mutates reports whether a command changes the structure's state
+func (ds *DataStructure) mutates(command string) bool {
+	return mutatingCommands[command] || slices.Contains(ds.Mutating, command)
+}
+
+// validate checks one flag value against the accepted values or the numeric bounds
+func (flag DataStructureFlag) validate(value string) error {
+	if flag.List {
+		items := strings.Split(value, ",")
+		if flag.MaxItems != 0 && len(items) > flag.MaxItems {
+			return &ValidationError{fmt.Sprintf("Invalid %s. Must list at most %d values", flag.Param, flag.MaxItems)}
+		}
+		flag.List = false
+		for _, item := range items {
+			if err := flag.validate(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(flag.Values) > 0 {
+		if !slices.Contains(flag.Values, value) {
+			return &ValidationError{fmt.Sprintf("Invalid %s. Must be one of: %s", flag.Param, strings.Join(flag.Values, ", "))}
+		}
+		return nil
+	}
+
+	kind := "integer"
+	var n float64
+	var err error
+	if flag.Float {
+		kind = "number"
+		n, err = strconv.ParseFloat(value, 64)
+	} else {
+		var i int
+		i, err = strconv.Atoi(value)
+		n = float64(i)
+	}
+	if err != nil || math.IsNaN(n) || n < flag.Min || (flag.Max != 0 && n > flag.Max) {
+		// 'f' keeps bounds like 4294967295 out of exponent notation
+		low := strconv.FormatFloat(flag.Min, 'f', -1, 64)
+		if flag.Max != 0 {
+			high := strconv.FormatFloat(flag.Max, 'f', -1, 64)
+			return &ValidationError{fmt.Sprintf("Invalid %s. Must be %s between %s and %s", flag.Param, kind, low, high)}
+		}
+		return &ValidationError{fmt.Sprintf("Invalid %s. Must be %s >= %s", flag.Param, kind, low)}
+	}
+	return nil
+}
+
+// buildFlags turns the parameters a client set into the structure's command line
+// arguments, each flag and each value its own element
+func (ds *DataStructure) buildFlags(params url.Values) ([]string, error) {
+	args := []string{}
+	for _, flag := range ds.Flags {
+		value := params.Get(flag.Param)
+		if value == "" {
+			if flag.Required {
+				return nil, &ValidationError{"Missing required parameter: " + flag.Param}
+			}
+			continue
+		}
+		if err := flag.validate(value); err != nil {
+			return nil, err
+		}
+		if flag.Flag != "" {
+			args = append(args, flag.Flag, value)
+		}
+	}
+	if sizer, ok := flagSizers[ds.Sizing]; ok {
+		args = append(args, sizer.size(params)...)
+	}
+	if len(args) == 0 {
+		return strings.Fields(ds.DefaultFlags), nil
+	}
+	return args, nil
+}
+
+// commandLineFlag returns the registered flag, or flag its sizer emits, with the given name
+func (ds *DataStructure) commandLineFlag(name string) (DataStructureFlag, bool) {
+	flags := ds.Flags
+	if sizer, ok := flagSizers[ds.Sizing]; ok {
+		flags = append(slices.Clip(flags), sizer.flags...)
+	}
+	for _, flag := range flags {
+		if flag.Flag != "" && flag.Flag == name {
+			return flag, true
+		}
+	}
+	return DataStructureFlag{}, false
+}
+
+// flagArgs splits the stored flags of a session into command line arguments
+// Flags come back from saved trees, records and demo links, so each must be one
+// the structure accepts, given once, with a valid value
+func (ds *DataStructure) flagArgs(flags string) ([]string, error) {
+	fields := strings.Fields(flags)
+	seen := make(map[string]bool)
+	for i := 0; i < len(fields); i += 2 {
+		flag, ok := ds.commandLineFlag(fields[i])
+		if !ok {
+			return nil, &ValidationError{fmt.Sprintf("Flag %s is not accepted by %s", fields[i], ds.Name)}
+		}
+		if seen[flag.Flag] {
+			return nil, &ValidationError{"Flag given twice: " + flag.Flag}
+		}
+		seen[flag.Flag] = true
+		if i+1 == len(fields) {
+			return nil, &ValidationError{"Missing value for flag " + flag.Flag}
+		}
+		if err := flag.validate(fields[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
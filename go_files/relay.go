@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrUnknownResumeToken is returned for resume tokens of no live relayed session
+var ErrUnknownResumeToken = errors.New("unknown resume token")
+
+// relayMessage is one message sent to a relayed client, kept for replay on reconnect
+type relayMessage struct {
+	seq     int64
+	message *outgoingMessage
+}
+
+// sessionRelay stands in for the client of a session started with relay=1. It stays
+// attached while the client's connections come and go: every message gets a sequence
+// number and is kept in a backlog, so a client reconnecting with its resume token and
+// the last sequence number it saw gets exactly the messages it missed, once.
+type sessionRelay struct {
+	token   string
+	session *Session
+	encoder messageEncoder // the encoding negotiated at session start, kept on resume
+
+	mu       sync.Mutex
+	conn     io.ReadWriter // current connection; nil while the client is away
+	connGen  int           // bumped per connection, so a replaced one does not detach its successor
+	seq      int64         // sequence number of the last message sent
+	backlog  []relayMessage
+	received int64       // client lines dispatched, so a client knows what to resend
+	grace    *time.Timer // ends the session when the client stays away; nil while connected
+	fanoutID int
+	ended    bool
+}
+
+var (
+	relaysMu sync.Mutex
+	relays   = make(map[string]*sessionRelay)
+)
+
+// startRelay attaches a relay as the session's participant and serves the first
+// connection on it
+func (s *Session) startRelay(socket io.ReadWriter) (*sessionRelay, error) {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	relay := &sessionRelay{token: hex.EncodeToString(buf), session: s, encoder: encoderOf(socket)}
+	id, err := s.clients.add(relay, false)
+	if err != nil {
+		return nil, err
+	}
+	relay.fanoutID = id
+
+	relaysMu.Lock()
+	relays[relay.token] = relay
+	relaysMu.Unlock()
+	go func() {
+		<-s.closed
+		relay.end()
+	}()
+	go relay.serve(socket, 0)
+	return relay, nil
+}
+
+// lookupRelay returns the relay owning a resume token
+func lookupRelay(token string) (*sessionRelay, bool) {
+	relaysMu.Lock()
+	defer relaysMu.Unlock()
+	relay, ok := relays[token]
+	return relay, ok
+}
+
+// WriteMessage implements messageWriter for the session fanout: it numbers the message,
+// keeps it and sends it if the client is connected. It never fails, so an absent client
+// does not end the session before its grace period does
+func (r *sessionRelay) WriteMessage(m *outgoingMessage) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	message := relayMessage{seq: r.seq, message: m}
+	r.backlog = append(r.backlog, message)
+	if limit := max(config.RelayBacklogMessages, 1); len(r.backlog) > limit {
+		r.backlog = append(r.backlog[:0:0], r.backlog[len(r.backlog)-limit:]...)
+	}
+	if r.conn != nil {
+		if err := message.writeTo(r.conn); err != nil {
+			r.detachLocked(r.connGen)
+		}
+	}
+	return 0, nil
+}
+
+// Write implements io.Writer for messages written to the session as JSON lines
+func (r *sessionRelay) Write(p []byte) (int, error) {
+	r.WriteMessage(rawMessage(p))
+	return len(p), nil
+}
+
+// writeTo sends the message to a connection in its encoding, with its sequence number
+func (message relayMessage) writeTo(conn io.Writer) error {
+	encoder := encoderOf(conn)
+	data, err := message.message.encode(encoder)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(encoder.WithSequence(data, message.seq))
+	return err
+}
+
+// serve makes socket the client's connection, replays what it missed after lastSeq,
+// then dispatches its input until it drops
+func (r *sessionRelay) serve(socket io.ReadWriter, lastSeq int64) error {
+	r.mu.Lock()
+	if r.ended {
+		r.mu.Unlock()
+		return ErrUnknownResumeToken
+	}
+	if old, ok := r.conn.(io.Closer); ok && r.conn != socket {
+		old.Close() // a half-open connection the client already gave up on
+	}
+	if r.grace != nil {
+		r.grace.Stop()
+		r.grace = nil
+	}
+	if lastSeq > 0 {
+		sendJSONMessage(socket, "server", fmt.Sprintf("RESUMED session=%s last_seq=%d received=%d", r.session.ID, r.seq, r.received))
+	}
+	if len(r.backlog) > 0 && r.backlog[0].seq > lastSeq+1 {
+		sendJSONMessage(socket, "server", fmt.Sprintf("RELAY_GAP from=%d to=%d", lastSeq+1, r.backlog[0].seq-1))
+	}
+	for _, message := range r.backlog {
+		if message.seq > lastSeq {
+			message.writeTo(socket)
+		}
+	}
+	r.conn = socket
+	r.connGen++
+	gen := r.connGen
+	r.mu.Unlock()
+
+	guard := &protocolGuard{clientSocket: socket}
+	scanner := bufio.NewScanner(socket)
+	for scanner.Scan() {
+		r.mu.Lock()
+		current := r.connGen == gen
+		if current {
+			r.received++
+		}
+		r.mu.Unlock()
+		if !current {
+			break
+		}
+		if violation := r.session.dispatch(scanner.Text()); violation != nil && !guard.report(violation) {
+			break
+		}
+	}
+
+	r.mu.Lock()
+	r.detachLocked(gen)
+	r.mu.Unlock()
+	return nil
+}
+
+// detachLocked forgets connection gen if it is still the current one and starts the
+// grace period after which the session ends (mu must be held)
+func (r *sessionRelay) detachLocked(gen int) {
+	if r.connGen != gen || r.conn == nil || r.ended {
+		return
+	}
+	r.conn = nil
+	grace := time.Duration(config.RelayGraceSeconds) * time.Second
+	r.grace = time.AfterFunc(grace, func() {
+		r.mu.Lock()
+		away := r.conn == nil && !r.ended
+		r.mu.Unlock()
+		if away {
+			fmt.Printf("[Client %s] Relay client did not reconnect within %s\n", r.session.ID, grace)
+			r.session.clients.remove(r.fanoutID)
+		}
+	})
+}
+
+// end retires the relay once its session is over, dropping the token and the connection
+func (r *sessionRelay) end() {
+	relaysMu.Lock()
+	delete(relays, r.token)
+	relaysMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ended = true
+	if r.grace != nil {
+		r.grace.Stop()
+	}
+	if r.conn != nil {
+		code, reason := r.session.closeFrame()
+		closeSocket(r.conn, code, reason)
+	}
+	r.conn = nil
+}
+
+// handleResumeClient reconnects a WebSocket to a relayed session: ?resume=<token>&last_seq=<n>
+func handleResumeClient(w http.ResponseWriter, r *http.Request, token string) {
+	relay, ok := lookupRelay(token)
+	if !ok {
+		httpError(w, ErrUnknownResumeToken)
+		return
+	}
+	var lastSeq int64
+	if value := r.URL.Query().Get("last_seq"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			httpError(w, &ValidationError{"Invalid last_seq. Must be integer >= 0"})
+			return
+		}
+		lastSeq = n
+	}
+
+	// A client resuming without encoding= keeps the encoding it started with
+	conn, err := upgradeClient(w, r, relay.encoder)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+	defer conn.Close()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s (resume: %s, last_seq: %d)\n", clientID, clientAddr(r), relay.session.ID, lastSeq)
+	if err := relay.serve(conn, lastSeq); err != nil {
+		sendError(conn, err)
+	}
+	fmt.Printf("[Client %s] Relay connection to session %s dropped\n", clientID, relay.session.ID)
+}
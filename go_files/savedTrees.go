@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+// ErrSavedTreeNotFound is returned when loading a name that was never saved
+var ErrSavedTreeNotFound = errors.New("saved tree not found")
+
+var validTreeName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// mutatingCommands are the C++ commands that change the structure state
+var mutatingCommands = map[string]bool{
+	"init":   true,
+	"insert": true,
+	"remove": true,
+	// heap
+	"push":    true,
+	"pop":     true,
+	"extract": true,
+	"heapify": true,
+	// linkedlist
+	"push_back":  true,
+	"push_front": true,
+	"insert_at":  true,
+	"remove_at":  true,
+	"reverse":    true,
+}
+
+// SavedTree is a structure persisted as the operations that rebuild it
+type SavedTree struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Flags   string    `json:"flags"`
+	SavedAt time.Time `json:"saved_at"`
+	Ops     []string  `json:"ops"`
+
+	Owner       string `json:"owner,omitempty"` // user ID of the signed-in user who saved it
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source,omitempty"` // bundle URL the tree was imported from
+}
+
+// saveTree writes the session's applied state-changing operations under a name
+func saveTree(name string, session *Session) (*SavedTree, error) {
+	if !validTreeName.MatchString(name) {
+		return nil, &ValidationError{"Invalid name. Use 1-64 letters, digits, '_' or '-'"}
+	}
+
+	if existing, err := loadTree(name); err == nil && existing.Owner != "" && existing.Owner != session.Owner {
+		return nil, &ValidationError{"Name is taken by another user"}
+	}
+
+	tree := &SavedTree{
+		Owner:   session.Owner,
+		Name:    name,
+		Type:    session.DataType,
+		Flags:   session.Flags,
+		SavedAt: time.Now(),
+		Ops:     session.journal.applied(),
+	}
+	return tree, writeSavedTree(tree)
+}
+
+// writeSavedTree stores a tree under its name, replacing any previous one
+func writeSavedTree(tree *SavedTree) error {
+	return store.saveTree(tree)
+}
+
+// listSavedTrees reads every saved tree
+func listSavedTrees() ([]*SavedTree, error) {
+	return store.listTrees()
+}
+
+// savedTreeExists reports whether a tree was saved under the name
+func savedTreeExists(name string) bool {
+	_, err := loadTree(name)
+	return err == nil
+}
+
+// loadTree reads a saved tree by name
+func loadTree(name string) (*SavedTree, error) {
+	if !validTreeName.MatchString(name) {
+		return nil, &ValidationError{"Invalid name. Use 1-64 letters, digits, '_' or '-'"}
+	}
+	return store.loadTree(name)
+}
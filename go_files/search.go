@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultSearchLimit is how many sessions a search returns without a limit
+	defaultSearchLimit = 100
+	// maxSearchLimit caps the limit a search may ask for
+	maxSearchLimit = 1000
+)
+
+// searchOpAliases maps the names maintainers use for an op to the command it is journaled as
+var searchOpAliases = map[string]string{
+	"delete": "remove",
+	"erase":  "remove",
+	"add":    "insert",
+}
+
+// searchTerm is an index entry: an op alone, or an op with its first key
+func searchTerm(op, key string) string {
+	if key == "" {
+		return op
+	}
+	return op + "\x00" + key
+}
+
+// searchTerms returns the index entries of a session's ops, each once
+// Keys are indexed unquoted, the way a maintainer types them
+func searchTerms(ops []string) []string {
+	seen := make(map[string]bool)
+	terms := []string{}
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, line := range ops {
+		fields, err := splitCommand(line)
+		if err != nil || len(fields) == 0 {
+			continue
+		}
+		add(searchTerm(fields[0], ""))
+		if len(fields) > 1 {
+			add(searchTerm(fields[0], fields[1]))
+		}
+	}
+	return terms
+}
+
+// matchesSearch reports whether a record's ops contain the term; the file store's scan
+func matchesSearch(rec *SessionRecord, term string) bool {
+	return slices.Contains(searchTerms(rec.Ops), term)
+}
+
+// searchResult is one session found by GET /admin/search
+type searchResult struct {
+	ID      string    `json:"id"`
+	Type    string    `json:"type"`
+	Flags   string    `json:"flags"`
+	Owner   string    `json:"owner,omitempty"`
+	Parent  string    `json:"parent,omitempty"`
+	Created time.Time `json:"created"`
+	Ended   time.Time `json:"ended"`
+	Ops     int       `json:"ops"`
+	// Matches counts the ops of the session that matched the query
+	Matches int `json:"matches"`
+}
+
+// handleSearch serves GET /admin/search?op=remove&key=42[&type=btree][&limit=100]
+// It finds archived sessions whose journal used an op, optionally on a key, newest first
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	op := query.Get("op")
+	if op == "" {
+		httpError(w, &ValidationError{"Missing required parameter: op"})
+		return
+	}
+	if alias, ok := searchOpAliases[op]; ok {
+		op = alias
+	}
+	key := query.Get("key")
+	dataType := query.Get("type")
+	limit := defaultSearchLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			httpError(w, &ValidationError{"Invalid limit. Must be integer between 1 and " + strconv.Itoa(maxSearchLimit)})
+			return
+		}
+		limit = n
+	}
+
+	IDs, err := store.searchSessions(searchTerm(op, key))
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	results := []searchResult{}
+	for _, ID := range IDs {
+		rec, err := store.loadSession(ID)
+		if err != nil {
+			continue // removed since it was indexed
+		}
+		if dataType != "" && rec.Type != dataType {
+			continue
+		}
+		matches := 0
+		for _, line := range rec.Ops {
+			fields, err := splitCommand(line)
+			if err == nil && len(fields) > 0 && fields[0] == op && (key == "" || len(fields) > 1 && fields[1] == key) {
+				matches++
+			}
+		}
+		results = append(results, searchResult{
+			ID:      rec.ID,
+			Type:    rec.Type,
+			Flags:   rec.Flags,
+			Owner:   rec.Owner,
+			Parent:  rec.Parent,
+			Created: rec.Created,
+			Ended:   rec.Ended,
+			Ops:     len(rec.Ops),
+			Matches: matches,
+		})
+	}
+	slices.SortFunc(results, func(a, b searchResult) int { return b.Created.Compare(a.Created) })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	writeJSON(w, results)
+}
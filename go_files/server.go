@@ -2,50 +2,361 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// datasSubprotocol is the WebSocket subprotocol of protocolVersion; clients may request it
+// to pin the revision they speak, or request none
+const datasSubprotocol = "datas.v1"
+
 var upgrader = websocket.Upgrader{
-	// CheckOrigin controls whether to accept connections from any origin
-	CheckOrigin: func(r *http.Request) bool { return true }, // allow all for dev
+	// CheckOrigin accepts browsers only from config.AllowedOrigins
+	CheckOrigin:  checkOrigin,
+	Subprotocols: []string{datasSubprotocol},
 }
 
+// configureUpgraders applies the configured buffer sizes and handshake timeout to the
+// WebSocket upgraders
+func configureUpgraders() {
+	for _, u := range []*websocket.Upgrader{&upgrader, &graphqlUpgrader} {
+		u.ReadBufferSize = config.WSReadBufferSize
+		u.WriteBufferSize = config.WSWriteBufferSize
+		u.HandshakeTimeout = time.Duration(config.WSHandshakeTimeoutSeconds) * time.Second
+	}
+}
+
+// upgradeWebSocket upgrades a request to a WebSocket with the configured message size limit
+// A client requesting subprotocols gets one the upgrader speaks, or a 400 naming those it does
+func upgradeWebSocket(u *websocket.Upgrader, w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	if requested := websocket.Subprotocols(r); len(requested) > 0 {
+		supported := slices.ContainsFunc(requested, func(p string) bool { return slices.Contains(u.Subprotocols, p) })
+		if !supported {
+			err := &ValidationError{fmt.Sprintf("Unsupported WebSocket subprotocol %s. Supported: %s",
+				strings.Join(requested, ", "), strings.Join(u.Subprotocols, ", "))}
+			httpError(w, err)
+			return nil, err
+		}
+	}
+	ws, err := u.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if config.WSMaxMessageBytes > 0 {
+		ws.SetReadLimit(config.WSMaxMessageBytes)
+	}
+	return ws, nil
+}
+
+// upgradeClient upgrades a session client to a WebSocket writing messages in the encoding
+// it asked for with encoding=, or in fallback when it names none (nil is JSON); an
+// unsupported encoding is answered with 400
+func upgradeClient(w http.ResponseWriter, r *http.Request, fallback messageEncoder) (*WebSocketWrapper, error) {
+	encoder := fallback
+	if name := r.URL.Query().Get("encoding"); name != "" || encoder == nil {
+		encoding, err := parseEncoding(name)
+		if err != nil {
+			httpError(w, err)
+			return nil, err
+		}
+		encoder = newMessageEncoder(encoding)
+	}
+	ws, err := upgradeWebSocket(&upgrader, w, r)
+	if err != nil {
+		return nil, err
+	}
+	return &WebSocketWrapper{Conn: ws, encoder: encoder}, nil
+}
+
+// unixSocketMode lets the server's user and group connect to the Unix socket, nobody else
+const unixSocketMode = 0660
+
 // handleClient runs in its own goroutine for each client
-func handleClient(conn net.Conn, clientID string) {
+// A non-nil tlsConfig serves it over TLS, after any PROXY header
+func handleClient(conn net.Conn, clientID string, tlsConfig *tls.Config) {
 	defer conn.Close()
+	proxied, err := acceptProxyHeader(conn)
+	if err != nil {
+		fmt.Printf("[Client %s] Rejected connection from %s: %v\n", clientID, conn.RemoteAddr(), err)
+		return
+	}
+	conn = proxied
+	if tlsConfig != nil {
+		tlsConn, err := acceptTLS(conn, tlsConfig)
+		if err != nil {
+			fmt.Printf("[Client %s] Rejected connection from %s: %v\n", clientID, conn.RemoteAddr(), err)
+			return
+		}
+		if name := peerName(tlsConn); name != "" {
+			fmt.Printf("[Client %s] Client certificate: %s\n", clientID, name)
+		}
+		conn = tlsConn
+	}
+	socket, err := negotiateFraming(conn)
+	if err != nil {
+		fmt.Printf("[Client %s] Rejected connection from %s: %v\n", clientID, conn.RemoteAddr(), err)
+		return
+	}
+	if draining.Load() {
+		sendError(socket, ErrDraining)
+		return
+	}
+	if err := admitSession(config.Engine); err != nil {
+		sendBusy(socket)
+		return
+	}
 	fmt.Printf("[Client %s] Connected from %s\n", clientID, conn.RemoteAddr())
-	runClientThread(clientID, "btree", "", conn)
+	flags := ""
+	if ds, ok := lookupDataStructure("btree"); ok {
+		flags = ds.DefaultFlags
+	}
+	runClientThread(clientID, "btree", flags, socket, nil)
 }
 
 func handleHttpClient(w http.ResponseWriter, r *http.Request) {
-	// Validate request and get parameters
+	// Joining a running session skips type validation: the session already has one
+	if code := r.URL.Query().Get("join"); code != "" {
+		handleJoinClient(w, r, code)
+		return
+	}
+	if token := r.URL.Query().Get("invite"); token != "" {
+		handleInviteClient(w, r, token)
+		return
+	}
+	if ID := r.URL.Query().Get("spectate"); ID != "" {
+		handleSpectateClient(w, r, ID)
+		return
+	}
+	if ID := r.URL.Query().Get("watch"); ID != "" {
+		handleWatchClient(w, r, ID)
+		return
+	}
+	if token := r.URL.Query().Get("resume"); token != "" {
+		handleResumeClient(w, r, token)
+		return
+	}
+
+	// Everything below starts a new session, which a draining server refuses
+	if draining.Load() {
+		httpError(w, ErrDraining)
+		return
+	}
+	if ID := r.URL.Query().Get("fork"); ID != "" {
+		handleForkClient(w, r, ID)
+		return
+	}
+	if token := r.URL.Query().Get("demo"); token != "" {
+		handleDemoClient(w, r, token)
+		return
+	}
+
+	request, ok := parseSessionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Upgrade to WebSocket
+	conn, err := upgradeClient(w, r, nil)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+
+	defer conn.Close()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s (type: %s, flags: %s, engine: %s, encoding: %s)\n",
+		clientID, clientAddr(r), request.dataType, request.flags, request.engine, request.encoding)
+
+	runClientThread(clientID, request.dataType, request.flags, conn, request.setup)
+}
+
+// sessionRequest is a validated request to start a new session
+type sessionRequest struct {
+	dataType string
+	flags    string
+	engine   string
+	encoding string // how messages are sent to the client
+	setup    *sessionSetup
+}
+
+// errAdminRequired is returned for session options only admins may turn on
+var errAdminRequired = errors.New("admin token required")
+
+// parseSessionRequest validates the parameters of a new session requested over HTTP;
+// when they are invalid it answers the request itself and returns false
+func parseSessionRequest(w http.ResponseWriter, r *http.Request) (*sessionRequest, bool) {
+	request, err := newSessionRequest(r)
+	switch {
+	case err == nil:
+		return request, true
+	case errors.Is(err, ErrServerBusy):
+		httpBusy(w)
+	case errors.Is(err, errAdminRequired):
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	default:
+		httpError(w, err)
+	}
+	return nil, false
+}
+
+// newSessionRequest validates the parameters of a new session, whatever transport carries them
+func newSessionRequest(r *http.Request) (*sessionRequest, error) {
 	dataType, flags, err := validateRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, err
+	}
+
+	engine, err := parseEngine(r.URL.Query().Get("engine"))
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := parseEncoding(r.URL.Query().Get("encoding"))
+	if err != nil {
+		return nil, err
+	}
+	if err := admitSession(engine); err != nil {
+		return nil, err
+	}
+
+	// Fail before the upgrade when the data type cannot run on the engine
+	if engine == engineCpp {
+		err = interfaceAvailable(dataType)
+	} else {
+		_, _, err = goEngineFor(dataType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lang, err := parseLanguage(r.URL.Query().Get("lang"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore a saved tree if requested
+	setup := &sessionSetup{engine: engine, language: lang.String()}
+	if name := r.URL.Query().Get("load"); name != "" {
+		tree, err := loadTree(name)
+		if err != nil {
+			return nil, err
+		}
+		if tree.Owner != "" && tree.Owner != currentUserID(r) {
+			return nil, ErrTreeNotOwned
+		}
+		if tree.Type != dataType {
+			return nil, &ValidationError{"Saved tree type does not match: " + tree.Type}
+		}
+		flags = tree.Flags
+		setup.replay = tree.Ops
+	}
+
+	// Attach a dataset uploaded with POST /session
+	if token := r.URL.Query().Get("import"); token != "" {
+		pending, err := claimImport(token, dataType)
+		if err != nil {
+			return nil, err
+		}
+		setup.bulkKeys = pending.Keys
+	}
+
+	if value := r.URL.Query().Get("seed"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, &ValidationError{"Invalid seed. Must be integer"}
+		}
+		setup.seed = seed
+	}
+
+	// Tree logs batched into one "logs" message per interval, for clients doing bulk work
+	if value := r.URL.Query().Get("coalesce_ms"); value != "" {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms < 1 || time.Duration(ms)*time.Millisecond > maxLogCoalesce {
+			return nil, &ValidationError{fmt.Sprintf("Invalid coalesce_ms. Must be integer between 1 and %d", maxLogCoalesce.Milliseconds())}
+		}
+		setup.logCoalesce = time.Duration(ms) * time.Millisecond
+	}
+
+	// Delta mode: the structure is dumped after each mutating command and only what changed is sent
+	if r.URL.Query().Get("deltas") == "1" {
+		if _, ok := snapshotSpecs[dataType]; !ok {
+			return nil, ErrSnapshotUnsupported
+		}
+		setup.deltas = true
+	}
+
+	// Presenter mode: viewers can follow the session with ?watch=
+	setup.broadcast = r.URL.Query().Get("broadcast") == "1"
+	setup.owner = currentUserID(r)
+	// Relay mode: the client may drop and resume with the token it is sent
+	setup.relay = r.URL.Query().Get("relay") == "1"
+
+	// Protocol debug mode exposes every message of the session, so only admins may turn it on
+	if r.URL.Query().Get("debug_protocol") == "true" {
+		if !adminAuthorized(r) {
+			return nil, errAdminRequired
+		}
+		setup.debugProtocol = true
+	}
+
+	return &sessionRequest{dataType: dataType, flags: flags, engine: engine, encoding: encoding, setup: setup}, nil
+}
+
+// handleJoinClient attaches a WebSocket client to the session owning the join code
+func handleJoinClient(w http.ResponseWriter, r *http.Request, code string) {
+	session, ok := lookupJoinCode(code)
+	if !ok {
+		httpError(w, ErrUnknownJoinCode)
 		return
 	}
 
-	// Upgrade to WebSocket
-	ws, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgradeClient(w, r, nil)
 	if err != nil {
 		fmt.Println("Upgrade error:", err)
 		return
 	}
 
-	conn := WebSocketWrapper{Conn: ws}
 	defer conn.Close()
 
 	clientID := genID()
-	fmt.Printf("[Client %s] Connected from %s (type: %s, flags: %s)\n",
-		clientID, conn.RemoteAddr(), dataType, flags)
+	fmt.Printf("[Client %s] Connected from %s (join: %s)\n", clientID, clientAddr(r), session.ID)
+	joinSession(session, clientID, conn)
+}
+
+// handleSpectateClient attaches a read-only WebSocket client to a live session
+func handleSpectateClient(w http.ResponseWriter, r *http.Request, ID string) {
+	session, ok := lookupSession(ID)
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	if !session.visibleTo(r) {
+		httpError(w, ErrSessionPrivate)
+		return
+	}
 
-	runClientThread(clientID, dataType, flags, &conn)
+	conn, err := upgradeClient(w, r, nil)
+	if err != nil {
+		fmt.Println("Upgrade error:", err)
+		return
+	}
+
+	defer conn.Close()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s (spectate: %s)\n", clientID, clientAddr(r), session.ID)
+	spectateSession(session, clientID, conn)
 }
 
 // startServer runs the TCP server and listens until shutdown is requested
@@ -59,8 +370,50 @@ func startRawTcpServer(ctx context.Context, wg *sync.WaitGroup, port string) {
 	}
 	defer ln.Close()
 
-	fmt.Println("Server listening on port", port)
+	tlsConfig, err := tcpTLSConfig()
+	if err != nil {
+		fmt.Println("Error configuring TLS for the TCP server:", err)
+		return
+	}
+	if tlsConfig != nil {
+		fmt.Println("Server listening on port", port, "(TLS)")
+	} else {
+		fmt.Println("Server listening on port", port)
+	}
+	acceptRawClients(ctx, ln.(*net.TCPListener), tlsConfig)
+}
+
+// startUnixServer serves the raw TCP protocol on a Unix socket until shutdown is requested
+func startUnixServer(ctx context.Context, wg *sync.WaitGroup, path string) {
+	defer wg.Done()
+	if path == "" {
+		return
+	}
+
+	// A socket left behind by a server that did not stop cleanly would fail the bind
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		fmt.Println("Error starting Unix socket server:", err)
+		return
+	}
+	defer ln.Close()
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		fmt.Println("Error setting Unix socket permissions:", err)
+		return
+	}
 
+	fmt.Println("Server listening on Unix socket", path)
+	acceptRawClients(ctx, ln.(*net.UnixListener), nil)
+}
+
+// acceptRawClients serves raw protocol clients from a listener until ctx is done
+func acceptRawClients(ctx context.Context, ln interface {
+	net.Listener
+	SetDeadline(time.Time) error
+}, tlsConfig *tls.Config) {
 	for {
 		// Non-blocking check for shutdown
 		select {
@@ -70,7 +423,7 @@ func startRawTcpServer(ctx context.Context, wg *sync.WaitGroup, port string) {
 		default:
 		}
 
-		ln.(*net.TCPListener).SetDeadline(time.Now().Add(1 * time.Second))
+		ln.SetDeadline(time.Now().Add(1 * time.Second))
 		conn, err := ln.Accept()
 		if err != nil {
 			// Timeout = retry loop to check ctx.Done()
@@ -81,17 +434,70 @@ func startRawTcpServer(ctx context.Context, wg *sync.WaitGroup, port string) {
 			continue
 		}
 
-		go handleClient(conn, genID())
+		go handleClient(conn, genID(), tlsConfig)
 	}
 }
 
 func startHttpServer(ctx context.Context, wg *sync.WaitGroup, port string) {
 	defer wg.Done()
-	srv := &http.Server{Addr: ":" + port}
+	configureUpgraders()
+	srv := &http.Server{Addr: ":" + port, Handler: withCORS(http.DefaultServeMux)}
 	fmt.Printf("HTTP server listin on port %s\n", port)
 	http.HandleFunc("/session", handleHttpClient)
+	http.HandleFunc("POST /session", handleSessionImport)
+	http.HandleFunc("GET /session/events", handleNewSessionEvents)
+	http.HandleFunc("GET /session/{id}/events", handleSessionEvents)
+	http.HandleFunc("POST /session/{id}/command", handleSessionCommand)
+	http.HandleFunc("GET /session/{id}/snapshot", compressed(handleSnapshot))
+	http.HandleFunc("GET /sessions/{id}/ops.csv", compressed(handleOpsCSV))
+	http.HandleFunc("GET /session/{id}/compare/{other}", compressed(handleCompareForks))
+	http.HandleFunc("POST /session/{id}/invite", handleCreateInvite)
+	http.HandleFunc("POST /templates/import", requireAdmin(handleTemplateImport))
+	http.HandleFunc("POST /api/v1/demo-link", requireAdmin(handleCreateDemoLink))
+	http.HandleFunc("/graphql", handleGraphQL)
+	http.HandleFunc("GET /graphql/schema.graphql", handleGraphQLSchema)
+	http.HandleFunc("POST /trees", handleCreateTree)
+	http.HandleFunc("POST /trees/{name}/ops", handleTreeOps)
+	http.HandleFunc("GET /capabilities", compressed(handleCapabilities))
+	http.HandleFunc("GET /structures", compressed(handleStructures))
+	http.HandleFunc("GET /structures/{name}", compressed(handleStructure))
+	http.HandleFunc("GET /api/v1/workloads", handleWorkloads)
+	http.HandleFunc("GET /api/v1/workloads/{name}", compressed(handleWorkloadFile))
+	http.HandleFunc("GET /login", handleLogin)
+	http.HandleFunc("GET /callback", handleCallback)
+	http.HandleFunc("POST /logout", handleLogout)
+	http.HandleFunc("GET /me", handleMe)
+	http.HandleFunc("/internal/prestop", requireAdmin(handlePreStop))
+	http.HandleFunc("GET /internal/ready", handleReady)
+	http.HandleFunc("GET /internal/leader", handleLeader)
+	http.HandleFunc("GET /metrics", handleMetrics)
+	http.HandleFunc("GET /admin/stats", requireAdmin(handleAdminStats))
+	http.HandleFunc("GET /admin/sessions", requireAdmin(compressed(handleAdminSessions)))
+	http.HandleFunc("GET /admin/dashboard", requireAdmin(handleDashboard))
+	http.HandleFunc("POST /admin/sessions/{id}/network", requireAdmin(handleNetworkSimulation))
+	http.HandleFunc("GET /admin/sessions/{id}/inspect", requireAdmin(handleInspectClient))
+	http.HandleFunc("POST /admin/sessions/{id}/clone", requireAdmin(handleCloneSession))
+	http.HandleFunc("POST /admin/sessions/{id}/kill", requireAdmin(handleKillSession))
+	http.HandleFunc("GET /admin/audit", requireAdmin(compressed(handleAudit)))
+	http.HandleFunc("GET /admin/search", requireAdmin(compressed(handleSearch)))
+	http.HandleFunc("GET /admin/interfaces", requireAdmin(handleInterfaces))
+	http.HandleFunc("POST /admin/interfaces", requireAdmin(handleInterfaces))
+	http.HandleFunc("POST /admin/interfaces/update", requireAdmin(handleInterfaceUpdate))
+	http.HandleFunc("GET /admin/canaries", requireAdmin(handleCanaries))
+	http.HandleFunc("POST /admin/canaries/{name}/promote", requireAdmin(handleCanaryDecision))
+	http.HandleFunc("POST /admin/canaries/{name}/rollback", requireAdmin(handleCanaryDecision))
+	http.HandleFunc("PUT /admin/workloads/{name}", requireAdmin(handleWorkloadUpload))
+	if err := configureHTTP2(srv); err != nil {
+		fmt.Println("HTTP/2 configuration error:", err)
+	}
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if config.TLSCertFile != "" {
+			err = srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			fmt.Println("HTTP server error:", err)
 		}
 	}()
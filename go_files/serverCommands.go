@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// serverCommand is a client command handled by the Go server instead of the C++ process
+type serverCommand func(session *Session, args []string) error
+
+var serverCommands = map[string]serverCommand{
+	"save":      cmdSave,
+	"gen":       cmdGen,
+	"undo":      cmdUndo,
+	"redo":      cmdRedo,
+	"goto":      cmdGoto,
+	"journal":   cmdJournal,
+	"get":       cmdGet,
+	"challenge": cmdChallenge,
+}
+
+// handleServerCommand runs the line if it names a server command
+// Returns true if the line was handled and must not reach the C++ process
+func handleServerCommand(session *Session, line string) bool {
+	fields, err := splitCommand(line)
+	if err != nil {
+		fields = strings.Fields(line)
+	}
+	if len(fields) == 0 {
+		return false
+	}
+	command, ok := serverCommands[fields[0]]
+	if !ok {
+		return false
+	}
+
+	if err := command(session, fields[1:]); err != nil {
+		session.reply(fmt.Sprintf("ERROR command=%s error=%s", fields[0], err))
+	}
+	return true
+}
+
+// cmdSave persists the session's current structure under a name
+func cmdSave(session *Session, args []string) error {
+	if len(args) != 1 {
+		return &ValidationError{"usage=save_<name>"}
+	}
+	tree, err := saveTree(args[0], session)
+	if err != nil {
+		return err
+	}
+	session.reply(fmt.Sprintf("SAVE_SUCCESS name=%s ops=%d", tree.Name, len(tree.Ops)))
+	return nil
+}
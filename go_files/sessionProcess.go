@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrNoProcess is returned when a command is sent while no interface process is attached
+var ErrNoProcess = errors.New("no interface process attached to session")
+
+const (
+	// exitGracePeriod is how long a closed FIFO waits for the process exit status
+	exitGracePeriod = 100 * time.Millisecond
+	// forwarderStopTimeout bounds how long a stop waits for FIFO forwarders to drain
+	forwardStopTimeout = time.Second
+)
+
+// interfaceProcess is one running interface attached to a session
+type interfaceProcess struct {
+	handle   *processHandle
+	stdin    io.WriteCloser
+	progFifo string
+	logFifo  string
+	progDone <-chan struct{}
+	logDone  <-chan struct{}
+	exited   chan struct{} // closed once Wait returned
+	exitErr  error
+}
+
+// sessionEnd explains why a session's process stopped
+type sessionEnd struct {
+	message string
+	err     error // nil when the process completed; wraps ErrProcessCrashed or ErrClientGone otherwise
+	// reason ends the session for something other than its process, e.g. shutdownReason
+	reason string
+}
+
+// runningProcesses counts interface processes started and not yet exited, on either engine
+var runningProcesses atomic.Int64
+
+// startProcess launches the interface for the session on its engine and starts forwarding its output
+func (s *Session) startProcess() error {
+	s.procMu.Lock()
+	defer s.procMu.Unlock()
+	return s.startProcessLocked()
+}
+
+// startProcessLocked is startProcess with procMu already held
+func (s *Session) startProcessLocked() error {
+	// Each process gets its own FIFOs so a restart never races the previous forwarders
+	s.generation++
+	s.ops.restarted()
+	prefix := fmt.Sprintf("fifos/%s_%s_%d", s.ID, s.DataType, s.generation)
+	progFifo := prefix + "_program.fifo"
+	logFifo := prefix + "_log.fifo"
+
+	if err := makeFifo(progFifo); err != nil {
+		return fmt.Errorf("creating program FIFO: %w", err)
+	}
+	if err := makeFifo(logFifo); err != nil {
+		os.Remove(progFifo)
+		return fmt.Errorf("creating log FIFO: %w", err)
+	}
+
+	handle, err := launchProcess(s.Engine, s.DataType, s.executable(), s.Flags, progFifo, logFifo)
+	if err != nil {
+		os.Remove(progFifo)
+		os.Remove(logFifo)
+		return fmt.Errorf("starting %s interface: %w", s.Engine, err)
+	}
+
+	p := &interfaceProcess{
+		handle:   handle,
+		stdin:    handle.stdin,
+		progFifo: progFifo,
+		logFifo:  logFifo,
+		exited:   make(chan struct{}),
+	}
+	// Forward FIFO → client socket as JSON messages
+	p.progDone, p.logDone = forwardOutput(progFifo, logFifo, s.clients, s.consumeProgram, s.consumeLog, s.logCoalesce)
+	runningProcesses.Add(1)
+	go func() {
+		p.exitErr = handle.wait()
+		runningProcesses.Add(-1)
+		close(p.exited)
+	}()
+
+	s.proc = p
+	s.hibernated = false
+	s.snapshot = nil
+	go s.monitorProcess(p)
+	return nil
+}
+
+// monitorProcess reports the end of the session's current process
+// Processes replaced by a restart end silently
+func (s *Session) monitorProcess(p *interfaceProcess) {
+	var end sessionEnd
+	select {
+	case <-p.exited:
+		end = p.exitEnd()
+	case <-p.progDone:
+		end = p.forwardEnd("Program FIFO forwarding stopped (client likely disconnected)")
+	case <-p.logDone:
+		end = p.forwardEnd("Log FIFO forwarding stopped (client likely disconnected)")
+	}
+
+	s.procMu.Lock()
+	current := s.proc == p
+	s.procMu.Unlock()
+	if current {
+		select {
+		case s.ended <- end:
+		default:
+		}
+	}
+}
+
+// exitEnd describes how the process exited
+func (p *interfaceProcess) exitEnd() sessionEnd {
+	if err := processExitError(p.exitErr); err != nil {
+		return sessionEnd{message: fmt.Sprintf("Interface process exited with error: %v", p.exitErr), err: err}
+	}
+	return sessionEnd{message: "Interface process completed successfully"}
+}
+
+// forwardEnd describes a stopped forwarder, preferring the exit status when the
+// FIFO closed because the process died
+func (p *interfaceProcess) forwardEnd(message string) sessionEnd {
+	select {
+	case <-p.exited:
+		return p.exitEnd()
+	case <-time.After(exitGracePeriod):
+		return sessionEnd{message: message, err: ErrClientGone}
+	}
+}
+
+// stop kills the process and waits for its forwarders before removing the FIFOs
+func (p *interfaceProcess) stop() {
+	p.stdin.Close()
+	p.handle.kill()
+	<-p.exited
+
+	// A forwarder may still be blocked opening a FIFO the process never opened
+	unblockFifo(p.progFifo)
+	unblockFifo(p.logFifo)
+	for _, done := range []<-chan struct{}{p.progDone, p.logDone} {
+		select {
+		case <-done:
+		case <-time.After(forwardStopTimeout):
+		}
+	}
+
+	os.Remove(p.progFifo)
+	os.Remove(p.logFifo)
+}
+
+// restartProcess replaces the session's process with a fresh one
+func (s *Session) restartProcess() error {
+	s.procMu.Lock()
+	defer s.procMu.Unlock()
+	old := s.proc
+	s.proc = nil
+	if old != nil {
+		old.stop()
+	}
+	if s.hibernated {
+		hibernation.remove(s)
+	}
+	metrics.processRestarts.Add(1)
+	return s.startProcessLocked()
+}
+
+// unblockFifo opens and closes the write end of a FIFO so a reader stuck in open returns
+func unblockFifo(path string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err == nil {
+		f.Close()
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSessionNotFound is returned for IDs of sessions that are not running
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrUnknownJoinCode is returned for join codes of no running session
+var ErrUnknownJoinCode = errors.New("unknown join code")
+
+// ErrCaptureBusy is returned when another capture is already waiting on the session output
+var ErrCaptureBusy = errors.New("session output capture already in progress")
+
+// ErrCaptureTimeout is returned when the C++ process did not answer in time
+var ErrCaptureTimeout = errors.New("timed out waiting for session output")
+
+// Session represents one live client session and its C++ interface process
+type Session struct {
+	ID       string
+	DataType string
+	Flags    string
+	Engine   string // engineCpp or engineGo
+	Language string // BCP 47 tag used to format exports; protocol messages ignore it
+	Started  time.Time
+	Seed     int64 // seeds gen runs without a seed of their own, so a transcript can be replayed
+
+	env sessionEnvironment // binary and settings reported in the hello and summary messages
+
+	JoinCode string // lets other clients attach with ?join=
+	Owner    string // user ID of the signed-in user who started the session, if any
+	Private  bool   // an instructor's clone: only admins may spectate or snapshot it
+
+	stored *SessionRecord // persisted metadata and lineage
+
+	clients *clientFanout // client sockets receiving JSON messages
+	hub     *broadcastHub // viewers of a presenter session; nil unless broadcasting
+	// inspector mirrors every protocol message with its handling; nil unless debug_protocol=true
+	inspector *broadcastHub
+
+	procMu     sync.Mutex // guards proc and serializes writes to its stdin
+	proc       *interfaceProcess
+	hibernated bool            // proc was stopped while idle and is started again on the next command
+	snapshot   []string        // journal applied when the session hibernated, replayed on wake
+	generation int             // number of processes started, used to name FIFOs
+	canary     *canaryRelease  // canary in progress when the session started, if any
+	onCanary   bool            // the session runs the canary binary rather than the stable one
+	ended      chan sessionEnd // receives why the current process stopped
+
+	controlQueue chan clientMessage
+	dataQueue    chan clientMessage // interactive commands from the client
+	bulkQueue    chan clientMessage // commands fed by batch jobs such as gen
+	inputClosed  chan struct{}      // closed when the session stops taking commands, before its process is flushed
+	closed       chan struct{}      // closed when the session ends
+	endReason    string             // teardown reason, set before closed is closed and the hubs end
+
+	pauseMu sync.Mutex
+	paused  bool
+	resumed chan struct{} // closed when a pause ends
+
+	subsMu       sync.Mutex
+	unsubscribed map[string]bool // output streams the client opted out of
+
+	generating atomic.Bool    // a gen job is feeding the bulk queue
+	genRuns    atomic.Int64   // gen jobs started, each seeded from Seed
+	challenges challengeState // target structure the client is asked to build
+	lastActive atomic.Int64   // unix nanoseconds of the last client message
+
+	scriptMu sync.Mutex
+	script   []string // command lines sent to the current process, in order
+
+	journal commandJournal // accepted state-changing commands, for undo/redo
+	ops     opStats        // timing of every command, for the ops.csv export
+
+	bandwidth bandwidthUsage // mode of the optional bandwidth cap
+	// logCoalesce batches tree log lines over this interval into one message; 0 sends each alone
+	logCoalesce time.Duration
+	verbosity   atomic.Int32 // logVerbosity of the tree logs forwarded, changed with the verbosity op
+	deltas      *deltaStream // nil unless the client asked for deltas=1
+
+	captureMu sync.Mutex
+	capture   *outputCapture
+
+	confirmMu sync.Mutex
+	confirm   *pendingConfirmation // destructive op awaiting its nonce
+}
+
+// outputCapture collects program lines between a start and end marker
+type outputCapture struct {
+	start  string
+	end    string
+	active bool
+	lines  []string
+	done   chan []string
+}
+
+var (
+	sessionsMu sync.RWMutex
+	sessions   = make(map[string]*Session)
+	joinCodes  = make(map[string]*Session)
+)
+
+// newSession creates a session record for the given client
+func newSession(ID, ds, flags string) *Session {
+	s := &Session{
+		ID:       ID,
+		DataType: ds,
+		Flags:    flags,
+		Started:  time.Now(),
+		clients:  newClientFanout(),
+
+		controlQueue: make(chan clientMessage, controlQueueSize),
+		dataQueue:    make(chan clientMessage, dataQueueSize),
+		bulkQueue:    make(chan clientMessage, bulkQueueSize),
+		inputClosed:  make(chan struct{}),
+		closed:       make(chan struct{}),
+		ended:        make(chan sessionEnd, 1),
+	}
+	level, _ := parseVerbosity(config.LogVerbosity)
+	s.verbosity.Store(int32(level))
+	s.touch()
+	return s
+}
+
+// registerSession makes a session reachable by its ID and a fresh join code
+func registerSession(s *Session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	sessions[s.ID] = s
+	for {
+		code := newJoinCode()
+		if _, taken := joinCodes[code]; !taken {
+			s.JoinCode = code
+			joinCodes[code] = s
+			return
+		}
+	}
+}
+
+// unregisterSession removes a finished session from the registry
+func unregisterSession(ID string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[ID]; ok {
+		delete(joinCodes, s.JoinCode)
+	}
+	delete(sessions, ID)
+}
+
+// lookupJoinCode returns the live session a join code belongs to
+func lookupJoinCode(code string) (*Session, bool) {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	s, ok := joinCodes[code]
+	return s, ok
+}
+
+// listSessions returns all live sessions
+func listSessions() []*Session {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	list := make([]*Session, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	return list
+}
+
+// sessionCount returns the number of live sessions
+func sessionCount() int {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	return len(sessions)
+}
+
+// lookupSession returns the live session with the given ID
+func lookupSession(ID string) (*Session, bool) {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	s, ok := sessions[ID]
+	return s, ok
+}
+
+// sendCommand writes one command line to the C++ process stdin
+func (s *Session) sendCommand(line string) error {
+	s.procMu.Lock()
+	defer s.procMu.Unlock()
+	if s.proc == nil && s.hibernated {
+		if err := s.wakeLocked(); err != nil {
+			return err
+		}
+	}
+	if s.proc == nil {
+		return ErrNoProcess
+	}
+	s.ops.sent(line)
+	_, err := io.WriteString(s.proc.stdin, line+"\n")
+	if err == nil {
+		metrics.commands.Add(1)
+	}
+	return err
+}
+
+// reply sends a server-generated message to the client
+func (s *Session) reply(message string) error {
+	err := sendJSONMessage(s.clients, "server", message)
+	decision, detail := sentDecision(err)
+	s.inspect(inspectOut, "server", message, decision, detail)
+	return err
+}
+
+// send writes a structured JSON message to the client
+func (s *Session) send(v any) error {
+	err := sendJSONValue(s.clients, v)
+	s.inspectValue(v, err)
+	return err
+}
+
+// replay sends recorded commands to the C++ process as if the client typed them
+func (s *Session) replay(ops []string) error {
+	s.reply(fmt.Sprintf("REPLAY_START ops=%d", len(ops)))
+	for _, line := range ops {
+		s.record(line)
+		if err := s.sendCommand(line); err != nil {
+			return err
+		}
+	}
+	s.reply(fmt.Sprintf("REPLAY_DONE ops=%d", len(ops)))
+	return nil
+}
+
+// record appends a command line to the session script, and to the journal if it changes state
+func (s *Session) record(line string) {
+	s.scriptMu.Lock()
+	s.script = append(s.script, line)
+	s.scriptMu.Unlock()
+
+	name := commandName(line)
+	mutates := mutatingCommands[name]
+	if registered, ok := lookupDataStructure(s.DataType); ok {
+		mutates = registered.mutates(name)
+	}
+	if mutates {
+		s.journal.append(line)
+	}
+}
+
+// recordedScript returns a copy of the commands received so far
+func (s *Session) recordedScript() []string {
+	s.scriptMu.Lock()
+	defer s.scriptMu.Unlock()
+	return append([]string(nil), s.script...)
+}
+
+// captureOutput sends a command and collects the program lines printed between start and end
+func (s *Session) captureOutput(command, start, end string, timeout time.Duration) ([]string, error) {
+	capture := &outputCapture{start: start, end: end, done: make(chan []string, 1)}
+
+	s.captureMu.Lock()
+	if s.capture != nil {
+		s.captureMu.Unlock()
+		return nil, ErrCaptureBusy
+	}
+	s.capture = capture
+	s.captureMu.Unlock()
+
+	defer func() {
+		s.captureMu.Lock()
+		if s.capture == capture {
+			s.capture = nil
+		}
+		s.captureMu.Unlock()
+	}()
+
+	if err := s.sendCommand(command); err != nil {
+		return nil, err
+	}
+
+	select {
+	case lines := <-capture.done:
+		return lines, nil
+	case <-time.After(timeout):
+		return nil, ErrCaptureTimeout
+	}
+}
+
+// observeProgram feeds a program line to an active capture
+// Returns true if the line was consumed and should not be forwarded to the client
+func (s *Session) observeProgram(line string) bool {
+	s.captureMu.Lock()
+	defer s.captureMu.Unlock()
+
+	c := s.capture
+	if c == nil {
+		return false
+	}
+	if !c.active {
+		if line == c.start {
+			c.active = true
+			return true
+		}
+		return false
+	}
+	if line == c.end {
+		c.done <- c.lines
+		s.capture = nil
+		return true
+	}
+	c.lines = append(c.lines, line)
+	return true
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// shutdownReason is the teardown reason of sessions the server ends when it stops
+	shutdownReason = "server shutdown"
+	// shutdownTeardownTimeout bounds how long a stop waits for the sessions it ended to tear down
+	shutdownTeardownTimeout = 10 * time.Second
+	// shutdownWebhookTimeout bounds posting the report to the webhook
+	shutdownWebhookTimeout = 5 * time.Second
+)
+
+// shutdownReport is the final account of a server stop, so operators can check a restart was clean
+type shutdownReport struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	// DrainDuration is how long the drain ran before the stop; 0 when the server stopped without one
+	DrainDuration time.Duration `json:"drain_duration"`
+	// SessionsTerminated counts sessions still running at the stop, ended by the server
+	SessionsTerminated int `json:"sessions_terminated"`
+	// SnapshotsWritten counts their records saved with the final journal
+	SnapshotsWritten int `json:"snapshots_written"`
+	// FailedTeardowns lists ended sessions with a failing teardown step, as "<id>: <steps>"
+	FailedTeardowns []string `json:"failed_teardowns"`
+	// Leaks lists resources still held once everything stopped
+	Leaks []string `json:"leaks"`
+	Clean bool     `json:"clean"`
+}
+
+// teardownCollector gathers the summaries of sessions ended by the shutdown
+type teardownCollector struct {
+	mu        sync.Mutex
+	summaries []*teardownSummary
+}
+
+// shutdownTeardowns receives every teardown with shutdownReason
+var shutdownTeardowns teardownCollector
+
+// add records one finished teardown
+func (c *teardownCollector) add(summary *teardownSummary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summaries = append(c.summaries, summary)
+}
+
+// collected returns the summaries so far
+func (c *teardownCollector) collected() []*teardownSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*teardownSummary(nil), c.summaries...)
+}
+
+// terminateSessions stops new sessions and ends the live ones, waiting for their teardown
+func terminateSessions() *shutdownReport {
+	report := &shutdownReport{Started: time.Now(), FailedTeardowns: []string{}, Leaks: []string{}}
+	if started := drainStarted.Load(); started != 0 {
+		report.DrainDuration = report.Started.Sub(time.Unix(0, started))
+	}
+	draining.Store(true)
+
+	for _, session := range listSessions() {
+		select {
+		case session.ended <- sessionEnd{message: "Server shutting down", reason: shutdownReason}:
+			report.SessionsTerminated++
+		default: // already ending on its own
+		}
+	}
+	deadline := time.Now().Add(shutdownTeardownTimeout)
+	for sessionCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainPollInterval)
+	}
+
+	for _, summary := range shutdownTeardowns.collected() {
+		if summary.Saved {
+			report.SnapshotsWritten++
+		}
+		if failed := summary.failed(); len(failed) > 0 {
+			report.FailedTeardowns = append(report.FailedTeardowns, summary.Session+": "+strings.Join(failed, ", "))
+		}
+	}
+	return report
+}
+
+// detectLeaks looks for what should be gone once every session and server stopped
+func (r *shutdownReport) detectLeaks() {
+	for _, session := range listSessions() {
+		r.Leaks = append(r.Leaks, "session "+session.ID+" still registered")
+	}
+	if n := runningProcesses.Load(); n > 0 {
+		r.Leaks = append(r.Leaks, fmt.Sprintf("%d interface processes still running", n))
+	}
+	fifos, _ := filepath.Glob(filepath.Join("fifos", "*"))
+	for _, fifo := range fifos {
+		r.Leaks = append(r.Leaks, "fifo "+fifo+" not removed")
+	}
+	r.Duration = time.Since(r.Started)
+	r.Clean = len(r.Leaks) == 0 && len(r.FailedTeardowns) == 0 && r.SnapshotsWritten == r.SessionsTerminated
+}
+
+// publish writes the report to the log and posts it to the configured webhook
+func (r *shutdownReport) publish() {
+	body, _ := json.Marshal(r)
+	fmt.Printf("Shutdown report: %s\n", body)
+	if config.ShutdownWebhookURL == "" {
+		return
+	}
+	client := &http.Client{Timeout: shutdownWebhookTimeout}
+	resp, err := client.Post(config.ShutdownWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Println("Posting shutdown report failed:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Println("Posting shutdown report failed: webhook answered", resp.Status)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrSnapshotUnsupported is returned for data types without a dump command
+var ErrSnapshotUnsupported = errors.New("snapshot not supported for this data type")
+
+// snapshotTimeout bounds how long we wait for the C++ process to dump its state
+const snapshotTimeout = 5 * time.Second
+
+// snapshotSpec describes how to make a data structure dump its state
+type snapshotSpec struct {
+	command string // command sent to the C++ process
+	start   string // program line opening the dump
+	end     string // program line closing the dump
+}
+
+var snapshotSpecs = map[string]snapshotSpec{
+	"btree":        {command: "print", start: "TREE_START", end: "TREE_END"},
+	"avltree":      {command: "structure", start: "TREE_STRUCTURE_START", end: "TREE_STRUCTURE_END"},
+	"rbtree":       {command: "structure", start: "TREE_STRUCTURE_START", end: "TREE_STRUCTURE_END"},
+	"heap":         {command: "print", start: "HEAP_START", end: "HEAP_END"},
+	"trie":         {command: "structure", start: "TREE_STRUCTURE_START", end: "TREE_STRUCTURE_END"},
+	"skiplist":     {command: "print", start: "SKIPLIST_START", end: "SKIPLIST_END"},
+	"hashtable":    {command: "print", start: "HASHTABLE_START", end: "HASHTABLE_END"},
+	"linkedlist":   {command: "print", start: "LIST_START", end: "LIST_END"},
+	"splaytree":    {command: "structure", start: "TREE_STRUCTURE_START", end: "TREE_STRUCTURE_END"},
+	"treap":        {command: "structure", start: "TREE_STRUCTURE_START", end: "TREE_STRUCTURE_END"},
+	"segtree":      {command: "print", start: "ARRAY_START", end: "ARRAY_END"},
+	"fenwick":      {command: "print", start: "ARRAY_START", end: "ARRAY_END"},
+	"dsu":          {command: "structure", start: "TREE_STRUCTURE_START", end: "TREE_STRUCTURE_END"},
+	"bplustree":    {command: "print", start: "TREE_START", end: "TREE_END"},
+	"graph":        {command: "print", start: "ADJACENCY_START", end: "ADJACENCY_END"},
+	"lrucache":     {command: "print", start: "CACHE_START", end: "CACHE_END"},
+	"queue":        {command: "print", start: "QUEUE_START", end: "QUEUE_END"},
+	"deque":        {command: "print", start: "DEQUE_START", end: "DEQUE_END"},
+	"bloomfilter":  {command: "print", start: "BITS_START", end: "BITS_END"},
+	"intervaltree": {command: "structure", start: "TREE_STRUCTURE_START", end: "TREE_STRUCTURE_END"},
+}
+
+// Snapshot is the serialized state of a session's data structure
+type Snapshot struct {
+	Session string    `json:"session"`
+	Type    string    `json:"type"`
+	Flags   string    `json:"flags"`
+	TakenAt time.Time `json:"taken_at"`
+	Lines   []string  `json:"lines"`
+}
+
+// takeSnapshot asks the C++ process to dump its structure and collects the result
+func (s *Session) takeSnapshot() (*Snapshot, error) {
+	spec, ok := snapshotSpecs[s.DataType]
+	if !ok {
+		return nil, ErrSnapshotUnsupported
+	}
+
+	lines, err := s.captureOutput(spec.command, spec.start, spec.end, snapshotTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if lines == nil {
+		lines = []string{}
+	}
+
+	return &Snapshot{
+		Session: s.ID,
+		Type:    s.DataType,
+		Flags:   s.Flags,
+		TakenAt: time.Now(),
+		Lines:   lines,
+	}, nil
+}
+
+// handleSnapshot serves GET /session/{id}/snapshot
+func handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	if !session.visibleTo(r) {
+		httpError(w, ErrSessionPrivate)
+		return
+	}
+
+	snapshot, err := session.takeSnapshot()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	writeJSON(w, snapshot)
+}
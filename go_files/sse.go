@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sseKeepAlive is how often an idle event stream gets a comment, so proxies keep it open
+	sseKeepAlive = 15 * time.Second
+	// maxCommandBody bounds the body of POST /session/{id}/command
+	maxCommandBody = 64 << 10
+)
+
+// sseClient is an event stream attached to a session like a WebSocket: every
+// message is sent as one event; its input arrives through POST /session/{id}/command
+type sseClient struct {
+	mu      sync.Mutex // serializes events
+	w       http.ResponseWriter
+	control *http.ResponseController
+	done    chan struct{} // closed when the stream ends, from either side
+	once    sync.Once
+}
+
+// newSSEClient starts an event stream on a response
+func newSSEClient(w http.ResponseWriter, r *http.Request) *sseClient {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // nginx would hold the events back
+	w.WriteHeader(http.StatusOK)
+	c := &sseClient{w: w, control: http.NewResponseController(w), done: make(chan struct{})}
+	c.control.Flush()
+	go func() {
+		select {
+		case <-r.Context().Done():
+			c.Close()
+		case <-c.done:
+		}
+	}()
+	return c
+}
+
+// Write implements io.Writer: each line of p is sent as one event, named after its
+// message type
+func (c *sseClient) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var events bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		var envelope struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal([]byte(line), &envelope) == nil && envelope.Type != "" {
+			fmt.Fprintf(&events, "event: %s\n", envelope.Type)
+		}
+		fmt.Fprintf(&events, "data: %s\n\n", line)
+	}
+	if err := c.writeLocked(events.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeLocked sends raw stream bytes and flushes them (mu must be held)
+func (c *sseClient) writeLocked(p []byte) error {
+	select {
+	case <-c.done:
+		return ErrClientGone
+	default:
+	}
+	if timeout := writeTimeout(); timeout > 0 {
+		c.control.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	if _, err := c.w.Write(p); err != nil {
+		return err
+	}
+	return c.control.Flush()
+}
+
+// SetWriteDeadline lets the fanout give the stream an outbound queue, as it does sockets
+func (c *sseClient) SetWriteDeadline(t time.Time) error {
+	return c.control.SetWriteDeadline(t)
+}
+
+// Read implements io.Reader: an event stream has no input, so it blocks until the stream ends
+func (c *sseClient) Read(p []byte) (int, error) {
+	<-c.done
+	return 0, io.EOF
+}
+
+// CloseWithCode sends a close event with the WebSocket close code, then ends the stream
+func (c *sseClient) CloseWithCode(code int, reason string) error {
+	c.mu.Lock()
+	data, _ := json.Marshal(struct {
+		Code   int    `json:"code"`
+		Reason string `json:"reason"`
+	}{code, reason})
+	c.writeLocked([]byte("event: close\ndata: " + string(data) + "\n\n"))
+	c.mu.Unlock()
+	return c.Close()
+}
+
+// Close ends the stream, once a write in progress returned
+func (c *sseClient) Close() error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		close(c.done)
+		c.mu.Unlock()
+	})
+	return nil
+}
+
+// keepAlive sends a comment while the stream is idle, until it ends
+func (c *sseClient) keepAlive() {
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			err := c.writeLocked([]byte(": keep-alive\n\n"))
+			c.mu.Unlock()
+			if err != nil {
+				c.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// handleNewSessionEvents serves GET /session/events: starts a session with the same
+// parameters as the WebSocket endpoint and streams its output; the SESSION event
+// carries the ID and join code POST /session/{id}/command needs
+func handleNewSessionEvents(w http.ResponseWriter, r *http.Request) {
+	if draining.Load() {
+		httpError(w, ErrDraining)
+		return
+	}
+	request, ok := parseSessionRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := textEncodingOnly(request); err != nil {
+		httpError(w, err)
+		return
+	}
+
+	client := newSSEClient(w, r)
+	defer client.Close()
+	go client.keepAlive()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s over SSE (type: %s, flags: %s, engine: %s)\n",
+		clientID, clientAddr(r), request.dataType, request.flags, request.engine)
+	runClientThread(clientID, request.dataType, request.flags, client, request.setup)
+	<-client.done
+}
+
+// handleSessionEvents serves GET /session/{id}/events: follows a running session,
+// as a participant with ?join=<code>, otherwise read-only like a spectator
+func handleSessionEvents(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	code := r.URL.Query().Get("join")
+	if code != "" && subtle.ConstantTimeCompare([]byte(code), []byte(session.JoinCode)) != 1 {
+		httpError(w, ErrJoinCodeMismatch)
+		return
+	}
+	if code == "" && !session.visibleTo(r) {
+		httpError(w, ErrSessionPrivate)
+		return
+	}
+
+	client := newSSEClient(w, r)
+	defer client.Close()
+	go client.keepAlive()
+
+	clientID := genID()
+	fmt.Printf("[Client %s] Connected from %s over SSE (session: %s)\n", clientID, clientAddr(r), session.ID)
+	if code != "" {
+		joinSession(session, clientID, client)
+	} else {
+		spectateSession(session, clientID, client)
+	}
+}
+
+// commandResult is the response of POST /session/{id}/command
+type commandResult struct {
+	Session  string `json:"session"`
+	Accepted int    `json:"accepted"` // lines queued; their output arrives on the event stream
+}
+
+// handleSessionCommand serves POST /session/{id}/command: the input side of an event
+// stream. Each line of the body is handled like a WebSocket message; the session's
+// join code must be given with ?join= or the X-Join-Code header
+func handleSessionCommand(w http.ResponseWriter, r *http.Request) {
+	session, ok := lookupSession(r.PathValue("id"))
+	if !ok {
+		httpError(w, ErrSessionNotFound)
+		return
+	}
+	code := r.URL.Query().Get("join")
+	if code == "" {
+		code = r.Header.Get("X-Join-Code")
+	}
+	if subtle.ConstantTimeCompare([]byte(code), []byte(session.JoinCode)) != 1 {
+		httpError(w, ErrJoinCodeMismatch)
+		return
+	}
+
+	result := commandResult{Session: session.ID}
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxCommandBody))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if violation := session.dispatch(line); violation != nil {
+			httpError(w, &ValidationError{fmt.Sprintf("%s (line %d, %d accepted before it)", violation.Message, result.Accepted+1, result.Accepted)})
+			return
+		}
+		result.Accepted++
+	}
+	if err := scanner.Err(); err != nil {
+		httpError(w, &ValidationError{"Invalid command body: " + err.Error()})
+		return
+	}
+	writeJSON(w, result)
+}
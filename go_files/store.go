@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrRecordNotFound is returned when the store has no record for an ID
+var ErrRecordNotFound = errors.New("record not found")
+
+// SessionRecord is the stored metadata of a session, including its fork lineage
+type SessionRecord struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	Flags        string    `json:"flags"`
+	Engine       string    `json:"engine,omitempty"`        // empty for records from before engines could be picked
+	Language     string    `json:"language,omitempty"`      // BCP 47 tag used to format exports
+	Owner        string    `json:"owner,omitempty"`         // user ID of the signed-in user who started it
+	Parent       string    `json:"parent,omitempty"`        // session this one was forked from
+	ForkPosition int       `json:"fork_position,omitempty"` // parent journal position at fork time
+	ClonedBy     string    `json:"cloned_by,omitempty"`     // admin who cloned the parent into this private session
+	ForkToken    string    `json:"fork_token,omitempty"`    // secret a fork is opened with, from its fork URL
+	Created      time.Time `json:"created"`
+	Started      time.Time `json:"started"`
+	Ended        time.Time `json:"ended"`
+	Ops          []string  `json:"ops"`                  // applied journal, updated when the session ends
+	BytesSent    int64     `json:"bytes_sent,omitempty"` // written to clients, updated when the session ends
+	Seed         int64     `json:"seed,omitempty"`       // session seed; forks inherit it
+	// Environment is the binary and settings the session ran with, for reproducing it
+	Environment *sessionEnvironment `json:"environment,omitempty"`
+}
+
+// LoginRecord is a signed-in browser, keyed by its cookie token
+type LoginRecord struct {
+	Token   string    `json:"token"`
+	User    *User     `json:"user"`
+	Expires time.Time `json:"expires"`
+}
+
+// dataStore persists everything that must survive a restart:
+// session records and recordings, saved trees, users and their logins
+type dataStore interface {
+	saveSession(rec *SessionRecord) error
+	loadSession(ID string) (*SessionRecord, error)
+	listSessions() ([]*SessionRecord, error)
+	// searchSessions returns the IDs of sessions with an op matching a searchTerm
+	searchSessions(term string) ([]string, error)
+
+	saveTree(tree *SavedTree) error
+	loadTree(name string) (*SavedTree, error) // ErrSavedTreeNotFound if missing
+	listTrees() ([]*SavedTree, error)
+
+	saveUser(user *User) error
+	saveLogin(login *LoginRecord) error
+	loadLogin(token string) (*LoginRecord, error)
+	deleteLogin(token string) error
+
+	close() error
+}
+
+// store is the active data store
+var store dataStore = newFileStore("store")
+
+// openStore opens the store selected in the config
+func openStore(cfg Config) (dataStore, error) {
+	switch cfg.Storage {
+	case "file":
+		return newFileStore(cfg.StoragePath), nil
+	case "bolt":
+		return openBoltStore(cfg.StoragePath)
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+	}
+}
+
+// fileStore keeps one JSON file per record
+type fileStore struct {
+	dir string
+}
+
+// newFileStore creates a file store rooted at dir
+func newFileStore(dir string) *fileStore {
+	return &fileStore{dir: dir}
+}
+
+// path returns the file of a record in one of the store's collections
+func (f *fileStore) path(collection, key string) string {
+	return filepath.Join(f.dir, collection, key+".json")
+}
+
+// write stores a record atomically
+func (f *fileStore) write(collection, key string, v any) error {
+	if err := os.MkdirAll(filepath.Join(f.dir, collection), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename so readers never see a partial record
+	tmp := f.path(collection, key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path(collection, key))
+}
+
+// read loads a record, returning ErrRecordNotFound if it does not exist
+func (f *fileStore) read(collection, key string, v any) error {
+	if key == "" || strings.ContainsAny(key, `/\`) {
+		return ErrRecordNotFound
+	}
+	data, err := os.ReadFile(f.path(collection, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// keys lists the record keys of a collection
+func (f *fileStore) keys(collection string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(f.dir, collection, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		keys = append(keys, strings.TrimSuffix(filepath.Base(path), ".json"))
+	}
+	return keys, nil
+}
+
+func (f *fileStore) saveSession(rec *SessionRecord) error {
+	return f.write("sessions", rec.ID, rec)
+}
+
+func (f *fileStore) loadSession(ID string) (*SessionRecord, error) {
+	var rec SessionRecord
+	if err := f.read("sessions", ID, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (f *fileStore) listSessions() ([]*SessionRecord, error) {
+	keys, err := f.keys("sessions")
+	if err != nil {
+		return nil, err
+	}
+	records := []*SessionRecord{}
+	for _, key := range keys {
+		rec, err := f.loadSession(key)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	sortSessionRecords(records)
+	return records, nil
+}
+
+// searchSessions scans every record; the file store keeps no index
+func (f *fileStore) searchSessions(term string) ([]string, error) {
+	records, err := f.listSessions()
+	if err != nil {
+		return nil, err
+	}
+	IDs := []string{}
+	for _, rec := range records {
+		if matchesSearch(rec, term) {
+			IDs = append(IDs, rec.ID)
+		}
+	}
+	return IDs, nil
+}
+
+func (f *fileStore) saveTree(tree *SavedTree) error {
+	return f.write("trees", tree.Name, tree)
+}
+
+func (f *fileStore) loadTree(name string) (*SavedTree, error) {
+	var tree SavedTree
+	err := f.read("trees", name, &tree)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil, ErrSavedTreeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tree, nil
+}
+
+func (f *fileStore) listTrees() ([]*SavedTree, error) {
+	keys, err := f.keys("trees")
+	if err != nil {
+		return nil, err
+	}
+	trees := []*SavedTree{}
+	for _, key := range keys {
+		tree, err := f.loadTree(key)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
+	return trees, nil
+}
+
+func (f *fileStore) saveUser(user *User) error {
+	return f.write("users", user.ID, user)
+}
+
+func (f *fileStore) saveLogin(login *LoginRecord) error {
+	// Drop expired logins so the collection does not grow forever
+	if keys, err := f.keys("logins"); err == nil {
+		for _, key := range keys {
+			if old, err := f.loadLogin(key); err == nil && time.Now().After(old.Expires) {
+				f.deleteLogin(key)
+			}
+		}
+	}
+	return f.write("logins", login.Token, login)
+}
+
+func (f *fileStore) loadLogin(token string) (*LoginRecord, error) {
+	var login LoginRecord
+	if err := f.read("logins", token, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+func (f *fileStore) deleteLogin(token string) error {
+	if token == "" || strings.ContainsAny(token, `/\`) {
+		return nil
+	}
+	err := os.Remove(f.path("logins", token))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (f *fileStore) close() error {
+	return nil
+}
+
+// sortSessionRecords orders records by creation time
+func sortSessionRecords(records []*SessionRecord) {
+	sort.Slice(records, func(i, j int) bool { return records[i].Created.Before(records[j].Created) })
+}
+
+// saveRecord stores the session's current state in its record
+func (s *Session) saveRecord() {
+	s.stored.Ops = s.journal.applied()
+	if err := store.saveSession(s.stored); err != nil {
+		fmt.Printf("[Client %s] Error saving session record: %v\n", s.ID, err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// tcpTLSHandshakeTimeout bounds the TLS handshake of a raw TCP client
+const tcpTLSHandshakeTimeout = 10 * time.Second
+
+// tcpTLSConfig returns the TLS settings of the raw TCP listener: the HTTPS certificate,
+// and client certificates verified against TCPClientCAFile when it is set. It returns nil
+// when no certificate is configured, leaving the listener in cleartext
+func tcpTLSConfig() (*tls.Config, error) {
+	if config.TLSCertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if config.TCPClientCAFile != "" {
+		pem, err := os.ReadFile(config.TCPClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s holds no PEM certificates", config.TCPClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// acceptTLS completes the TLS handshake of a raw TCP client
+func acceptTLS(conn net.Conn, tlsConfig *tls.Config) (*tls.Conn, error) {
+	tlsConn := tls.Server(conn, tlsConfig)
+	conn.SetDeadline(time.Now().Add(tcpTLSHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("TLS handshake: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// peerName describes the verified client certificate of a connection, if any
+func peerName(conn *tls.Conn) string {
+	if certs := conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		return certs[0].Subject.CommonName
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// processFlushTimeout is how long an ending session waits for its process to
+// exit on its own after stdin closes, so output it buffered still reaches the clients
+const processFlushTimeout = time.Second
+
+// teardownStep is one stage of ending a session
+type teardownStep struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Err      string        `json:"error,omitempty"`
+}
+
+// teardownSummary aggregates how a session ended, logged once when it is gone
+type teardownSummary struct {
+	Session   string `json:"session"`
+	Reason    string `json:"reason"`
+	Processes int    `json:"processes"` // interface processes started, restarts included
+	Commands  int    `json:"commands"`  // command lines the clients sent
+	Journal   int    `json:"journal"`   // state-changing commands applied at the end
+	BytesSent int64  `json:"bytes_sent"`
+	Saved     bool   `json:"saved"` // the record was written with the final journal
+	// Environment repeats the hello's reproducibility context at the end of the transcript
+	Environment sessionEnvironment `json:"environment"`
+	Steps       []teardownStep     `json:"steps"`
+}
+
+// failed lists the steps that returned an error
+func (t *teardownSummary) failed() []string {
+	var names []string
+	for _, step := range t.Steps {
+		if step.Err != "" {
+			names = append(names, step.Name)
+		}
+	}
+	return names
+}
+
+// teardown ends a session in dependency order: no new input, then the process is
+// flushed, then what hangs off the session (viewers, registry, joined clients)
+// is released before the record is saved. Every step runs even when an earlier
+// one failed, so a partial failure never leaves a sibling running
+func (s *Session) teardown(reason string) {
+	summary := &teardownSummary{Session: s.ID, Reason: reason, Environment: s.env}
+	s.endReason = reason
+	run := func(name string, step func() error) {
+		started := time.Now()
+		err := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			return step()
+		}()
+		result := teardownStep{Name: name, Duration: time.Since(started)}
+		if err != nil {
+			result.Err = err.Error()
+			logError(s.ID, "tearing down ("+name+")", err)
+		}
+		summary.Steps = append(summary.Steps, result)
+	}
+
+	// Queue workers and gen jobs stop before the process goes, so none of them
+	// writes to a closing stdin
+	run("input", func() error {
+		close(s.inputClosed)
+		return nil
+	})
+	run("process", s.flushProcess)
+
+	s.procMu.Lock()
+	summary.Processes = s.generation
+	s.procMu.Unlock()
+	summary.Commands = len(s.recordedScript())
+	summary.Journal = len(s.journal.applied())
+	summary.BytesSent = s.bytesSent()
+
+	// Clients still attached get the summary before they are detached
+	run("summary", func() error {
+		err := s.reply(fmt.Sprintf("SESSION_END reason=%s processes=%d commands=%d journal=%d %s",
+			strings.ReplaceAll(reason, " ", "_"), summary.Processes, summary.Commands, summary.Journal, summary.Environment))
+		if errors.Is(err, ErrSessionEmpty) {
+			return nil
+		}
+		return err
+	})
+	run("broadcast", func() error {
+		s.stopBroadcast()
+		if s.inspector != nil {
+			s.inspector.end()
+		}
+		return nil
+	})
+	run("registry", func() error {
+		unregisterSession(s.ID)
+		return nil
+	})
+	run("clients", func() error {
+		s.clients.closeAll(s.closeFrame())
+		close(s.closed)
+		return nil
+	})
+	run("record", func() error {
+		if s.stored == nil {
+			return nil
+		}
+		s.stored.Ops = s.journal.applied()
+		s.stored.Ended = time.Now()
+		s.stored.BytesSent = summary.BytesSent
+		if err := store.saveSession(s.stored); err != nil {
+			return err
+		}
+		summary.Saved = true
+		return nil
+	})
+
+	if failed := summary.failed(); len(failed) > 0 {
+		fmt.Printf("[Client %s] Session ended (%s) after %d processes; failed steps: %s\n",
+			s.ID, reason, summary.Processes, strings.Join(failed, ", "))
+	} else {
+		fmt.Printf("[Client %s] Session ended (%s) after %d processes\n", s.ID, reason, summary.Processes)
+	}
+	if reason == shutdownReason {
+		shutdownTeardowns.add(summary)
+	}
+	s.publishSessionEnded(summary)
+}
+
+// flushProcess closes the process's stdin and gives it processFlushTimeout to
+// exit and drain its FIFOs before it is killed
+func (s *Session) flushProcess() error {
+	s.procMu.Lock()
+	defer s.procMu.Unlock()
+	if s.hibernated {
+		hibernation.remove(s)
+		s.hibernated = false
+	}
+	p := s.proc
+	if p == nil {
+		return nil
+	}
+	s.proc = nil
+
+	var err error
+	p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(processFlushTimeout):
+		err = fmt.Errorf("process did not exit within %s of its input closing", processFlushTimeout)
+	}
+	p.stop()
+	return err
+}
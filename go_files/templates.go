@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBundleSize caps the size of a downloaded template bundle
+	maxBundleSize = 1 << 20
+	// bundleFetchTimeout bounds downloading a template bundle
+	bundleFetchTimeout = 10 * time.Second
+)
+
+var (
+	// ErrHostNotAllowed is returned for bundle URLs outside the trusted hosts
+	ErrHostNotAllowed = errors.New("host is not in the trusted template hosts")
+	// ErrChecksumMismatch is returned when a bundle does not match its expected SHA-256
+	ErrChecksumMismatch = errors.New("bundle checksum mismatch")
+	// ErrTemplateExists is returned when a template would replace a saved tree
+	ErrTemplateExists = errors.New("a saved tree with this name already exists")
+	// ErrTemplateFetch is returned when a bundle could not be downloaded
+	ErrTemplateFetch = errors.New("fetching bundle failed")
+)
+
+// TemplateBundle is a set of presets, tutorial scripts or assignments shared as one JSON file
+type TemplateBundle struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Templates   []Template `json:"templates"`
+}
+
+// Template is one structure in a bundle, stored as a saved tree on import
+type Template struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Order       int      `json:"order,omitempty"` // btree only
+	Ops         []string `json:"ops"`
+}
+
+// templateImportRequest is the body of POST /templates/import
+type templateImportRequest struct {
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Overwrite bool   `json:"overwrite"` // replace saved trees with the same names
+}
+
+// importedTemplate describes one template after import
+type importedTemplate struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	SessionURL string `json:"session_url"`
+}
+
+// templateImportResponse is returned after a bundle was imported
+type templateImportResponse struct {
+	Bundle    string             `json:"bundle"`
+	Source    string             `json:"source"`
+	Templates []importedTemplate `json:"templates"`
+}
+
+// handleTemplateImport serves POST /templates/import: downloads, verifies and stores a bundle
+func handleTemplateImport(w http.ResponseWriter, r *http.Request) {
+	var req templateImportRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		httpError(w, &ValidationError{"Invalid JSON body"})
+		return
+	}
+
+	response, err := importTemplateBundle(req)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// importTemplateBundle fetches a bundle and saves each of its templates as a saved tree
+func importTemplateBundle(req templateImportRequest) (*templateImportResponse, error) {
+	if req.URL == "" || req.SHA256 == "" {
+		return nil, &ValidationError{"Missing required fields: url, sha256"}
+	}
+	source, err := url.Parse(req.URL)
+	if err != nil || (source.Scheme != "https" && source.Scheme != "http") {
+		return nil, &ValidationError{"Invalid url. Must be http or https"}
+	}
+
+	data, err := fetchBundle(source)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), req.SHA256) {
+		return nil, ErrChecksumMismatch
+	}
+
+	var bundle TemplateBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, &ValidationError{"Invalid bundle: " + err.Error()}
+	}
+
+	// Validate every template before writing any of them
+	trees := make([]*SavedTree, 0, len(bundle.Templates))
+	for _, template := range bundle.Templates {
+		tree, err := template.savedTree(source.String())
+		if err != nil {
+			return nil, err
+		}
+		if !req.Overwrite && savedTreeExists(tree.Name) {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateExists, tree.Name)
+		}
+		trees = append(trees, tree)
+	}
+	if len(trees) == 0 {
+		return nil, &ValidationError{"Bundle has no templates"}
+	}
+
+	response := &templateImportResponse{Bundle: bundle.Name, Source: source.String()}
+	for _, tree := range trees {
+		if err := writeSavedTree(tree); err != nil {
+			return nil, err
+		}
+		response.Templates = append(response.Templates, importedTemplate{
+			Name:       tree.Name,
+			Type:       tree.Type,
+			SessionURL: "/session?type=" + tree.Type + "&load=" + tree.Name,
+		})
+	}
+	fmt.Printf("Imported %d templates from bundle %q (%s)\n", len(trees), bundle.Name, source)
+	return response, nil
+}
+
+// savedTree validates the template and converts it to a saved tree
+func (t *Template) savedTree(source string) (*SavedTree, error) {
+	if !validTreeName.MatchString(t.Name) {
+		return nil, &ValidationError{"Invalid template name: " + t.Name}
+	}
+	if !validateDataType(t.Type) {
+		return nil, &ValidationError{"Invalid type in template " + t.Name}
+	}
+	params := url.Values{}
+	if t.Order != 0 {
+		params.Set("order", strconv.Itoa(t.Order))
+	}
+	flags, err := buildFlagsFromParams(t.Type, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range t.Ops {
+		if strings.ContainsAny(line, "\r\n") || !mutatingCommands[commandName(line)] {
+			return nil, &ValidationError{fmt.Sprintf("Invalid op in template %s: %q", t.Name, line)}
+		}
+	}
+
+	return &SavedTree{
+		Name:        t.Name,
+		Type:        t.Type,
+		Flags:       flags,
+		SavedAt:     time.Now(),
+		Ops:         t.Ops,
+		Description: t.Description,
+		Source:      source,
+	}, nil
+}
+
+// templateHostAllowed reports whether bundles may be downloaded from the URL's host
+func templateHostAllowed(u *url.URL) bool {
+	return slices.Contains(config.TemplateHosts, strings.ToLower(u.Hostname()))
+}
+
+// fetchBundle downloads a bundle, following redirects only to trusted hosts
+func fetchBundle(source *url.URL) ([]byte, error) {
+	if !templateHostAllowed(source) {
+		return nil, ErrHostNotAllowed
+	}
+
+	client := &http.Client{
+		Timeout: bundleFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if !templateHostAllowed(req.URL) {
+				return ErrHostNotAllowed
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(source.String())
+	if err != nil {
+		if errors.Is(err, ErrHostNotAllowed) {
+			return nil, ErrHostNotAllowed
+		}
+		return nil, fmt.Errorf("%w: %v", ErrTemplateFetch, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateFetch, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBundleSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateFetch, err)
+	}
+	if len(data) > maxBundleSize {
+		return nil, &ValidationError{"Bundle too large"}
+	}
+	return data, nil
+}
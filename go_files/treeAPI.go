@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The REST facade over saved trees: each call replays a tree in throwaway processes,
+// so scripts can work on structures without keeping a session socket open
+
+// maxTreeOps bounds the operations one POST /trees/{name}/ops applies
+const maxTreeOps = 1000
+
+// maxTreeOpsBody bounds the body of POST /trees/{name}/ops
+const maxTreeOpsBody = 256 << 10
+
+var (
+	// ErrTreeExists is returned when creating a tree under a name already saved
+	ErrTreeExists = errors.New("a tree with this name already exists")
+	// ErrTreeNotOwned is returned when changing or loading a tree another user saved
+	ErrTreeNotOwned = errors.New("tree belongs to another user")
+)
+
+// treeLocks serializes the calls changing one tree, so none loses another's operations
+var treeLocks sync.Map // name -> *sync.Mutex
+
+// lockTree locks a tree by name and returns its unlock function
+func lockTree(name string) func() {
+	mu, _ := treeLocks.LoadOrStore(name, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// createTreeRequest is the body of POST /trees
+type createTreeRequest struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Params      map[string]string `json:"params"` // structure parameters, as on /session
+	Description string            `json:"description"`
+}
+
+// treeOpsRequest is the body of POST /trees/{name}/ops
+type treeOpsRequest struct {
+	Ops []string `json:"ops"`
+}
+
+// treeOpsResult is the response of POST /trees/{name}/ops
+type treeOpsResult struct {
+	Tree    string   `json:"tree"`
+	Applied int      `json:"applied"` // state-changing ops added to the tree
+	Ops     int      `json:"ops"`     // ops the tree is now rebuilt from
+	Program []string `json:"program"` // program lines the ops produced
+	Log     []string `json:"log"`     // tree log lines the ops produced
+	// Structure is the dump of the tree after the ops; absent for types without a dump command
+	Structure []string `json:"structure,omitempty"`
+}
+
+// handleCreateTree serves POST /trees: saves an empty tree other calls apply ops to
+func handleCreateTree(w http.ResponseWriter, r *http.Request) {
+	var req createTreeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		httpError(w, &ValidationError{"Invalid JSON body"})
+		return
+	}
+	tree, err := req.tree(currentUserID(r))
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	unlock := lockTree(tree.Name)
+	defer unlock()
+	if savedTreeExists(tree.Name) {
+		httpError(w, ErrTreeExists)
+		return
+	}
+	if err := writeSavedTree(tree); err != nil {
+		httpError(w, err)
+		return
+	}
+
+	fmt.Printf("Created tree %s (type: %s, flags: %s)\n", tree.Name, tree.Type, tree.Flags)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/trees/"+tree.Name)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tree)
+}
+
+// tree validates the request and turns it into an empty saved tree
+func (req *createTreeRequest) tree(owner string) (*SavedTree, error) {
+	if !validTreeName.MatchString(req.Name) {
+		return nil, &ValidationError{"Invalid name. Use 1-64 letters, digits, '_' or '-'"}
+	}
+	if req.Type == "" {
+		return nil, &ValidationError{"Missing required field: type"}
+	}
+	if !validateDataType(req.Type) {
+		return nil, &ValidationError{"Invalid type. Supported types: " + strings.Join(dataStructureNames(), ", ")}
+	}
+	params := url.Values{}
+	for name, value := range req.Params {
+		params.Set(name, value)
+	}
+	flags, err := buildFlagsFromParams(req.Type, params)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedTree{
+		Name:        req.Name,
+		Type:        req.Type,
+		Flags:       flags,
+		SavedAt:     time.Now(),
+		Ops:         []string{},
+		Owner:       owner,
+		Description: req.Description,
+	}, nil
+}
+
+// handleTreeOps serves POST /trees/{name}/ops: runs the ops against the tree and answers
+// with their output and the resulting structure; the state-changing ops are saved
+func handleTreeOps(w http.ResponseWriter, r *http.Request) {
+	var req treeOpsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTreeOpsBody)).Decode(&req); err != nil {
+		httpError(w, &ValidationError{"Invalid JSON body"})
+		return
+	}
+
+	name := r.PathValue("name")
+	unlock := lockTree(name)
+	defer unlock()
+	tree, err := loadTree(name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if tree.Owner != "" && tree.Owner != currentUserID(r) {
+		httpError(w, ErrTreeNotOwned)
+		return
+	}
+
+	result, err := applyTreeOps(r.Context(), tree, req.Ops)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, result)
+}
+
+// applyTreeOps runs ops after the tree's saved ops, appends those changing the
+// structure and saves the tree
+func applyTreeOps(ctx context.Context, tree *SavedTree, ops []string) (*treeOpsResult, error) {
+	registered, ok := lookupDataStructure(tree.Type)
+	if !ok {
+		return nil, &ValidationError{"Unsupported data type"}
+	}
+	if len(ops) == 0 {
+		return nil, &ValidationError{"Missing required field: ops"}
+	}
+	if len(ops) > maxTreeOps {
+		return nil, &ValidationError{fmt.Sprintf("Too many ops. At most %d per request", maxTreeOps)}
+	}
+	var mutating []string
+	for _, op := range ops {
+		name := commandName(op)
+		switch {
+		case name == "" || strings.ContainsAny(op, "\r\n"):
+			return nil, &ValidationError{fmt.Sprintf("Invalid op: %q", op)}
+		case strings.HasPrefix(name, "{"):
+			return nil, &ValidationError{"Only interface commands can be applied, not JSON ops"}
+		}
+		if _, ok := serverCommands[name]; ok {
+			return nil, &ValidationError{"Server commands cannot be applied: " + name}
+		}
+		if registered.mutates(name) {
+			mutating = append(mutating, op)
+		}
+	}
+
+	program, log, err := runAfter(ctx, tree.Type, "", tree.Flags, tree.Ops, ops)
+	if err != nil {
+		return nil, err
+	}
+	result := &treeOpsResult{Tree: tree.Name, Applied: len(mutating), Program: program, Log: log}
+
+	if len(mutating) > 0 {
+		tree.Ops = append(tree.Ops, mutating...)
+		tree.SavedAt = time.Now()
+		if err := writeSavedTree(tree); err != nil {
+			return nil, err
+		}
+	}
+	result.Ops = len(tree.Ops)
+
+	structure, err := dumpStructure(ctx, tree.Type, "", tree.Flags, tree.Ops)
+	if err != nil && !errors.Is(err, ErrSnapshotUnsupported) {
+		return nil, err
+	}
+	result.Structure = structure
+	return result, nil
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxManifestSize caps the size of the update manifest and its signature
+	maxManifestSize = 1 << 20
+	// maxBinarySize caps the size of one downloaded interface binary
+	maxBinarySize = 64 << 20
+	// artifactFetchTimeout bounds downloading one manifest or binary
+	artifactFetchTimeout = 2 * time.Minute
+	// selftestTimeout bounds the scripted session run against a staged binary
+	selftestTimeout = 5 * time.Second
+)
+
+var (
+	// ErrUpdaterDisabled is returned when no manifest URL or public key is configured
+	ErrUpdaterDisabled = errors.New("interface updater is not configured")
+	// ErrUpdateBusy is returned while another update is running
+	ErrUpdateBusy = errors.New("an update is already running")
+	// ErrSignatureInvalid is returned when the manifest does not match its signature
+	ErrSignatureInvalid = errors.New("manifest signature invalid")
+	// ErrArtifactFetch is returned when the manifest or a binary could not be downloaded
+	ErrArtifactFetch = errors.New("fetching artifact failed")
+	// ErrSelftestFailed is returned when a staged binary does not pass the selftest
+	ErrSelftestFailed = errors.New("selftest failed")
+	// ErrReleaseNotNewer is returned for a manifest whose version is not newer than the
+	// installed release, so a replayed old manifest cannot roll the binaries back
+	ErrReleaseNotNewer = errors.New("release is not newer than the installed one")
+)
+
+// selftestReplies are what every interface must answer its selftestScript with, in order
+var selftestReplies = []string{"READY", "INSERT_SUCCESS", "STATUS", "GOODBYE"}
+
+// updateMu allows one update at a time; releaseMu guards the recorded release
+var updateMu, releaseMu sync.Mutex
+
+// updateManifest lists the binaries of one release; it is signed as a whole
+// with a detached Ed25519 signature served at <manifest url>.sig
+type updateManifest struct {
+	Version  string           `json:"version"`
+	Binaries []updateArtifact `json:"binaries"`
+}
+
+// updateArtifact is one interface binary of a release
+type updateArtifact struct {
+	Name   string `json:"name"`   // registered data structure
+	URL    string `json:"url"`    // relative to the manifest URL
+	SHA256 string `json:"sha256"` // hex
+}
+
+// updateResult describes what happened to one binary
+type updateResult struct {
+	Name       string `json:"name"`
+	Executable string `json:"executable"`
+	Status     string `json:"status"` // "promoted", "canary", "staged" (dry run) or "unchanged"
+	Version    string `json:"version,omitempty"`
+	SHA256     string `json:"sha256"`
+}
+
+// updateResponse is returned by POST /admin/interfaces/update
+type updateResponse struct {
+	Release  string         `json:"release"`
+	DryRun   bool           `json:"dry_run"`
+	Binaries []updateResult `json:"binaries"`
+}
+
+// stagedBinary is a verified binary waiting next to the executable it replaces
+type stagedBinary struct {
+	ds      DataStructure // registry entry pointing at the staged file
+	target  string        // executable the staged file replaces
+	version string        // answer to --version, known once the selftest passed
+}
+
+// handleInterfaceUpdate serves POST /admin/interfaces/update[?dry_run=1]: downloads the
+// binaries of the configured release, stages and selftests them, then promotes them all,
+// or starts a canary of each when canary_percent is set.
+// Nothing is promoted unless every binary passed; running sessions keep their old process
+func handleInterfaceUpdate(w http.ResponseWriter, r *http.Request) {
+	if !updateMu.TryLock() {
+		httpError(w, ErrUpdateBusy)
+		return
+	}
+	defer updateMu.Unlock()
+
+	response, err := updateInterfaces(r.URL.Query().Get("dry_run") == "1")
+	if err != nil {
+		fmt.Println("Interface update failed:", err)
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, response)
+}
+
+// updateInterfaces runs one update; staged files are removed unless promoted
+func updateInterfaces(dryRun bool) (*updateResponse, error) {
+	if config.UpdateManifestURL == "" || config.UpdatePublicKey == "" {
+		return nil, ErrUpdaterDisabled
+	}
+	manifestURL, err := url.Parse(config.UpdateManifestURL)
+	if err != nil {
+		return nil, &ValidationError{"Invalid update_manifest_url"}
+	}
+	manifest, err := fetchManifest(manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := installedRelease()
+	if err != nil {
+		return nil, err
+	}
+	if installed != "" && compareVersions(manifest.Version, installed) <= 0 {
+		return nil, fmt.Errorf("%w: %s, installed %s", ErrReleaseNotNewer, manifest.Version, installed)
+	}
+
+	response := &updateResponse{Release: manifest.Version, DryRun: dryRun, Binaries: []updateResult{}}
+	var staged []stagedBinary
+	defer func() {
+		for _, binary := range staged {
+			os.Remove(binary.ds.Executable)
+		}
+	}()
+
+	for _, artifact := range manifest.Binaries {
+		ds, ok := lookupDataStructure(artifact.Name)
+		if !ok {
+			return nil, &ValidationError{"Manifest lists unregistered data structure: " + artifact.Name}
+		}
+		result := updateResult{Name: ds.Name, Executable: ds.Executable, SHA256: strings.ToLower(artifact.SHA256)}
+		if fileSHA256(ds.Executable) == result.SHA256 {
+			result.Status = "unchanged"
+			response.Binaries = append(response.Binaries, result)
+			continue
+		}
+
+		binary, err := stageArtifact(*ds, manifestURL, artifact)
+		if binary != nil {
+			staged = append(staged, *binary)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if result.Version, err = selftestInterface(binary.ds); err != nil {
+			return nil, err
+		}
+		staged[len(staged)-1].version = result.Version
+		result.Status = "staged"
+		response.Binaries = append(response.Binaries, result)
+	}
+	if dryRun {
+		return response, nil
+	}
+	if config.CanaryPercent > 0 {
+		if err := startCanaries(manifest.Version, response, &staged); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	// Rename replaces each executable atomically; a failure midway leaves a mix of
+	// releases that all passed the selftest, so the ones already promoted stay
+	promoted := len(staged)
+	for len(staged) > 0 {
+		if err := os.Rename(staged[0].ds.Executable, staged[0].target); err != nil {
+			return nil, fmt.Errorf("promoting %s: %w", staged[0].ds.Name, err)
+		}
+		staged = staged[1:]
+	}
+	for i := range response.Binaries {
+		if response.Binaries[i].Status == "staged" {
+			response.Binaries[i].Status = "promoted"
+		}
+	}
+	if promoted > 0 {
+		fmt.Printf("Interface update %s promoted %d binaries\n", manifest.Version, promoted)
+		checkInterfaces()
+	}
+	if err := recordRelease(manifest.Version); err != nil {
+		return nil, fmt.Errorf("recording release %s: %w", manifest.Version, err)
+	}
+	return response, nil
+}
+
+// installedRelease returns the last promoted release, or "" before the first update
+func installedRelease() (string, error) {
+	data, err := os.ReadFile(config.UpdateReleasePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// recordRelease persists a promoted release; releases only move forward, so a canary
+// of an older release finishing late does not lower it
+func recordRelease(version string) error {
+	releaseMu.Lock()
+	defer releaseMu.Unlock()
+	installed, err := installedRelease()
+	if err != nil {
+		return err
+	}
+	if installed != "" && compareVersions(version, installed) <= 0 {
+		return nil
+	}
+	// Write then rename so a crash never leaves a partial release behind
+	tmp := config.UpdateReleasePath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(version+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, config.UpdateReleasePath)
+}
+
+// compareVersions orders release versions such as "v1.10.2": dot separated parts are
+// compared numerically when both are numbers and as text otherwise
+func compareVersions(a, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var partA, partB string
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		numA, errA := strconv.ParseUint(partA, 10, 64)
+		numB, errB := strconv.ParseUint(partB, 10, 64)
+		switch {
+		case errA == nil && errB == nil && numA != numB:
+			if numA < numB {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && partA != partB:
+			return strings.Compare(partA, partB)
+		}
+	}
+	return 0
+}
+
+// startCanaries hands every staged binary to a canary instead of promoting it
+func startCanaries(release string, response *updateResponse, staged *[]stagedBinary) error {
+	for len(*staged) > 0 {
+		binary := (*staged)[0]
+		registered := binary.ds
+		registered.Executable = binary.target
+		if _, err := startCanary(registered, release, binary.version, binary.ds.Executable); err != nil {
+			return fmt.Errorf("starting canary of %s: %w", registered.Name, err)
+		}
+		*staged = (*staged)[1:]
+		for i := range response.Binaries {
+			if response.Binaries[i].Name == registered.Name {
+				response.Binaries[i].Status = "canary"
+			}
+		}
+	}
+	return nil
+}
+
+// fetchManifest downloads the manifest and checks its detached signature
+func fetchManifest(manifestURL *url.URL) (*updateManifest, error) {
+	key, err := base64.StdEncoding.DecodeString(config.UpdatePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, &ValidationError{"Invalid update_public_key. Must be a base64 Ed25519 public key"}
+	}
+	data, err := fetchArtifact(manifestURL.String(), maxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	encodedSignature, err := fetchArtifact(manifestURL.String()+".sig", maxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil || !ed25519.Verify(key, data, signature) {
+		return nil, ErrSignatureInvalid
+	}
+
+	var manifest updateManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, &ValidationError{"Invalid manifest: " + err.Error()}
+	}
+	if manifest.Version == "" {
+		return nil, &ValidationError{"Manifest has no version"}
+	}
+	if len(manifest.Binaries) == 0 {
+		return nil, &ValidationError{"Manifest has no binaries"}
+	}
+	return &manifest, nil
+}
+
+// stageArtifact downloads a binary next to the executable it would replace and verifies
+// its checksum; the returned staged binary must be removed by the caller even on error
+func stageArtifact(ds DataStructure, base *url.URL, artifact updateArtifact) (*stagedBinary, error) {
+	source, err := base.Parse(artifact.URL)
+	if err != nil || artifact.URL == "" {
+		return nil, &ValidationError{"Invalid url for " + artifact.Name}
+	}
+	data, err := fetchArtifact(source.String(), maxBinarySize)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), artifact.SHA256) {
+		return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, artifact.Name)
+	}
+
+	// The same directory keeps the promoting rename on one filesystem
+	binary := &stagedBinary{ds: ds, target: ds.Executable}
+	binary.ds.Executable += ".staged"
+	if err := os.WriteFile(binary.ds.Executable, data, 0755); err != nil {
+		return binary, err
+	}
+	// WriteFile keeps the mode of a leftover file from an interrupted update
+	return binary, os.Chmod(binary.ds.Executable, 0755)
+}
+
+// selftestInterface probes a binary's version and runs a short scripted session against it
+func selftestInterface(ds DataStructure) (string, error) {
+	health := probeInterface(ds)
+	if health.err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrSelftestFailed, ds.Name, health.err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), selftestTimeout)
+	defer cancel()
+	args, err := ds.flagArgs(ds.DefaultFlags)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrSelftestFailed, ds.Name, err)
+	}
+	args = append(args, "--batch", "--tree-log-out", "null")
+	cmd := exec.CommandContext(ctx, ds.Executable, args...)
+	cmd.Stdin = strings.NewReader(selftestScript(ds))
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := runInterfaceCommand(cmd); err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrSelftestFailed, ds.Name, err)
+	}
+
+	expected := selftestReplies
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "ERROR") {
+			return "", fmt.Errorf("%w: %s: %s", ErrSelftestFailed, ds.Name, line)
+		}
+		if len(expected) > 0 && commandName(line) == expected[0] {
+			expected = expected[1:]
+		}
+	}
+	if len(expected) > 0 {
+		return "", fmt.Errorf("%w: %s: no %s reply", ErrSelftestFailed, ds.Name, expected[0])
+	}
+	return health.Version, nil
+}
+
+// selftestScript is fed to a staged binary: one insert with as many keys of the structure's
+// key type as its key commands take, ascending so an interval's low end comes first
+func selftestScript(ds DataStructure) string {
+	keys := make([]string, ds.keyArity())
+	for i := range keys {
+		switch ds.keyType() {
+		case keyFloat:
+			keys[i] = strconv.Itoa(i+1) + ".5"
+		case keyString:
+			keys[i] = "key" + strconv.Itoa(i+1)
+		default:
+			keys[i] = strconv.Itoa(i + 1)
+		}
+	}
+	return "insert " + strings.Join(keys, " ") + "\nstatus\nquit\n"
+}
+
+// fetchArtifact downloads a file of at most limit bytes
+func fetchArtifact(source string, limit int64) ([]byte, error) {
+	client := &http.Client{Timeout: artifactFetchTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArtifactFetch, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s: %s", ErrArtifactFetch, source, resp.Status)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(resp.Body, limit+1)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArtifactFetch, err)
+	}
+	if int64(buf.Len()) > limit {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrArtifactFetch, source, limit)
+	}
+	return buf.Bytes(), nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file, or "" if it cannot be read
+func fileSHA256(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
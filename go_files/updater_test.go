@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelftestScript(t *testing.T) {
+	for _, ds := range defaultDataStructures() {
+		t.Run(ds.Name, func(t *testing.T) {
+			script := selftestScript(ds)
+			if !strings.HasSuffix(script, "\nstatus\nquit\n") {
+				t.Fatalf("script %q does not end with status and quit", script)
+			}
+			insert, _, _ := strings.Cut(script, "\n")
+			// The insert must be what a client could send the structure
+			normalized, err := ds.normalizeCommand(insert)
+			if err != nil {
+				t.Fatalf("%q is not a valid insert: %v", insert, err)
+			}
+			if normalized != insert {
+				t.Errorf("%q is sent as %q", insert, normalized)
+			}
+		})
+	}
+}
+
+// TestSelftestInterfaces runs the selftest of every default structure against the built
+// interfaces in $DATAS_INTERFACE_DIR, or the working directory; missing ones are skipped
+func TestSelftestInterfaces(t *testing.T) {
+	dir := os.Getenv("DATAS_INTERFACE_DIR")
+	for _, ds := range defaultDataStructures() {
+		t.Run(ds.Name, func(t *testing.T) {
+			if dir != "" {
+				ds.Executable = filepath.Join(dir, filepath.Base(ds.Executable))
+			}
+			if _, err := os.Stat(ds.Executable); err != nil {
+				t.Skipf("%s is not built", ds.Executable)
+			}
+			if _, err := selftestInterface(ds); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"1.2.3", "v1.2.3", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		{"v1.2", "v1.2.1", -1},
+		{"v2", "v1.99.99", 1},
+		{"v1.2.0-rc1", "v1.2.0-rc2", -1},
+		{"v1.2.x", "v1.2.3", 1},
+		{"", "v0.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := compareVersions(tt.b, tt.a); got != -tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
@@ -1,20 +1,27 @@
 package main
 
 import (
-	"fmt"
 	"os"
+	"strings"
 	"syscall"
 )
 
-var nextID = 0
-
 func makeFifo(path string) error {
 	// Remove old FIFO if exists
 	_ = os.Remove(path)
 	return syscall.Mkfifo(path, 0666)
 }
 
+// genID returns a new unique session ID from the configured generator
 func genID() string {
-	nextID++
-	return fmt.Sprintf("%04d", nextID)
+	return idGen.next()
+}
+
+// commandName returns the first word of a command line
+func commandName(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// lookupValue returns the value attached to a key by the latest applied insert;
+// key holds one wire form key per key of the structure's key commands
+// Values are not kept by the interfaces; the journal is their only record, so
+// undo, goto, forks and saved trees carry them for free
+func (s *Session) lookupValue(key []string) (string, bool) {
+	n := len(key)
+	ops := s.journal.applied()
+	for i := len(ops) - 1; i >= 0; i-- {
+		fields, err := splitCommand(ops[i])
+		if err != nil || len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0] == "init":
+			return "", false
+		case len(fields) < n+1 || !slices.Equal(quoteFields(fields[1:n+1]), key):
+		case fields[0] == "remove":
+			return "", false
+		case fields[0] == "insert":
+			if len(fields) == n+2 {
+				return fields[n+1], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// quoteFields returns the wire forms of fields
+func quoteFields(fields []string) []string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = quoteField(field)
+	}
+	return quoted
+}
+
+// cmdGet answers the value stored under a key: get <key>, or get <low> <high>
+// on a structure whose keys are intervals
+func cmdGet(session *Session, args []string) error {
+	registered, ok := lookupDataStructure(session.DataType)
+	if !ok || len(args) != registered.keyArity() {
+		usage := "<key>"
+		if ok {
+			usage = registered.keyUsage()
+		}
+		return &ValidationError{"Usage: get " + usage}
+	}
+	key := make([]string, len(args))
+	for i, raw := range args {
+		normalized, err := normalizeKey(registered.keyType(), raw)
+		if err != nil {
+			return err
+		}
+		key[i] = normalized
+	}
+
+	// Several keys print joined by commas so the reply stays one field per key=
+	shown := strings.Join(key, ",")
+	value, found := session.lookupValue(key)
+	if !found {
+		return session.reply(fmt.Sprintf("GET_RESULT key=%s found=false", shown))
+	}
+	return session.reply(fmt.Sprintf("GET_RESULT key=%s found=true value=%s", shown, quoteField(value)))
+}
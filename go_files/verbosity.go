@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// logVerbosity selects which tree log categories a session forwards, lowest first
+type logVerbosity int32
+
+const (
+	// verbosityQuiet forwards only the outcome of each operation
+	verbosityQuiet logVerbosity = iota
+	// verbosityNormal forwards the changes to the structure, without the walks leading to them
+	verbosityNormal
+	// verbosityTrace forwards every line the interface logs
+	verbosityTrace
+)
+
+// verbosityNames are the names clients and the config use for each level
+var verbosityNames = []string{"quiet", "normal", "trace"}
+
+// String returns the name of the level
+func (v logVerbosity) String() string {
+	return verbosityNames[v]
+}
+
+// parseVerbosity looks up a level by name
+func parseVerbosity(name string) (logVerbosity, error) {
+	for i, known := range verbosityNames {
+		if name == known {
+			return logVerbosity(i), nil
+		}
+	}
+	return 0, &ValidationError{fmt.Sprintf("Unknown verbosity: %s. Must be one of %s", name, strings.Join(verbosityNames, ", "))}
+}
+
+// traceCategories are the step-by-step walks only verbosityTrace forwards
+var traceCategories = map[string]bool{
+	"TRAVERSE":         true,
+	"TRAVERSAL_START":  true,
+	"TRAVERSAL_END":    true,
+	"VISIT":            true,
+	"FIND":             true,
+	"FIND_SUCCESSOR":   true,
+	"FIND_PREDECESSOR": true,
+	"CHAIN_WALK":       true,
+	"EDGE_EXPLORE":     true,
+	"SCAN_LEAF":        true,
+	"SKIP":             true,
+	"NODE_STATE":       true,
+	"PARENT_CHILD":     true,
+	"IMBALANCE":        true,
+}
+
+// outcomeSuffixes mark the categories reporting how an operation ended, which
+// every level forwards
+var outcomeSuffixes = []string{"_RESULT", "_COMPLETE", "_FAILED", "DUPLICATE"}
+
+// logCategory returns the bracketed category a log line starts with, e.g. "NODE_CREATE"
+func logCategory(line string) string {
+	if !strings.HasPrefix(line, "[") {
+		return ""
+	}
+	category, _, found := strings.Cut(line[1:], "]")
+	if !found {
+		return ""
+	}
+	return category
+}
+
+// categoryVerbosity returns the lowest level forwarding a log category
+// Lines without a category are kept from normal up
+func categoryVerbosity(category string) logVerbosity {
+	for _, suffix := range outcomeSuffixes {
+		if strings.HasSuffix(category, suffix) {
+			return verbosityQuiet
+		}
+	}
+	if traceCategories[category] {
+		return verbosityTrace
+	}
+	return verbosityNormal
+}
+
+// verbose reports whether a log line is forwarded at the session's current verbosity
+func (s *Session) verbose(line string) bool {
+	return categoryVerbosity(logCategory(line)) <= logVerbosity(s.verbosity.Load())
+}
+
+// opVerbosity changes which log categories are forwarded, leaving the process running
+// Sent as {"op":"verbosity","verbosity":"quiet|normal|trace"} or {"type":"control","verbosity":...}
+func opVerbosity(session *Session, msg clientMessage) error {
+	level, err := parseVerbosity(msg.Verbosity)
+	if err != nil {
+		return err
+	}
+	session.verbosity.Store(int32(level))
+	return session.reply("VERBOSITY level=" + level.String())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	// closeProtocolViolation is the WebSocket close code sent when a client is
+	// disconnected for repeated protocol violations (4000-4999 is application defined)
+	closeProtocolViolation = 4002
+	// violationSnippetLength caps how much of the offending payload is echoed back
+	violationSnippetLength = 80
+)
+
+// Protocol violation codes
+const (
+	violationMalformedJSON = "malformed_json"
+	violationMissingOp     = "missing_op"
+	violationUnknownOp     = "unknown_op"
+	violationQueueFull     = "queue_full" // the client pipelined past its window
+	// violationMultilineCommand is a framed command holding a line break
+	violationMultilineCommand = "multiline_command"
+)
+
+// ProtocolViolation is a client message the server could not accept
+type ProtocolViolation struct {
+	Code    string
+	Message string
+	Payload string
+}
+
+func (v *ProtocolViolation) Error() string {
+	return v.Code + ": " + v.Message
+}
+
+// Unwrap classifies a full queue as a capacity error
+func (v *ProtocolViolation) Unwrap() error {
+	if v.Code == violationQueueFull {
+		return ErrCapacity
+	}
+	return nil
+}
+
+// protocolErrorMessage is sent to the offending client for each violation
+type protocolErrorMessage struct {
+	Type          string `json:"type"` // always "protocol_error"
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+	Snippet       string `json:"snippet"`
+	Violations    int    `json:"violations"`
+	MaxViolations int    `json:"max_violations"`
+}
+
+// protocolGuard counts one client's violations and disconnects it past the limit
+type protocolGuard struct {
+	clientSocket io.ReadWriter
+	violations   int
+}
+
+// report tells the client about a violation
+// Returns false once the client exceeded the limit and was disconnected
+func (g *protocolGuard) report(v *ProtocolViolation) bool {
+	g.violations++
+	metrics.protocolViolations.Add(1)
+	if errors.Is(v, ErrCapacity) {
+		recordError(v)
+	}
+	sendJSONValue(g.clientSocket, protocolErrorMessage{
+		Type:          "protocol_error",
+		Code:          v.Code,
+		Message:       v.Message,
+		Snippet:       snippet(v.Payload),
+		Violations:    g.violations,
+		MaxViolations: config.MaxProtocolViolations,
+	})
+
+	if config.MaxProtocolViolations <= 0 || g.violations < config.MaxProtocolViolations {
+		return true
+	}
+	reason := fmt.Sprintf("too many protocol violations (%d)", g.violations)
+	closeSocket(g.clientSocket, closeProtocolViolation, reason)
+	return false
+}
+
+// snippet shortens a payload for echoing it back, keeping whole UTF-8 characters
+func snippet(payload string) string {
+	if len(payload) <= violationSnippetLength {
+		return payload
+	}
+	cut := violationSnippetLength
+	for cut > 0 && !utf8.RuneStart(payload[cut]) {
+		cut--
+	}
+	return payload[:cut] + "..."
+}
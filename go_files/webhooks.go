@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Session lifecycle events posted to config.Webhooks
+const (
+	webhookSessionStarted = "session.started"
+	webhookSessionEnded   = "session.ended"
+	webhookProcessCrashed = "process.crashed"
+)
+
+// webhookEvents are the events a webhook may subscribe to
+var webhookEvents = []string{webhookSessionStarted, webhookSessionEnded, webhookProcessCrashed}
+
+const (
+	// webhookTimeout bounds one delivery attempt
+	webhookTimeout = 5 * time.Second
+	// webhookAttempts is how many times a delivery is tried before it is dropped
+	webhookAttempts = 3
+	// webhookQueueSize bounds the deliveries waiting; past it events are dropped, never blocking a session
+	webhookQueueSize = 256
+)
+
+// webhookEvent is the body of a webhook POST
+type webhookEvent struct {
+	Event    string    `json:"event"`
+	Delivery string    `json:"delivery"` // unique per event, repeated across retries
+	Time     time.Time `json:"time"`
+	Session  string    `json:"session"`
+	Type     string    `json:"type"`
+	Flags    string    `json:"flags"`
+	Engine   string    `json:"engine"`
+	Owner    string    `json:"owner,omitempty"`
+
+	// Ended is set for session.ended, Crash for process.crashed
+	Ended *sessionEndedStats `json:"ended,omitempty"`
+	Crash *CrashReport       `json:"crash,omitempty"`
+}
+
+// sessionEndedStats describes how a session ended
+type sessionEndedStats struct {
+	Reason string `json:"reason"`
+	// ExitStatus is "completed" or "crashed" when the process ended the session,
+	// "stopped" when the server stopped it (clients left, kill, shutdown, ...)
+	ExitStatus      string  `json:"exit_status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Processes       int     `json:"processes"`
+	Commands        int     `json:"commands"`
+	Journal         int     `json:"journal"`
+	BytesSent       int64   `json:"bytes_sent"`
+}
+
+// webhookDelivery is one event on its way to one webhook
+type webhookDelivery struct {
+	hook  WebhookConfig
+	event string
+	body  []byte
+}
+
+var (
+	webhookQueue = make(chan webhookDelivery, webhookQueueSize)
+	webhookOnce  sync.Once
+)
+
+// validateWebhooks checks the configured webhooks
+func validateWebhooks(hooks []WebhookConfig) error {
+	for i, hook := range hooks {
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks[%d]: url must be an http or https URL", i)
+		}
+		for _, event := range hook.Events {
+			if !slices.Contains(webhookEvents, event) {
+				return fmt.Errorf("webhooks[%d]: unknown event %q", i, event)
+			}
+		}
+	}
+	return nil
+}
+
+// wants reports whether the webhook subscribes to an event
+func (hook WebhookConfig) wants(event string) bool {
+	return len(hook.Events) == 0 || slices.Contains(hook.Events, event)
+}
+
+// newWebhookEvent describes an event of a session
+func newWebhookEvent(event string, s *Session) *webhookEvent {
+	delivery := make([]byte, 16)
+	rand.Read(delivery)
+	return &webhookEvent{
+		Event:    event,
+		Delivery: hex.EncodeToString(delivery),
+		Time:     time.Now(),
+		Session:  s.ID,
+		Type:     s.DataType,
+		Flags:    s.Flags,
+		Engine:   s.Engine,
+		Owner:    s.Owner,
+	}
+}
+
+// publish queues the event for every webhook subscribing to it
+func (e *webhookEvent) publish() {
+	if len(config.Webhooks) == 0 {
+		return
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		logError(e.Session, "encoding webhook event", err)
+		return
+	}
+	webhookOnce.Do(func() { go runWebhooks() })
+	for _, hook := range config.Webhooks {
+		if !hook.wants(e.Event) {
+			continue
+		}
+		select {
+		case webhookQueue <- webhookDelivery{hook: hook, event: e.Event, body: body}:
+		default:
+			fmt.Printf("[Client %s] Webhook queue full, dropping %s for %s\n", e.Session, e.Event, hook.URL)
+		}
+	}
+}
+
+// runWebhooks delivers queued events in order
+func runWebhooks() {
+	client := &http.Client{Timeout: webhookTimeout}
+	for delivery := range webhookQueue {
+		delivery.send(client)
+	}
+}
+
+// send posts the event, retrying with backoff when the webhook fails
+func (d webhookDelivery) send(client *http.Client) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := d.post(client)
+		if err == nil {
+			return
+		}
+		if attempt == webhookAttempts {
+			fmt.Printf("Webhook %s failed for %s after %d attempts: %v\n", d.hook.URL, d.event, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt; with a secret, the body is signed with HMAC-SHA256
+func (d webhookDelivery) post(client *http.Client) error {
+	req, err := http.NewRequest(http.MethodPost, d.hook.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Datas-Event", d.event)
+	if d.hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(d.hook.Secret))
+		mac.Write(d.body)
+		req.Header.Set("X-Datas-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// publishSessionEnded posts session.ended with the teardown summary
+func (s *Session) publishSessionEnded(summary *teardownSummary) {
+	event := newWebhookEvent(webhookSessionEnded, s)
+	status := "stopped"
+	switch summary.Reason {
+	case "process ended":
+		status = "completed"
+	case "process crashed":
+		status = "crashed"
+	}
+	event.Ended = &sessionEndedStats{
+		Reason:          summary.Reason,
+		ExitStatus:      status,
+		DurationSeconds: time.Since(s.Started).Seconds(),
+		Processes:       summary.Processes,
+		Commands:        summary.Commands,
+		Journal:         summary.Journal,
+		BytesSent:       summary.BytesSent,
+	}
+	event.publish()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/webtransport-go"
+)
+
+// Experimental WebTransport endpoint: a browser opens https://host:<webtransport_port>/session
+// with the parameters of /session, then a bidirectional stream carrying the session's lines
+// both ways. QUIC recovers lost packets per stream, so a lossy link stalls less than a
+// WebSocket. Further bidirectional streams of the same WebTransport session join it as
+// extra participants.
+
+// wtStream is a WebTransport stream attached to a session like a WebSocket
+type wtStream struct {
+	webtransport.Stream
+	conn    *webtransport.Session
+	primary bool // the stream that started the session; closing it closes the connection
+}
+
+// CloseWithCode closes the WebTransport session with the WebSocket close code, which the
+// browser reports as closeCode and reason; an extra stream is only closed itself
+func (s *wtStream) CloseWithCode(code int, reason string) error {
+	if !s.primary {
+		return s.Close()
+	}
+	return s.conn.CloseWithError(webtransport.SessionErrorCode(code), reason)
+}
+
+// Close ends the stream; the client sees the end of its output
+func (s *wtStream) Close() error {
+	s.CancelRead(0)
+	return s.Stream.Close()
+}
+
+// newWebTransportHandler serves the WebTransport session endpoint of server
+func newWebTransportHandler(server *webtransport.Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			httpError(w, ErrDraining)
+			return
+		}
+		request, ok := parseSessionRequest(w, r)
+		if !ok {
+			return
+		}
+		if err := textEncodingOnly(request); err != nil {
+			httpError(w, err)
+			return
+		}
+		conn, err := server.Upgrade(w, r)
+		if err != nil {
+			fmt.Println("WebTransport upgrade error:", err)
+			return
+		}
+		serveWebTransport(conn, r, request)
+	})
+	return mux
+}
+
+// serveWebTransport runs the session of a WebTransport connection on its first
+// bidirectional stream and joins later streams to it
+func serveWebTransport(conn *webtransport.Session, r *http.Request, request *sessionRequest) {
+	ctx := conn.Context()
+	first, err := conn.AcceptStream(ctx)
+	if err != nil {
+		return
+	}
+	clientID := genID()
+	go acceptWebTransportJoins(ctx, conn, clientID)
+
+	fmt.Printf("[Client %s] Connected from %s over WebTransport (type: %s, flags: %s, engine: %s)\n",
+		clientID, clientAddr(r), request.dataType, request.flags, request.engine)
+	stream := &wtStream{Stream: first, conn: conn, primary: true}
+	runClientThread(clientID, request.dataType, request.flags, stream, request.setup)
+	// A client that closed its stream was detached without a close code
+	conn.CloseWithError(0, "")
+}
+
+// acceptWebTransportJoins attaches every further stream of the connection to the session
+// as a participant, until the connection closes
+func acceptWebTransportJoins(ctx context.Context, conn *webtransport.Session, sessionID string) {
+	for {
+		next, err := conn.AcceptStream(ctx)
+		if err != nil {
+			return
+		}
+		stream := &wtStream{Stream: next, conn: conn}
+		session, ok := lookupSession(sessionID)
+		if !ok {
+			sendError(stream, ErrSessionNotFound)
+			stream.Close()
+			continue
+		}
+		go func() {
+			joinSession(session, genID(), stream)
+			stream.Close()
+		}()
+	}
+}
+
+// startWebTransportServer serves the WebTransport endpoint over QUIC on a UDP port until
+// shutdown is requested; an empty port disables it
+func startWebTransportServer(ctx context.Context, wg *sync.WaitGroup, port string) {
+	defer wg.Done()
+	if port == "" {
+		return
+	}
+	server := &webtransport.Server{
+		H3:          http3.Server{Addr: ":" + port},
+		CheckOrigin: checkOrigin,
+	}
+	server.H3.Handler = newWebTransportHandler(server)
+	fmt.Printf("WebTransport server listening on UDP port %s\n", port)
+	go func() {
+		if err := server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile); err != nil && ctx.Err() == nil {
+			fmt.Println("WebTransport server error:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	server.Close()
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrWorkloadNotFound is returned for workload names without a file
+var ErrWorkloadNotFound = errors.New("workload not found")
+
+// maxWorkloadFileBytes caps an uploaded workload file
+const maxWorkloadFileBytes = 64 << 10
+
+// Key distributions a workload draws keys from
+const (
+	distUniform    = "uniform"
+	distSequential = "sequential" // 0, 1, 2, ... wrapping at the range
+	distReverse    = "reverse"    // range-1, range-2, ...
+	distZipf       = "zipf"       // a few hot keys, most keys rare
+	distNormal     = "normal"     // clustered around the middle of the range
+)
+
+// workloadOps are the commands a workload mix may contain; each takes one key
+var workloadOps = map[string]bool{"insert": true, "remove": true, "find": true}
+
+// Workload is a performance scenario read from a YAML file in config.WorkloadDir,
+// so the same op mix can be shared and replayed by name
+//
+//	description: Mostly reads of a few hot keys
+//	type: btree
+//	params: {order: 4}
+//	seed: 7
+//	prefill: 200
+//	operations: 1000
+//	rate: 200
+//	keys: {distribution: zipf, range: 1000, skew: 1.3}
+//	mix: {insert: 20, find: 75, remove: 5}
+type Workload struct {
+	Name        string `yaml:"-" json:"name"` // the file name without .yaml
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Type and Params, when set, are the structure the scenario was written for
+	Type   string            `yaml:"type" json:"type,omitempty"`
+	Params map[string]string `yaml:"params" json:"params,omitempty"`
+	// Seed fixes the generated keys; 0 picks one per run
+	Seed int64 `yaml:"seed" json:"seed,omitempty"`
+	// Prefill inserts this many keys before the mix starts
+	Prefill    int          `yaml:"prefill" json:"prefill,omitempty"`
+	Operations int          `yaml:"operations" json:"operations"`
+	Rate       int          `yaml:"rate" json:"rate"` // operations per second
+	Keys       workloadKeys `yaml:"keys" json:"keys"`
+	// Mix weighs the operations after the prefill, e.g. {insert: 70, find: 30}
+	Mix map[string]int `yaml:"mix" json:"mix"`
+}
+
+// workloadKeys describes how a workload picks keys
+type workloadKeys struct {
+	Distribution string  `yaml:"distribution" json:"distribution"`
+	Range        int     `yaml:"range" json:"range"`         // keys are drawn from 0 to Range-1
+	Skew         float64 `yaml:"skew" json:"skew,omitempty"` // zipf exponent, above 1
+}
+
+// validate fills the defaults of a workload and checks its fields
+func (w *Workload) validate() error {
+	if w.Type != "" {
+		if _, ok := lookupDataStructure(w.Type); !ok {
+			return &ValidationError{"Unknown type: " + w.Type}
+		}
+	}
+	if w.Operations < 1 || w.Prefill < 0 || w.Prefill+w.Operations > maxGenKeys {
+		return &ValidationError{fmt.Sprintf("Invalid operations. Prefill and operations must total 1 to %d", maxGenKeys)}
+	}
+	if w.Rate == 0 {
+		w.Rate = genInsertsPerSecond
+	}
+	if w.Rate < 1 || w.Rate > 10000 {
+		return &ValidationError{"Invalid rate. Must be 1 to 10000 operations per second"}
+	}
+	if w.Keys.Distribution == "" {
+		w.Keys.Distribution = distUniform
+	}
+	switch w.Keys.Distribution {
+	case distUniform, distSequential, distReverse, distNormal:
+	case distZipf:
+		if w.Keys.Skew == 0 {
+			w.Keys.Skew = 1.2
+		}
+		if w.Keys.Skew <= 1 {
+			return &ValidationError{"Invalid skew. Must be above 1"}
+		}
+	default:
+		return &ValidationError{"Invalid distribution. Use uniform, sequential, reverse, zipf or normal"}
+	}
+	if w.Keys.Range == 0 {
+		w.Keys.Range = max((w.Prefill+w.Operations)*10, 100)
+	}
+	if w.Keys.Range < 1 {
+		return &ValidationError{"Invalid key range. Must be positive"}
+	}
+	if len(w.Mix) == 0 {
+		w.Mix = map[string]int{"insert": 1}
+	}
+	total := 0
+	for op, weight := range w.Mix {
+		if !workloadOps[op] {
+			return &ValidationError{"Invalid mix operation: " + op + ". Use insert, remove or find"}
+		}
+		if weight < 0 {
+			return &ValidationError{"Invalid mix weight of " + op}
+		}
+		total += weight
+	}
+	if total == 0 {
+		return &ValidationError{"Invalid mix. Weights must not all be zero"}
+	}
+	return nil
+}
+
+// parseWorkload decodes and validates a workload file
+func parseWorkload(name string, data []byte) (*Workload, error) {
+	w := &Workload{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(w); err != nil && err != io.EOF {
+		return nil, &ValidationError{"Invalid workload file: " + err.Error()}
+	}
+	w.Name = name
+	return w, w.validate()
+}
+
+// workloadPath returns the file of a workload name
+func workloadPath(name string) (string, error) {
+	if !validTreeName.MatchString(name) {
+		return "", &ValidationError{"Invalid workload name. Use 1-64 letters, digits, '_' or '-'"}
+	}
+	return filepath.Join(config.WorkloadDir, name+".yaml"), nil
+}
+
+// loadWorkload reads a workload by name; files are read on every use, so a
+// workload dropped into the directory is available without a restart
+func loadWorkload(name string) (*Workload, error) {
+	path, err := workloadPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrWorkloadNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseWorkload(name, data)
+}
+
+// listWorkloads reads every workload file, skipping those that do not parse
+func listWorkloads() ([]*Workload, error) {
+	paths, err := filepath.Glob(filepath.Join(config.WorkloadDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Workload, 0, len(paths))
+	for _, path := range paths {
+		w, err := loadWorkload(strings.TrimSuffix(filepath.Base(path), ".yaml"))
+		if err != nil {
+			fmt.Printf("Skipping workload %s: %v\n", path, err)
+			continue
+		}
+		list = append(list, w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// nextKey draws the key of the i-th operation
+func (k workloadKeys) nextKey(rng *rand.Rand, zipf *rand.Zipf, i int) int {
+	switch k.Distribution {
+	case distSequential:
+		return i % k.Range
+	case distReverse:
+		return k.Range - 1 - i%k.Range
+	case distZipf:
+		return int(zipf.Uint64())
+	case distNormal:
+		key := int(math.Round(rng.NormFloat64()*float64(k.Range)/6 + float64(k.Range)/2))
+		return min(max(key, 0), k.Range-1)
+	default:
+		return rng.Intn(k.Range)
+	}
+}
+
+// formatKey writes a drawn key in the key type of a structure
+func formatKey(keyType string, key int) string {
+	switch keyType {
+	case keyFloat:
+		return strconv.FormatFloat(float64(key)/2, 'g', -1, 64)
+	case keyString:
+		return fmt.Sprintf("k%05d", key)
+	default:
+		return strconv.Itoa(key)
+	}
+}
+
+// commands returns the command lines of a workload run: the prefill inserts, then the mix
+// The same workload, seed and key type always give the same lines
+func (w *Workload) commands(seed int64, keyType string) []string {
+	rng := rand.New(rand.NewSource(seed))
+	var zipf *rand.Zipf
+	if w.Keys.Distribution == distZipf {
+		zipf = rand.NewZipf(rng, w.Keys.Skew, 1, uint64(w.Keys.Range-1))
+	}
+
+	ops := make([]string, 0, len(w.Mix))
+	total := 0
+	for op, weight := range w.Mix {
+		ops = append(ops, op)
+		total += weight
+	}
+	sort.Strings(ops) // map order would change the lines between runs
+
+	lines := make([]string, 0, w.Prefill+w.Operations)
+	for i := 0; i < w.Prefill+w.Operations; i++ {
+		op := "insert"
+		if i >= w.Prefill {
+			pick := rng.Intn(total)
+			for _, candidate := range ops {
+				if pick < w.Mix[candidate] {
+					op = candidate
+					break
+				}
+				pick -= w.Mix[candidate]
+			}
+		}
+		lines = append(lines, op+" "+formatKey(keyType, w.Keys.nextKey(rng, zipf, i)))
+	}
+	return lines
+}
+
+// handleWorkloads serves GET /api/v1/workloads
+func handleWorkloads(w http.ResponseWriter, r *http.Request) {
+	list, err := listWorkloads()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, list)
+}
+
+// handleWorkloadFile serves GET /api/v1/workloads/{name}, the YAML file itself
+func handleWorkloadFile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := loadWorkload(name); err != nil {
+		httpError(w, err)
+		return
+	}
+	path, _ := workloadPath(name)
+	w.Header().Set("Content-Type", "application/yaml")
+	http.ServeFile(w, r, path)
+}
+
+// handleWorkloadUpload serves PUT /admin/workloads/{name}, storing a validated workload file
+func handleWorkloadUpload(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	path, err := workloadPath(name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWorkloadFileBytes))
+	if err != nil {
+		httpError(w, &ValidationError{"Workload file too large"})
+		return
+	}
+	workload, err := parseWorkload(name, data)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if err := os.MkdirAll(config.WorkloadDir, 0o755); err != nil {
+		httpError(w, err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		httpError(w, err)
+		return
+	}
+	writeJSON(w, workload)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -10,39 +11,60 @@ import (
 type WebSocketWrapper struct {
 	*websocket.Conn
 	writeMutex sync.Mutex
+	pending    []byte         // rest of a message larger than the last Read buffer
+	encoder    messageEncoder // how messages are sent; nil sends JSON text
 }
 
 // Read implements io.Reader
-// Reads one WebSocket message and returns its data
+// Reads one WebSocket message and returns its data; a message larger than p is
+// returned over several reads
 func (ws *WebSocketWrapper) Read(p []byte) (int, error) {
-	_, data, err := ws.Conn.ReadMessage()
-	if err != nil {
-		return 0, err
+	if len(ws.pending) == 0 {
+		_, data, err := ws.Conn.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		ws.pending = data
 	}
 
-	// Copy data to the provided buffer
-	n := copy(p, data)
-	if n < len(data) {
-		// Buffer too small - this is a limitation of the io.Reader interface
-		// In practice, make sure your read buffer is large enough
-		return n, nil
-	}
+	// Copy data to the provided buffer, keeping what does not fit for the next read
+	n := copy(p, ws.pending)
+	ws.pending = ws.pending[n:]
 	return n, nil
 }
 
 // Write implements io.Writer
-// Writes data as a WebSocket text message (thread-safe)
+// Writes one encoded message as a WebSocket message, text unless the client chose a
+// binary encoding (thread-safe)
 func (ws *WebSocketWrapper) Write(p []byte) (int, error) {
 	ws.writeMutex.Lock()
 	defer ws.writeMutex.Unlock()
 
-	err := ws.Conn.WriteMessage(websocket.TextMessage, p)
+	if timeout := writeTimeout(); timeout > 0 {
+		ws.Conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	err := ws.Conn.WriteMessage(encoderOf(ws).FrameType(), p)
 	if err != nil {
 		return 0, err
 	}
 	return len(p), nil
 }
 
+// Encoder returns the encoding the client chose with encoding=
+func (ws *WebSocketWrapper) Encoder() messageEncoder {
+	return ws.encoder
+}
+
+// CloseWithCode sends a close frame with the code and reason, then closes the connection
+func (ws *WebSocketWrapper) CloseWithCode(code int, reason string) error {
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
+
+	message := websocket.FormatCloseMessage(code, reason)
+	ws.Conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	return ws.Conn.Close()
+}
+
 // WrapWebSocket creates a new WebSocketWrapper
 func WrapWebSocket(conn *websocket.Conn) *WebSocketWrapper {
 	return &WebSocketWrapper{Conn: conn}